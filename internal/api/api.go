@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -89,6 +90,21 @@ func UnauthorizedResponse(w http.ResponseWriter, r *http.Request) {
 	ErrorResponse(w, r, http.StatusUnauthorized, forbiddenMsg)
 }
 
+// ForbiddenResponse responds to authenticated requests missing the scope required by the
+// route.
+func ForbiddenResponse(w http.ResponseWriter, r *http.Request, scope string) {
+	logger := logging.LoggerFromContext(r.Context())
+
+	const forbiddenMsg string = "request forbidden"
+
+	logger.Info(forbiddenMsg, slog.String("requiredScope", scope))
+	w.Header().Set(
+		"WWW-Authenticate",
+		fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope),
+	)
+	ErrorResponse(w, r, http.StatusForbidden, fmt.Sprintf("%s: requires scope %s", forbiddenMsg, scope))
+}
+
 func RespondWithJSON(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -180,6 +196,24 @@ func TimeoutResponse(ctx context.Context, w http.ResponseWriter, r *http.Request
 	ErrorResponse(w, r, http.StatusRequestTimeout, timeoutMsg)
 }
 
+func RateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	const rateLimitMsg string = "rate limit exceeded, please try again later"
+
+	logger := logging.LoggerFromContext(r.Context())
+	logger.Info(rateLimitMsg, slog.Duration("retryAfter", retryAfter))
+	SetRetryAfter(w, retryAfter)
+	ErrorResponse(w, r, http.StatusTooManyRequests, rateLimitMsg)
+}
+
+// SetRetryAfter sets the Retry-After header to the duration in whole seconds, rounded up. The
+// header is not set for durations of zero or less.
+func SetRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
 func NotFoundResponse(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	logger := logging.LoggerFromContext(ctx)
 	logger.LogAttrs(ctx, slog.LevelInfo, notFoundMsg)
@@ -323,6 +357,19 @@ func ReadOptionalQueryString(qs url.Values, key string) *string {
 	return &s
 }
 
+// ReadQueryStrings reads all values of a repeated query parameter, e.g. ?tag=a&tag=b. Empty
+// values are skipped.
+func ReadQueryStrings(qs url.Values, key string) []string {
+	values := make([]string, 0, len(qs[key]))
+	for _, s := range qs[key] {
+		if s == "" {
+			continue
+		}
+		values = append(values, s)
+	}
+	return values
+}
+
 func ReadQueryNullString(qs url.Values, key string, v *validator.Validator) sql.NullString {
 	s := qs.Get(key)
 	if s == "" {
@@ -412,6 +459,20 @@ func ParseQueryBoolean(qs url.Values, key string, v *validator.Validator) func()
 	}
 }
 
+func ParseQueryFloat32(qs url.Values, key string, v *validator.Validator) func() (float32, bool) {
+	return func() (float32, bool) {
+		s := qs.Get(key)
+		if s == "" {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			v.AddError(key, fmt.Sprintf("unable to parse value: %s", err.Error()))
+		}
+		return float32(f), true
+	}
+}
+
 func ParseQueryDate(qs url.Values, key string, v *validator.Validator) func() (time.Time, bool) {
 	return func() (time.Time, bool) {
 		s := qs.Get(key)
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// OAuth 2.0 error codes of RFC 6749.
+const (
+	OAuthInvalidRequest = "invalid_request"
+	OAuthInvalidClient  = "invalid_client"
+)
+
+// OAuthError is the error response of OAuth 2.0 endpoints, as defined by RFC 6749, which clients
+// of the endpoints expect instead of the ErrorMessage of other endpoints.
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func OAuthErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	code string,
+	description string,
+) {
+	ctx := r.Context()
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"writing oauth error response",
+		slog.Int("status", status),
+		slog.String("error", code),
+		slog.String("description", description),
+	)
+	RespondWithJSON(
+		w,
+		r,
+		status,
+		OAuthError{Error: code, ErrorDescription: description},
+		http.Header{"Cache-Control": []string{"no-store"}},
+	)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+type principalKey string
+
+const PrincipalKey principalKey = "principalKey"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject is the sub claim of the access token, identifying the user who acted.
+	Subject string `json:"subject"`
+	// Scopes are the scopes granted to the caller.
+	Scopes []string `json:"scopes"`
+	// SessionID is the sid claim of the access token, identifying the login session the token
+	// was issued to. It is empty for callers without a session.
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// HasScope reports whether the principal has been granted the scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// ContextWithPrincipal returns a copy of the context carrying the principal.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller of the request. False is returned for
+// unauthenticated requests.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(PrincipalKey).(Principal)
+	return principal, ok
+}
+
+// RequireScope returns a middleware rejecting requests from principals missing any of the
+// scopes. The middleware must be placed after the middleware authenticating the principal.
+func RequireScope(scopes ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				UnauthorizedResponse(w, r)
+				return
+			}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					ForbiddenResponse(w, r, scope)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
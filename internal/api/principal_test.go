@@ -0,0 +1,45 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	handler := api.RequireScope("blog:write")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	tests := []struct {
+		name      string
+		principal *api.Principal
+		expected  int
+	}{
+		{"Unauthenticated", nil, http.StatusUnauthorized},
+		{"MissingScope", &api.Principal{Subject: "a", Scopes: []string{"blog:read"}}, http.StatusForbidden},
+		{"Granted", &api.Principal{Subject: "a", Scopes: []string{"media:read", "blog:write"}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.principal != nil {
+				req = req.WithContext(api.ContextWithPrincipal(req.Context(), *tt.principal))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+			if tt.expected == http.StatusForbidden {
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "insufficient_scope")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often RateLimiter removes the buckets of keys that have been idle long
+// enough to be full again.
+const pruneInterval = time.Minute
+
+// RateLimiter limits the number of events per key, e.g. per client IP address, with a token
+// bucket per key. Buckets are kept in memory, so the limit applies per application instance.
+type RateLimiter struct {
+	mu sync.Mutex
+	// rate is the number of tokens added to a bucket per second.
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing limit events per key within the window. Up to
+// limit events may happen in a burst, after which events are allowed at an even pace.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		rate:    float64(limit) / window.Seconds(),
+		burst:   float64(limit),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow records an event for the key, and reports whether it is within the limit. If not, the
+// returned duration is how long until the next event would be allowed.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration(math.Ceil((1-b.tokens)/l.rate*1000)) * time.Millisecond
+		return false, wait
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ClientIP returns the IP address of the client making the request. If header is set, e.g.
+// to "X-Forwarded-For", the last address in the header is used, as added by the reverse proxy
+// in front of the application. The header must only be set when such a proxy is present, as
+// clients can set any header themselves.
+func ClientIP(r *http.Request, header string) string {
+	if header != "" {
+		values := strings.Split(r.Header.Get(header), ",")
+		if ip := strings.TrimSpace(values[len(values)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
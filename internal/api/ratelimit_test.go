@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	t.Run("Burst", func(t *testing.T) {
+		for range 2 {
+			ok, _ := limiter.Allow("a")
+			assert.True(t, ok)
+		}
+		ok, wait := limiter.Allow("a")
+		assert.False(t, ok)
+		assert.Equal(t, 30*time.Second, wait)
+
+		ok, _ = limiter.Allow("b")
+		assert.True(t, ok, "keys are limited independently")
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		now = now.Add(30 * time.Second)
+		ok, _ := limiter.Allow("a")
+		assert.True(t, ok)
+		ok, _ = limiter.Allow("a")
+		assert.False(t, ok)
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		limiter.Allow("c")
+		assert.Len(t, limiter.buckets, 1)
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	r.RemoteAddr = "192.0.2.1:51234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+
+	assert.Equal(t, "192.0.2.1", ClientIP(r, ""))
+	assert.Equal(t, "203.0.113.9", ClientIP(r, "X-Forwarded-For"))
+	assert.Equal(t, "192.0.2.1", ClientIP(r, "X-Real-IP"))
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+)
+
+// auditRetention periodically deletes the audit events older than the retention period.
+// Running the job on several instances is safe, as deleting the events is idempotent.
+type auditRetention struct {
+	*db.PeriodicWorker
+	audit     repo.AuditService
+	retention time.Duration
+	now       func() time.Time
+	logger    *slog.Logger
+}
+
+// newAuditRetention returns the job pruning the audit events. The job is disabled, keeping the
+// events forever, if the retention or the interval is not positive.
+func newAuditRetention(
+	audit repo.AuditService,
+	retention time.Duration,
+	interval time.Duration,
+	logger *slog.Logger,
+) *auditRetention {
+	j := &auditRetention{
+		audit:     audit,
+		retention: retention,
+		now:       time.Now,
+		logger: logger.With(slog.Group(
+			"auditRetention",
+			slog.Duration("retention", retention),
+			slog.Duration("interval", interval),
+		)),
+	}
+	if retention <= 0 {
+		interval = 0
+	}
+	j.PeriodicWorker = db.NewPeriodicWorker("audit retention", interval, j.run, j.logger)
+
+	return j
+}
+
+func (j *auditRetention) run(ctx context.Context) {
+	if _, err := j.audit.Prune(ctx, j.now().Add(-j.retention)); err != nil && ctx.Err() == nil {
+		j.logger.LogAttrs(
+			ctx, slog.LevelError, "unable to prune audit events", slog.String("error", err.Error()),
+		)
+	}
+}
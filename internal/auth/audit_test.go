@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAudit struct {
+	mu      sync.Mutex
+	befores []time.Time
+}
+
+func (f *fakeAudit) Record(ctx context.Context, input repo.AuditEventInput) {}
+
+func (f *fakeAudit) List(
+	ctx context.Context,
+	filter data.AuditEventFilter,
+) ([]*repo.AuditEvent, *data.Metadata, error) {
+	return nil, &data.Metadata{}, nil
+}
+
+func (f *fakeAudit) Prune(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.befores = append(f.befores, before)
+	return 1, nil
+}
+
+func (f *fakeAudit) prunes() []time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Time(nil), f.befores...)
+}
+
+func TestAuditRetention(t *testing.T) {
+	logger := testsuite.NewTestLogger()
+
+	t.Run("PrunesBeforeRetention", func(t *testing.T) {
+		audit := &fakeAudit{}
+		now := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+		job := newAuditRetention(audit, 30*24*time.Hour, time.Hour, &logger)
+		job.now = func() time.Time { return now }
+
+		job.run(context.Background())
+		assert.Equal(t, []time.Time{time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}, audit.prunes())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		for _, retention := range []time.Duration{0, time.Hour} {
+			audit := &fakeAudit{}
+			interval := time.Millisecond
+			if retention > 0 {
+				interval = 0
+			}
+			job := newAuditRetention(audit, retention, interval, &logger)
+
+			job.Start(context.Background())
+			job.Stop()
+			assert.Empty(t, audit.prunes())
+		}
+	})
+}
@@ -2,12 +2,20 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	authconfig "github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/r3d5un/islandwind/internal/auth/mail"
+	"github.com/r3d5un/islandwind/internal/auth/oidc"
 	"github.com/r3d5un/islandwind/internal/auth/repo"
 	"github.com/r3d5un/islandwind/internal/config"
 	"github.com/r3d5un/islandwind/internal/logging"
@@ -15,6 +23,10 @@ import (
 
 const moduleName string = "auth"
 
+// minSigningSecretLength is the minimum length in bytes of the refresh and challenge signing
+// secrets.
+const minSigningSecretLength = 32
+
 type Module struct {
 	name       string
 	logger     *slog.Logger
@@ -23,12 +35,53 @@ type Module struct {
 	mux        *http.ServeMux
 	instanceID uuid.UUID
 	repo       repo.Repository
+	// clients are the services allowed to introspect and revoke tokens.
+	clients repo.ClientService
+	// denylist keeps the revoked access tokens of the repository in sync across instances.
+	denylist *denylistSync
+	// auditRetention deletes audit events past the retention period.
+	auditRetention *auditRetention
+	// tokenCleanup deletes expired refresh tokens.
+	tokenCleanup *tokenCleanup
 }
 
 func NewModule(ctx context.Context, cfg *config.Config, db *pgxpool.Pool) (*Module, error) {
 	ctx, logger := logging.ContextLogger(ctx, slog.Group("module", slog.String("name", moduleName)))
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "setting up module")
+	keySet, err := loadKeys(ctx, cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	authCfg := cfg.Auth
+	authCfg.RefreshSigningSecret, err = loadSigningSecret(
+		ctx, "refresh signing secret", cfg.Auth.RefreshSigningSecret,
+	)
+	if err != nil {
+		return nil, err
+	}
+	authCfg.ChallengeSigningSecret, err = loadSigningSecret(
+		ctx, "challenge signing secret", cfg.Auth.ChallengeSigningSecret,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if authCfg.ChallengeSigningSecret == authCfg.RefreshSigningSecret {
+		return nil, errors.New("challenge signing secret must differ from refresh signing secret")
+	}
+	clients, err := cfg.Auth.ClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	mailer, err := newMailer(cfg.Auth.Mail)
+	if err != nil {
+		return nil, err
+	}
+	oidcClient, err := newOIDCClient(ctx, cfg.Auth.OIDC)
+	if err != nil {
+		return nil, err
+	}
+
 	module := Module{
 		name:   moduleName,
 		logger: logger,
@@ -37,19 +90,152 @@ func NewModule(ctx context.Context, cfg *config.Config, db *pgxpool.Pool) (*Modu
 		repo: repo.NewRepository(
 			db,
 			new(time.Duration(cfg.DB.TimeoutSeconds)*time.Second),
-			cfg.Auth,
+			authCfg,
+			keySet,
+			mailer,
+			oidcClient,
 		),
+		clients: repo.NewClientRepository(clients),
+	}
+	module.denylist = newDenylistSync(module.repo.Denylist, logger)
+	module.auditRetention = newAuditRetention(
+		module.repo.Audit,
+		cfg.Auth.Audit.Retention(),
+		cfg.Auth.Audit.PruneInterval(),
+		logger,
+	)
+	module.tokenCleanup = newTokenCleanup(
+		module.repo.Tokens,
+		cfg.Auth.RefreshTokenCleanup.Interval(),
+		cfg.Auth.RefreshTokenCleanup.BatchSize,
+		logger,
+	)
+
+	// The basic authentication credentials from the configuration are used for the first user,
+	// so that a new installation can be logged into.
+	created, err := module.repo.Users.EnsureInitialUser(
+		ctx,
+		cfg.BasicAuth.Username,
+		cfg.BasicAuth.Password,
+	)
+	switch {
+	case errors.Is(err, repo.ErrInsecureInitialPassword):
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"initial user not created, configure a basic auth password other than the username",
+			slog.Any("basicAuth", cfg.BasicAuth),
+		)
+	case err != nil:
+		return nil, err
+	case created:
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"created initial user from basic auth configuration, change the password",
+			slog.Any("basicAuth", cfg.BasicAuth),
+		)
 	}
 	logger.LogAttrs(ctx, slog.LevelInfo, "module setup complete")
 
 	return &module, nil
 }
 
+// loadKeys reads the keys signing access tokens from the configured files. A key is generated if
+// no signing key is configured, so that development setups work without keys.
+func loadKeys(ctx context.Context, cfg authconfig.Config) (*keys.Set, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if cfg.SigningKeyFile == "" {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"no signing key configured, generating a key valid until shutdown",
+		)
+		return keys.Generate()
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "loading signing keys", slog.Any("config", cfg))
+	keySet, err := keys.Load(cfg.SigningKeyFile, cfg.VerificationKeyFiles...)
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(
+		ctx, slog.LevelInfo, "signing keys loaded", slog.String("kid", keySet.SigningKeyID()),
+	)
+
+	return keySet, nil
+}
+
+// loadSigningSecret returns the configured HMAC secret with the name. A secret is generated if
+// none is configured, so that development setups work without a secret.
+func loadSigningSecret(ctx context.Context, name string, secret string) (string, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if secret == "" {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			fmt.Sprintf("no %s configured, generating a secret valid until shutdown", name),
+		)
+		generated := make([]byte, minSigningSecretLength)
+		_, _ = rand.Read(generated)
+		return hex.EncodeToString(generated), nil
+	}
+	if len(secret) < minSigningSecretLength {
+		return "", fmt.Errorf("%s must be at least %d bytes", name, minSigningSecretLength)
+	}
+
+	return secret, nil
+}
+
+// newMailer returns the mailer of the configured transport.
+func newMailer(cfg authconfig.MailConfig) (mail.Mailer, error) {
+	switch cfg.Transport {
+	case authconfig.FileTransport:
+		return mail.NewFileMailer(cfg.File.Dir, cfg.From)
+	case authconfig.SMTPTransport:
+		return mail.NewSMTPMailer(cfg.SMTP, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %q", cfg.Transport)
+	}
+}
+
+// newOIDCClient discovers the configured OpenID Connect provider. No client is returned if no
+// provider is configured.
+func newOIDCClient(ctx context.Context, cfg authconfig.OIDCConfig) (*oidc.Client, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if !cfg.Enabled() {
+		logger.LogAttrs(ctx, slog.LevelInfo, "no oidc provider configured")
+		return nil, nil
+	}
+	if _, err := cfg.Roles(); err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "discovering oidc provider", slog.Any("config", cfg))
+	client, err := oidc.NewClient(ctx, cfg, &http.Client{Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "oidc provider discovered")
+
+	return client, nil
+}
+
 func (m *Module) Start(ctx context.Context, mux *http.ServeMux) {
 	m.mux = mux
 	m.addRoutes(ctx)
+	m.denylist.Start(ctx)
+	m.auditRetention.Start(ctx)
+	m.tokenCleanup.Start(ctx)
 }
 
 func (m *Module) Shutdown() {
 	m.logger.LogAttrs(context.Background(), slog.LevelInfo, "shutting down module")
+	m.denylist.Stop()
+	m.auditRetention.Stop()
+	m.tokenCleanup.Stop()
+	m.repo.Emails.Wait()
 }
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSigningSecret(t *testing.T) {
+	valid := strings.Repeat("s", minSigningSecretLength)
+
+	testCases := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{name: "Empty", secret: ""},
+		{name: "Short", secret: valid[1:], wantErr: true},
+		{name: "Valid", secret: valid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := loadSigningSecret(context.Background(), "signing secret", tc.secret)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, len(secret), minSigningSecretLength)
+			if tc.secret != "" {
+				assert.Equal(t, tc.secret, secret)
+			}
+		})
+	}
+
+	t.Run("Generated", func(t *testing.T) {
+		// Each start without a configured secret generates another secret.
+		first, err := loadSigningSecret(context.Background(), "signing secret", "")
+		require.NoError(t, err)
+		second, err := loadSigningSecret(context.Background(), "signing secret", "")
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+}
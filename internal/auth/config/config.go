@@ -1,31 +1,388 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 )
 
 type Config struct {
-	SigningSecret        string `json:"signingSecret"`
+	// SigningKeyFile is the path to the PEM encoded private key signing access tokens. Ed25519
+	// keys sign with EdDSA, and RSA keys of at least 2048 bits sign with RS256. If empty, a key
+	// is generated at startup, and tokens are not valid after a restart or on other instances.
+	//
+	// Set through the ISLANDWIND_AUTH_SIGNINGKEYFILE environment variable.
+	SigningKeyFile string `json:"signingKeyFile"`
+	// VerificationKeyFiles are paths to PEM encoded keys that previously signed access tokens.
+	// Tokens signed by the keys are accepted, and the keys are published, so that the signing
+	// key can be rotated without invalidating issued tokens. Public keys are sufficient.
+	//
+	// Set through the ISLANDWIND_AUTH_VERIFICATIONKEYFILES environment variable, separating
+	// paths by commas.
+	VerificationKeyFiles []string `json:"verificationKeyFiles"`
+	// RefreshSigningSecret is the HMAC secret signing refresh tokens, which are only verified
+	// by the auth module. The secret must be at least 32 bytes. A secret valid until shutdown
+	// is generated if none is configured, logging out all users on restart.
+	//
+	// Set through the ISLANDWIND_AUTH_REFRESHSIGNINGSECRET environment variable.
 	RefreshSigningSecret string `json:"refreshSigningSecret"`
-	TokenIssuer          string `json:"tokenIssuer"`
+	// ChallengeSigningSecret is the HMAC secret signing the TOTP challenges issued after the
+	// password of a user with two-factor authentication is accepted. It must differ from the
+	// RefreshSigningSecret, and has the same length requirement. Challenges are never signed
+	// with the published keys, so that they cannot be mistaken for access tokens.
+	//
+	// Set through the ISLANDWIND_AUTH_CHALLENGESIGNINGSECRET environment variable.
+	ChallengeSigningSecret string `json:"challengeSigningSecret"`
+	TokenIssuer            string `json:"tokenIssuer"`
+	// Lockout throttles failed login attempts.
+	Lockout LockoutConfig `json:"lockout"`
+	// Clients are the credentials of the services allowed to introspect and revoke tokens, as
+	// "id:secret" pairs. Secrets should be long random strings.
+	//
+	// Set through the ISLANDWIND_AUTH_CLIENTS environment variable, separating pairs by
+	// commas.
+	Clients []string `json:"clients"`
+	// Audit configures the retention of audit events.
+	Audit AuditConfig `json:"audit"`
+	// RefreshTokenCleanup configures the worker deleting expired refresh tokens.
+	RefreshTokenCleanup RefreshTokenCleanupConfig `json:"refreshTokenCleanup"`
+	// AllowedOrigins are the origins of the browser clients allowed to make credentialed
+	// cross-origin requests, sending the refresh token cookie. If empty, any origin may make
+	// requests without credentials.
+	//
+	// Set through the ISLANDWIND_AUTH_ALLOWEDORIGINS environment variable, separating origins
+	// by commas.
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// Mail configures the emails sent to users to verify their email address and to reset
+	// their password.
+	Mail MailConfig `json:"mail"`
+	// OIDC configures logging in with an external OpenID Connect provider.
+	OIDC OIDCConfig `json:"oidc"`
+}
+
+// ErrInvalidClient is returned when a client credential is not an "id:secret" pair.
+var ErrInvalidClient = errors.New("client credentials must be id:secret pairs")
+
+// ClientCredentials returns the secrets of the Clients by client ID.
+func (c Config) ClientCredentials() (map[string]string, error) {
+	credentials := make(map[string]string, len(c.Clients))
+	for _, client := range c.Clients {
+		id, secret, ok := strings.Cut(strings.TrimSpace(client), ":")
+		if !ok || id == "" || secret == "" {
+			return nil, ErrInvalidClient
+		}
+		credentials[id] = secret
+	}
+	return credentials, nil
 }
 
 func (c Config) LogValue() slog.Value {
-	return slog.GroupValue(slog.String("signingSecret", "omitted"))
+	return slog.GroupValue(
+		slog.String("signingKeyFile", c.SigningKeyFile),
+		slog.Any("verificationKeyFiles", c.VerificationKeyFiles),
+		slog.String("refreshSigningSecret", "omitted"),
+		slog.String("challengeSigningSecret", "omitted"),
+		slog.String("tokenIssuer", c.TokenIssuer),
+		slog.Any("lockout", c.Lockout),
+		slog.Int("clients", len(c.Clients)),
+		slog.Any("audit", c.Audit),
+		slog.Any("refreshTokenCleanup", c.RefreshTokenCleanup),
+		slog.Any("allowedOrigins", c.AllowedOrigins),
+		slog.Any("mail", c.Mail),
+		slog.Any("oidc", c.OIDC),
+	)
+}
+
+// LockoutConfig throttles failed login attempts, counted per username, per client IP address
+// and per user completing a TOTP challenge. After FreeAttempts failures, each failure delays
+// the next attempt by BaseDelaySeconds, doubling up to MaxDelaySeconds. Reaching the threshold
+// locks the username or IP address for DurationSeconds.
+type LockoutConfig struct {
+	// FreeAttempts is the number of failures allowed before attempts are delayed.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_FREEATTEMPTS environment variable.
+	FreeAttempts int `json:"freeAttempts"`
+	// BaseDelaySeconds is the delay after the first failure beyond the FreeAttempts.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_BASEDELAYSECONDS environment variable.
+	BaseDelaySeconds int `json:"baseDelaySeconds"`
+	// MaxDelaySeconds limits the delay between attempts before the lockout.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_MAXDELAYSECONDS environment variable.
+	MaxDelaySeconds int `json:"maxDelaySeconds"`
+	// Threshold is the number of failures locking a username, or the second factor of a user.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_THRESHOLD environment variable.
+	Threshold int `json:"threshold"`
+	// IPThreshold is the number of failures locking a client IP address. It should be higher
+	// than the Threshold, as many clients may share an address.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_IPTHRESHOLD environment variable.
+	IPThreshold int `json:"ipThreshold"`
+	// DurationSeconds is how long a lockout lasts.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_DURATIONSECONDS environment variable.
+	DurationSeconds int `json:"durationSeconds"`
+	// ResetSeconds is how long after the last failure the failures are forgotten.
+	//
+	// Set through the ISLANDWIND_AUTH_LOCKOUT_RESETSECONDS environment variable.
+	ResetSeconds int `json:"resetSeconds"`
+}
+
+func (c LockoutConfig) BaseDelay() time.Duration {
+	return time.Duration(c.BaseDelaySeconds) * time.Second
+}
+
+func (c LockoutConfig) MaxDelay() time.Duration {
+	return time.Duration(c.MaxDelaySeconds) * time.Second
+}
+
+func (c LockoutConfig) Duration() time.Duration {
+	return time.Duration(c.DurationSeconds) * time.Second
+}
+
+func (c LockoutConfig) Reset() time.Duration {
+	return time.Duration(c.ResetSeconds) * time.Second
+}
+
+// AuditConfig configures the retention job deleting audit events older than RetentionDays.
+type AuditConfig struct {
+	// RetentionDays is how long audit events are kept. Events are kept forever if it is not
+	// positive.
+	//
+	// Set through the ISLANDWIND_AUTH_AUDIT_RETENTIONDAYS environment variable.
+	RetentionDays int `json:"retentionDays"`
+	// PruneIntervalSeconds is how often events past the retention are deleted. The retention
+	// job is disabled if it is not positive.
+	//
+	// Set through the ISLANDWIND_AUTH_AUDIT_PRUNEINTERVALSECONDS environment variable.
+	PruneIntervalSeconds int `json:"pruneIntervalSeconds"`
+}
+
+func (c AuditConfig) Retention() time.Duration {
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+func (c AuditConfig) PruneInterval() time.Duration {
+	return time.Duration(c.PruneIntervalSeconds) * time.Second
+}
+
+// RefreshTokenCleanupConfig configures the worker deleting expired refresh tokens. Only one
+// instance of the application runs the worker at a time.
+type RefreshTokenCleanupConfig struct {
+	// IntervalSeconds is how often expired refresh tokens are deleted. The worker is disabled
+	// if it is not positive.
+	//
+	// Set through the ISLANDWIND_AUTH_REFRESHTOKENCLEANUP_INTERVALSECONDS environment variable.
+	IntervalSeconds int `json:"intervalSeconds"`
+	// BatchSize is the maximum number of refresh tokens deleted per transaction. Batches are
+	// deleted until no expired tokens remain. The worker is disabled if it is not positive.
+	//
+	// Set through the ISLANDWIND_AUTH_REFRESHTOKENCLEANUP_BATCHSIZE environment variable.
+	BatchSize int `json:"batchSize"`
+}
+
+func (c RefreshTokenCleanupConfig) Interval() time.Duration {
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+const (
+	SMTPTransport string = "smtp"
+	FileTransport string = "file"
+)
+
+// MailConfig configures how emails are sent, and the links they contain.
+type MailConfig struct {
+	// Transport selects how emails are sent, either "smtp", or "file" for development, which
+	// writes the emails to files instead.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_TRANSPORT environment variable.
+	Transport string `json:"transport"`
+	// From is the sender address of the emails, e.g. "islandwind <noreply@example.com>".
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_FROM environment variable.
+	From string `json:"from"`
+	// BaseURL is the URL of the web client, which the links of the emails point to.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_BASEURL environment variable.
+	BaseURL string `json:"baseUrl"`
+	// PasswordResetMinutes is how long password reset tokens are valid.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_PASSWORDRESETMINUTES environment variable.
+	PasswordResetMinutes int `json:"passwordResetMinutes"`
+	// EmailVerificationHours is how long email verification tokens are valid.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_EMAILVERIFICATIONHOURS environment variable.
+	EmailVerificationHours int        `json:"emailVerificationHours"`
+	SMTP                   SMTPConfig `json:"smtp"`
+	File                   FileConfig `json:"file"`
+}
+
+func (c MailConfig) PasswordResetDuration() time.Duration {
+	return time.Duration(c.PasswordResetMinutes) * time.Minute
+}
+
+func (c MailConfig) EmailVerificationDuration() time.Duration {
+	return time.Duration(c.EmailVerificationHours) * time.Hour
+}
+
+// SMTPConfig contains the configuration of the SMTP transport. STARTTLS is used if the server
+// supports it, and credentials are only sent over TLS or to localhost.
+type SMTPConfig struct {
+	// Host is the host name of the SMTP server.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_SMTP_HOST environment variable.
+	Host string `json:"host"`
+	// Port is the port of the SMTP server, usually 587 for submission.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_SMTP_PORT environment variable.
+	Port int `json:"port"`
+	// Username authenticates with the SMTP server. Authentication is skipped if empty.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_SMTP_USERNAME environment variable.
+	Username string `json:"username"`
+	// Password authenticates with the SMTP server.
+	//
+	// Field is safe for logging as the [SMTPConfig] contains a custom [SMTPConfig.LogValue] method.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_SMTP_PASSWORD environment variable.
+	Password string `json:"password"`
+}
+
+func (c SMTPConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", c.Host),
+		slog.Int("port", c.Port),
+		slog.String("username", c.Username),
+	)
+}
+
+// FileConfig contains the configuration of the file transport.
+type FileConfig struct {
+	// Dir is the directory emails are written to. It is created if it does not exist.
+	//
+	// Set through the ISLANDWIND_AUTH_MAIL_FILE_DIR environment variable.
+	Dir string `json:"dir"`
+}
+
+// OIDCConfig configures logging in with an OpenID Connect provider using the authorization code
+// flow with PKCE. Users are created on their first login, and their role is mapped from a claim
+// of the ID token on every login.
+type OIDCConfig struct {
+	// Issuer is the issuer URL of the provider, where the discovery document is published.
+	// Logging in with the provider is disabled if empty.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_ISSUER environment variable.
+	Issuer string `json:"issuer"`
+	// ClientID is the client ID registered with the provider.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_CLIENTID environment variable.
+	ClientID string `json:"clientId"`
+	// ClientSecret is the client secret registered with the provider.
+	//
+	// Field is safe for logging as the [OIDCConfig] contains a custom [OIDCConfig.LogValue] method.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_CLIENTSECRET environment variable.
+	ClientSecret string `json:"clientSecret"`
+	// RedirectURL is the URL of the callback endpoint registered with the provider, e.g.
+	// "https://example.com/api/v1/auth/oidc/callback".
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_REDIRECTURL environment variable.
+	RedirectURL string `json:"redirectUrl"`
+	// PostLoginURL is the URL of the web client users are sent to after logging in, with the
+	// refresh token set in a cookie. If empty, the callback responds with the tokens instead.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_POSTLOGINURL environment variable.
+	PostLoginURL string `json:"postLoginUrl"`
+	// Scopes are the scopes requested from the provider. The openid scope is always requested.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_SCOPES environment variable, separating scopes by
+	// commas.
+	Scopes []string `json:"scopes"`
+	// UsernameClaim is the claim of the ID token naming users created on their first login.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_USERNAMECLAIM environment variable.
+	UsernameClaim string `json:"usernameClaim"`
+	// RoleClaim is the claim of the ID token mapped to roles, either a string or an array of
+	// strings, e.g. "groups".
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_ROLECLAIM environment variable.
+	RoleClaim string `json:"roleClaim"`
+	// RoleMappings map values of the RoleClaim to roles, as "value:role" pairs. Users are given
+	// the most privileged role mapped from their claim values.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_ROLEMAPPINGS environment variable, separating pairs
+	// by commas.
+	RoleMappings []string `json:"roleMappings"`
+	// DefaultRole is the role of users without mapped claim values. Such users are denied if
+	// empty.
+	//
+	// Set through the ISLANDWIND_AUTH_OIDC_DEFAULTROLE environment variable.
+	DefaultRole string `json:"defaultRole"`
+}
+
+// ErrInvalidRoleMapping is returned when a role mapping is not a "value:role" pair of a known
+// role.
+var ErrInvalidRoleMapping = errors.New("role mappings must be value:role pairs of known roles")
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// Roles returns the roles of the RoleMappings by claim value.
+func (c OIDCConfig) Roles() (map[string]scope.Role, error) {
+	roles := make(map[string]scope.Role, len(c.RoleMappings))
+	for _, mapping := range c.RoleMappings {
+		// The value is cut at the last colon, as claim values may contain colons.
+		i := strings.LastIndex(mapping, ":")
+		if i < 0 {
+			return nil, ErrInvalidRoleMapping
+		}
+		value, role := strings.TrimSpace(mapping[:i]), scope.Role(strings.TrimSpace(mapping[i+1:]))
+		if value == "" || !role.Valid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRoleMapping, mapping)
+		}
+		roles[value] = role
+	}
+	if c.DefaultRole != "" && !scope.Role(c.DefaultRole).Valid() {
+		return nil, fmt.Errorf("%w: default role %q", ErrInvalidRoleMapping, c.DefaultRole)
+	}
+	return roles, nil
+}
+
+func (c OIDCConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("issuer", c.Issuer),
+		slog.String("clientId", c.ClientID),
+		slog.String("clientSecret", "omitted"),
+		slog.String("redirectUrl", c.RedirectURL),
+		slog.String("postLoginUrl", c.PostLoginURL),
+		slog.Any("scopes", c.Scopes),
+		slog.String("usernameClaim", c.UsernameClaim),
+		slog.String("roleClaim", c.RoleClaim),
+		slog.Any("roleMappings", c.RoleMappings),
+		slog.String("defaultRole", c.DefaultRole),
+	)
 }
 
-// BasicAuthConfig contains the username and password used in the basic authentication
-// for the HTTP server.
+// BasicAuthConfig contains the username and password of the initial user, created when no
+// users exist. Users log in with basic authentication against the stored users, so changing
+// the configuration after the first start has no effect. The initial user is not created if
+// the password is empty or equal to the username.
 type BasicAuthConfig struct {
-	// Username is the admin username.
+	// Username is the username of the initial user.
 	//
-	// Set through the ISLANDWIND_SERVER_AUTHENTICATION_USERNAME environment variable.
+	// Set through the ISLANDWIND_BASICAUTH_USERNAME environment variable.
 	Username string `json:"username"`
-	// Password is the admin password.
+	// Password is the password of the initial user.
 	//
 	// Field is safe for logging as the [BasicAuthConfig] contains a custom [BasicAuthConfig.LogValue] method.
 	//
-	// Set through the ISLANDWIND_SERVER_AUTHENTICATION_PASSWORD environment variable.
+	// Set through the ISLANDWIND_BASICAUTH_PASSWORD environment variable.
 	Password string `json:"password"`
 }
 
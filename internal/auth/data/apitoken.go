@@ -0,0 +1,308 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// APIToken is the database record of a personal access token.
+type APIToken struct {
+	ID     uuid.UUID `json:"id"     db:"id"`
+	UserID uuid.UUID `json:"userId" db:"user_id"`
+	Name   string    `json:"name"   db:"name"`
+	// TokenHash is the hex encoded SHA-256 hash of the token. The token itself is not stored.
+	TokenHash string `json:"-"      db:"token_hash"`
+	// Scopes limits the scopes of the token. The token is never granted scopes beyond the role
+	// of the user.
+	Scopes     []string            `json:"scopes"     db:"scopes"`
+	CreatedAt  time.Time           `json:"createdAt"  db:"created_at"`
+	ExpiresAt  sql.Null[time.Time] `json:"expiresAt"  db:"expires_at"`
+	LastUsedAt sql.Null[time.Time] `json:"lastUsedAt" db:"last_used_at"`
+	RevokedAt  sql.Null[time.Time] `json:"revokedAt"  db:"revoked_at"`
+}
+
+var apiTokenColumns = builder.ColumnsFrom(APIToken{})
+
+type APITokenInput struct {
+	UserID    uuid.UUID           `json:"userId"`
+	Name      string              `json:"name"`
+	TokenHash string              `json:"-"`
+	Scopes    []string            `json:"scopes"`
+	ExpiresAt sql.Null[time.Time] `json:"expiresAt"`
+}
+
+type APITokenFilter struct {
+	ID     sql.Null[uuid.UUID] `json:"id"`
+	UserID sql.Null[uuid.UUID] `json:"userId"`
+
+	LastSeen uuid.UUID `json:"lastSeen"`
+	PageSize int       `json:"pageSize"`
+}
+
+type APITokenModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *APITokenModel) insert(
+	ctx context.Context,
+	q db.Queryable,
+	input APITokenInput,
+) (*APIToken, error) {
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"user_id":    {V: input.UserID, Valid: true},
+			"name":       {V: input.Name, Valid: true},
+			"token_hash": {V: input.TokenHash, Valid: true},
+			"scopes":     {V: input.Scopes, Valid: true},
+			"expires_at": {V: input.ExpiresAt.V, Valid: input.ExpiresAt.Valid},
+		}).
+		Returning(apiTokenColumns...).
+		Into("auth.api_token")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	t, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "api token inserted", slog.String("id", t.ID.String()))
+
+	return &t, nil
+}
+
+func (m *APITokenModel) Insert(ctx context.Context, input APITokenInput) (*APIToken, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *APITokenModel) InsertTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	input APITokenInput,
+) (*APIToken, error) {
+	return m.insert(ctx, tx, input)
+}
+
+// SelectByHash selects the token with the hash, regardless of whether it is expired or revoked.
+func (m *APITokenModel) SelectByHash(ctx context.Context, hash string) (*APIToken, error) {
+	stmt, args := builder.From("auth.api_token").
+		Where(builder.NewGenericPredicate("token_hash", builder.Equal, hash)).
+		Select(apiTokenColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	t, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "api token selected", slog.String("id", t.ID.String()))
+
+	return &t, nil
+}
+
+func (m *APITokenModel) selectMany(
+	ctx context.Context,
+	q db.Queryable,
+	filter APITokenFilter,
+) ([]*APIToken, *Metadata, error) {
+	stmt, args := builder.From("auth.api_token").
+		Where(
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("user_id", builder.Equal, filter.UserID),
+			builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen),
+		).
+		OrderBy(builder.OrderBy{Column: "id", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(apiTokenColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	tokens := make([]*APIToken, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		t, err := m.scan(rows)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		tokens[i] = &t
+		i++
+	}
+	tokens = tokens[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(tokens),
+	}
+	if len(tokens) > 0 {
+		metadata.LastSeen = tokens[metadata.ResponseLength-1].ID
+		metadata.Next = true
+	}
+
+	return tokens, &metadata, nil
+}
+
+func (m *APITokenModel) SelectMany(
+	ctx context.Context,
+	filter APITokenFilter,
+) ([]*APIToken, *Metadata, error) {
+	return m.selectMany(ctx, m.DB, filter)
+}
+
+func (m *APITokenModel) SelectManyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	filter APITokenFilter,
+) ([]*APIToken, *Metadata, error) {
+	return m.selectMany(ctx, tx, filter)
+}
+
+// revoke revokes the token of the user. ErrRecordNotFound is returned if the user has no
+// token with the ID, or the token is already revoked.
+func (m *APITokenModel) revoke(
+	ctx context.Context,
+	q db.Queryable,
+	id uuid.UUID,
+	userID uuid.UUID,
+) (*APIToken, error) {
+	stmt, args, err := builder.
+		Update("auth.api_token").
+		Where(
+			builder.NewGenericPredicate("id", builder.Equal, id),
+			builder.NewGenericPredicate("user_id", builder.Equal, userID),
+			builder.NewPredicate("revoked_at IS NULL", nil),
+		).
+		Returning(apiTokenColumns...).
+		Set(builder.NewAssignment("revoked_at = NOW()", nil))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	t, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "api token revoked")
+
+	return &t, nil
+}
+
+func (m *APITokenModel) Revoke(
+	ctx context.Context,
+	id uuid.UUID,
+	userID uuid.UUID,
+) (*APIToken, error) {
+	return m.revoke(ctx, m.DB, id, userID)
+}
+
+func (m *APITokenModel) RevokeTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	userID uuid.UUID,
+) (*APIToken, error) {
+	return m.revoke(ctx, tx, id, userID)
+}
+
+// Touch records that the token was used. The timestamp is only written once per minute, so
+// that busy automation does not update the row on every request.
+func (m *APITokenModel) Touch(ctx context.Context, id uuid.UUID) error {
+	const stmt string = `
+UPDATE auth.api_token
+SET last_used_at = NOW()
+WHERE id = $1::UUID
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := m.DB.Exec(ctx, stmt, id); err != nil {
+		return db.HandleError(ctx, err)
+	}
+
+	return nil
+}
+
+func (m *APITokenModel) scan(row pgx.Row) (APIToken, error) {
+	var t APIToken
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.TokenHash,
+		&t.Scopes,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.RevokedAt,
+	)
+	if err != nil {
+		return t, err
+	}
+	return t, nil
+}
@@ -0,0 +1,87 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	user, err := models.Users.Insert(ctx, data.UserInput{
+		Username:     "ci",
+		PasswordHash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+	})
+	require.NoError(t, err)
+
+	var token *data.APIToken
+
+	t.Run("Insert", func(t *testing.T) {
+		inserted, err := models.APITokens.Insert(ctx, data.APITokenInput{
+			UserID:    user.ID,
+			Name:      "publishing",
+			TokenHash: "7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069",
+			Scopes:    []string{"blog:write", "media:write"},
+			ExpiresAt: sql.Null[time.Time]{V: time.Now().Add(time.Hour), Valid: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"blog:write", "media:write"}, inserted.Scopes)
+		assert.False(t, inserted.LastUsedAt.Valid)
+		assert.False(t, inserted.RevokedAt.Valid)
+
+		token = inserted
+	})
+
+	t.Run("SelectByHash", func(t *testing.T) {
+		selected, err := models.APITokens.SelectByHash(ctx, token.TokenHash)
+		require.NoError(t, err)
+		assert.Equal(t, token.ID, selected.ID)
+
+		_, err = models.APITokens.SelectByHash(ctx, "unknown")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("SelectMany", func(t *testing.T) {
+		tokens, _, err := models.APITokens.SelectMany(ctx, data.APITokenFilter{
+			UserID:   sql.Null[uuid.UUID]{V: user.ID, Valid: true},
+			PageSize: 10,
+		})
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		err := models.APITokens.Touch(ctx, token.ID)
+		require.NoError(t, err)
+
+		selected, err := models.APITokens.SelectByHash(ctx, token.TokenHash)
+		require.NoError(t, err)
+		assert.True(t, selected.LastUsedAt.Valid)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		// Tokens cannot be revoked by other users.
+		_, err := models.APITokens.Revoke(ctx, token.ID, uuid.New())
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+
+		revoked, err := models.APITokens.Revoke(ctx, token.ID, user.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked.RevokedAt.Valid)
+
+		_, err = models.APITokens.Revoke(ctx, token.ID, user.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+}
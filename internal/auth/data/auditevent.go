@@ -0,0 +1,255 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// AuditEvent is the database record of a security relevant event. Events are never updated.
+type AuditEvent struct {
+	// ID is a UUIDv7, ordering the events by the time they occurred.
+	ID         uuid.UUID `json:"id"         db:"id"`
+	OccurredAt time.Time `json:"occurredAt" db:"occurred_at"`
+	EventType  string    `json:"eventType"  db:"event_type"`
+	Outcome    string    `json:"outcome"    db:"outcome"`
+	// ActorID is the user acting, or the user the event concerns if the actor is unknown.
+	ActorID   uuid.NullUUID    `json:"actorId"   db:"actor_id"`
+	ClientIP  sql.Null[string] `json:"clientIp"  db:"client_ip"`
+	UserAgent sql.Null[string] `json:"userAgent" db:"user_agent"`
+	SessionID uuid.NullUUID    `json:"sessionId" db:"session_id"`
+	// TokenIDs are the IDs of the tokens the event concerns.
+	TokenIDs []uuid.UUID `json:"tokenIds" db:"token_ids"`
+	// Details are additional event specific fields, e.g. the reason of a failure.
+	Details map[string]any `json:"details" db:"details"`
+}
+
+var auditEventColumns = builder.ColumnsFrom(AuditEvent{})
+
+type AuditEventInput struct {
+	EventType string           `json:"eventType"`
+	Outcome   string           `json:"outcome"`
+	ActorID   uuid.NullUUID    `json:"actorId"`
+	ClientIP  sql.Null[string] `json:"clientIp"`
+	UserAgent sql.Null[string] `json:"userAgent"`
+	SessionID uuid.NullUUID    `json:"sessionId"`
+	TokenIDs  []uuid.UUID      `json:"tokenIds"`
+	Details   map[string]any   `json:"details"`
+}
+
+type AuditEventFilter struct {
+	ID             sql.Null[uuid.UUID] `json:"id"`
+	EventType      sql.Null[string]    `json:"eventType"`
+	Outcome        sql.Null[string]    `json:"outcome"`
+	ActorID        sql.Null[uuid.UUID] `json:"actorId"`
+	ClientIP       sql.Null[string]    `json:"clientIp"`
+	SessionID      sql.Null[uuid.UUID] `json:"sessionId"`
+	TokenID        sql.Null[uuid.UUID] `json:"tokenId"`
+	OccurredAtFrom sql.Null[time.Time] `json:"occurredAtFrom"`
+	OccurredAtTo   sql.Null[time.Time] `json:"occurredAtTo"`
+
+	PageSize int       `json:"pageSize"`
+	LastSeen uuid.UUID `json:"lastSeen"`
+}
+
+type AuditEventModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *AuditEventModel) insert(
+	ctx context.Context,
+	q db.Queryable,
+	input AuditEventInput,
+) (*AuditEvent, error) {
+	tokenIDs := input.TokenIDs
+	if tokenIDs == nil {
+		tokenIDs = []uuid.UUID{}
+	}
+	details := input.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"event_type": {V: input.EventType, Valid: true},
+			"outcome":    {V: input.Outcome, Valid: true},
+			"actor_id":   {V: input.ActorID.UUID, Valid: input.ActorID.Valid},
+			"client_ip":  {V: input.ClientIP.V, Valid: input.ClientIP.Valid},
+			"user_agent": {V: input.UserAgent.V, Valid: input.UserAgent.Valid},
+			"session_id": {V: input.SessionID.UUID, Valid: input.SessionID.Valid},
+			"token_ids":  {V: tokenIDs, Valid: true},
+			"details":    {V: details, Valid: true},
+		}).
+		Returning(auditEventColumns...).
+		Into("auth.audit_event")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	e, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "audit event inserted", slog.String("id", e.ID.String()))
+
+	return &e, nil
+}
+
+func (m *AuditEventModel) Insert(ctx context.Context, input AuditEventInput) (*AuditEvent, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *AuditEventModel) InsertTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	input AuditEventInput,
+) (*AuditEvent, error) {
+	return m.insert(ctx, tx, input)
+}
+
+// SelectMany selects the events matching the filter in the order they occurred, continuing
+// after the LastSeen event.
+func (m *AuditEventModel) SelectMany(
+	ctx context.Context,
+	filter AuditEventFilter,
+) ([]*AuditEvent, *Metadata, error) {
+	tokenID := builder.NewPredicate("", nil)
+	if filter.TokenID.Valid {
+		tokenID = builder.NewPredicate(
+			"@token_id = ANY(token_ids)",
+			pgx.NamedArgs{"token_id": filter.TokenID.V},
+		)
+	}
+
+	stmt, args := builder.From("auth.audit_event").
+		Where(
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("event_type", builder.Equal, filter.EventType),
+			builder.NewNullPredicate("outcome", builder.Equal, filter.Outcome),
+			builder.NewNullPredicate("actor_id", builder.Equal, filter.ActorID),
+			builder.NewNullPredicate("client_ip", builder.Equal, filter.ClientIP),
+			builder.NewNullPredicate("session_id", builder.Equal, filter.SessionID),
+			tokenID,
+			builder.NewNullPredicate("occurred_at", builder.GreaterOrEqual, filter.OccurredAtFrom),
+			builder.NewNullPredicate("occurred_at", builder.Less, filter.OccurredAtTo),
+			builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen),
+		).
+		OrderBy(builder.OrderBy{Column: "id", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(auditEventColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := m.DB.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	events := make([]*AuditEvent, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		e, err := m.scan(rows)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		events[i] = &e
+		i++
+	}
+	events = events[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(events),
+	}
+	if len(events) > 0 {
+		metadata.LastSeen = events[metadata.ResponseLength-1].ID
+		metadata.Next = true
+	}
+
+	return events, &metadata, nil
+}
+
+// DeleteBefore deletes the events that occurred before the time, returning the number of
+// events deleted.
+func (m *AuditEventModel) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	const stmt string = `
+DELETE FROM auth.audit_event
+WHERE occurred_at < $1::TIMESTAMPTZ;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Time("before", before),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	tag, err := m.DB.Exec(ctx, stmt, before)
+	if err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"audit events deleted",
+		slog.Int64("rowsAffected", tag.RowsAffected()),
+	)
+
+	return tag.RowsAffected(), nil
+}
+
+func (m *AuditEventModel) scan(row pgx.Row) (AuditEvent, error) {
+	var e AuditEvent
+	err := row.Scan(
+		&e.ID,
+		&e.OccurredAt,
+		&e.EventType,
+		&e.Outcome,
+		&e.ActorID,
+		&e.ClientIP,
+		&e.UserAgent,
+		&e.SessionID,
+		&e.TokenIDs,
+		&e.Details,
+	)
+	if err != nil {
+		return e, err
+	}
+	return e, nil
+}
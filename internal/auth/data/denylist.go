@@ -0,0 +1,356 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+const (
+	// DenylistChannel is the channel notified of every access token added to the denylist,
+	// with the DeniedAccessToken as the JSON payload.
+	DenylistChannel string = "auth_access_token_denylist"
+	// SessionDenylistChannel is the channel notified of every session added to the denylist,
+	// with the DeniedSession as the JSON payload.
+	SessionDenylistChannel string = "auth_session_denylist"
+)
+
+// DeniedAccessToken is the database record of a revoked access token.
+type DeniedAccessToken struct {
+	JTI uuid.UUID `json:"jti" db:"jti"`
+	// ExpiresAt is the expiry of the token, after which the record is no longer needed.
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+	RevokedAt time.Time `json:"revokedAt" db:"revoked_at"`
+}
+
+// DeniedSession is the database record of a revoked session, denying all access tokens issued
+// for the session.
+type DeniedSession struct {
+	SessionID uuid.UUID `json:"sessionId" db:"session_id"`
+	// ExpiresAt is when the last access token issued for the session expires, after which the
+	// record is no longer needed.
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+	RevokedAt time.Time `json:"revokedAt" db:"revoked_at"`
+}
+
+// AccessTokenDenylistModel stores the IDs of revoked access tokens, and of the sessions whose
+// access tokens are revoked, until the tokens expire.
+type AccessTokenDenylistModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// Insert adds the access token to the denylist. Adding a token twice is not an error.
+func (m *AccessTokenDenylistModel) Insert(
+	ctx context.Context,
+	jti uuid.UUID,
+	expiresAt time.Time,
+) error {
+	const stmt string = `
+INSERT INTO auth.access_token_denylist (jti, expires_at)
+VALUES ($1::UUID, $2::TIMESTAMPTZ)
+ON CONFLICT (jti) DO NOTHING;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("jti", jti.String()),
+		slog.Time("expiresAt", expiresAt),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := m.DB.Exec(ctx, stmt, jti, expiresAt); err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "access token denied")
+
+	return nil
+}
+
+// InsertSession adds the session to the denylist. Adding a session twice is not an error.
+func (m *AccessTokenDenylistModel) InsertSession(
+	ctx context.Context,
+	sessionID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	const stmt string = `
+INSERT INTO auth.session_denylist (session_id, expires_at)
+VALUES ($1::UUID, $2::TIMESTAMPTZ)
+ON CONFLICT (session_id) DO NOTHING;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("sessionId", sessionID.String()),
+		slog.Time("expiresAt", expiresAt),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := m.DB.Exec(ctx, stmt, sessionID, expiresAt); err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "session denied")
+
+	return nil
+}
+
+// Exists reports whether the access token, or the session it was issued for, is on the
+// denylist. Access tokens without a session are checked with uuid.Nil as the session ID.
+func (m *AccessTokenDenylistModel) Exists(
+	ctx context.Context,
+	jti uuid.UUID,
+	sessionID uuid.UUID,
+) (bool, error) {
+	const stmt string = `
+SELECT EXISTS (SELECT 1 FROM auth.access_token_denylist WHERE jti = $1::UUID)
+    OR EXISTS (SELECT 1 FROM auth.session_denylist WHERE session_id = $2::UUID);
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("jti", jti.String()),
+		slog.String("sessionId", sessionID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	var exists bool
+	if err := m.DB.QueryRow(ctx, stmt, jti, sessionID).Scan(&exists); err != nil {
+		return false, db.HandleError(ctx, err)
+	}
+
+	return exists, nil
+}
+
+// SelectActive selects the access tokens on the denylist that expire after the time.
+func (m *AccessTokenDenylistModel) SelectActive(
+	ctx context.Context,
+	at time.Time,
+) ([]*DeniedAccessToken, error) {
+	const stmt string = `
+SELECT jti, expires_at, revoked_at
+FROM auth.access_token_denylist
+WHERE expires_at > $1::TIMESTAMPTZ;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Time("at", at),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := m.DB.Query(ctx, stmt, at)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	denied := []*DeniedAccessToken{}
+	for rows.Next() {
+		var d DeniedAccessToken
+		if err := rows.Scan(&d.JTI, &d.ExpiresAt, &d.RevokedAt); err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		denied = append(denied, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx, slog.LevelInfo, "denied access tokens selected", slog.Int("count", len(denied)),
+	)
+
+	return denied, nil
+}
+
+// SelectActiveSessions selects the sessions on the denylist that expire after the time.
+func (m *AccessTokenDenylistModel) SelectActiveSessions(
+	ctx context.Context,
+	at time.Time,
+) ([]*DeniedSession, error) {
+	const stmt string = `
+SELECT session_id, expires_at, revoked_at
+FROM auth.session_denylist
+WHERE expires_at > $1::TIMESTAMPTZ;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Time("at", at),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := m.DB.Query(ctx, stmt, at)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	denied := []*DeniedSession{}
+	for rows.Next() {
+		var d DeniedSession
+		if err := rows.Scan(&d.SessionID, &d.ExpiresAt, &d.RevokedAt); err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		denied = append(denied, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx, slog.LevelInfo, "denied sessions selected", slog.Int("count", len(denied)),
+	)
+
+	return denied, nil
+}
+
+// DeleteExpired deletes the access tokens and sessions that expired before the time, returning
+// the number of rows deleted.
+func (m *AccessTokenDenylistModel) DeleteExpired(
+	ctx context.Context,
+	before time.Time,
+) (int64, error) {
+	const stmt string = `
+WITH tokens AS (
+    DELETE FROM auth.access_token_denylist
+    WHERE expires_at <= $1::TIMESTAMPTZ
+    RETURNING jti
+),
+sessions AS (
+    DELETE FROM auth.session_denylist
+    WHERE expires_at <= $1::TIMESTAMPTZ
+    RETURNING session_id
+)
+SELECT (SELECT COUNT(*) FROM tokens) + (SELECT COUNT(*) FROM sessions);
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Time("before", before),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	var deleted int64
+	if err := m.DB.QueryRow(ctx, stmt, before).Scan(&deleted); err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"expired denied access tokens and sessions deleted",
+		slog.Int64("rowsAffected", deleted),
+	)
+
+	return deleted, nil
+}
+
+// Listen listens on the DenylistChannel and the SessionDenylistChannel on a dedicated
+// connection, calling notify with each access token and notifySession with each session added
+// to the denylist. The listening function is called once the connection listens, so that no
+// token or session added after it reads the denylist is missed. Listen returns when the context
+// is done or the connection fails.
+func (m *AccessTokenDenylistModel) Listen(
+	ctx context.Context,
+	listening func(ctx context.Context) error,
+	notify func(denied DeniedAccessToken),
+	notifySession func(denied DeniedSession),
+) error {
+	logger := logging.LoggerFromContext(ctx).With(
+		slog.Any("channels", []string{DenylistChannel, SessionDenylistChannel}),
+	)
+
+	pooled, err := m.DB.Acquire(ctx)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	// The connection is taken from the pool, as it would otherwise keep listening after being
+	// returned to it.
+	conn := pooled.Hijack()
+	defer func() {
+		if err := conn.Close(context.WithoutCancel(ctx)); err != nil {
+			logger.LogAttrs(
+				ctx, slog.LevelWarn, "unable to close connection", slog.String("error", err.Error()),
+			)
+		}
+	}()
+
+	for _, channel := range []string{DenylistChannel, SessionDenylistChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return db.HandleError(ctx, err)
+		}
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "listening for denied access tokens")
+	if err := listening(ctx); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch notification.Channel {
+		case SessionDenylistChannel:
+			var denied DeniedSession
+			if err := json.Unmarshal([]byte(notification.Payload), &denied); err != nil {
+				logger.LogAttrs(
+					ctx,
+					slog.LevelWarn,
+					"unable to parse session denylist notification",
+					slog.String("payload", notification.Payload),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			notifySession(denied)
+		default:
+			var denied DeniedAccessToken
+			if err := json.Unmarshal([]byte(notification.Payload), &denied); err != nil {
+				logger.LogAttrs(
+					ctx,
+					slog.LevelWarn,
+					"unable to parse denylist notification",
+					slog.String("payload", notification.Payload),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			notify(denied)
+		}
+	}
+}
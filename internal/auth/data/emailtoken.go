@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// EmailToken is the database record of a single-use token sent to a user by email.
+type EmailToken struct {
+	ID     uuid.UUID `json:"id"      db:"id"`
+	UserID uuid.UUID `json:"userId"  db:"user_id"`
+	// Purpose is either "password_reset" or "email_verification".
+	Purpose string `json:"purpose" db:"purpose"`
+	// TokenHash is the hex encoded SHA-256 hash of the token. The token itself is not stored.
+	TokenHash string `json:"-"       db:"token_hash"`
+	// Email is the address the token was sent to.
+	Email     string              `json:"email"     db:"email"`
+	CreatedAt time.Time           `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time           `json:"expiresAt" db:"expires_at"`
+	UsedAt    sql.Null[time.Time] `json:"usedAt"    db:"used_at"`
+}
+
+var emailTokenColumns = builder.ColumnsFrom(EmailToken{})
+
+type EmailTokenInput struct {
+	UserID    uuid.UUID `json:"userId"`
+	Purpose   string    `json:"purpose"`
+	TokenHash string    `json:"-"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type EmailTokenModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// replace deletes the tokens of the user for the purpose of the input, and inserts the input.
+func (m *EmailTokenModel) replace(
+	ctx context.Context,
+	q db.Queryable,
+	input EmailTokenInput,
+) (*EmailToken, error) {
+	if err := m.deleteByUser(ctx, q, input.UserID, input.Purpose); err != nil {
+		return nil, err
+	}
+
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"user_id":    {V: input.UserID, Valid: true},
+			"purpose":    {V: input.Purpose, Valid: true},
+			"token_hash": {V: input.TokenHash, Valid: true},
+			"email":      {V: input.Email, Valid: true},
+			"expires_at": {V: input.ExpiresAt, Valid: true},
+		}).
+		Returning(emailTokenColumns...).
+		Into("auth.email_token")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	t, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "email token inserted", slog.String("id", t.ID.String()))
+
+	return &t, nil
+}
+
+func (m *EmailTokenModel) Replace(ctx context.Context, input EmailTokenInput) (*EmailToken, error) {
+	return m.replace(ctx, m.DB, input)
+}
+
+func (m *EmailTokenModel) ReplaceTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	input EmailTokenInput,
+) (*EmailToken, error) {
+	return m.replace(ctx, tx, input)
+}
+
+// UseTx marks the unused and unexpired token with the purpose and hash as used.
+// ErrRecordNotFound is returned if there is no such token.
+func (m *EmailTokenModel) UseTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	purpose string,
+	hash string,
+) (*EmailToken, error) {
+	stmt, args, err := builder.
+		Update("auth.email_token").
+		Where(
+			builder.NewGenericPredicate("purpose", builder.Equal, purpose),
+			builder.NewGenericPredicate("token_hash", builder.Equal, hash),
+			builder.NewPredicate("used_at IS NULL", nil),
+			builder.NewPredicate("expires_at > NOW()", nil),
+		).
+		Returning(emailTokenColumns...).
+		Set(builder.NewAssignment("used_at = NOW()", nil))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("purpose", purpose),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	t, err := m.scan(tx.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "email token used", slog.String("id", t.ID.String()))
+
+	return &t, nil
+}
+
+func (m *EmailTokenModel) deleteByUser(
+	ctx context.Context,
+	q db.Queryable,
+	userID uuid.UUID,
+	purpose string,
+) error {
+	stmt, args := builder.From("auth.email_token").
+		Where(
+			builder.NewGenericPredicate("user_id", builder.Equal, userID),
+			builder.NewGenericPredicate("purpose", builder.Equal, purpose),
+		).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.String("purpose", purpose),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"email tokens deleted",
+		slog.Int64("rowsAffected", res.RowsAffected()),
+	)
+
+	return nil
+}
+
+// DeleteByUserTx deletes the tokens of the user for the purpose, used or not.
+func (m *EmailTokenModel) DeleteByUserTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	purpose string,
+) error {
+	return m.deleteByUser(ctx, tx, userID, purpose)
+}
+
+func (m *EmailTokenModel) scan(row pgx.Row) (EmailToken, error) {
+	var t EmailToken
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.Email,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&t.UsedAt,
+	)
+	if err != nil {
+		return t, err
+	}
+	return t, nil
+}
@@ -0,0 +1,80 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailTokenModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	user, err := models.Users.Insert(ctx, data.UserInput{
+		Username:     "ivan",
+		PasswordHash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+	})
+	require.NoError(t, err)
+
+	input := data.EmailTokenInput{
+		UserID:    user.ID,
+		Purpose:   "password_reset",
+		TokenHash: "first",
+		Email:     "ivan@example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	t.Run("Replace", func(t *testing.T) {
+		inserted, err := models.EmailTokens.Replace(ctx, input)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, inserted.UserID)
+		assert.False(t, inserted.UsedAt.Valid)
+
+		second := input
+		second.TokenHash = "second"
+		_, err = models.EmailTokens.Replace(ctx, second)
+		require.NoError(t, err)
+	})
+
+	t.Run("UseTx", func(t *testing.T) {
+		tx, rollback, err := models.BeginTx(ctx)
+		require.NoError(t, err)
+		defer rollback()
+
+		// The second token replaced the first.
+		_, err = models.EmailTokens.UseTx(ctx, tx, "password_reset", "first")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+
+		used, err := models.EmailTokens.UseTx(ctx, tx, "password_reset", "second")
+		require.NoError(t, err)
+		assert.True(t, used.UsedAt.Valid)
+
+		_, err = models.EmailTokens.UseTx(ctx, tx, "password_reset", "second")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+		require.NoError(t, tx.Commit(ctx))
+	})
+
+	t.Run("UseTxExpired", func(t *testing.T) {
+		expired := input
+		expired.TokenHash = "expired"
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		_, err := models.EmailTokens.Replace(ctx, expired)
+		require.NoError(t, err)
+
+		tx, rollback, err := models.BeginTx(ctx)
+		require.NoError(t, err)
+		defer rollback()
+
+		_, err = models.EmailTokens.UseTx(ctx, tx, "password_reset", "expired")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+}
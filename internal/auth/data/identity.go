@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// UserIdentity is the database record linking a user to the subject of an OpenID Connect
+// provider.
+type UserIdentity struct {
+	ID     uuid.UUID `json:"id"     db:"id"`
+	UserID uuid.UUID `json:"userId" db:"user_id"`
+	// Issuer is the issuer URL of the provider.
+	Issuer string `json:"issuer"  db:"issuer"`
+	// Subject is the sub claim of the ID tokens of the user, unique per issuer.
+	Subject     string    `json:"subject"     db:"subject"`
+	CreatedAt   time.Time `json:"createdAt"   db:"created_at"`
+	LastLoginAt time.Time `json:"lastLoginAt" db:"last_login_at"`
+}
+
+var userIdentityColumns = builder.ColumnsFrom(UserIdentity{})
+
+type UserIdentityInput struct {
+	UserID  uuid.UUID `json:"userId"`
+	Issuer  string    `json:"issuer"`
+	Subject string    `json:"subject"`
+}
+
+type UserIdentityModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *UserIdentityModel) insert(
+	ctx context.Context,
+	q db.Queryable,
+	input UserIdentityInput,
+) (*UserIdentity, error) {
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"user_id": {V: input.UserID, Valid: true},
+			"issuer":  {V: input.Issuer, Valid: true},
+			"subject": {V: input.Subject, Valid: true},
+		}).
+		Returning(userIdentityColumns...).
+		Into("auth.user_identity")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	i, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user identity inserted", slog.String("id", i.ID.String()))
+
+	return &i, nil
+}
+
+func (m *UserIdentityModel) Insert(
+	ctx context.Context,
+	input UserIdentityInput,
+) (*UserIdentity, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *UserIdentityModel) InsertTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	input UserIdentityInput,
+) (*UserIdentity, error) {
+	return m.insert(ctx, tx, input)
+}
+
+// touch selects the identity of the subject of the issuer, and sets the time of its last
+// login.
+func (m *UserIdentityModel) touch(
+	ctx context.Context,
+	q db.Queryable,
+	issuer string,
+	subject string,
+) (*UserIdentity, error) {
+	stmt, args, err := builder.
+		Update("auth.user_identity").
+		Where(
+			builder.NewGenericPredicate("issuer", builder.Equal, issuer),
+			builder.NewGenericPredicate("subject", builder.Equal, subject),
+		).
+		Returning(userIdentityColumns...).
+		Set(builder.NewAssignment("last_login_at = NOW()", nil))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("issuer", issuer),
+		slog.String("subject", subject),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	i, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user identity touched", slog.String("id", i.ID.String()))
+
+	return &i, nil
+}
+
+func (m *UserIdentityModel) Touch(
+	ctx context.Context,
+	issuer string,
+	subject string,
+) (*UserIdentity, error) {
+	return m.touch(ctx, m.DB, issuer, subject)
+}
+
+func (m *UserIdentityModel) TouchTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	issuer string,
+	subject string,
+) (*UserIdentity, error) {
+	return m.touch(ctx, tx, issuer, subject)
+}
+
+func (m *UserIdentityModel) scan(row pgx.Row) (UserIdentity, error) {
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Issuer,
+		&i.Subject,
+		&i.CreatedAt,
+		&i.LastLoginAt,
+	)
+	if err != nil {
+		return i, err
+	}
+	return i, nil
+}
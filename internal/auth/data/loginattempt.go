@@ -0,0 +1,333 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// LoginAttempt is the database record of the failed login attempts of a username, client IP
+// address or user completing a TOTP challenge.
+type LoginAttempt struct {
+	Kind string `json:"kind" db:"kind"`
+	Key  string `json:"key"  db:"key"`
+	// Failures is the number of failed attempts since the last successful attempt, or since
+	// the counter was last reset.
+	Failures      int       `json:"failures"      db:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt" db:"last_failure_at"`
+	// BlockedUntil is the time until which attempts are rejected.
+	BlockedUntil sql.Null[time.Time] `json:"blockedUntil" db:"blocked_until"`
+	// Locked is set when the failures reached the lockout threshold.
+	Locked bool `json:"locked" db:"locked"`
+}
+
+var loginAttemptColumns = builder.ColumnsFrom(LoginAttempt{})
+
+// LoginAttemptKey identifies the LoginAttempt of a username, client IP address or user.
+type LoginAttemptKey struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+}
+
+type LoginAttemptFilter struct {
+	Kind sql.Null[string] `json:"kind"`
+	Key  sql.Null[string] `json:"key"`
+	// BlockedAfter selects attempts blocked beyond the time.
+	BlockedAfter sql.Null[time.Time] `json:"blockedAfter"`
+
+	PageSize int `json:"pageSize"`
+}
+
+type LoginAttemptModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// recordFailure counts a failed attempt at the time. The failures are counted from one again if
+// the last failure was before resetBefore.
+func (m *LoginAttemptModel) recordFailure(
+	ctx context.Context,
+	q db.Queryable,
+	key LoginAttemptKey,
+	at time.Time,
+	resetBefore time.Time,
+) (*LoginAttempt, error) {
+	const stmt string = `
+INSERT INTO auth.login_attempt (kind, key, failures, last_failure_at)
+VALUES (@kind, @key, 1, @at)
+ON CONFLICT (kind, key) DO UPDATE
+SET failures        = CASE
+                          WHEN auth.login_attempt.last_failure_at < @reset_before THEN 1
+                          ELSE auth.login_attempt.failures + 1
+                      END,
+    locked          = auth.login_attempt.locked
+                          AND auth.login_attempt.last_failure_at >= @reset_before,
+    last_failure_at = EXCLUDED.last_failure_at
+RETURNING kind, key, failures, last_failure_at, blocked_until, locked;
+`
+	args := pgx.NamedArgs{"kind": key.Kind, "key": key.Key, "at": at, "reset_before": resetBefore}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("key", key),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	a, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "login failure recorded", slog.Int("failures", a.Failures))
+
+	return &a, nil
+}
+
+func (m *LoginAttemptModel) RecordFailure(
+	ctx context.Context,
+	key LoginAttemptKey,
+	at time.Time,
+	resetBefore time.Time,
+) (*LoginAttempt, error) {
+	return m.recordFailure(ctx, m.DB, key, at, resetBefore)
+}
+
+func (m *LoginAttemptModel) RecordFailureTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	key LoginAttemptKey,
+	at time.Time,
+	resetBefore time.Time,
+) (*LoginAttempt, error) {
+	return m.recordFailure(ctx, tx, key, at, resetBefore)
+}
+
+// block rejects attempts until the time. A later existing block is kept.
+func (m *LoginAttemptModel) block(
+	ctx context.Context,
+	q db.Queryable,
+	key LoginAttemptKey,
+	until time.Time,
+	locked bool,
+) (*LoginAttempt, error) {
+	stmt, args, err := builder.
+		Update("auth.login_attempt").
+		Where(
+			builder.NewGenericPredicate("kind", builder.Equal, key.Kind),
+			builder.NewGenericPredicate("key", builder.Equal, key.Key),
+		).
+		Returning(loginAttemptColumns...).
+		Set(
+			builder.NewAssignment(
+				"blocked_until = GREATEST(blocked_until, @until)",
+				pgx.NamedArgs{"until": until},
+			),
+			builder.NewAssignment("locked = locked OR @locked", pgx.NamedArgs{"locked": locked}),
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("key", key),
+		slog.Time("until", until),
+		slog.Bool("locked", locked),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	a, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "login attempts blocked")
+
+	return &a, nil
+}
+
+func (m *LoginAttemptModel) Block(
+	ctx context.Context,
+	key LoginAttemptKey,
+	until time.Time,
+	locked bool,
+) (*LoginAttempt, error) {
+	return m.block(ctx, m.DB, key, until, locked)
+}
+
+func (m *LoginAttemptModel) BlockTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	key LoginAttemptKey,
+	until time.Time,
+	locked bool,
+) (*LoginAttempt, error) {
+	return m.block(ctx, tx, key, until, locked)
+}
+
+// SelectBlocked selects the attempts of the keys that are blocked beyond the time.
+func (m *LoginAttemptModel) SelectBlocked(
+	ctx context.Context,
+	keys []LoginAttemptKey,
+	at time.Time,
+) ([]*LoginAttempt, error) {
+	const stmt string = `
+SELECT kind, key, failures, last_failure_at, blocked_until, locked
+FROM auth.login_attempt
+WHERE (kind, key) IN (SELECT * FROM UNNEST(@kinds::VARCHAR[], @keys::VARCHAR[]))
+  AND blocked_until > @at;
+`
+	kinds := make([]string, len(keys))
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		kinds[i] = key.Kind
+		values[i] = key.Key
+	}
+	args := pgx.NamedArgs{"kinds": kinds, "keys": values, "at": at}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("keys", keys),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := m.DB.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	attempts := make([]*LoginAttempt, 0, len(keys))
+	for rows.Next() {
+		a, err := m.scan(rows)
+		if err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		attempts = append(attempts, &a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"blocked login attempts selected",
+		slog.Int("count", len(attempts)),
+	)
+
+	return attempts, nil
+}
+
+// SelectMany selects the attempts matching the filter, with the most recent failures first.
+func (m *LoginAttemptModel) SelectMany(
+	ctx context.Context,
+	filter LoginAttemptFilter,
+) ([]*LoginAttempt, error) {
+	stmt, args := builder.From("auth.login_attempt").
+		Where(
+			builder.NewNullPredicate("kind", builder.Equal, filter.Kind),
+			builder.NewNullPredicate("key", builder.Equal, filter.Key),
+			builder.NewNullPredicate("blocked_until", builder.Greater, filter.BlockedAfter),
+		).
+		OrderBy(builder.OrderBy{Column: "last_failure_at", Order: builder.Desc}).
+		Limit(filter.PageSize).
+		Select(loginAttemptColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := m.DB.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	attempts := make([]*LoginAttempt, 0, filter.PageSize)
+	for rows.Next() {
+		a, err := m.scan(rows)
+		if err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		attempts = append(attempts, &a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	return attempts, nil
+}
+
+// Delete deletes the attempts of the key, resetting the failures and lifting any block.
+// ErrRecordNotFound is returned if the key has no recorded failures.
+func (m *LoginAttemptModel) Delete(
+	ctx context.Context,
+	key LoginAttemptKey,
+) (*LoginAttempt, error) {
+	stmt, args := builder.From("auth.login_attempt").
+		Where(
+			builder.NewGenericPredicate("kind", builder.Equal, key.Kind),
+			builder.NewGenericPredicate("key", builder.Equal, key.Key),
+		).
+		Returning(loginAttemptColumns...).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("key", key),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	a, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "login attempts deleted")
+
+	return &a, nil
+}
+
+func (m *LoginAttemptModel) scan(row pgx.Row) (LoginAttempt, error) {
+	var a LoginAttempt
+	err := row.Scan(
+		&a.Kind,
+		&a.Key,
+		&a.Failures,
+		&a.LastFailureAt,
+		&a.BlockedUntil,
+		&a.Locked,
+	)
+	if err != nil {
+		return a, err
+	}
+	return a, nil
+}
@@ -12,14 +12,34 @@ import (
 )
 
 type Models struct {
-	db            *pgxpool.Pool
-	RefreshTokens RefreshTokenModel
+	db             *pgxpool.Pool
+	RefreshTokens  RefreshTokenModel
+	Users          UserModel
+	APITokens      APITokenModel
+	RecoveryCodes  RecoveryCodeModel
+	LoginAttempts  LoginAttemptModel
+	Denylist       AccessTokenDenylistModel
+	AuditEvents    AuditEventModel
+	EmailTokens    EmailTokenModel
+	Identities     UserIdentityModel
+	TOTPChallenges TOTPChallengeModel
+	OIDCLogins     OIDCLoginModel
 }
 
 func NewModels(pool *pgxpool.Pool, timeout *time.Duration) Models {
 	return Models{
-		db:            pool,
-		RefreshTokens: RefreshTokenModel{DB: pool, Timeout: timeout},
+		db:             pool,
+		RefreshTokens:  RefreshTokenModel{DB: pool, Timeout: timeout},
+		Users:          UserModel{DB: pool, Timeout: timeout},
+		APITokens:      APITokenModel{DB: pool, Timeout: timeout},
+		RecoveryCodes:  RecoveryCodeModel{DB: pool, Timeout: timeout},
+		LoginAttempts:  LoginAttemptModel{DB: pool, Timeout: timeout},
+		Denylist:       AccessTokenDenylistModel{DB: pool, Timeout: timeout},
+		AuditEvents:    AuditEventModel{DB: pool, Timeout: timeout},
+		EmailTokens:    EmailTokenModel{DB: pool, Timeout: timeout},
+		Identities:     UserIdentityModel{DB: pool, Timeout: timeout},
+		TOTPChallenges: TOTPChallengeModel{DB: pool, Timeout: timeout},
+		OIDCLogins:     OIDCLoginModel{DB: pool, Timeout: timeout},
 	}
 }
 
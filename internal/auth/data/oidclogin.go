@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// OIDCLogin is the database record of a login with the OpenID Connect provider that has not
+// been completed yet.
+type OIDCLogin struct {
+	ID uuid.UUID `json:"id"        db:"id"`
+	// TokenHash is the hex encoded SHA-256 hash of the login token kept by the browser.
+	TokenHash string `json:"-"         db:"token_hash"`
+	State     string `json:"-"         db:"state"`
+	Nonce     string `json:"-"         db:"nonce"`
+	// Verifier is the PKCE code verifier of the login.
+	Verifier  string    `json:"-"         db:"verifier"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+}
+
+var oidcLoginColumns = builder.ColumnsFrom(OIDCLogin{})
+
+type OIDCLoginInput struct {
+	TokenHash string    `json:"-"`
+	State     string    `json:"-"`
+	Nonce     string    `json:"-"`
+	Verifier  string    `json:"-"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type OIDCLoginModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// Insert deletes the expired logins, and inserts the input.
+func (m *OIDCLoginModel) Insert(ctx context.Context, input OIDCLoginInput) (*OIDCLogin, error) {
+	if err := m.deleteExpired(ctx); err != nil {
+		return nil, err
+	}
+
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"token_hash": {V: input.TokenHash, Valid: true},
+			"state":      {V: input.State, Valid: true},
+			"nonce":      {V: input.Nonce, Valid: true},
+			"verifier":   {V: input.Verifier, Valid: true},
+			"expires_at": {V: input.ExpiresAt, Valid: true},
+		}).
+		Returning(oidcLoginColumns...).
+		Into("auth.oidc_login")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	l, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "oidc login inserted", slog.String("id", l.ID.String()))
+
+	return &l, nil
+}
+
+// Consume deletes the unexpired login with the token hash, and returns it. ErrRecordNotFound
+// is returned if there is no such login.
+func (m *OIDCLoginModel) Consume(ctx context.Context, hash string) (*OIDCLogin, error) {
+	stmt, args := builder.From("auth.oidc_login").
+		Where(
+			builder.NewGenericPredicate("token_hash", builder.Equal, hash),
+			builder.NewPredicate("expires_at > NOW()", nil),
+		).
+		Returning(oidcLoginColumns...).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	l, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "oidc login consumed", slog.String("id", l.ID.String()))
+
+	return &l, nil
+}
+
+func (m *OIDCLoginModel) deleteExpired(ctx context.Context) error {
+	stmt, args := builder.From("auth.oidc_login").
+		Where(builder.NewPredicate("expires_at <= NOW()", nil)).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := m.DB.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"expired oidc logins deleted",
+		slog.Int64("rowsAffected", res.RowsAffected()),
+	)
+
+	return nil
+}
+
+func (m *OIDCLoginModel) scan(row pgx.Row) (OIDCLogin, error) {
+	var l OIDCLogin
+	err := row.Scan(
+		&l.ID,
+		&l.TokenHash,
+		&l.State,
+		&l.Nonce,
+		&l.Verifier,
+		&l.CreatedAt,
+		&l.ExpiresAt,
+	)
+	if err != nil {
+		return l, err
+	}
+	return l, nil
+}
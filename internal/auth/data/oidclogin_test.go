@@ -0,0 +1,53 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCLoginModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	input := data.OIDCLoginInput{
+		TokenHash: "login",
+		State:     "state",
+		Nonce:     "nonce",
+		Verifier:  "verifier",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	t.Run("Consume", func(t *testing.T) {
+		inserted, err := models.OIDCLogins.Insert(ctx, input)
+		require.NoError(t, err)
+
+		consumed, err := models.OIDCLogins.Consume(ctx, "login")
+		require.NoError(t, err)
+		assert.Equal(t, inserted.ID, consumed.ID)
+		assert.Equal(t, "verifier", consumed.Verifier)
+
+		_, err = models.OIDCLogins.Consume(ctx, "login")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("ConsumeExpired", func(t *testing.T) {
+		expired := input
+		expired.TokenHash = "expired"
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		_, err := models.OIDCLogins.Insert(ctx, expired)
+		require.NoError(t, err)
+
+		_, err = models.OIDCLogins.Consume(ctx, "expired")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// RecoveryCodeModel stores the one-time recovery codes of users with two-factor
+// authentication. Only the hex encoded SHA-256 hash of each code is stored.
+type RecoveryCodeModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// replace deletes the recovery codes of the user, and inserts the code hashes.
+func (m *RecoveryCodeModel) replace(
+	ctx context.Context,
+	q db.Queryable,
+	userID uuid.UUID,
+	hashes []string,
+) error {
+	if err := m.deleteByUser(ctx, q, userID); err != nil {
+		return err
+	}
+
+	tuples := make([]builder.Tuple, len(hashes))
+	for i, hash := range hashes {
+		tuples[i] = builder.Tuple{
+			"user_id":   {V: userID, Valid: true},
+			"code_hash": {V: hash, Valid: true},
+		}
+	}
+	stmt, args, err := builder.Insert(tuples...).Into("auth.recovery_code")
+	if err != nil {
+		return err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := q.Exec(ctx, stmt, args); err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "recovery codes inserted", slog.Int("count", len(hashes)))
+
+	return nil
+}
+
+func (m *RecoveryCodeModel) Replace(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	return m.replace(ctx, m.DB, userID, hashes)
+}
+
+func (m *RecoveryCodeModel) ReplaceTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	hashes []string,
+) error {
+	return m.replace(ctx, tx, userID, hashes)
+}
+
+// Use marks the unused recovery code of the user as used. False is returned if the user has no
+// unused code with the hash.
+func (m *RecoveryCodeModel) Use(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	const stmt string = `
+UPDATE auth.recovery_code
+SET used_at = NOW()
+WHERE user_id = $1::UUID
+  AND code_hash = $2::VARCHAR(64)
+  AND used_at IS NULL;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := m.DB.Exec(ctx, stmt, userID, hash)
+	if err != nil {
+		return false, db.HandleError(ctx, err)
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+// CountUnused returns the number of unused recovery codes of the user.
+func (m *RecoveryCodeModel) CountUnused(ctx context.Context, userID uuid.UUID) (int64, error) {
+	const stmt string = `
+SELECT COUNT(*)
+FROM auth.recovery_code
+WHERE user_id = $1::UUID
+  AND used_at IS NULL;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	var count int64
+	if err := m.DB.QueryRow(ctx, stmt, userID).Scan(&count); err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+
+	return count, nil
+}
+
+func (m *RecoveryCodeModel) deleteByUser(
+	ctx context.Context,
+	q db.Queryable,
+	userID uuid.UUID,
+) error {
+	stmt, args := builder.From("auth.recovery_code").
+		Where(builder.NewGenericPredicate("user_id", builder.Equal, userID)).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"recovery codes deleted",
+		slog.Int64("rowsAffected", res.RowsAffected()),
+	)
+
+	return nil
+}
+
+func (m *RecoveryCodeModel) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	return m.deleteByUser(ctx, m.DB, userID)
+}
+
+func (m *RecoveryCodeModel) DeleteByUserTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	return m.deleteByUser(ctx, tx, userID)
+}
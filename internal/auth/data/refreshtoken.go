@@ -21,14 +21,29 @@ type RefreshToken struct {
 	IssuedAt      time.Time     `json:"issuedAt"      db:"issued_at"`
 	Invalidated   bool          `json:"invalidated"   db:"invalidated"`
 	InvalidatedBy uuid.NullUUID `json:"invalidatedBy" db:"invalidated_by"`
+	// UserID is the user the token was issued to.
+	UserID uuid.NullUUID `json:"userId" db:"user_id"`
+	// SessionID identifies the session, i.e. the token issued at login and the tokens
+	// replacing it.
+	SessionID  uuid.UUID           `json:"sessionId"  db:"session_id"`
+	UserAgent  sql.Null[string]    `json:"userAgent"  db:"user_agent"`
+	ClientIP   sql.Null[string]    `json:"clientIp"   db:"client_ip"`
+	Label      sql.Null[string]    `json:"label"      db:"label"`
+	LastUsedAt sql.Null[time.Time] `json:"lastUsedAt" db:"last_used_at"`
 }
 
 var refreshTokenColumns = builder.ColumnsFrom(RefreshToken{})
 
 type RefreshTokenInput struct {
-	Issuer     string    `json:"issuer"`
-	Expiration time.Time `json:"expiration"`
-	IssuedAt   time.Time `json:"issuedAt"`
+	Issuer     string              `json:"issuer"`
+	Expiration time.Time           `json:"expiration"`
+	IssuedAt   time.Time           `json:"issuedAt"`
+	UserID     uuid.NullUUID       `json:"userId"`
+	SessionID  uuid.UUID           `json:"sessionId"`
+	UserAgent  sql.Null[string]    `json:"userAgent"`
+	ClientIP   sql.Null[string]    `json:"clientIp"`
+	Label      sql.Null[string]    `json:"label"`
+	LastUsedAt sql.Null[time.Time] `json:"lastUsedAt"`
 }
 
 type RefreshTokenPatch struct {
@@ -53,6 +68,15 @@ func (m *RefreshTokenModel) insert(
 			"issuer":     {V: input.Issuer, Valid: true},
 			"expiration": {V: input.Expiration, Valid: true},
 			"issued_at":  {V: input.IssuedAt, Valid: true},
+			"user_id":    {V: input.UserID.UUID, Valid: input.UserID.Valid},
+			"session_id": {V: input.SessionID, Valid: true},
+			"user_agent": {V: input.UserAgent.V, Valid: input.UserAgent.Valid},
+			"client_ip":  {V: input.ClientIP.V, Valid: input.ClientIP.Valid},
+			"label":      {V: input.Label.V, Valid: input.Label.Valid},
+			"last_used_at": {
+				V:     input.LastUsedAt.V,
+				Valid: input.LastUsedAt.Valid,
+			},
 		}).
 		Returning(
 			refreshTokenColumns...,
@@ -149,6 +173,8 @@ type RefreshTokenFilter struct {
 	IssuedAtTo     sql.Null[time.Time] `json:"issuedAtTo"`
 	Invalidated    sql.Null[bool]      `json:"invalidated"`
 	InvalidatedBy  sql.Null[uuid.UUID] `json:"invalidatedBy"`
+	UserID         sql.Null[uuid.UUID] `json:"userId"`
+	SessionID      sql.Null[uuid.UUID] `json:"sessionId"`
 
 	PageSize int       `json:"pageSize"`
 	LastSeen uuid.UUID `json:"lastSeen"`
@@ -161,14 +187,16 @@ func (m *RefreshTokenModel) selectMany(
 ) ([]*RefreshToken, *Metadata, error) {
 	stmt, args := builder.From("auth.refresh_token").
 		Where(
-			builder.NewGenericPredicate("id", builder.Equal, filter.ID),
-			builder.NewGenericPredicate("issuer", builder.Equal, filter.Issuer),
-			builder.NewGenericPredicate("expiration", builder.LessOrEqual, filter.ExpirationFrom),
-			builder.NewGenericPredicate("expiration", builder.Greater, filter.ExpirationTo),
-			builder.NewGenericPredicate("issued_at", builder.LessOrEqual, filter.IssuedAtFrom),
-			builder.NewGenericPredicate("issued_at", builder.Greater, filter.IssuedAtTo),
-			builder.NewGenericPredicate("invalidated", builder.Equal, filter.Invalidated),
-			builder.NewGenericPredicate("invalidated_by", builder.Equal, filter.InvalidatedBy),
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("issuer", builder.Equal, filter.Issuer),
+			builder.NewNullPredicate("expiration", builder.LessOrEqual, filter.ExpirationFrom),
+			builder.NewNullPredicate("expiration", builder.Greater, filter.ExpirationTo),
+			builder.NewNullPredicate("issued_at", builder.LessOrEqual, filter.IssuedAtFrom),
+			builder.NewNullPredicate("issued_at", builder.Greater, filter.IssuedAtTo),
+			builder.NewNullPredicate("invalidated", builder.Equal, filter.Invalidated),
+			builder.NewNullPredicate("invalidated_by", builder.Equal, filter.InvalidatedBy),
+			builder.NewNullPredicate("user_id", builder.Equal, filter.UserID),
+			builder.NewNullPredicate("session_id", builder.Equal, filter.SessionID),
 		).
 		OrderBy(
 			builder.OrderBy{Column: "expiration", Order: builder.Asc},
@@ -251,7 +279,13 @@ RETURNING
     expiration,
     issued_at,
     invalidated,
-    invalidated_by;
+    invalidated_by,
+    user_id,
+    session_id,
+    user_agent,
+    client_ip,
+    label,
+    last_used_at;
 `
 
 	logger := logging.LoggerFromContext(ctx).With(slog.Group(
@@ -311,7 +345,13 @@ RETURNING
     expiration,
     issued_at,
     invalidated,
-    invalidated_by;
+    invalidated_by,
+    user_id,
+    session_id,
+    user_agent,
+    client_ip,
+    label,
+    last_used_at;
 `
 
 	logger := logging.LoggerFromContext(ctx).With(slog.Group(
@@ -421,6 +461,324 @@ func (m *RefreshTokenModel) DeleteManyTx(
 	return m.deleteMany(ctx, tx, filter)
 }
 
+// deleteExpired deletes at most limit refresh tokens that expired before the time, returning
+// the number of rows deleted. The oldest tokens are deleted first, as replaced tokens reference
+// their replacements, which always expire later. Tokens locked by concurrent refreshes are
+// skipped.
+func (m *RefreshTokenModel) deleteExpired(
+	ctx context.Context,
+	q db.Queryable,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const stmt string = `
+DELETE
+FROM auth.refresh_token
+WHERE id IN (SELECT id
+             FROM auth.refresh_token
+             WHERE expiration <= $1::TIMESTAMPTZ
+             ORDER BY expiration
+             LIMIT $2 FOR UPDATE SKIP LOCKED);
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Time("before", before),
+		slog.Int("limit", limit),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	tag, err := q.Exec(ctx, stmt, before, limit)
+	if err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"expired refresh tokens deleted",
+		slog.Int64("rowsAffected", tag.RowsAffected()),
+	)
+
+	return tag.RowsAffected(), nil
+}
+
+func (m *RefreshTokenModel) DeleteExpired(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	return m.deleteExpired(ctx, m.DB, before, limit)
+}
+
+func (m *RefreshTokenModel) DeleteExpiredTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	return m.deleteExpired(ctx, tx, before, limit)
+}
+
+// invalidateByUser invalidates all refresh tokens issued to the user, returning the number of
+// tokens invalidated.
+func (m *RefreshTokenModel) invalidateByUser(
+	ctx context.Context,
+	q db.Queryable,
+	userID uuid.UUID,
+) (int64, error) {
+	const stmt string = `
+UPDATE auth.refresh_token
+SET invalidated = TRUE
+WHERE user_id = $1::UUID
+  AND invalidated = FALSE;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, userID)
+	if err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"refresh tokens invalidated",
+		slog.Int64("rowsAffected", res.RowsAffected()),
+	)
+
+	return res.RowsAffected(), nil
+}
+
+func (m *RefreshTokenModel) InvalidateByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return m.invalidateByUser(ctx, m.DB, userID)
+}
+
+func (m *RefreshTokenModel) InvalidateByUserTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+) (int64, error) {
+	return m.invalidateByUser(ctx, tx, userID)
+}
+
+// rotate invalidates the token, recording the token replacing it. The token is only rotated if
+// it has not been invalidated, so that concurrent refreshes of the same token cannot both
+// succeed. ErrRecordNotFound is returned if the token is missing or already invalidated.
+func (m *RefreshTokenModel) rotate(
+	ctx context.Context,
+	q db.Queryable,
+	id uuid.UUID,
+	replacedBy uuid.UUID,
+) (*RefreshToken, error) {
+	const stmt string = `
+UPDATE auth.refresh_token
+SET invalidated = TRUE,
+    invalidated_by = $2::UUID
+WHERE id = $1::UUID
+  AND invalidated IS NOT TRUE
+RETURNING
+    id,
+    issuer,
+    expiration,
+    issued_at,
+    invalidated,
+    invalidated_by,
+    user_id,
+    session_id,
+    user_agent,
+    client_ip,
+    label,
+    last_used_at;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.String("replacedBy", replacedBy.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	r, err := m.scan(q.QueryRow(ctx, stmt, id, replacedBy))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "refresh token rotated")
+
+	return &r, nil
+}
+
+func (m *RefreshTokenModel) Rotate(
+	ctx context.Context,
+	id uuid.UUID,
+	replacedBy uuid.UUID,
+) (*RefreshToken, error) {
+	return m.rotate(ctx, m.DB, id, replacedBy)
+}
+
+func (m *RefreshTokenModel) RotateTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	replacedBy uuid.UUID,
+) (*RefreshToken, error) {
+	return m.rotate(ctx, tx, id, replacedBy)
+}
+
+// invalidateFamily invalidates the token and every token descending from it, following the
+// chain of replacements recorded in invalidated_by. The number of tokens invalidated is
+// returned.
+func (m *RefreshTokenModel) invalidateFamily(
+	ctx context.Context,
+	q db.Queryable,
+	id uuid.UUID,
+) (int64, error) {
+	const stmt string = `
+WITH RECURSIVE family AS (
+    SELECT id, invalidated_by
+    FROM auth.refresh_token
+    WHERE id = $1::UUID
+    UNION
+    SELECT t.id, t.invalidated_by
+    FROM auth.refresh_token t
+    JOIN family f ON t.id = f.invalidated_by
+)
+UPDATE auth.refresh_token
+SET invalidated = TRUE
+WHERE id IN (SELECT id FROM family)
+  AND invalidated IS NOT TRUE;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, id)
+	if err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"refresh token family invalidated",
+		slog.Int64("rowsAffected", res.RowsAffected()),
+	)
+
+	return res.RowsAffected(), nil
+}
+
+func (m *RefreshTokenModel) InvalidateFamily(ctx context.Context, id uuid.UUID) (int64, error) {
+	return m.invalidateFamily(ctx, m.DB, id)
+}
+
+func (m *RefreshTokenModel) InvalidateFamilyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+) (int64, error) {
+	return m.invalidateFamily(ctx, tx, id)
+}
+
+// invalidateSessions invalidates the refresh tokens of the sessions of the user. If except is
+// false, the session is invalidated. If except is true, all other sessions are invalidated. The
+// IDs of the sessions with tokens invalidated are returned.
+func (m *RefreshTokenModel) invalidateSessions(
+	ctx context.Context,
+	q db.Queryable,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+	except bool,
+) ([]uuid.UUID, error) {
+	const stmt string = `
+WITH invalidated AS (
+    UPDATE auth.refresh_token
+    SET invalidated = TRUE
+    WHERE user_id = $1::UUID
+      AND ((NOT $3::BOOLEAN AND session_id = $2::UUID)
+        OR ($3::BOOLEAN AND session_id != $2::UUID))
+      AND invalidated IS NOT TRUE
+    RETURNING session_id
+)
+SELECT DISTINCT session_id
+FROM invalidated;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.String("sessionId", sessionID.String()),
+		slog.Bool("except", except),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, userID, sessionID, except)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	sessionIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"sessions invalidated",
+		slog.Int("sessions", len(sessionIDs)),
+	)
+
+	return sessionIDs, nil
+}
+
+// InvalidateSession invalidates the session of the user. The session ID is returned unless the
+// user has no valid session with the ID.
+func (m *RefreshTokenModel) InvalidateSession(
+	ctx context.Context,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+) ([]uuid.UUID, error) {
+	return m.invalidateSessions(ctx, m.DB, userID, sessionID, false)
+}
+
+// InvalidateOtherSessions invalidates all sessions of the user except the given session,
+// returning the IDs of the sessions invalidated.
+func (m *RefreshTokenModel) InvalidateOtherSessions(
+	ctx context.Context,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+) ([]uuid.UUID, error) {
+	return m.invalidateSessions(ctx, m.DB, userID, sessionID, true)
+}
+
 func (m *RefreshTokenModel) scan(row pgx.Row) (RefreshToken, error) {
 	var r RefreshToken
 	err := row.Scan(
@@ -430,6 +788,12 @@ func (m *RefreshTokenModel) scan(row pgx.Row) (RefreshToken, error) {
 		&r.IssuedAt,
 		&r.Invalidated,
 		&r.InvalidatedBy,
+		&r.UserID,
+		&r.SessionID,
+		&r.UserAgent,
+		&r.ClientIP,
+		&r.Label,
+		&r.LastUsedAt,
 	)
 	if err != nil {
 		return r, err
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -89,4 +90,41 @@ func TestRefreshTokenModel(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, *rowsAffected)
 	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		now := time.Now()
+		insert := func(expiration time.Time) data.RefreshToken {
+			inserted, err := models.RefreshTokens.Insert(ctx, data.RefreshTokenInput{
+				Issuer:     "islandwind",
+				Expiration: expiration,
+				IssuedAt:   expiration.Add(-time.Hour),
+			})
+			assert.NoError(t, err)
+			return *inserted
+		}
+		replaced, replacement := insert(now.Add(-2*time.Hour)), insert(now.Add(-time.Hour))
+		valid := insert(now.Add(time.Hour))
+		_, err := models.RefreshTokens.Update(ctx, data.RefreshTokenPatch{
+			ID:            replaced.ID,
+			Invalidated:   sql.NullBool{Bool: true, Valid: true},
+			InvalidatedBy: uuid.NullUUID{Valid: true, UUID: replacement.ID},
+		})
+		assert.NoError(t, err)
+
+		// The replaced token expired first, so it is deleted before its replacement.
+		deleted, err := models.RefreshTokens.DeleteExpired(ctx, now, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		_, err = models.RefreshTokens.SelectOne(ctx, replaced.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+
+		for deleted > 0 {
+			deleted, err = models.RefreshTokens.DeleteExpired(ctx, now, 1)
+			assert.NoError(t, err)
+		}
+		_, err = models.RefreshTokens.SelectOne(ctx, replacement.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+		_, err = models.RefreshTokens.SelectOne(ctx, valid.ID)
+		assert.NoError(t, err)
+	})
 }
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// TOTPChallenge is the database record of a challenge issued to a user with two-factor
+// authentication. The ID is the jti claim of the challenge token.
+type TOTPChallenge struct {
+	ID        uuid.UUID           `json:"id"        db:"id"`
+	UserID    uuid.UUID           `json:"userId"    db:"user_id"`
+	CreatedAt time.Time           `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time           `json:"expiresAt" db:"expires_at"`
+	UsedAt    sql.Null[time.Time] `json:"usedAt"    db:"used_at"`
+}
+
+var totpChallengeColumns = builder.ColumnsFrom(TOTPChallenge{})
+
+type TOTPChallengeInput struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type TOTPChallengeModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// Insert deletes the used and expired challenges of the user, and inserts the input.
+func (m *TOTPChallengeModel) Insert(
+	ctx context.Context,
+	input TOTPChallengeInput,
+) (*TOTPChallenge, error) {
+	if err := m.deleteStale(ctx, m.DB, input.UserID); err != nil {
+		return nil, err
+	}
+
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"id":         {V: input.ID, Valid: true},
+			"user_id":    {V: input.UserID, Valid: true},
+			"expires_at": {V: input.ExpiresAt, Valid: true},
+		}).
+		Returning(totpChallengeColumns...).
+		Into("auth.totp_challenge")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	c, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp challenge inserted")
+
+	return &c, nil
+}
+
+// Use marks the unused and unexpired challenge with the ID as used. ErrRecordNotFound is
+// returned if there is no such challenge.
+func (m *TOTPChallengeModel) Use(ctx context.Context, ID uuid.UUID) (*TOTPChallenge, error) {
+	stmt, args, err := builder.
+		Update("auth.totp_challenge").
+		Where(
+			builder.NewGenericPredicate("id", builder.Equal, ID),
+			builder.NewPredicate("used_at IS NULL", nil),
+			builder.NewPredicate("expires_at > NOW()", nil),
+		).
+		Returning(totpChallengeColumns...).
+		Set(builder.NewAssignment("used_at = NOW()", nil))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", ID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	c, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp challenge used")
+
+	return &c, nil
+}
+
+func (m *TOTPChallengeModel) deleteStale(
+	ctx context.Context,
+	q db.Queryable,
+	userID uuid.UUID,
+) error {
+	stmt, args := builder.From("auth.totp_challenge").
+		Where(
+			builder.NewGenericPredicate("user_id", builder.Equal, userID),
+			builder.NewPredicate("(used_at IS NOT NULL OR expires_at <= NOW())", nil),
+		).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("userId", userID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"stale totp challenges deleted",
+		slog.Int64("rowsAffected", res.RowsAffected()),
+	)
+
+	return nil
+}
+
+func (m *TOTPChallengeModel) scan(row pgx.Row) (TOTPChallenge, error) {
+	var c TOTPChallenge
+	err := row.Scan(
+		&c.ID,
+		&c.UserID,
+		&c.CreatedAt,
+		&c.ExpiresAt,
+		&c.UsedAt,
+	)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,62 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPChallengeModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	user, err := models.Users.Insert(ctx, data.UserInput{
+		Username:     "judy",
+		PasswordHash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+	})
+	require.NoError(t, err)
+
+	t.Run("Use", func(t *testing.T) {
+		inserted, err := models.TOTPChallenges.Insert(ctx, data.TOTPChallengeInput{
+			ID:        uuid.New(),
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		assert.False(t, inserted.UsedAt.Valid)
+
+		used, err := models.TOTPChallenges.Use(ctx, inserted.ID)
+		require.NoError(t, err)
+		assert.True(t, used.UsedAt.Valid)
+
+		_, err = models.TOTPChallenges.Use(ctx, inserted.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("UseExpired", func(t *testing.T) {
+		inserted, err := models.TOTPChallenges.Insert(ctx, data.TOTPChallengeInput{
+			ID:        uuid.New(),
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(-time.Minute),
+		})
+		require.NoError(t, err)
+
+		_, err = models.TOTPChallenges.Use(ctx, inserted.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("UseUnknown", func(t *testing.T) {
+		_, err := models.TOTPChallenges.Use(ctx, uuid.New())
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+}
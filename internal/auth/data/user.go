@@ -0,0 +1,537 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// User is the database record of a user account.
+type User struct {
+	ID       uuid.UUID `json:"id"       db:"id"`
+	Username string    `json:"username" db:"username"`
+	// PasswordHash is the argon2id hash of the password, encoded in the PHC string format.
+	PasswordHash string              `json:"-"           db:"password_hash"`
+	Disabled     bool                `json:"disabled"    db:"disabled"`
+	CreatedAt    time.Time           `json:"createdAt"   db:"created_at"`
+	UpdatedAt    time.Time           `json:"updatedAt"   db:"updated_at"`
+	LastLoginAt  sql.Null[time.Time] `json:"lastLoginAt" db:"last_login_at"`
+	// Role determines the scopes granted to the user.
+	Role scope.Role `json:"role" db:"role"`
+	// TOTPSecret is the base32 encoded TOTP secret, set when enrolment starts.
+	TOTPSecret sql.Null[string] `json:"-" db:"totp_secret"`
+	// TOTPEnabled is set once the user has confirmed the enrolment with a valid code.
+	TOTPEnabled bool `json:"totpEnabled" db:"totp_enabled"`
+	// TOTPLastStep is the time step of the last accepted TOTP code.
+	TOTPLastStep sql.Null[int64] `json:"-" db:"totp_last_step"`
+	// Email is the address password reset emails are sent to, once verified.
+	Email sql.Null[string] `json:"email" db:"email"`
+	// EmailVerifiedAt is set when the user verifies the Email, and cleared when it changes.
+	EmailVerifiedAt sql.Null[time.Time] `json:"emailVerifiedAt" db:"email_verified_at"`
+}
+
+var userColumns = builder.ColumnsFrom(User{})
+
+type UserInput struct {
+	Username     string     `json:"username"`
+	PasswordHash string     `json:"-"`
+	Role         scope.Role `json:"role"`
+}
+
+type UserPatch struct {
+	ID           uuid.UUID            `json:"id"`
+	PasswordHash sql.Null[string]     `json:"-"`
+	Disabled     sql.Null[bool]       `json:"disabled"`
+	LastLoginAt  sql.Null[time.Time]  `json:"lastLoginAt"`
+	Role         sql.Null[scope.Role] `json:"role"`
+	TOTPSecret   sql.Null[string]     `json:"-"`
+	TOTPEnabled  sql.Null[bool]       `json:"totpEnabled"`
+}
+
+type UserFilter struct {
+	ID       sql.Null[uuid.UUID]  `json:"id"`
+	Username sql.Null[string]     `json:"username"`
+	Disabled sql.Null[bool]       `json:"disabled"`
+	Role     sql.Null[scope.Role] `json:"role"`
+
+	LastSeen uuid.UUID `json:"lastSeen"`
+	PageSize int       `json:"pageSize"`
+}
+
+type UserModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *UserModel) insert(ctx context.Context, q db.Queryable, input UserInput) (*User, error) {
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"username":      {V: input.Username, Valid: true},
+			"password_hash": {V: input.PasswordHash, Valid: true},
+			"role":          {V: input.Role, Valid: true},
+		}).
+		Returning(userColumns...).
+		Into("auth.user")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("username", input.Username),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user inserted", slog.String("id", u.ID.String()))
+
+	return &u, nil
+}
+
+func (m *UserModel) Insert(ctx context.Context, input UserInput) (*User, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *UserModel) InsertTx(ctx context.Context, tx pgx.Tx, input UserInput) (*User, error) {
+	return m.insert(ctx, tx, input)
+}
+
+func (m *UserModel) selectOne(ctx context.Context, q db.Queryable, id uuid.UUID) (*User, error) {
+	stmt, args := builder.From("auth.user").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Select(userColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user selected")
+
+	return &u, nil
+}
+
+func (m *UserModel) SelectOne(ctx context.Context, id uuid.UUID) (*User, error) {
+	return m.selectOne(ctx, m.DB, id)
+}
+
+func (m *UserModel) SelectOneTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*User, error) {
+	return m.selectOne(ctx, tx, id)
+}
+
+// SelectByUsername selects the user with the username, ignoring case.
+func (m *UserModel) SelectByUsername(ctx context.Context, username string) (*User, error) {
+	stmt, args := builder.From("auth.user").
+		Where(builder.NewPredicate(
+			"LOWER(username) = LOWER(@username)",
+			pgx.NamedArgs{"username": username},
+		)).
+		Select(userColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("username", username),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user selected", slog.String("id", u.ID.String()))
+
+	return &u, nil
+}
+
+// SelectByEmail selects the user with the email address, ignoring case.
+func (m *UserModel) SelectByEmail(ctx context.Context, email string) (*User, error) {
+	stmt, args := builder.From("auth.user").
+		Where(builder.NewPredicate(
+			"LOWER(email) = LOWER(@email)",
+			pgx.NamedArgs{"email": email},
+		)).
+		Select(userColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(m.DB.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user selected", slog.String("id", u.ID.String()))
+
+	return &u, nil
+}
+
+func (m *UserModel) selectMany(
+	ctx context.Context,
+	q db.Queryable,
+	filter UserFilter,
+) ([]*User, *Metadata, error) {
+	stmt, args := builder.From("auth.user").
+		Where(
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("username", builder.Equal, filter.Username),
+			builder.NewNullPredicate("disabled", builder.Equal, filter.Disabled),
+			builder.NewNullPredicate("role", builder.Equal, filter.Role),
+			builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen),
+		).
+		OrderBy(builder.OrderBy{Column: "id", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(userColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	users := make([]*User, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		u, err := m.scan(rows)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		users[i] = &u
+		i++
+	}
+	users = users[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(users),
+	}
+	if len(users) > 0 {
+		metadata.LastSeen = users[metadata.ResponseLength-1].ID
+		metadata.Next = true
+	}
+
+	return users, &metadata, nil
+}
+
+func (m *UserModel) SelectMany(ctx context.Context, filter UserFilter) ([]*User, *Metadata, error) {
+	return m.selectMany(ctx, m.DB, filter)
+}
+
+func (m *UserModel) SelectManyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	filter UserFilter,
+) ([]*User, *Metadata, error) {
+	return m.selectMany(ctx, tx, filter)
+}
+
+// Count returns the number of users, including disabled users.
+func (m *UserModel) Count(ctx context.Context) (int64, error) {
+	const stmt string = `SELECT COUNT(*) FROM auth.user;`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", stmt),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	var count int64
+	if err := m.DB.QueryRow(ctx, stmt).Scan(&count); err != nil {
+		return 0, db.HandleError(ctx, err)
+	}
+
+	return count, nil
+}
+
+func (m *UserModel) update(ctx context.Context, q db.Queryable, patch UserPatch) (*User, error) {
+	stmt, args, err := builder.
+		Update("auth.user").
+		Where(builder.NewGenericPredicate("id", builder.Equal, patch.ID)).
+		Returning(userColumns...).
+		Set(
+			builder.NewNullAssignment("password_hash", patch.PasswordHash),
+			builder.NewNullAssignment("disabled", patch.Disabled),
+			builder.NewNullAssignment("last_login_at", patch.LastLoginAt),
+			builder.NewNullAssignment("role", patch.Role),
+			builder.NewNullAssignment("totp_secret", patch.TOTPSecret),
+			builder.NewNullAssignment("totp_enabled", patch.TOTPEnabled),
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", patch.ID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user updated")
+
+	return &u, nil
+}
+
+func (m *UserModel) Update(ctx context.Context, patch UserPatch) (*User, error) {
+	return m.update(ctx, m.DB, patch)
+}
+
+func (m *UserModel) UpdateTx(ctx context.Context, tx pgx.Tx, patch UserPatch) (*User, error) {
+	return m.update(ctx, tx, patch)
+}
+
+// setEmail changes the email address of the user. The verification of the address is cleared
+// unless the address is unchanged, ignoring case.
+func (m *UserModel) setEmail(
+	ctx context.Context,
+	q db.Queryable,
+	id uuid.UUID,
+	email sql.Null[string],
+) (*User, error) {
+	stmt, args, err := builder.
+		Update("auth.user").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Returning(userColumns...).
+		Set(
+			builder.NewAssignment(
+				"email_verified_at = CASE WHEN LOWER(email) = LOWER(@email) "+
+					"THEN email_verified_at END",
+				pgx.NamedArgs{"email": email},
+			),
+			builder.NewAssignment("email = @email", pgx.NamedArgs{"email": email}),
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "email updated")
+
+	return &u, nil
+}
+
+func (m *UserModel) SetEmail(
+	ctx context.Context,
+	id uuid.UUID,
+	email sql.Null[string],
+) (*User, error) {
+	return m.setEmail(ctx, m.DB, id, email)
+}
+
+func (m *UserModel) SetEmailTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	email sql.Null[string],
+) (*User, error) {
+	return m.setEmail(ctx, tx, id, email)
+}
+
+// VerifyEmailTx marks the email address of the user as verified, if the user still has the
+// address. ErrRecordNotFound is returned otherwise.
+func (m *UserModel) VerifyEmailTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	email string,
+) (*User, error) {
+	stmt, args, err := builder.
+		Update("auth.user").
+		Where(
+			builder.NewGenericPredicate("id", builder.Equal, id),
+			builder.NewPredicate("LOWER(email) = LOWER(@email)", pgx.NamedArgs{"email": email}),
+		).
+		Returning(userColumns...).
+		Set(builder.NewAssignment("email_verified_at = COALESCE(email_verified_at, NOW())", nil))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	u, err := m.scan(tx.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "email verified")
+
+	return &u, nil
+}
+
+// clearTOTP removes the TOTP secret of the user, disabling two-factor authentication.
+func (m *UserModel) clearTOTP(ctx context.Context, q db.Queryable, id uuid.UUID) error {
+	const stmt string = `
+UPDATE auth.user
+SET totp_secret = NULL,
+    totp_enabled = FALSE,
+    totp_last_step = NULL
+WHERE id = $1::UUID;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, id)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	if res.RowsAffected() == 0 {
+		return db.ErrRecordNotFound
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp cleared")
+
+	return nil
+}
+
+func (m *UserModel) ClearTOTP(ctx context.Context, id uuid.UUID) error {
+	return m.clearTOTP(ctx, m.DB, id)
+}
+
+func (m *UserModel) ClearTOTPTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	return m.clearTOTP(ctx, tx, id)
+}
+
+// AdvanceTOTPStep records the time step of an accepted TOTP code. The step is only recorded if
+// it is later than the last accepted step, so false is returned when a code is replayed.
+func (m *UserModel) AdvanceTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	const stmt string = `
+UPDATE auth.user
+SET totp_last_step = $2::BIGINT
+WHERE id = $1::UUID
+  AND (totp_last_step IS NULL OR totp_last_step < $2::BIGINT);
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Int64("step", step),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := m.DB.Exec(ctx, stmt, id, step)
+	if err != nil {
+		return false, db.HandleError(ctx, err)
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+func (m *UserModel) scan(row pgx.Row) (User, error) {
+	var u User
+	err := row.Scan(
+		&u.ID,
+		&u.Username,
+		&u.PasswordHash,
+		&u.Disabled,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&u.LastLoginAt,
+		&u.Role,
+		&u.TOTPSecret,
+		&u.TOTPEnabled,
+		&u.TOTPLastStep,
+		&u.Email,
+		&u.EmailVerifiedAt,
+	)
+	if err != nil {
+		return u, err
+	}
+	return u, nil
+}
@@ -0,0 +1,105 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	var user *data.User
+
+	t.Run("Insert", func(t *testing.T) {
+		inserted, err := models.Users.Insert(ctx, data.UserInput{
+			Username:     "Alice",
+			PasswordHash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		})
+		require.NoError(t, err)
+		assert.False(t, inserted.Disabled)
+		assert.False(t, inserted.LastLoginAt.Valid)
+
+		user = inserted
+	})
+
+	t.Run("InsertDuplicateUsername", func(t *testing.T) {
+		_, err := models.Users.Insert(ctx, data.UserInput{
+			Username:     "alice",
+			PasswordHash: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		})
+		assert.ErrorIs(t, err, db.ErrUniqueConstraintViolation)
+	})
+
+	t.Run("SelectOne", func(t *testing.T) {
+		selected, err := models.Users.SelectOne(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user, selected)
+	})
+
+	t.Run("SelectByUsername", func(t *testing.T) {
+		selected, err := models.Users.SelectByUsername(ctx, "ALICE")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, selected.ID)
+
+		_, err = models.Users.SelectByUsername(ctx, "bob")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("SelectMany", func(t *testing.T) {
+		users, metadata, err := models.Users.SelectMany(ctx, data.UserFilter{
+			ID:       sql.Null[uuid.UUID]{V: user.ID, Valid: true},
+			PageSize: 10,
+		})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, user.ID, metadata.LastSeen)
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		count, err := models.Users.Count(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, count, int64(1))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		updated, err := models.Users.Update(ctx, data.UserPatch{
+			ID:       user.ID,
+			Disabled: sql.Null[bool]{V: true, Valid: true},
+		})
+		require.NoError(t, err)
+		assert.True(t, updated.Disabled)
+		assert.Equal(t, user.PasswordHash, updated.PasswordHash)
+	})
+
+	t.Run("InvalidateByUser", func(t *testing.T) {
+		timestamp := time.Now()
+		token, err := models.RefreshTokens.Insert(ctx, data.RefreshTokenInput{
+			Issuer:     "islandwind",
+			Expiration: timestamp.Add(time.Hour),
+			IssuedAt:   timestamp,
+			UserID:     uuid.NullUUID{UUID: user.ID, Valid: true},
+		})
+		require.NoError(t, err)
+
+		affected, err := models.RefreshTokens.InvalidateByUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), affected)
+
+		selected, err := models.RefreshTokens.SelectOne(ctx, token.ID)
+		require.NoError(t, err)
+		assert.True(t, selected.Invalidated)
+	})
+}
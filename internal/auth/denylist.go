@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+)
+
+const (
+	// denylistRetryInterval is how long to wait before listening again after the connection
+	// listening for denied access tokens failed.
+	denylistRetryInterval = 5 * time.Second
+	// denylistPruneInterval is how often expired access tokens are removed from the denylist.
+	denylistPruneInterval = time.Minute
+)
+
+// denylistSync keeps the in-memory denylist of the instance in sync with the access tokens
+// revoked by all instances, and prunes the expired tokens.
+type denylistSync struct {
+	denylist      repo.DenylistService
+	retryInterval time.Duration
+	pruneInterval time.Duration
+	logger        *slog.Logger
+	cancel        context.CancelFunc
+	done          chan struct{}
+}
+
+func newDenylistSync(denylist repo.DenylistService, logger *slog.Logger) *denylistSync {
+	return &denylistSync{
+		denylist:      denylist,
+		retryInterval: denylistRetryInterval,
+		pruneInterval: denylistPruneInterval,
+		logger:        logger.With(slog.String("worker", "denylist")),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start syncs the denylist in the background until Stop is called.
+func (s *denylistSync) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	var wg sync.WaitGroup
+	wg.Go(func() { s.listen(ctx) })
+	wg.Go(func() { s.prune(ctx) })
+	go func() {
+		wg.Wait()
+		close(s.done)
+	}()
+}
+
+// Stop cancels the sync and waits for it to return.
+func (s *denylistSync) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// listen runs the sync, and runs it again after a delay whenever the connection fails.
+// Meanwhile, the denylist is checked against the database.
+func (s *denylistSync) listen(ctx context.Context) {
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "starting denylist sync")
+	for {
+		err := s.denylist.Sync(ctx)
+		if ctx.Err() != nil {
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "denylist sync stopped")
+			return
+		}
+		s.logger.LogAttrs(
+			ctx,
+			slog.LevelError,
+			"denylist sync failed, retrying",
+			slog.String("error", err.Error()),
+			slog.Duration("retryInterval", s.retryInterval),
+		)
+
+		select {
+		case <-ctx.Done():
+			s.logger.LogAttrs(ctx, slog.LevelInfo, "denylist sync stopped")
+			return
+		case <-time.After(s.retryInterval):
+		}
+	}
+}
+
+func (s *denylistSync) prune(ctx context.Context) {
+	ticker := time.NewTicker(s.pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.denylist.Prune(ctx); err != nil && ctx.Err() == nil {
+			s.logger.LogAttrs(
+				ctx, slog.LevelError, "unable to prune denylist", slog.String("error", err.Error()),
+			)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDenylist fails the first sync, as if the connection was lost, and blocks later syncs
+// until the context is done.
+type fakeDenylist struct {
+	syncs  atomic.Int32
+	prunes atomic.Int32
+}
+
+func (f *fakeDenylist) Deny(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	return nil
+}
+
+func (f *fakeDenylist) DenySession(
+	ctx context.Context,
+	sessionID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	return nil
+}
+
+func (f *fakeDenylist) IsDenied(
+	ctx context.Context,
+	jti uuid.UUID,
+	sessionID uuid.UUID,
+) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeDenylist) Sync(ctx context.Context) error {
+	if f.syncs.Add(1) == 1 {
+		return errors.New("connection lost")
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fakeDenylist) Prune(ctx context.Context) error {
+	f.prunes.Add(1)
+	return nil
+}
+
+func TestDenylistSync(t *testing.T) {
+	logger := testsuite.NewTestLogger()
+
+	t.Run("RetriesUntilStopped", func(t *testing.T) {
+		denylist := &fakeDenylist{}
+		s := newDenylistSync(denylist, &logger)
+		s.retryInterval = time.Millisecond
+		s.pruneInterval = 10 * time.Millisecond
+
+		s.Start(context.Background())
+		assert.Eventually(t, func() bool {
+			return denylist.syncs.Load() == 2 && denylist.prunes.Load() >= 2
+		}, time.Second, 5*time.Millisecond)
+
+		s.Stop()
+		prunes := denylist.prunes.Load()
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, int32(2), denylist.syncs.Load())
+		assert.Equal(t, prunes, denylist.prunes.Load())
+	})
+
+	t.Run("StopWithoutStart", func(t *testing.T) {
+		s := newDenylistSync(&fakeDenylist{}, &logger)
+		s.Stop()
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+const maxAPITokenNameLength int = 128
+
+type APITokenRequestBody struct {
+	Data repo.APITokenInput `json:"data"`
+}
+
+// CreatedAPIToken is a newly created personal access token. The token is only included in
+// this response.
+type CreatedAPIToken struct {
+	repo.APIToken
+	Token string `json:"token"`
+}
+
+type CreatedAPITokenResponse struct {
+	Data CreatedAPIToken `json:"data"`
+}
+
+type APITokenListResponse struct {
+	Data []*repo.APIToken `json:"data"`
+}
+
+// PostAPITokenHandler creates a personal access token for the caller. The token may only be
+// given scopes the caller has.
+func PostAPITokenHandler(apiTokens repo.APITokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		principal, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var body APITokenRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		validateAPIToken(v, principal, body.Data)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		apiToken, token, err := apiTokens.Create(ctx, userID, body.Data)
+		if err != nil {
+			apiTokenErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(apiToken, "apiToken should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusCreated,
+			CreatedAPITokenResponse{Data: CreatedAPIToken{APIToken: *apiToken, Token: token}},
+			http.Header{"Cache-Control": []string{"no-store"}},
+		)
+	}
+}
+
+// ListAPITokensHandler lists the personal access tokens of the caller.
+func ListAPITokensHandler(apiTokens repo.APITokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+
+		list, err := apiTokens.List(ctx, userID)
+		if err != nil {
+			apiTokenErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		api.RespondWithJSON(w, r, http.StatusOK, APITokenListResponse{Data: list}, nil)
+	}
+}
+
+// RevokeAPITokenHandler revokes the personal access token in the path, if it belongs to the
+// caller.
+func RevokeAPITokenHandler(apiTokens repo.APITokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		if err := apiTokens.Revoke(ctx, userID, *id); err != nil {
+			apiTokenErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func validateAPIToken(v *validator.Validator, principal api.Principal, input repo.APITokenInput) {
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(
+		utf8.RuneCountInString(input.Name) <= maxAPITokenNameLength,
+		"name",
+		fmt.Sprintf("must not be more than %d characters long", maxAPITokenNameLength),
+	)
+
+	v.Check(len(input.Scopes) > 0, "scopes", "must contain at least one scope")
+	for _, s := range input.Scopes {
+		if !principal.HasScope(s) {
+			v.AddError("scopes", fmt.Sprintf("scope %q is not granted to the user", s))
+		}
+	}
+	v.Check(!hasDuplicates(input.Scopes), "scopes", "must not contain duplicates")
+
+	if input.ExpiresAt != nil {
+		v.Check(input.ExpiresAt.After(time.Now()), "expiresAt", "must be in the future")
+	}
+}
+
+func hasDuplicates(values []string) bool {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return len(slices.Compact(sorted)) != len(values)
+}
+
+func apiTokenErrorResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		api.NotFoundResponse(ctx, w, r)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	principal := api.Principal{
+		Subject: testUser.ID.String(),
+		Scopes:  []string{scope.BlogWrite, scope.AuthSelf},
+	}
+	var created handlers.CreatedAPITokenResponse
+
+	t.Run("PostAPITokenHandler", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(`{"data":{"name":"ci","scopes":["blog:write"]}}`),
+		)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.PostAPITokenHandler(authRepo.APITokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		assert.True(t, strings.HasPrefix(created.Data.Token, repo.APITokenPrefix))
+		assert.Equal(t, []string{scope.BlogWrite}, created.Data.Scopes)
+	})
+
+	t.Run("PostAPITokenHandlerScopeNotGranted", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost,
+			"/",
+			strings.NewReader(`{"data":{"name":"ci","scopes":["auth:users"]}}`),
+		)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.PostAPITokenHandler(authRepo.APITokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("ListAPITokensHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.ListAPITokensHandler(authRepo.APITokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		// The token is never listed.
+		assert.NotContains(t, rr.Body.String(), created.Data.Token)
+
+		var resp handlers.APITokenListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.NotEmpty(t, resp.Data)
+	})
+
+	t.Run("RevokeAPITokenHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", created.Data.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.RevokeAPITokenHandler(authRepo.APITokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = httptest.NewRecorder()
+		handlers.RevokeAPITokenHandler(authRepo.APITokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
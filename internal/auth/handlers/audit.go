@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+type AuditEventListResponse struct {
+	Metadata data.Metadata      `json:"metadata"`
+	Data     []*repo.AuditEvent `json:"data"`
+}
+
+// ListAuditEventsHandler lists the audit events matching the query in the order they
+// occurred. The next page continues after the last_seen event of the metadata.
+func ListAuditEventsHandler(audit repo.AuditService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.AuditEventFilter{}
+
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 25, v)
+		filters.ID = api.ReadQueryNull(api.ParseQueryUUID(qs, "id", v))
+		if eventType, ok := api.ParseQueryString(qs, "type", v)(); ok {
+			v.Check(
+				repo.AuditEventType(eventType).Valid(),
+				"type",
+				"must be one of login, refresh, logout, revoke, refresh_token_delete or "+
+					"password_reset",
+			)
+			filters.EventType = sql.Null[string]{V: eventType, Valid: true}
+		}
+		if outcome, ok := api.ParseQueryString(qs, "outcome", v)(); ok {
+			v.Check(repo.AuditOutcome(outcome).Valid(), "outcome", "must be success or failure")
+			filters.Outcome = sql.Null[string]{V: outcome, Valid: true}
+		}
+		filters.ActorID = api.ReadQueryNull(api.ParseQueryUUID(qs, "actor_id", v))
+		filters.ClientIP = api.ReadQueryNull(api.ParseQueryString(qs, "client_ip", v))
+		filters.SessionID = api.ReadQueryNull(api.ParseQueryUUID(qs, "session_id", v))
+		filters.TokenID = api.ReadQueryNull(api.ParseQueryUUID(qs, "token_id", v))
+		filters.OccurredAtFrom = api.ReadQueryNull(api.ParseQueryDate(qs, "occurred_at_from", v))
+		filters.OccurredAtTo = api.ReadQueryNull(api.ParseQueryDate(qs, "occurred_at_to", v))
+		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		events, metadata, err := audit.List(ctx, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			AuditEventListResponse{
+				Metadata: *metadata,
+				Data:     events,
+			},
+			nil,
+		)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	_, _, err := authRepo.Tokens.CreateSession(
+		ctx, api.Principal{Subject: testUser.ID.String()}, repo.Device{},
+	)
+	require.NoError(t, err)
+
+	t.Run("ListAuditEventsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodGet,
+			"/?type=login&outcome=success&actor_id="+testUser.ID.String(),
+			nil,
+		)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListAuditEventsHandler(authRepo.Audit).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var list handlers.AuditEventListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+		require.NotEmpty(t, list.Data)
+		for _, event := range list.Data {
+			assert.Equal(t, repo.AuditLogin, event.Type)
+			assert.Equal(t, testUser.ID, *event.ActorID)
+		}
+		assert.Equal(t, list.Data[len(list.Data)-1].ID, list.Metadata.LastSeen)
+	})
+
+	t.Run("ListAuditEventsHandlerInvalidFilter", func(t *testing.T) {
+		for _, query := range []string{"?type=signup", "?outcome=maybe", "?actor_id=nobody"} {
+			req, err := http.NewRequest(http.MethodGet, "/"+query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handlers.ListAuditEventsHandler(authRepo.Audit).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, query)
+		}
+	})
+}
@@ -3,8 +3,10 @@ package handlers
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/r3d5un/islandwind/internal/auth/data"
 	"github.com/r3d5un/islandwind/internal/ensure"
@@ -15,8 +17,24 @@ import (
 )
 
 type Response struct {
-	AccessToken  string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
+	AccessToken string `json:"accessToken"`
+	// RefreshToken is omitted if the refresh token is set in the RefreshTokenCookie.
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// CSRFToken is the token to repeat in the CSRFTokenHeader of requests authenticated by the
+	// RefreshTokenCookie.
+	CSRFToken string `json:"csrfToken,omitempty"`
+}
+
+// LoginRequestBody is the optional body of login requests.
+type LoginRequestBody struct {
+	// Label names the session, e.g. "work laptop".
+	Label *string `json:"label"`
+}
+
+// LoginChallengeResponse is returned when logging in as a user with two-factor authentication.
+type LoginChallengeResponse struct {
+	TOTPRequired   bool   `json:"totpRequired"`
+	ChallengeToken string `json:"challengeToken"`
 }
 
 type RefreshRequestBody struct {
@@ -157,47 +175,97 @@ func DeleteRefreshTokenHandler(tokens repo.TokenService) http.HandlerFunc {
 	}
 }
 
-func LoginHandler(tokens repo.TokenService) http.HandlerFunc {
+// LoginHandler starts a session for the user authenticated by the BasicAuthMiddleware, and
+// issues the tokens of the session. The user agent and IP address of the client are recorded
+// with the session, and the body may label the session.
+//
+// If the user has two-factor authentication enabled, no tokens are issued. Instead, a
+// LoginChallengeResponse is returned, and the challenge token is exchanged for the tokens with
+// a TOTP code at the TOTPLoginHandler.
+//
+// Browser clients should log in with the cookie query parameter set to true, which sets the
+// refresh token in an HttpOnly cookie rather than the response body.
+func LoginHandler(
+	tokens repo.TokenService,
+	users repo.UserService,
+	clientIPHeader string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		accessToken, err := tokens.CreateAccessToken()
+		principal, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var body LoginRequestBody
+		if err := api.ReadJSON(r, &body); err != nil && !errors.Is(err, io.EOF) {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+		v := validator.New()
+		if body.Label != nil {
+			validateSessionLabel(v, *body.Label)
+		}
+		cookie := useCookie(r, v)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		user, err := users.Read(ctx, userID)
 		if err != nil {
 			api.ServerErrorResponse(w, r, err)
 			return
 		}
-		ensure.NotNil(accessToken, "accessToken should not be nil without errors")
+		if user.TOTPEnabled {
+			challenge, err := tokens.CreateTOTPChallenge(ctx, repo.TOTPChallenge{
+				UserID: userID,
+				Label:  body.Label,
+			})
+			if err != nil {
+				api.ServerErrorResponse(w, r, err)
+				return
+			}
+			ensure.NotNil(challenge, "challenge should not be nil without errors")
+
+			api.RespondWithJSON(
+				w,
+				r,
+				http.StatusOK,
+				LoginChallengeResponse{TOTPRequired: true, ChallengeToken: *challenge},
+				nil,
+			)
+			return
+		}
 
-		refreshToken, err := tokens.CreateRefreshToken(ctx)
+		device := newDevice(r, clientIPHeader)
+		device.Label = body.Label
+		accessToken, refreshToken, err := tokens.CreateSession(ctx, principal, device)
 		if err != nil {
 			api.ServerErrorResponse(w, r, err)
 			return
 		}
+		ensure.NotNil(accessToken, "accessToken should not be nil without errors")
 		ensure.NotNil(refreshToken, "refreshToken should not be nil without errors")
 
-		api.RespondWithJSON(
-			w,
-			r,
-			http.StatusOK,
-			Response{
-				AccessToken:  *accessToken,
-				RefreshToken: *refreshToken,
-			},
-			nil,
-		)
+		respondWithTokens(w, r, *accessToken, *refreshToken, cookie)
 	}
 }
 
+// LogoutHandler invalidates the refresh token of the request body, or of the refresh token
+// cookie, which is then cleared. The bearer access token of the request, if any, is revoked as
+// well, so that it is rejected right away instead of when it expires.
 func LogoutHandler(tokens repo.TokenService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		var body RefreshRequestBody
-		if err := api.ReadJSON(r, &body); err != nil {
-			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+		refreshToken, fromCookie, err := readRefreshToken(r)
+		if err != nil {
+			readRefreshTokenErrorResponse(w, r, err)
 			return
 		}
 
-		err := tokens.InvalidateRefreshToken(ctx, body.RefreshToken)
+		err = tokens.InvalidateRefreshToken(ctx, refreshToken)
 		if err != nil {
 			switch {
 			case errors.Is(err, repo.ErrVerifyingToken), errors.Is(err, repo.ErrParsingToken):
@@ -214,6 +282,16 @@ func LogoutHandler(tokens repo.TokenService) http.HandlerFunc {
 			return
 		}
 
+		if accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if err := tokens.Revoke(ctx, accessToken, repo.AccessTokenHint); err != nil {
+				api.ServerErrorResponse(w, r, err)
+				return
+			}
+		}
+		if fromCookie {
+			clearRefreshTokenCookies(w)
+		}
+
 		api.RespondWithJSON(
 			w,
 			r,
@@ -224,16 +302,21 @@ func LogoutHandler(tokens repo.TokenService) http.HandlerFunc {
 	}
 }
 
-func RefreshHandler(tokens repo.TokenService) http.HandlerFunc {
+// RefreshHandler replaces the refresh token of the request body, or of the refresh token
+// cookie, with new tokens. Tokens read from the cookie are replaced in the cookie along with
+// the CSRF token.
+func RefreshHandler(tokens repo.TokenService, clientIPHeader string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		var body RefreshRequestBody
-		if err := api.ReadJSON(r, &body); err != nil {
-			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+		token, fromCookie, err := readRefreshToken(r)
+		if err != nil {
+			readRefreshTokenErrorResponse(w, r, err)
 			return
 		}
 
-		accessToken, refreshToken, err := tokens.Refresh(ctx, body.RefreshToken)
+		accessToken, refreshToken, err := tokens.Refresh(
+			ctx, token, newDevice(r, clientIPHeader),
+		)
 		if err != nil {
 			switch {
 			case errors.Is(err, repo.ErrVerifyingToken), errors.Is(err, repo.ErrParsingToken):
@@ -252,16 +335,7 @@ func RefreshHandler(tokens repo.TokenService) http.HandlerFunc {
 		ensure.NotNil(accessToken, "accessToken should not be nil without errors")
 		ensure.NotNil(refreshToken, "refreshToken should not be nil without errors")
 
-		api.RespondWithJSON(
-			w,
-			r,
-			http.StatusOK,
-			Response{
-				AccessToken:  *accessToken,
-				RefreshToken: *refreshToken,
-			},
-			nil,
-		)
+		respondWithTokens(w, r, *accessToken, *refreshToken, fromCookie)
 	}
 }
 
@@ -279,4 +353,6 @@ func addRefreshTokenFilters(
 	filters.ExpirationTo = api.ReadQueryNull(api.ParseQueryDate(qs, "expiration_to", v))
 	filters.Invalidated = api.ReadQueryNull(api.ParseQueryBoolean(qs, "invalidated", v))
 	filters.InvalidatedBy = api.ReadQueryNull(api.ParseQueryUUID(qs, "invalidated_by", v))
+	filters.UserID = api.ReadQueryNull(api.ParseQueryUUID(qs, "user_id", v))
+	filters.SessionID = api.ReadQueryNull(api.ParseQueryUUID(qs, "session_id", v))
 }
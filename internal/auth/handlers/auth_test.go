@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/r3d5un/islandwind/internal/api"
 	"github.com/r3d5un/islandwind/internal/auth/handlers"
 	"github.com/r3d5un/islandwind/internal/auth/repo"
 	"github.com/stretchr/testify/assert"
@@ -28,9 +29,12 @@ func TestAuthHandlers(t *testing.T) {
 	t.Run("LoginHandler", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodPost, "/", nil)
 		assert.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+		}))
 
 		rr := httptest.NewRecorder()
-		handler := handlers.LoginHandler(authRepo.Tokens)
+		handler := handlers.LoginHandler(authRepo.Tokens, authRepo.Users, "")
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
@@ -41,7 +45,7 @@ func TestAuthHandlers(t *testing.T) {
 	})
 
 	t.Run("LogoutHandler", func(t *testing.T) {
-		token, err := authRepo.Tokens.CreateRefreshToken(ctx)
+		token, err := authRepo.Tokens.CreateRefreshToken(ctx, testUser.ID)
 		assert.NoError(t, err)
 		body, err := json.Marshal(handlers.RefreshRequestBody{RefreshToken: *token})
 		assert.NoError(t, err)
@@ -59,6 +63,28 @@ func TestAuthHandlers(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("LogoutHandlerRevokesAccessToken", func(t *testing.T) {
+		accessToken, refreshToken, err := authRepo.Tokens.CreateSession(
+			ctx,
+			api.Principal{Subject: testUser.ID.String()},
+			repo.Device{},
+		)
+		assert.NoError(t, err)
+		body, err := json.Marshal(handlers.RefreshRequestBody{RefreshToken: *refreshToken})
+		assert.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+*accessToken)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.LogoutHandler(authRepo.Tokens)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		_, err = authRepo.Tokens.Authenticate(ctx, *accessToken)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
+	})
+
 	t.Run("RefreshHandler", func(t *testing.T) {
 		body, err := json.Marshal(handlers.RefreshRequestBody{
 			RefreshToken: login.RefreshToken,
@@ -68,7 +94,7 @@ func TestAuthHandlers(t *testing.T) {
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
-		handler := handlers.RefreshHandler(authRepo.Tokens)
+		handler := handlers.RefreshHandler(authRepo.Tokens, "")
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
@@ -87,7 +113,7 @@ func TestAuthHandlers(t *testing.T) {
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
-		handler := handlers.RefreshHandler(authRepo.Tokens)
+		handler := handlers.RefreshHandler(authRepo.Tokens, "")
 		handler.ServeHTTP(rr, req)
 
 		assert.Equal(t, http.StatusUnauthorized, rr.Code)
@@ -97,6 +123,117 @@ func TestAuthHandlers(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	var cookies []*http.Cookie
+	var cookieLogin handlers.Response
+
+	t.Run("LoginHandlerCookie", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/?cookie=true", nil)
+		assert.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handler := handlers.LoginHandler(authRepo.Tokens, authRepo.Users, "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		err = json.Unmarshal(rr.Body.Bytes(), &cookieLogin)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, cookieLogin.AccessToken)
+		assert.Empty(t, cookieLogin.RefreshToken)
+		assert.NotEmpty(t, cookieLogin.CSRFToken)
+
+		cookies = rr.Result().Cookies()
+		refreshCookie := findCookie(cookies, handlers.RefreshTokenCookie)
+		if assert.NotNil(t, refreshCookie) {
+			assert.NotEmpty(t, refreshCookie.Value)
+			assert.True(t, refreshCookie.HttpOnly)
+			assert.True(t, refreshCookie.Secure)
+			assert.Equal(t, http.SameSiteStrictMode, refreshCookie.SameSite)
+		}
+		csrfCookie := findCookie(cookies, handlers.CSRFTokenCookie)
+		if assert.NotNil(t, csrfCookie) {
+			assert.Equal(t, cookieLogin.CSRFToken, csrfCookie.Value)
+			assert.False(t, csrfCookie.HttpOnly)
+		}
+	})
+
+	t.Run("RefreshHandlerCookieInvalidCSRFToken", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		assert.NoError(t, err)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		req.Header.Set(handlers.CSRFTokenHeader, "invalid")
+
+		rr := httptest.NewRecorder()
+		handler := handlers.RefreshHandler(authRepo.Tokens, "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("RefreshHandlerCookie", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		assert.NoError(t, err)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		req.Header.Set(handlers.CSRFTokenHeader, cookieLogin.CSRFToken)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.RefreshHandler(authRepo.Tokens, "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var refreshed handlers.Response
+		err = json.Unmarshal(rr.Body.Bytes(), &refreshed)
+		assert.NoError(t, err)
+		assert.Empty(t, refreshed.RefreshToken)
+		assert.NotEqual(t, cookieLogin.CSRFToken, refreshed.CSRFToken)
+
+		previous := findCookie(cookies, handlers.RefreshTokenCookie)
+		cookies = rr.Result().Cookies()
+		refreshCookie := findCookie(cookies, handlers.RefreshTokenCookie)
+		if assert.NotNil(t, refreshCookie) && assert.NotNil(t, previous) {
+			assert.NotEqual(t, previous.Value, refreshCookie.Value)
+		}
+		cookieLogin = refreshed
+	})
+
+	t.Run("LogoutHandlerCookie", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		assert.NoError(t, err)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		req.Header.Set(handlers.CSRFTokenHeader, cookieLogin.CSRFToken)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.LogoutHandler(authRepo.Tokens)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		for _, name := range []string{handlers.RefreshTokenCookie, handlers.CSRFTokenCookie} {
+			cookie := findCookie(rr.Result().Cookies(), name)
+			if assert.NotNil(t, cookie) {
+				assert.Negative(t, cookie.MaxAge)
+			}
+		}
+	})
+
+	t.Run("RefreshHandlerMissingRefreshToken", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.RefreshHandler(authRepo.Tokens, "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
 	t.Run("ListRefreshTokenHandler", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "/", nil)
 		assert.NoError(t, err)
@@ -158,3 +295,12 @@ func TestAuthHandlers(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+const (
+	// RefreshTokenCookie holds the refresh token of browser clients logging in with the cookie
+	// query parameter, out of reach of scripts.
+	RefreshTokenCookie = "islandwind_refresh_token"
+	// CSRFTokenCookie holds the CSRF token issued with the RefreshTokenCookie. Requests
+	// authenticated by the RefreshTokenCookie must repeat the token in the CSRFTokenHeader.
+	CSRFTokenCookie = "islandwind_csrf_token"
+	CSRFTokenHeader = "X-CSRF-Token"
+	// refreshTokenCookiePath limits the refresh token cookie to the auth routes.
+	refreshTokenCookiePath = "/api/v1/auth"
+)
+
+var (
+	ErrMissingRefreshToken = errors.New("missing refresh token")
+	ErrInvalidCSRFToken    = errors.New("invalid CSRF token")
+)
+
+// useCookie reports whether the client asked for the refresh token in a cookie with the cookie
+// query parameter.
+func useCookie(r *http.Request, v *validator.Validator) bool {
+	cookie, _ := api.ParseQueryBoolean(r.URL.Query(), "cookie", v)()
+	return cookie
+}
+
+// respondWithTokens responds with the tokens of a session. If cookie is set, the refresh token
+// is set in the RefreshTokenCookie instead, and the body holds the CSRF token.
+func respondWithTokens(
+	w http.ResponseWriter,
+	r *http.Request,
+	accessToken string,
+	refreshToken string,
+	cookie bool,
+) {
+	response := Response{AccessToken: accessToken, RefreshToken: refreshToken}
+	if cookie {
+		response.RefreshToken = ""
+		response.CSRFToken = setRefreshTokenCookies(w, refreshToken)
+	}
+
+	api.RespondWithJSON(w, r, http.StatusOK, response, nil)
+}
+
+// setRefreshTokenCookies sets the refresh token cookie along with a new CSRF token cookie,
+// returning the CSRF token. The CSRF token cookie is readable by scripts, so that clients may
+// recover the token after reloading.
+func setRefreshTokenCookies(w http.ResponseWriter, refreshToken string) string {
+	csrfToken := rand.Text()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookie,
+		Value:    refreshToken,
+		Path:     refreshTokenCookiePath,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFTokenCookie,
+		Value:    csrfToken,
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return csrfToken
+}
+
+func clearRefreshTokenCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookie,
+		Path:     refreshTokenCookiePath,
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFTokenCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// readRefreshToken reads the refresh token from the request body, or from the
+// RefreshTokenCookie if the body has none. The token is only read from the cookie if the
+// CSRFTokenHeader matches the CSRFTokenCookie, as browsers send the cookie regardless of who
+// made the request.
+func readRefreshToken(r *http.Request) (token string, fromCookie bool, err error) {
+	var body RefreshRequestBody
+	if err := api.ReadJSON(r, &body); err != nil && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	if body.RefreshToken != "" {
+		return body.RefreshToken, false, nil
+	}
+
+	refreshCookie, err := r.Cookie(RefreshTokenCookie)
+	if err != nil || refreshCookie.Value == "" {
+		return "", false, ErrMissingRefreshToken
+	}
+	csrfCookie, err := r.Cookie(CSRFTokenCookie)
+	if err != nil || csrfCookie.Value == "" {
+		return "", true, ErrInvalidCSRFToken
+	}
+	header := r.Header.Get(CSRFTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(header), []byte(csrfCookie.Value)) != 1 {
+		return "", true, ErrInvalidCSRFToken
+	}
+
+	return refreshCookie.Value, true, nil
+}
+
+// readRefreshTokenErrorResponse responds to requests the refresh token could not be read from.
+func readRefreshTokenErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrMissingRefreshToken):
+		api.BadRequestResponse(w, r, err, "missing refresh token")
+	case errors.Is(err, ErrInvalidCSRFToken):
+		api.ErrorResponse(w, r, http.StatusForbidden, "invalid CSRF token")
+	default:
+		api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+	}
+}
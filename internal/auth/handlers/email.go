@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	netmail "net/mail"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+// maxEmailLength is the maximum length of an email address in a SMTP path.
+const maxEmailLength int = 254
+
+type EmailRequestBody struct {
+	Data EmailInput `json:"data"`
+}
+
+type EmailInput struct {
+	Email string `json:"email"`
+}
+
+type EmailTokenRequestBody struct {
+	Data EmailTokenInput `json:"data"`
+}
+
+// EmailTokenInput is the body used to verify an email address, with the token sent to it.
+type EmailTokenInput struct {
+	Token string `json:"token"`
+}
+
+type PasswordResetRequestBody struct {
+	Data PasswordReset `json:"data"`
+}
+
+// PasswordReset is the body used to reset a password, with the token sent to the verified email
+// address of the user.
+type PasswordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// SetEmailHandler changes the email address of a user, and sends a verification email to it.
+// Users may change their own address, while changing the address of others requires the
+// auth:users scope.
+func SetEmailHandler(emails repo.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		var body EmailRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		principal, ok := api.PrincipalFromContext(ctx)
+		if !(ok && principal.Subject == id.String()) && !principal.HasScope(scope.AuthUsers) {
+			api.ForbiddenResponse(w, r, scope.AuthUsers)
+			return
+		}
+
+		v := validator.New()
+		validateEmail(v, "email", body.Data.Email)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		user, err := emails.SetEmail(ctx, *id, body.Data.Email)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrUniqueConstraintViolation):
+				api.ConstraintViolationResponse(w, r, err, "email already in use")
+			default:
+				userErrorResponse(ctx, w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, UserResponse{Data: *user}, nil)
+	}
+}
+
+// SendEmailVerificationHandler sends a new verification email to the unverified address of a
+// user, for when the earlier email was lost or expired.
+func SendEmailVerificationHandler(emails repo.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		principal, ok := api.PrincipalFromContext(ctx)
+		if !(ok && principal.Subject == id.String()) && !principal.HasScope(scope.AuthUsers) {
+			api.ForbiddenResponse(w, r, scope.AuthUsers)
+			return
+		}
+
+		if err := emails.SendVerification(ctx, *id); err != nil {
+			switch {
+			case errors.Is(err, repo.ErrNoEmail), errors.Is(err, repo.ErrEmailVerified):
+				api.ErrorResponse(w, r, http.StatusConflict, err.Error())
+			default:
+				userErrorResponse(ctx, w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// VerifyEmailHandler verifies the email address the token in the body was sent to. The token
+// authenticates the request, so no access token is required.
+func VerifyEmailHandler(emails repo.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body EmailTokenRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		v.Check(body.Data.Token != "", "token", "must be provided")
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		if _, err := emails.VerifyEmail(ctx, body.Data.Token); err != nil {
+			emailTokenErrorResponse(ctx, w, r, v, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RequestPasswordResetHandler sends a password reset email to the verified address in the body.
+// The request is accepted whether or not a user has the address, and before the email is sent,
+// so that neither the response nor its timing tell which addresses are registered.
+func RequestPasswordResetHandler(emails repo.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body EmailRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		validateEmail(v, "email", body.Data.Email)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		if err := emails.RequestPasswordReset(ctx, body.Data.Email); err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ResetPasswordHandler replaces the password of the user the token in the body was sent to. The
+// token authenticates the request, so no access token is required.
+func ResetPasswordHandler(emails repo.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body PasswordResetRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		v.Check(body.Data.Token != "", "token", "must be provided")
+		validatePassword(v, "password", body.Data.Password)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		if err := emails.ResetPassword(ctx, body.Data.Token, body.Data.Password); err != nil {
+			emailTokenErrorResponse(ctx, w, r, v, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validateEmail accepts a single bare address, such as "carol@example.com".
+func validateEmail(v *validator.Validator, key string, email string) {
+	v.Check(email != "", key, "must be provided")
+	v.Check(
+		len(email) <= maxEmailLength,
+		key,
+		fmt.Sprintf("must not be more than %d bytes long", maxEmailLength),
+	)
+	address, err := netmail.ParseAddress(email)
+	v.Check(
+		err == nil && address.Name == "" && address.Address == email,
+		key,
+		"must be a valid email address",
+	)
+}
+
+func emailTokenErrorResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	v *validator.Validator,
+	err error,
+) {
+	switch {
+	case errors.Is(err, repo.ErrInvalidEmailToken):
+		v.AddError("token", err.Error())
+		api.ValidationFailedResponse(ctx, w, r, v.Errors)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var emailLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+// lastEmailToken returns the token of the link in the last email sent.
+func lastEmailToken(t *testing.T) string {
+	t.Helper()
+
+	messages := mailbox.Messages()
+	require.NotEmpty(t, messages)
+	link, err := url.Parse(emailLinkPattern.FindString(messages[len(messages)-1].Text))
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestEmailHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	user, err := authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "heidi",
+		Password: "an initial password",
+	})
+	require.NoError(t, err)
+	principal := api.Principal{Subject: user.ID.String()}
+
+	t.Run("SetEmailHandlerInvalid", func(t *testing.T) {
+		for _, email := range []string{"", "heidi", "Heidi <heidi@example.com>"} {
+			body, err := json.Marshal(handlers.EmailRequestBody{
+				Data: handlers.EmailInput{Email: email},
+			})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+			require.NoError(t, err)
+			req.SetPathValue("id", user.ID.String())
+			req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+			rr := httptest.NewRecorder()
+			handlers.SetEmailHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, email)
+		}
+	})
+
+	t.Run("SetEmailHandlerForbidden", func(t *testing.T) {
+		body, err := json.Marshal(handlers.EmailRequestBody{
+			Data: handlers.EmailInput{Email: "mallory@example.com"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", testUser.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.SetEmailHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+		// Changing the email address of other users requires the auth:users scope.
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("SetEmailHandler", func(t *testing.T) {
+		body, err := json.Marshal(handlers.EmailRequestBody{
+			Data: handlers.EmailInput{Email: "heidi@example.com"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", user.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.SetEmailHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.UserResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Data.Email)
+		assert.Equal(t, "heidi@example.com", *resp.Data.Email)
+		assert.Nil(t, resp.Data.EmailVerifiedAt)
+	})
+
+	t.Run("SetEmailHandlerConflict", func(t *testing.T) {
+		body, err := json.Marshal(handlers.EmailRequestBody{
+			Data: handlers.EmailInput{Email: "HEIDI@example.com"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", testUser.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.SetEmailHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("SendEmailVerificationHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", user.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.SendEmailVerificationHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+
+	t.Run("VerifyEmailHandler", func(t *testing.T) {
+		body, err := json.Marshal(handlers.EmailTokenRequestBody{
+			Data: handlers.EmailTokenInput{Token: lastEmailToken(t)},
+		})
+		require.NoError(t, err)
+
+		for _, status := range []int{http.StatusNoContent, http.StatusUnprocessableEntity} {
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handlers.VerifyEmailHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+			// Tokens are single-use.
+			assert.Equal(t, status, rr.Code)
+		}
+	})
+
+	t.Run("RequestPasswordResetHandler", func(t *testing.T) {
+		for _, email := range []string{"heidi@example.com", "nobody@example.com"} {
+			sent := len(mailbox.Messages())
+			body, err := json.Marshal(handlers.EmailRequestBody{
+				Data: handlers.EmailInput{Email: email},
+			})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handlers.RequestPasswordResetHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+			// The response does not tell whether a user has the address.
+			assert.Equal(t, http.StatusAccepted, rr.Code)
+			authRepo.Emails.Wait()
+			if email == "heidi@example.com" {
+				assert.Len(t, mailbox.Messages(), sent+1)
+			} else {
+				assert.Len(t, mailbox.Messages(), sent)
+			}
+		}
+	})
+
+	t.Run("ResetPasswordHandlerShortPassword", func(t *testing.T) {
+		body, err := json.Marshal(handlers.PasswordResetRequestBody{
+			Data: handlers.PasswordReset{Token: lastEmailToken(t), Password: "short"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ResetPasswordHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("ResetPasswordHandler", func(t *testing.T) {
+		const newPassword string = "a password reset by email"
+		body, err := json.Marshal(handlers.PasswordResetRequestBody{
+			Data: handlers.PasswordReset{Token: lastEmailToken(t), Password: newPassword},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ResetPasswordHandler(authRepo.Emails).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		_, err = authRepo.Users.Authenticate(ctx, "heidi", newPassword)
+		assert.NoError(t, err)
+	})
+}
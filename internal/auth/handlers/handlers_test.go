@@ -10,12 +10,30 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/r3d5un/islandwind/internal/auth/mail"
+	"github.com/r3d5un/islandwind/internal/auth/oidc"
 	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 	"github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/testsuite"
 )
 
-var authRepo repo.Repository
+const (
+	testUserPassword string = "correct horse battery staple"
+	// oidcRedirectURL is the callback the OIDC provider redirects users back to.
+	oidcRedirectURL string = "http://localhost:4000/api/v1/auth/oidc/callback"
+)
+
+var (
+	authRepo repo.Repository
+	// testUser is the user tokens are issued to.
+	testUser *repo.User
+	// mailbox keeps the emails sent by the repository.
+	mailbox *mail.Mailbox
+	// oidcProvider is the OpenID Connect provider users log in with.
+	oidcProvider *testsuite.OIDCProvider
+)
 
 func TestMain(m *testing.M) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -36,12 +54,63 @@ func TestMain(m *testing.M) {
 		logger.Error("unable to create database connection pool", slog.String("error", err.Error()))
 		return
 	}
+	mailbox = mail.NewMailbox()
+	keySet, err := keys.Generate()
+	if err != nil {
+		logger.Error("unable to generate signing keys", slog.String("error", err.Error()))
+		return
+	}
+
+	oidcProvider = testsuite.NewOIDCProvider("islandwind", "a client secret")
+	defer oidcProvider.Close()
+	oidcConfig := config.OIDCConfig{
+		Issuer:        oidcProvider.Issuer,
+		ClientID:      oidcProvider.ClientID,
+		ClientSecret:  oidcProvider.ClientSecret,
+		RedirectURL:   oidcRedirectURL,
+		Scopes:        []string{"profile"},
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "groups",
+		RoleMappings:  []string{"blog-editors:editor", "blog-authors:author"},
+	}
+	oidcClient, err := oidc.NewClient(ctx, oidcConfig, nil)
+	if err != nil {
+		logger.Error("unable to create OIDC client", slog.String("error", err.Error()))
+		return
+	}
 	authRepo = repo.NewRepository(
 		pool,
 		new(cfg.TimeoutDuration()),
-		config.Config{SigningSecret: "islandwind", TokenIssuer: "islandwind"},
+		config.Config{
+			RefreshSigningSecret:   "islandwind",
+			ChallengeSigningSecret: "islandwind-challenge",
+			TokenIssuer:            "islandwind",
+			Lockout: config.LockoutConfig{
+				FreeAttempts:     2,
+				BaseDelaySeconds: 1,
+				MaxDelaySeconds:  4,
+				Threshold:        5,
+				IPThreshold:      1000,
+				DurationSeconds:  60,
+				ResetSeconds:     3600,
+			},
+			OIDC: oidcConfig,
+		},
+		keySet,
+		mailbox,
+		oidcClient,
 	)
 
+	testUser, err = authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "test",
+		Password: testUserPassword,
+		Role:     scope.RoleAdmin,
+	})
+	if err != nil {
+		logger.Error("unable to create test user", slog.String("error", err.Error()))
+		return
+	}
+
 	exitCode := m.Run()
 
 	defer os.Exit(exitCode)
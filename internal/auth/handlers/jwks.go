@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+)
+
+// JWKSHandler publishes the keys verifying access tokens as a JSON Web Key Set. Caches are
+// allowed to keep the key set for a few minutes, so new verification keys should be configured
+// some time before they start signing tokens.
+func JWKSHandler(keySet *keys.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			keySet.JWKS(),
+			http.Header{"Cache-Control": []string{"public, max-age=300"}},
+		)
+	}
+}
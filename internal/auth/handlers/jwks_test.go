@@ -0,0 +1,38 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handlers.JWKSHandler(authRepo.Keys).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var jwks keys.JWKS
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+
+	// Access tokens are signed by the published key.
+	accessToken, err := authRepo.Tokens.CreateAccessToken(api.Principal{
+		Subject: testUser.ID.String(),
+	})
+	require.NoError(t, err)
+	token, _, err := jwt.NewParser().ParseUnverified(*accessToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, jwks.Keys[0].KeyID, token.Header["kid"])
+	assert.Equal(t, jwks.Keys[0].Algorithm, token.Method.Alg())
+}
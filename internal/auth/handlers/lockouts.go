@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+type LoginAttemptListResponse struct {
+	Data []*repo.LoginAttempt `json:"data"`
+}
+
+// ListLoginAttemptsHandler lists the usernames, client IP addresses and users with failed login
+// attempts. Set blocked=true to only list those currently throttled or locked out.
+func ListLoginAttemptsHandler(lockouts repo.LockoutService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filter := repo.LoginAttemptFilter{}
+
+		if kind, ok := api.ParseQueryString(qs, "kind", v)(); ok {
+			validateLoginAttemptKind(v, repo.LoginAttemptKind(kind))
+			filter.Kind = new(repo.LoginAttemptKind(kind))
+		}
+		if key, ok := api.ParseQueryString(qs, "key", v)(); ok {
+			if filter.Kind == nil {
+				v.AddError("key", "must be combined with kind")
+			} else {
+				filter.Key = new(repo.NewLoginAttemptKey(*filter.Kind, key).Key)
+			}
+		}
+		if blocked, ok := api.ParseQueryBoolean(qs, "blocked", v)(); ok {
+			filter.Blocked = blocked
+		}
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, err := lockouts.List(ctx, filter)
+		if err != nil {
+			lockoutErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		api.RespondWithJSON(w, r, http.StatusOK, LoginAttemptListResponse{Data: list}, nil)
+	}
+}
+
+// ClearLoginAttemptsHandler resets the failed login attempts of the kind and key in the path,
+// lifting any lockout.
+func ClearLoginAttemptsHandler(lockouts repo.LockoutService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		kind := repo.LoginAttemptKind(r.PathValue("kind"))
+		key := r.PathValue("key")
+		v := validator.New()
+		validateLoginAttemptKind(v, kind)
+		v.Check(key != "", "key", "must be provided")
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		if err := lockouts.Clear(ctx, repo.NewLoginAttemptKey(kind, key)); err != nil {
+			lockoutErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func validateLoginAttemptKind(v *validator.Validator, kind repo.LoginAttemptKind) {
+	v.Check(kind.Valid(), "kind", "must be one of username, ip or totp")
+}
+
+func lockoutErrorResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		api.NotFoundResponse(ctx, w, r)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	// The test configuration locks out after five failures.
+	for range 5 {
+		_, err := authRepo.Lockouts.RecordFailure(ctx, repo.UsernameKey("locked"))
+		require.NoError(t, err)
+	}
+
+	t.Run("ListLoginAttemptsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/?kind=username&key=Locked&blocked=true", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListLoginAttemptsHandler(authRepo.Lockouts).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var list handlers.LoginAttemptListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+		require.Len(t, list.Data, 1)
+		assert.Equal(t, "locked", list.Data[0].Key)
+		assert.True(t, list.Data[0].Locked)
+	})
+
+	t.Run("ListLoginAttemptsHandlerInvalidKind", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/?kind=email", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListLoginAttemptsHandler(authRepo.Lockouts).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("ClearLoginAttemptsHandler", func(t *testing.T) {
+		for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+			req, err := http.NewRequest(http.MethodDelete, "/", nil)
+			require.NoError(t, err)
+			req.SetPathValue("kind", "username")
+			req.SetPathValue("key", "locked")
+
+			rr := httptest.NewRecorder()
+			handlers.ClearLoginAttemptsHandler(authRepo.Lockouts).ServeHTTP(rr, req)
+			assert.Equal(t, expected, rr.Code)
+		}
+
+		wait, err := authRepo.Lockouts.Check(ctx, repo.UsernameKey("locked"))
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+}
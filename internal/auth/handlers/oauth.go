@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/ensure"
+)
+
+// maxOAuthFormBytes limits the size of the form bodies of the OAuth 2.0 endpoints, which only
+// carry a token and a hint.
+const maxOAuthFormBytes int64 = 16 << 10
+
+// IntrospectHandler describes the token of the form-encoded request body, as defined by
+// RFC 7662, so that other services can validate tokens issued by the auth module. The
+// token_type_hint of the request is optional. The request must be authenticated with the
+// ClientAuthMiddleware.
+func IntrospectHandler(tokens repo.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, hint, ok := readTokenForm(w, r)
+		if !ok {
+			return
+		}
+
+		introspection, err := tokens.Introspect(ctx, token, hint)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(introspection, "introspection should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			introspection,
+			http.Header{"Cache-Control": []string{"no-store"}},
+		)
+	}
+}
+
+// RevokeHandler revokes the token of the form-encoded request body, as defined by RFC 7009.
+// Invalid tokens are ignored, so the response is the same whether or not anything was revoked.
+// The request must be authenticated with the ClientAuthMiddleware.
+func RevokeHandler(tokens repo.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, hint, ok := readTokenForm(w, r)
+		if !ok {
+			return
+		}
+
+		if err := tokens.Revoke(ctx, token, hint); err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// readTokenForm reads the token and token_type_hint parameters of the form-encoded request
+// body. Unknown hints are ignored, as the token is looked up as either type regardless. If
+// false is returned, a response has been written.
+func readTokenForm(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxOAuthFormBytes)
+	if err := r.ParseForm(); err != nil {
+		api.OAuthErrorResponse(
+			w, r, http.StatusBadRequest, api.OAuthInvalidRequest, "unable to parse form body",
+		)
+		return "", "", false
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		api.OAuthErrorResponse(
+			w, r, http.StatusBadRequest, api.OAuthInvalidRequest, "token must be provided",
+		)
+		return "", "", false
+	}
+
+	return token, r.PostForm.Get("token_type_hint"), true
+}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	accessToken, _, err := authRepo.Tokens.CreateSession(
+		ctx,
+		api.Principal{Subject: testUser.ID.String(), Scopes: []string{scope.MediaRead}},
+		repo.Device{},
+	)
+	require.NoError(t, err)
+
+	newFormRequest := func(t *testing.T, form url.Values) *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	t.Run("IntrospectHandler", func(t *testing.T) {
+		req := newFormRequest(t, url.Values{"token": {*accessToken}})
+
+		rr := httptest.NewRecorder()
+		handlers.IntrospectHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+		var introspection repo.Introspection
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &introspection))
+		assert.True(t, introspection.Active)
+		assert.Equal(t, scope.MediaRead, introspection.Scope)
+	})
+
+	t.Run("IntrospectHandlerMissingToken", func(t *testing.T) {
+		req := newFormRequest(t, url.Values{})
+
+		rr := httptest.NewRecorder()
+		handlers.IntrospectHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"error":"invalid_request"`)
+	})
+
+	t.Run("RevokeHandler", func(t *testing.T) {
+		req := newFormRequest(t, url.Values{
+			"token":           {*accessToken},
+			"token_type_hint": {repo.AccessTokenHint},
+		})
+
+		rr := httptest.NewRecorder()
+		handlers.RevokeHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		req = newFormRequest(t, url.Values{"token": {*accessToken}})
+		rr = httptest.NewRecorder()
+		handlers.IntrospectHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"active":false}`, rr.Body.String())
+	})
+
+	t.Run("RevokeHandlerInvalidToken", func(t *testing.T) {
+		req := newFormRequest(t, url.Values{"token": {"invalid"}})
+
+		rr := httptest.NewRecorder()
+		handlers.RevokeHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/ensure"
+)
+
+const (
+	// OIDCLoginCookie holds the login token of a login with the OpenID Connect provider until
+	// the provider redirects back to the callback.
+	OIDCLoginCookie = "islandwind_oidc_login"
+	// oidcCookiePath limits the login cookie to the OIDC routes.
+	oidcCookiePath = "/api/v1/auth/oidc"
+	// oidcLoginCookieMaxAge matches the time users have to log in with the provider.
+	oidcLoginCookieMaxAge = 600
+)
+
+// OIDCLoginHandler starts a login with the OpenID Connect provider, redirecting the browser to
+// the provider.
+func OIDCLoginHandler(logins repo.OIDCService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		login, err := logins.Begin(r.Context())
+		if err != nil {
+			api.ServerErrorResponse(w, r, err)
+			return
+		}
+		ensure.NotNil(login, "login should not be nil without errors")
+
+		// The cookie must be sent with the redirect from the provider, which is a cross-site
+		// navigation, so it cannot be strict.
+		http.SetCookie(w, &http.Cookie{
+			Name:     OIDCLoginCookie,
+			Value:    login.LoginToken,
+			Path:     oidcCookiePath,
+			MaxAge:   oidcLoginCookieMaxAge,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, login.AuthURL, http.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler completes a login with the OpenID Connect provider, and starts a session
+// for the user. The refresh token is set in the RefreshTokenCookie, and the browser is
+// redirected to the web client at the postLoginURL, which reads the CSRF token from the
+// CSRFTokenCookie and refreshes the session to get an access token. Without a postLoginURL,
+// the handler responds with the access token and the CSRF token like the LoginHandler.
+func OIDCCallbackHandler(
+	logins repo.OIDCService,
+	tokens repo.TokenService,
+	postLoginURL string,
+	clientIPHeader string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		cookie, err := r.Cookie(OIDCLoginCookie)
+		if err != nil {
+			api.BadRequestResponse(w, r, err, "no login in progress")
+			return
+		}
+		// The login token is single-use, as the stored login is deleted when it is completed.
+		http.SetCookie(w, &http.Cookie{
+			Name:     OIDCLoginCookie,
+			Path:     oidcCookiePath,
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		if providerError := query.Get("error"); providerError != "" {
+			api.ErrorResponse(w, r, http.StatusUnauthorized, "login failed: "+providerError)
+			return
+		}
+
+		user, err := logins.Complete(ctx, cookie.Value, query.Get("state"), query.Get("code"))
+		if err != nil {
+			switch {
+			case errors.Is(err, repo.ErrUnauthorized):
+				api.UnauthorizedResponse(w, r)
+			case errors.Is(err, repo.ErrNoRoleMapped):
+				api.ErrorResponse(w, r, http.StatusForbidden, err.Error())
+			case errors.Is(err, repo.ErrUsernameTaken):
+				api.ConstraintViolationResponse(w, r, err, err.Error())
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		principal := api.Principal{Subject: user.ID.String(), Scopes: user.Role.Scopes()}
+		accessToken, refreshToken, err := tokens.CreateSession(
+			ctx, principal, newDevice(r, clientIPHeader),
+		)
+		if err != nil {
+			api.ServerErrorResponse(w, r, err)
+			return
+		}
+		ensure.NotNil(accessToken, "accessToken should not be nil without errors")
+		ensure.NotNil(refreshToken, "refreshToken should not be nil without errors")
+
+		if postLoginURL == "" {
+			respondWithTokens(w, r, *accessToken, *refreshToken, true)
+			return
+		}
+		setRefreshTokenCookies(w, *refreshToken)
+		http.Redirect(w, r, postLoginURL, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	oidcProvider.SetUser(map[string]any{
+		"sub":                "00u1",
+		"preferred_username": "ivan",
+		"groups":             []string{"blog-editors"},
+	})
+
+	// login starts a login, returning the login cookie and the redirect back from the
+	// provider.
+	login := func(t *testing.T) (*http.Cookie, string) {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCLoginHandler(authRepo.OIDC)
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusFound, rr.Code)
+		cookie := findCookie(rr.Result().Cookies(), handlers.OIDCLoginCookie)
+		require.NotNil(t, cookie)
+		assert.True(t, cookie.HttpOnly)
+		assert.True(t, cookie.Secure)
+		assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+		client := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		resp, err := client.Get(rr.Header().Get("Location"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		location, err := resp.Location()
+		require.NoError(t, err)
+
+		return cookie, "/?" + location.RawQuery
+	}
+
+	t.Run("OIDCCallbackHandler", func(t *testing.T) {
+		cookie, callback := login(t)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, callback, nil)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCCallbackHandler(
+			authRepo.OIDC, authRepo.Tokens, "http://localhost:5173", "",
+		)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusSeeOther, rr.Code)
+		assert.Equal(t, "http://localhost:5173", rr.Header().Get("Location"))
+		cookies := rr.Result().Cookies()
+		refreshCookie := findCookie(cookies, handlers.RefreshTokenCookie)
+		if assert.NotNil(t, refreshCookie) {
+			assert.NotEmpty(t, refreshCookie.Value)
+		}
+		assert.NotNil(t, findCookie(cookies, handlers.CSRFTokenCookie))
+		loginCookie := findCookie(cookies, handlers.OIDCLoginCookie)
+		if assert.NotNil(t, loginCookie) {
+			assert.Less(t, loginCookie.MaxAge, 0)
+		}
+	})
+
+	t.Run("OIDCCallbackHandlerWithoutPostLoginURL", func(t *testing.T) {
+		cookie, callback := login(t)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, callback, nil)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCCallbackHandler(authRepo.OIDC, authRepo.Tokens, "", "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var response handlers.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.CSRFToken)
+		assert.NotNil(t, findCookie(rr.Result().Cookies(), handlers.RefreshTokenCookie))
+	})
+
+	t.Run("OIDCCallbackHandlerNoLogin", func(t *testing.T) {
+		_, callback := login(t)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, callback, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCCallbackHandler(authRepo.OIDC, authRepo.Tokens, "", "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("OIDCCallbackHandlerAnotherLogin", func(t *testing.T) {
+		cookie, _ := login(t)
+		_, callback := login(t)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, callback, nil)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCCallbackHandler(authRepo.OIDC, authRepo.Tokens, "", "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("OIDCCallbackHandlerProviderError", func(t *testing.T) {
+		cookie, _ := login(t)
+		req, err := http.NewRequestWithContext(
+			ctx, http.MethodGet, "/?error=access_denied", nil,
+		)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCCallbackHandler(authRepo.OIDC, authRepo.Tokens, "", "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("OIDCCallbackHandlerNoRoleMapped", func(t *testing.T) {
+		oidcProvider.SetUser(map[string]any{
+			"sub":                "00u2",
+			"preferred_username": "mallory",
+			"groups":             []string{"staff"},
+		})
+		t.Cleanup(func() {
+			oidcProvider.SetUser(map[string]any{
+				"sub":                "00u1",
+				"preferred_username": "ivan",
+				"groups":             []string{"blog-editors"},
+			})
+		})
+		cookie, callback := login(t)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, callback, nil)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.OIDCCallbackHandler(authRepo.OIDC, authRepo.Tokens, "", "")
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+const maxSessionLabelLength int = 128
+
+// SessionResponse is a session of the caller. Current marks the session of the access token
+// used for the request.
+type SessionResponse struct {
+	repo.Session
+	Current bool `json:"current"`
+}
+
+type SessionListResponse struct {
+	Data []SessionResponse `json:"data"`
+}
+
+type SessionRevokeResponse struct {
+	Data struct {
+		NumberRevoked int64 `json:"numberRevoked"`
+	} `json:"data"`
+}
+
+// ListSessionsHandler lists the valid sessions of the caller.
+func ListSessionsHandler(tokens repo.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		principal, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessions, err := tokens.ListSessions(ctx, userID)
+		if err != nil {
+			sessionErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		resp := SessionListResponse{Data: make([]SessionResponse, len(sessions))}
+		for i, session := range sessions {
+			resp.Data[i] = SessionResponse{
+				Session: *session,
+				Current: session.ID.String() == principal.SessionID,
+			}
+		}
+
+		api.RespondWithJSON(w, r, http.StatusOK, resp, nil)
+	}
+}
+
+// RevokeSessionHandler revokes the session in the path, if it belongs to the caller.
+func RevokeSessionHandler(tokens repo.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		if err := tokens.RevokeSession(ctx, userID, *id); err != nil {
+			sessionErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeOtherSessionsHandler revokes all sessions of the caller, except the session of the
+// access token used for the request.
+func RevokeOtherSessionsHandler(tokens repo.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		principal, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+		sessionID, err := uuid.Parse(principal.SessionID)
+		if err != nil {
+			v := validator.New()
+			v.AddError("sessionId", "the access token does not belong to a session")
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		revoked, err := tokens.RevokeOtherSessions(ctx, userID, sessionID)
+		if err != nil {
+			sessionErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		var resp SessionRevokeResponse
+		resp.Data.NumberRevoked = revoked
+		api.RespondWithJSON(w, r, http.StatusOK, resp, nil)
+	}
+}
+
+// principalUserID returns the principal of the request and the ID of the user it represents.
+// If false is returned, a response has been written.
+func principalUserID(w http.ResponseWriter, r *http.Request) (api.Principal, uuid.UUID, bool) {
+	principal, ok := api.PrincipalFromContext(r.Context())
+	if !ok {
+		api.UnauthorizedResponse(w, r)
+		return principal, uuid.Nil, false
+	}
+	userID, err := uuid.Parse(principal.Subject)
+	if err != nil {
+		api.ServerErrorResponse(w, r, err)
+		return principal, uuid.Nil, false
+	}
+	return principal, userID, true
+}
+
+// newDevice returns the device metadata of the request.
+func newDevice(r *http.Request, clientIPHeader string) repo.Device {
+	return repo.NewDevice(r.UserAgent(), api.ClientIP(r, clientIPHeader))
+}
+
+func validateSessionLabel(v *validator.Validator, label string) {
+	v.Check(label != "", "label", "must not be empty")
+	v.Check(
+		utf8.RuneCountInString(label) <= maxSessionLabelLength,
+		"label",
+		fmt.Sprintf("must not be more than %d characters long", maxSessionLabelLength),
+	)
+}
+
+func sessionErrorResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		api.NotFoundResponse(ctx, w, r)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	login := func(t *testing.T, label string) *api.Principal {
+		req, err := http.NewRequest(
+			http.MethodPost, "/", strings.NewReader(`{"label":"`+label+`"}`),
+		)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", "islandwind-test")
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+			Scopes:  testUser.Role.Scopes(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.LoginHandler(authRepo.Tokens, authRepo.Users, "").ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		principal, err := authRepo.Tokens.Authenticate(ctx, resp.AccessToken)
+		require.NoError(t, err)
+		return principal
+	}
+
+	current := login(t, "current")
+	other := login(t, "other")
+
+	t.Run("LoginHandlerInvalidLabel", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodPost, "/", strings.NewReader(`{"label":"`+strings.Repeat("a", 129)+`"}`),
+		)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, *current))
+
+		rr := httptest.NewRecorder()
+		handlers.LoginHandler(authRepo.Tokens, authRepo.Users, "").ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("ListSessionsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, *current))
+
+		rr := httptest.NewRecorder()
+		handlers.ListSessionsHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.SessionListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		found := 0
+		for _, session := range resp.Data {
+			switch session.ID.String() {
+			case current.SessionID:
+				assert.True(t, session.Current)
+				assert.Equal(t, "current", *session.Label)
+				assert.Equal(t, "islandwind-test", *session.UserAgent)
+				found++
+			case other.SessionID:
+				assert.False(t, session.Current)
+				found++
+			}
+		}
+		assert.Equal(t, 2, found)
+	})
+
+	t.Run("RevokeSessionHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", other.SessionID)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, *current))
+
+		rr := httptest.NewRecorder()
+		handlers.RevokeSessionHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		// The session has already been revoked.
+		rr = httptest.NewRecorder()
+		handlers.RevokeSessionHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("RevokeOtherSessionsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, *current))
+
+		rr := httptest.NewRecorder()
+		handlers.RevokeOtherSessionsHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// Access tokens without a session cannot tell which session to keep.
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+		}))
+		rr = httptest.NewRecorder()
+		handlers.RevokeOtherSessionsHandler(authRepo.Tokens).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+}
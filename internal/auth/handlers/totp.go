@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+// maxTOTPCodeLength limits the length of TOTP and recovery codes, including the dashes of
+// recovery codes.
+const maxTOTPCodeLength int = 32
+
+type TOTPLoginRequestBody struct {
+	ChallengeToken string `json:"challengeToken"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code"`
+}
+
+type TOTPCodeRequestBody struct {
+	// Code is a TOTP code, or a recovery code when TOTP is enabled.
+	Code string `json:"code"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Data repo.TOTPEnrollment `json:"data"`
+}
+
+type RecoveryCodesResponse struct {
+	Data struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	} `json:"data"`
+}
+
+func newRecoveryCodesResponse(codes []string) RecoveryCodesResponse {
+	var resp RecoveryCodesResponse
+	resp.Data.RecoveryCodes = codes
+	return resp
+}
+
+// TOTPLoginHandler exchanges the challenge token returned by the LoginHandler and a TOTP or
+// recovery code for the tokens of a new session. Failed codes are counted per user and client
+// IP address, so that codes cannot be guessed within the lifetime of challenges, and are
+// recorded as audit events. The cookie query parameter sets the refresh token in a cookie, as
+// for the LoginHandler.
+func TOTPLoginHandler(
+	tokens repo.TokenService,
+	users repo.UserService,
+	lockouts repo.LockoutService,
+	audit repo.AuditService,
+	clientIPHeader string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body TOTPLoginRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+		v := validator.New()
+		v.Check(body.ChallengeToken != "", "challengeToken", "must be provided")
+		validateTOTPCode(v, body.Code)
+		cookie := useCookie(r, v)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		challenge, err := tokens.ParseTOTPChallenge(body.ChallengeToken)
+		if err != nil {
+			api.UnauthorizedResponse(w, r)
+			return
+		}
+
+		totpKey := repo.TOTPKey(challenge.UserID)
+		keys := []repo.LoginAttemptKey{totpKey, repo.ClientIPKey(api.ClientIP(r, clientIPHeader))}
+		retryAfter, err := lockouts.Check(ctx, keys...)
+		if err != nil {
+			api.ServerErrorResponse(w, r, err)
+			return
+		}
+		failure := repo.AuditEventInput{
+			Type:    repo.AuditLogin,
+			Outcome: repo.AuditFailure,
+			ActorID: &challenge.UserID,
+		}
+		if retryAfter > 0 {
+			failure.Details = map[string]any{"reason": "throttled"}
+			audit.Record(ctx, failure)
+			api.RateLimitExceededResponse(w, r, retryAfter)
+			return
+		}
+
+		user, err := users.VerifySecondFactor(ctx, challenge.UserID, body.Code)
+		if err != nil {
+			switch {
+			case errors.Is(err, repo.ErrInvalidTOTPCode):
+				failure.Details = map[string]any{"reason": err.Error()}
+				audit.Record(ctx, failure)
+				retryAfter, err := lockouts.RecordFailure(ctx, keys...)
+				if err != nil {
+					api.ServerErrorResponse(w, r, err)
+					return
+				}
+				api.SetRetryAfter(w, retryAfter)
+				api.UnauthorizedResponse(w, r)
+			case errors.Is(err, repo.ErrInvalidCredentials),
+				errors.Is(err, repo.ErrTOTPNotEnabled),
+				errors.Is(err, db.ErrRecordNotFound):
+				api.UnauthorizedResponse(w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		// The challenge is consumed once the code is accepted, so that it logs in once.
+		if err := tokens.ConsumeTOTPChallenge(ctx, *challenge); err != nil {
+			switch {
+			case errors.Is(err, repo.ErrInvalidChallenge):
+				failure.Details = map[string]any{"reason": err.Error()}
+				audit.Record(ctx, failure)
+				api.UnauthorizedResponse(w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if err := lockouts.RecordSuccess(ctx, totpKey); err != nil {
+			api.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		device := newDevice(r, clientIPHeader)
+		device.Label = challenge.Label
+		accessToken, refreshToken, err := tokens.CreateSession(
+			ctx,
+			api.Principal{Subject: user.ID.String(), Scopes: user.Role.Scopes()},
+			device,
+		)
+		if err != nil {
+			api.ServerErrorResponse(w, r, err)
+			return
+		}
+		ensure.NotNil(accessToken, "accessToken should not be nil without errors")
+		ensure.NotNil(refreshToken, "refreshToken should not be nil without errors")
+
+		respondWithTokens(w, r, *accessToken, *refreshToken, cookie)
+	}
+}
+
+// EnrollTOTPHandler starts TOTP enrolment for the caller, returning the secret and the
+// provisioning URI. Enrolment is completed with the ConfirmTOTPHandler.
+func EnrollTOTPHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+
+		enrollment, err := users.EnrollTOTP(ctx, userID)
+		if err != nil {
+			totpErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(enrollment, "enrollment should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusCreated,
+			TOTPEnrollmentResponse{Data: *enrollment},
+			http.Header{"Cache-Control": []string{"no-store"}},
+		)
+	}
+}
+
+// TOTPQRCodeHandler returns the provisioning URI of the pending enrolment of the caller as a
+// QR code PNG.
+func TOTPQRCodeHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+
+		img, err := users.TOTPQRCode(ctx, userID)
+		if err != nil {
+			totpErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Request-ID", api.RequestIDFromContext(ctx).String())
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(img); err != nil {
+			api.LogError(r, err)
+		}
+	}
+}
+
+// ConfirmTOTPHandler enables TOTP for the caller if the code is valid for the pending
+// enrolment, and returns the recovery codes. The recovery codes are only shown here.
+func ConfirmTOTPHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+		code, ok := readTOTPCode(w, r)
+		if !ok {
+			return
+		}
+
+		codes, err := users.ConfirmTOTP(ctx, userID, code)
+		if err != nil {
+			totpErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			newRecoveryCodesResponse(codes),
+			http.Header{"Cache-Control": []string{"no-store"}},
+		)
+	}
+}
+
+// RegenerateRecoveryCodesHandler replaces the recovery codes of the caller.
+func RegenerateRecoveryCodesHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+		code, ok := readTOTPCode(w, r)
+		if !ok {
+			return
+		}
+
+		codes, err := users.RegenerateRecoveryCodes(ctx, userID, code)
+		if err != nil {
+			totpErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			newRecoveryCodesResponse(codes),
+			http.Header{"Cache-Control": []string{"no-store"}},
+		)
+	}
+}
+
+// DisableTOTPHandler disables TOTP for the caller.
+func DisableTOTPHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		_, userID, ok := principalUserID(w, r)
+		if !ok {
+			return
+		}
+		code, ok := readTOTPCode(w, r)
+		if !ok {
+			return
+		}
+
+		if err := users.DisableTOTP(ctx, userID, code); err != nil {
+			totpErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// readTOTPCode reads and validates the code of the request body. If false is returned, a
+// response has been written.
+func readTOTPCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body TOTPCodeRequestBody
+	if err := api.ReadJSON(r, &body); err != nil {
+		api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+		return "", false
+	}
+	v := validator.New()
+	validateTOTPCode(v, body.Code)
+	if !v.Valid() {
+		api.ValidationFailedResponse(r.Context(), w, r, v.Errors)
+		return "", false
+	}
+	return body.Code, true
+}
+
+func validateTOTPCode(v *validator.Validator, code string) {
+	v.Check(code != "", "code", "must be provided")
+	v.Check(len(code) <= maxTOTPCodeLength, "code", "must not be more than 32 bytes long")
+}
+
+func totpErrorResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, repo.ErrInvalidTOTPCode):
+		v := validator.New()
+		v.AddError("code", "invalid or already used")
+		api.ValidationFailedResponse(ctx, w, r, v.Errors)
+	case errors.Is(err, repo.ErrTOTPEnabled):
+		api.ConstraintViolationResponse(w, r, err, "two-factor authentication is already enabled")
+	case errors.Is(err, repo.ErrTOTPNotEnrolled), errors.Is(err, repo.ErrTOTPNotEnabled):
+		api.ConstraintViolationResponse(w, r, err, "two-factor authentication is not enabled")
+	case errors.Is(err, db.ErrRecordNotFound):
+		api.NotFoundResponse(ctx, w, r)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
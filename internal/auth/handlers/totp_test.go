@@ -0,0 +1,162 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	user, err := authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "totp",
+		Password: testUserPassword,
+		Role:     scope.RoleEditor,
+	})
+	require.NoError(t, err)
+	principal := api.Principal{Subject: user.ID.String(), Scopes: user.Role.Scopes()}
+
+	var enrollment handlers.TOTPEnrollmentResponse
+	var recoveryCodes handlers.RecoveryCodesResponse
+	var challenge handlers.LoginChallengeResponse
+
+	t.Run("EnrollTOTPHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.EnrollTOTPHandler(authRepo.Users).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &enrollment))
+	})
+
+	t.Run("TOTPQRCodeHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.TOTPQRCodeHandler(authRepo.Users).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("ConfirmTOTPHandler", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Data.Secret, time.Now().Add(-30*time.Second))
+		require.NoError(t, err)
+		req, err := http.NewRequest(
+			http.MethodPost, "/", strings.NewReader(`{"code":"`+code+`"}`),
+		)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.ConfirmTOTPHandler(authRepo.Users).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &recoveryCodes))
+		assert.NotEmpty(t, recoveryCodes.Data.RecoveryCodes)
+	})
+
+	t.Run("LoginHandlerChallenge", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.LoginHandler(authRepo.Tokens, authRepo.Users, "").ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &challenge))
+		assert.True(t, challenge.TOTPRequired)
+		assert.NotEmpty(t, challenge.ChallengeToken)
+		assert.NotContains(t, rr.Body.String(), "accessToken")
+	})
+
+	t.Run("TOTPLoginHandlerInvalidCode", func(t *testing.T) {
+		body, err := json.Marshal(handlers.TOTPLoginRequestBody{
+			ChallengeToken: challenge.ChallengeToken,
+			Code:           "000000",
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.TOTPLoginHandler(
+			authRepo.Tokens, authRepo.Users, authRepo.Lockouts, authRepo.Audit, "",
+		).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("TOTPLoginHandler", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Data.Secret, time.Now())
+		require.NoError(t, err)
+		body, err := json.Marshal(handlers.TOTPLoginRequestBody{
+			ChallengeToken: challenge.ChallengeToken,
+			Code:           code,
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.TOTPLoginHandler(
+			authRepo.Tokens, authRepo.Users, authRepo.Lockouts, authRepo.Audit, "",
+		).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var login handlers.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &login))
+		authenticated, err := authRepo.Tokens.Authenticate(ctx, login.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.String(), authenticated.Subject)
+	})
+
+	t.Run("TOTPLoginHandlerReusedChallenge", func(t *testing.T) {
+		body, err := json.Marshal(handlers.TOTPLoginRequestBody{
+			ChallengeToken: challenge.ChallengeToken,
+			Code:           recoveryCodes.Data.RecoveryCodes[1],
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.TOTPLoginHandler(
+			authRepo.Tokens, authRepo.Users, authRepo.Lockouts, authRepo.Audit, "",
+		).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("DisableTOTPHandler", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodDelete,
+			"/",
+			strings.NewReader(`{"code":"`+recoveryCodes.Data.RecoveryCodes[0]+`"}`),
+		)
+		require.NoError(t, err)
+		req = req.WithContext(api.ContextWithPrincipal(ctx, principal))
+
+		rr := httptest.NewRecorder()
+		handlers.DisableTOTPHandler(authRepo.Users).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+}
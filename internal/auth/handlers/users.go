@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+const (
+	maxUsernameLength int = 128
+	minPasswordLength int = 12
+	// maxPasswordLength limits the work done hashing passwords of arbitrary length.
+	maxPasswordLength int = 256
+)
+
+type UserResponse struct {
+	Data repo.User `json:"data"`
+}
+
+type UserListResponse struct {
+	Metadata data.Metadata `json:"metadata"`
+	Data     []*repo.User  `json:"data"`
+}
+
+type UserRequestBody struct {
+	Data repo.UserInput `json:"data"`
+}
+
+type RoleRequestBody struct {
+	Data RoleChange `json:"data"`
+}
+
+type RoleChange struct {
+	Role scope.Role `json:"role"`
+}
+
+type PasswordRequestBody struct {
+	Data PasswordChange `json:"data"`
+}
+
+// PasswordChange is the body used to change the password of a user. The current password is
+// required when users change their own password.
+type PasswordChange struct {
+	CurrentPassword *string `json:"currentPassword"`
+	Password        string  `json:"password"`
+}
+
+func PostUserHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body UserRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		validateUsername(v, body.Data.Username)
+		validatePassword(v, "password", body.Data.Password)
+		if body.Data.Role != "" {
+			validateRole(v, body.Data.Role)
+		}
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		user, err := users.Create(ctx, body.Data)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrUniqueConstraintViolation):
+				api.ConstraintViolationResponse(w, r, err, "username already exists")
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusCreated, UserResponse{Data: *user}, nil)
+	}
+}
+
+func ListUserHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.UserFilter{}
+
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 25, v)
+		filters.ID = api.ReadQueryNull(api.ParseQueryUUID(qs, "id", v))
+		filters.Username = api.ReadQueryNull(api.ParseQueryString(qs, "username", v))
+		filters.Disabled = api.ReadQueryNull(api.ParseQueryBoolean(qs, "disabled", v))
+		if role, ok := api.ParseQueryString(qs, "role", v)(); ok {
+			validateRole(v, scope.Role(role))
+			filters.Role = sql.Null[scope.Role]{V: scope.Role(role), Valid: true}
+		}
+		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, metadata, err := users.List(ctx, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			UserListResponse{
+				Metadata: *metadata,
+				Data:     list,
+			},
+			nil,
+		)
+	}
+}
+
+func GetUserHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		user, err := users.Read(ctx, *id)
+		if err != nil {
+			userErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, UserResponse{Data: *user}, nil)
+	}
+}
+
+// DisableUserHandler disables or enables the user in the path. Users cannot disable
+// themselves, so that at least one user is always able to log in.
+func DisableUserHandler(users repo.UserService, disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		if principal, ok := api.PrincipalFromContext(ctx); ok && disabled &&
+			principal.Subject == id.String() {
+			v := validator.New()
+			v.AddError("id", "users cannot disable themselves")
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		user, err := users.SetDisabled(ctx, *id, disabled)
+		if err != nil {
+			userErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, UserResponse{Data: *user}, nil)
+	}
+}
+
+// SetRoleHandler changes the role of the user in the path. Users cannot change their own role,
+// so that at least one admin always remains.
+func SetRoleHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		var body RoleRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		validateRole(v, body.Data.Role)
+		if principal, ok := api.PrincipalFromContext(ctx); ok && principal.Subject == id.String() {
+			v.AddError("id", "users cannot change their own role")
+		}
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		user, err := users.SetRole(ctx, *id, body.Data.Role)
+		if err != nil {
+			userErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(user, "user should not be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, UserResponse{Data: *user}, nil)
+	}
+}
+
+// ChangePasswordHandler replaces the password of the user in the path. Users changing their
+// own password must provide their current password, and changing the password of other users
+// requires the auth:users scope.
+func ChangePasswordHandler(users repo.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		var body PasswordRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		principal, ok := api.PrincipalFromContext(ctx)
+		self := ok && principal.Subject == id.String()
+		if !self && !principal.HasScope(scope.AuthUsers) {
+			api.ForbiddenResponse(w, r, scope.AuthUsers)
+			return
+		}
+
+		v := validator.New()
+		validatePassword(v, "password", body.Data.Password)
+		if self {
+			v.Check(
+				body.Data.CurrentPassword != nil,
+				"currentPassword",
+				"must be provided when changing your own password",
+			)
+		}
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		var current *string
+		if self {
+			current = body.Data.CurrentPassword
+		}
+		if err := users.ChangePassword(ctx, *id, current, body.Data.Password); err != nil {
+			switch {
+			case errors.Is(err, repo.ErrInvalidCredentials):
+				v.AddError("currentPassword", "is incorrect")
+				api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			default:
+				userErrorResponse(ctx, w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func validateUsername(v *validator.Validator, username string) {
+	v.Check(username != "", "username", "must be provided")
+	v.Check(
+		utf8.RuneCountInString(username) <= maxUsernameLength,
+		"username",
+		fmt.Sprintf("must not be more than %d characters long", maxUsernameLength),
+	)
+	v.Check(
+		!strings.ContainsFunc(username, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsControl(r)
+		}) && !strings.Contains(username, ":"),
+		"username",
+		"must not contain whitespace, control characters or colons",
+	)
+}
+
+func validateRole(v *validator.Validator, role scope.Role) {
+	v.Check(
+		role.Valid(),
+		"role",
+		fmt.Sprintf("must be one of %v", scope.Roles),
+	)
+}
+
+func validatePassword(v *validator.Validator, key string, password string) {
+	v.Check(
+		utf8.RuneCountInString(password) >= minPasswordLength,
+		key,
+		fmt.Sprintf("must be at least %d characters long", minPasswordLength),
+	)
+	v.Check(
+		len(password) <= maxPasswordLength,
+		key,
+		fmt.Sprintf("must not be more than %d bytes long", maxPasswordLength),
+	)
+}
+
+func userErrorResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		api.NotFoundResponse(ctx, w, r)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,212 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/handlers"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	var user handlers.UserResponse
+
+	t.Run("PostUserHandler", func(t *testing.T) {
+		body, err := json.Marshal(handlers.UserRequestBody{
+			Data: repo.UserInput{Username: "dave", Password: "a long enough password"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.PostUserHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &user))
+		assert.Equal(t, "dave", user.Data.Username)
+		assert.Equal(t, scope.RoleAuthor, user.Data.Role)
+		assert.NotContains(t, rr.Body.String(), "argon2id")
+	})
+
+	t.Run("PostUserHandlerShortPassword", func(t *testing.T) {
+		body, err := json.Marshal(handlers.UserRequestBody{
+			Data: repo.UserInput{Username: "eve", Password: "short"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.PostUserHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("PostUserHandlerInvalidRole", func(t *testing.T) {
+		body, err := json.Marshal(handlers.UserRequestBody{
+			Data: repo.UserInput{
+				Username: "frank",
+				Password: "a long enough password",
+				Role:     "owner",
+			},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.PostUserHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("ListUserHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/?username=dave", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListUserHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.UserListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+		assert.Equal(t, user.Data.ID, resp.Data[0].ID)
+	})
+
+	t.Run("GetUserHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", user.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.GetUserHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("ChangePasswordHandlerSelf", func(t *testing.T) {
+		body, err := json.Marshal(handlers.PasswordRequestBody{
+			Data: handlers.PasswordChange{Password: "another long password"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", user.Data.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: user.Data.ID.String(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.ChangePasswordHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		// The current password is required when changing your own password.
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("SetRoleHandler", func(t *testing.T) {
+		body, err := json.Marshal(handlers.RoleRequestBody{
+			Data: handlers.RoleChange{Role: scope.RoleEditor},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", user.Data.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+			Scopes:  testUser.Role.Scopes(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.SetRoleHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.UserResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, scope.RoleEditor, resp.Data.Role)
+	})
+
+	t.Run("ChangePasswordHandlerForbidden", func(t *testing.T) {
+		body, err := json.Marshal(handlers.PasswordRequestBody{
+			Data: handlers.PasswordChange{Password: "another long password"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", testUser.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: user.Data.ID.String(),
+			Scopes:  scope.RoleEditor.Scopes(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.ChangePasswordHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		// Changing the password of other users requires the auth:users scope.
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("ChangePasswordHandler", func(t *testing.T) {
+		body, err := json.Marshal(handlers.PasswordRequestBody{
+			Data: handlers.PasswordChange{Password: "another long password"},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", user.Data.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+			Scopes:  testUser.Role.Scopes(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.ChangePasswordHandler(authRepo.Users).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("DisableUserHandlerSelf", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", testUser.ID.String())
+		req = req.WithContext(api.ContextWithPrincipal(ctx, api.Principal{
+			Subject: testUser.ID.String(),
+		}))
+
+		rr := httptest.NewRecorder()
+		handlers.DisableUserHandler(authRepo.Users, true).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("DisableUserHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", user.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.DisableUserHandler(authRepo.Users, true).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.UserResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.Disabled)
+	})
+}
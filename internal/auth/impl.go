@@ -7,5 +7,5 @@ import (
 )
 
 func (m *Module) AccessTokenMiddleware(next http.Handler) http.Handler {
-	return middleware.AccessTokenMiddleware(next, m.repo.Tokens)
+	return middleware.AccessTokenMiddleware(next, m.repo.Tokens, m.repo.APITokens)
 }
@@ -0,0 +1,395 @@
+// Package keys manages the asymmetric keys signing and verifying access tokens. Ed25519 keys
+// sign with EdDSA, and RSA keys sign with RS256.
+//
+// A key set has one signing key and any number of verification keys. Keys are rotated by
+// making the new key the signing key while keeping the old key as a verification key until
+// the tokens it signed have expired. Each key is identified by its RFC 7638 thumbprint, which
+// is set as the kid header of the tokens it signs, and all keys are published as a JSON Web Key
+// Set so that other services can verify tokens. The keys published by other issuers, such as
+// OpenID Connect providers, are parsed from their key sets to verify their tokens.
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA modulus accepted, following NIST SP 800-57.
+const minRSABits int = 2048
+
+var (
+	// ErrUnsupportedKey is returned when a PEM file contains a key other than an Ed25519 or
+	// RSA key.
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	// ErrWeakKey is returned when an RSA key is shorter than 2048 bits.
+	ErrWeakKey = errors.New("rsa key must be at least 2048 bits")
+	// ErrNoPrivateKey is returned when the signing key is a public key.
+	ErrNoPrivateKey = errors.New("signing key must be a private key")
+	// ErrUnknownKey is returned when a token is signed by a key not in the set.
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Key is a signing or verification key.
+type Key struct {
+	// ID is the RFC 7638 thumbprint of the public key.
+	ID     string
+	Method jwt.SigningMethod
+	public crypto.PublicKey
+	// private is nil for verification keys loaded from public keys.
+	private crypto.Signer
+}
+
+func newKey(key any) (*Key, error) {
+	k := Key{}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		k.private = key
+		k.public = key.Public()
+	case ed25519.PublicKey:
+		k.public = key
+	case *rsa.PrivateKey:
+		k.private = key
+		k.public = key.Public()
+	case *rsa.PublicKey:
+		k.public = key
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		k.Method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return nil, ErrWeakKey
+		}
+		k.Method = jwt.SigningMethodRS256
+	}
+
+	thumbprint, err := k.JWK().Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	k.ID = thumbprint
+
+	return &k, nil
+}
+
+// JWK returns the public key as a JSON Web Key.
+func (k *Key) JWK() JWK {
+	jwk := JWK{
+		KeyID:     k.ID,
+		Algorithm: k.Method.Alg(),
+		Use:       "sig",
+	}
+
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encoding.EncodeToString(public.N.Bytes())
+		jwk.E = encoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+
+	return jwk
+}
+
+// ParseJWK returns the verification key of the JWK. The key is identified by the kid of the
+// JWK, or by its thumbprint if the JWK has no kid, so that the keys of other issuers can be
+// looked up by the kid header of their tokens.
+func ParseJWK(j JWK) (*Key, error) {
+	var public crypto.PublicKey
+	switch j.KeyType {
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, j.Curve)
+		}
+		x, err := encoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key size")
+		}
+		public = ed25519.PublicKey(x)
+	case "RSA":
+		n, err := encoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := encoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA public exponent")
+		}
+		public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, j.KeyType)
+	}
+
+	key, err := newKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if j.Algorithm != "" && j.Algorithm != key.Method.Alg() {
+		return nil, fmt.Errorf("%w: algorithm %s", ErrUnsupportedKey, j.Algorithm)
+	}
+	if j.KeyID != "" {
+		key.ID = j.KeyID
+	}
+
+	return key, nil
+}
+
+// JWK is a public key as described in RFC 7517. Only the members used by Ed25519 and RSA keys
+// are included.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	// Curve and X are the members of Ed25519 keys, as described in RFC 8037.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	// N and E are the modulus and exponent of RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of the key, the base64url encoded SHA-256 hash of
+// the required members of the key in lexicographic order.
+func (j JWK) Thumbprint() (string, error) {
+	var members any
+	switch j.KeyType {
+	case "OKP":
+		members = struct {
+			Curve   string `json:"crv"`
+			KeyType string `json:"kty"`
+			X       string `json:"x"`
+		}{j.Curve, j.KeyType, j.X}
+	case "RSA":
+		members = struct {
+			E       string `json:"e"`
+			KeyType string `json:"kty"`
+			N       string `json:"n"`
+		}{j.E, j.KeyType, j.N}
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedKey, j.KeyType)
+	}
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+
+	return encoding.EncodeToString(sum[:]), nil
+}
+
+// JWKS is a JSON Web Key Set as described in RFC 7517.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Set is the signing key and the verification keys of access tokens.
+type Set struct {
+	signing *Key
+	keys    map[string]*Key
+	// order is the key IDs in the order they were added, keeping the published key set stable.
+	order []string
+}
+
+// NewSet returns a key set signing with the signing key, and verifying with the signing key
+// and the verification keys.
+func NewSet(signing *Key, verification ...*Key) (*Set, error) {
+	if signing == nil || signing.private == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	s := Set{signing: signing, keys: make(map[string]*Key)}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if _, ok := s.keys[key.ID]; ok {
+			continue
+		}
+		s.keys[key.ID] = key
+		s.order = append(s.order, key.ID)
+	}
+
+	return &s, nil
+}
+
+// NewVerificationSet returns a key set verifying with the keys, e.g. the keys of another
+// issuer. The set has no signing key, so Sign returns ErrNoPrivateKey.
+func NewVerificationSet(verification ...*Key) *Set {
+	s := Set{keys: make(map[string]*Key)}
+	for _, key := range verification {
+		if _, ok := s.keys[key.ID]; ok {
+			continue
+		}
+		s.keys[key.ID] = key
+		s.order = append(s.order, key.ID)
+	}
+	return &s
+}
+
+// Load reads the signing key and the verification keys from PEM files. The signing key must be
+// a private key, while verification keys may be private or public keys.
+func Load(signingKeyFile string, verificationKeyFiles ...string) (*Set, error) {
+	signing, err := ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := make([]*Key, len(verificationKeyFiles))
+	for i, file := range verificationKeyFiles {
+		verification[i], err = ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewSet(signing, verification...)
+}
+
+// Generate returns a key set with a new Ed25519 signing key. Tokens signed by the key cannot
+// be verified after a restart, or by other instances, so it should only be used when no keys
+// are configured.
+func Generate() (*Set, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key, err := newKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSet(key)
+}
+
+// ReadFile reads the first key of a PEM file. PKCS #8 and PKCS #1 private keys, and PKIX and
+// PKCS #1 public keys, are supported.
+func ReadFile(name string) (*Key, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return key, nil
+}
+
+// Parse parses the first key of PEM encoded data.
+func Parse(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM block %s", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(key)
+}
+
+// Sign returns a token with the claims signed by the signing key, with the kid header set to
+// the ID of the key.
+func (s *Set) Sign(claims jwt.Claims) (string, error) {
+	if s.signing == nil {
+		return "", ErrNoPrivateKey
+	}
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+
+	return token.SignedString(s.signing.private)
+}
+
+// Keyfunc returns the verification key identified by the kid header of the token, for use with
+// [jwt.Parse]. The algorithm of the token must match the key, so that a public key cannot be
+// used as an HMAC secret.
+func (s *Set) Keyfunc(token *jwt.Token) (any, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("%w: algorithm %s does not match key", ErrUnknownKey, token.Method.Alg())
+	}
+
+	return key.public, nil
+}
+
+// Algorithms returns the signing algorithms of the keys, for use with [jwt.WithValidMethods].
+func (s *Set) Algorithms() []string {
+	algorithms := make([]string, 0, 2)
+	for _, id := range s.order {
+		alg := s.keys[id].Method.Alg()
+		if !slices.Contains(algorithms, alg) {
+			algorithms = append(algorithms, alg)
+		}
+	}
+	return algorithms
+}
+
+// SigningKeyID returns the ID of the signing key, or an empty string if the set has none.
+func (s *Set) SigningKeyID() string {
+	if s.signing == nil {
+		return ""
+	}
+	return s.signing.ID
+}
+
+// JWKS returns the public keys of the set, with the signing key first.
+func (s *Set) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, len(s.order))}
+	for i, id := range s.order {
+		jwks.Keys[i] = s.keys[id].JWK()
+	}
+	return jwks
+}
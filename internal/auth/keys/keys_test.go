@@ -0,0 +1,180 @@
+package keys_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbprint(t *testing.T) {
+	// The example of RFC 7638, section 3.1.
+	jwk := keys.JWK{
+		KeyType: "RSA",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECP" +
+			"ebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2Q" +
+			"vzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQF" +
+			"h6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E: "AQAB",
+	}
+
+	thumbprint, err := jwk.Thumbprint()
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+}
+
+func TestSet(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edFile := writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", pkcs8(t, edKey))
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaFile := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	rsaPublic, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	require.NoError(t, err)
+	rsaPublicFile := writePEM(t, dir, "rsa.pub.pem", "PUBLIC KEY", rsaPublic)
+
+	claims := jwt.MapClaims{"sub": "someone"}
+
+	t.Run("EdDSA", func(t *testing.T) {
+		set, err := keys.Load(edFile)
+		require.NoError(t, err)
+
+		signed, err := set.Sign(claims)
+		require.NoError(t, err)
+
+		token, err := jwt.Parse(signed, set.Keyfunc, jwt.WithValidMethods(set.Algorithms()))
+		require.NoError(t, err)
+		assert.Equal(t, "EdDSA", token.Method.Alg())
+		assert.Equal(t, set.SigningKeyID(), token.Header["kid"])
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		old, err := keys.Load(rsaFile)
+		require.NoError(t, err)
+		signed, err := old.Sign(claims)
+		require.NoError(t, err)
+
+		// The old key is kept as a verification key, using only its public key.
+		rotated, err := keys.Load(edFile, rsaPublicFile)
+		require.NoError(t, err)
+
+		token, err := jwt.Parse(signed, rotated.Keyfunc, jwt.WithValidMethods(rotated.Algorithms()))
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", token.Method.Alg())
+
+		jwks := rotated.JWKS()
+		require.Len(t, jwks.Keys, 2)
+		assert.Equal(t, rotated.SigningKeyID(), jwks.Keys[0].KeyID)
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+		assert.Equal(t, old.SigningKeyID(), jwks.Keys[1].KeyID)
+		assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		set, err := keys.Load(edFile)
+		require.NoError(t, err)
+		other, err := keys.Generate()
+		require.NoError(t, err)
+
+		signed, err := other.Sign(claims)
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, set.Keyfunc, jwt.WithValidMethods(set.Algorithms()))
+		assert.ErrorIs(t, err, keys.ErrUnknownKey)
+	})
+
+	t.Run("AlgorithmMismatch", func(t *testing.T) {
+		set, err := keys.Load(rsaFile)
+		require.NoError(t, err)
+
+		// A token signed with HMAC using the public key as the secret must not verify.
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = set.SigningKeyID()
+		signed, err := token.SignedString(rsaPublic)
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, set.Keyfunc)
+		assert.ErrorIs(t, err, keys.ErrUnknownKey)
+	})
+
+	t.Run("PublicSigningKey", func(t *testing.T) {
+		_, err := keys.Load(rsaPublicFile)
+		assert.ErrorIs(t, err, keys.ErrNoPrivateKey)
+	})
+
+	t.Run("WeakKey", func(t *testing.T) {
+		weak, err := rsa.GenerateKey(rand.Reader, 1024)
+		require.NoError(t, err)
+
+		_, err = keys.Parse(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(weak),
+		}))
+		assert.ErrorIs(t, err, keys.ErrWeakKey)
+	})
+
+	t.Run("ParseJWK", func(t *testing.T) {
+		set, err := keys.Load(edFile, rsaPublicFile)
+		require.NoError(t, err)
+		signed, err := set.Sign(claims)
+		require.NoError(t, err)
+
+		var parsed []*keys.Key
+		for _, jwk := range set.JWKS().Keys {
+			key, err := keys.ParseJWK(jwk)
+			require.NoError(t, err)
+			assert.Equal(t, jwk.KeyID, key.ID)
+			parsed = append(parsed, key)
+		}
+		verification := keys.NewVerificationSet(parsed...)
+		assert.Equal(t, set.JWKS(), verification.JWKS())
+
+		_, err = jwt.Parse(
+			signed, verification.Keyfunc, jwt.WithValidMethods(verification.Algorithms()),
+		)
+		require.NoError(t, err)
+		_, err = verification.Sign(claims)
+		assert.ErrorIs(t, err, keys.ErrNoPrivateKey)
+	})
+
+	t.Run("ParseJWKUnsupported", func(t *testing.T) {
+		_, err := keys.ParseJWK(keys.JWK{KeyType: "EC", Curve: "P-256"})
+		assert.ErrorIs(t, err, keys.ErrUnsupportedKey)
+
+		set, err := keys.Load(rsaFile)
+		require.NoError(t, err)
+		// Only RSA keys signing with RS256 are supported.
+		jwk := set.JWKS().Keys[0]
+		jwk.Algorithm = "RS512"
+		_, err = keys.ParseJWK(jwk)
+		assert.ErrorIs(t, err, keys.ErrUnsupportedKey)
+	})
+}
+
+func pkcs8(t *testing.T, key any) []byte {
+	t.Helper()
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return b
+}
+
+func writePEM(t *testing.T, dir string, name string, blockType string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: b}), 0o600)
+	require.NoError(t, err)
+	return path
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes emails to .eml files in a directory instead of sending them, so that the
+// emails can be read during development.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates the directory if it does not exist.
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes the message to a file named by the current time, so that the files sort in the
+// order the emails were sent.
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	now := time.Now().UTC()
+	body, _, err := compose(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	name := now.Format("20060102T150405.000000000Z") + "-" + rand.Text()[:8] + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), body, 0o600)
+}
@@ -0,0 +1,104 @@
+// Package mail contains the transports sending emails to users, and the templates of the
+// emails.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAddress = errors.New("invalid email address")
+
+// Message is an email to a single recipient, with a plain text and an HTML body.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	// Text is the plain text body, shown by clients without HTML support.
+	Text string `json:"text"`
+	// HTML is the HTML body.
+	HTML string `json:"html"`
+}
+
+// Mailer is an interface for sending emails.
+type Mailer interface {
+	// Send sends the message from the configured sender.
+	Send(ctx context.Context, msg Message) error
+}
+
+// envelope contains the addresses of the sender and recipient of a message, as given to the
+// SMTP server.
+type envelope struct {
+	from string
+	to   string
+}
+
+// compose encodes the message as a multipart/alternative MIME email from the sender.
+func compose(from string, msg Message, now time.Time) ([]byte, envelope, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, envelope{}, fmt.Errorf("%w: sender: %w", ErrInvalidAddress, err)
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, envelope{}, fmt.Errorf("%w: recipient: %w", ErrInvalidAddress, err)
+	}
+	_, domain, _ := strings.Cut(sender.Address, "@")
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: msg.Text},
+		{contentType: "text/html; charset=utf-8", content: msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, envelope{}, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, envelope{}, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, envelope{}, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, envelope{}, err
+	}
+
+	var b bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", sender.String()},
+		{"To", recipient.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", rand.Text(), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType(
+			"multipart/alternative",
+			map[string]string{"boundary": parts.Boundary()},
+		)},
+	} {
+		fmt.Fprintf(&b, "%s: %s\r\n", header[0], header[1])
+	}
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+
+	return b.Bytes(), envelope{from: sender.Address, to: recipient.Address}, nil
+}
@@ -0,0 +1,133 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/mail"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSender string = "islandwind <noreply@example.com>"
+
+func testMessage(t *testing.T) mail.Message {
+	msg, err := mail.Render(mail.PasswordResetTemplate, "carol@example.com", mail.LinkData{
+		Username: "<carol>",
+		Link:     "http://localhost:5173/reset-password?token=abc",
+		ValidFor: 30 * time.Minute,
+	})
+	require.NoError(t, err)
+	return msg
+}
+
+// assertMessage parses the email, and asserts that it contains the message.
+func assertMessage(t *testing.T, data []byte, msg mail.Message) {
+	parsed, err := netmail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, `"islandwind" <noreply@example.com>`, parsed.Header.Get("From"))
+	assert.Equal(t, "<carol@example.com>", parsed.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var bodies []string
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		bodies = append(bodies, string(bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))))
+	}
+	assert.Equal(t, []string{msg.Text, msg.HTML}, bodies)
+}
+
+func TestRender(t *testing.T) {
+	msg := testMessage(t)
+
+	assert.Equal(t, "carol@example.com", msg.To)
+	assert.Equal(t, "Reset your islandwind password", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi <carol>,")
+	assert.Contains(t, msg.Text, "http://localhost:5173/reset-password?token=abc")
+	assert.Contains(t, msg.Text, "valid for 30 minutes")
+	assert.Contains(t, msg.HTML, "Hi &lt;carol&gt;,", "data must be escaped in HTML")
+	assert.Contains(t, msg.HTML, `href="http://localhost:5173/reset-password?token=abc"`)
+
+	msg, err := mail.Render(mail.EmailVerificationTemplate, "carol@example.com", mail.LinkData{
+		Username: "carol",
+		Link:     "http://localhost:5173/verify-email?token=abc",
+		ValidFor: 24 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Verify your islandwind email address", msg.Subject)
+	assert.Contains(t, msg.Text, "valid for 24 hours")
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := testsuite.NewSMTPServer()
+	t.Cleanup(server.Close)
+
+	mailer := mail.NewSMTPMailer(config.SMTPConfig{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: "islandwind",
+		Password: "secret",
+	}, testSender)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg := testMessage(t)
+	require.NoError(t, mailer.Send(ctx, msg))
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "noreply@example.com", messages[0].From)
+	assert.Equal(t, []string{"carol@example.com"}, messages[0].To)
+	assert.Equal(t, "islandwind", messages[0].Username)
+	assert.Equal(t, "secret", messages[0].Password)
+	assertMessage(t, messages[0].Data, msg)
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		msg := testMessage(t)
+		msg.To = "carol@example.com\r\nBcc: mallory@example.com"
+		assert.ErrorIs(t, mailer.Send(ctx, msg), mail.ErrInvalidAddress)
+		assert.Len(t, server.Messages(), 1)
+	})
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := mail.NewFileMailer(dir, testSender)
+	require.NoError(t, err)
+
+	msg := testMessage(t)
+	require.NoError(t, mailer.Send(context.Background(), msg))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ".eml", filepath.Ext(entries[0].Name()))
+
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assertMessage(t, data, msg)
+}
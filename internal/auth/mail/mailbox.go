@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Mailbox keeps the emails in memory instead of sending them, so that tests can read them.
+type Mailbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMailbox() *Mailbox {
+	return &Mailbox{}
+}
+
+func (m *Mailbox) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the emails sent so far.
+func (m *Mailbox) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names an email. Each email has a text template, which also defines the subject as
+// "<name>_subject", and an HTML template.
+type Template string
+
+const (
+	PasswordResetTemplate     Template = "password_reset"
+	EmailVerificationTemplate Template = "email_verification"
+)
+
+// LinkData is the data of the emails asking the user to follow a link.
+type LinkData struct {
+	Username string
+	Link     string
+	// ValidFor is how long the link is valid.
+	ValidFor time.Duration
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var templateFuncs = map[string]any{"duration": formatDuration}
+
+var (
+	textTemplates = texttemplate.Must(
+		texttemplate.New("").Funcs(templateFuncs).ParseFS(templateFS, "templates/*.txt"),
+	)
+	htmlTemplates = htmltemplate.Must(
+		htmltemplate.New("").Funcs(templateFuncs).ParseFS(templateFS, "templates/*.html"),
+	)
+)
+
+// Render renders the email to the recipient. Values of the data are escaped in the HTML body.
+func Render(tmpl Template, to string, data any) (Message, error) {
+	var subject, text, html strings.Builder
+	if err := textTemplates.ExecuteTemplate(&subject, string(tmpl)+"_subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := textTemplates.ExecuteTemplate(&text, string(tmpl)+".txt", data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, string(tmpl)+".html", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// formatDuration formats whole hours as hours, and other durations as minutes.
+func formatDuration(d time.Duration) string {
+	unit, n := "minute", int64(d/time.Minute)
+	if d >= time.Hour && d%time.Hour == 0 {
+		unit, n = "hour", int64(d/time.Hour)
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
@@ -1,31 +1,36 @@
 package middleware_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/r3d5un/islandwind/internal/api"
-	"github.com/r3d5un/islandwind/internal/auth/config"
 	"github.com/r3d5un/islandwind/internal/auth/middleware"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBasicAuthMiddleware(t *testing.T) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := api.PrincipalFromContext(r.Context())
+		if !ok || principal.Subject != testUser.ID.String() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	})
 
-	cfg := config.BasicAuthConfig{
-		Username: "username",
-		Password: "password",
-	}
-
-	mw := middleware.BasicAuthMiddleware(handler, cfg)
+	mw := middleware.BasicAuthMiddleware(
+		handler, authRepo.Users, authRepo.Lockouts, authRepo.Audit, "",
+	)
 
 	t.Run("Authorized", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/", nil)
-		req.SetBasicAuth(cfg.Username, cfg.Password)
+		req.SetBasicAuth(testUser.Username, testUserPassword)
 
 		rr := httptest.NewRecorder()
 		mw.ServeHTTP(rr, req)
@@ -43,27 +48,65 @@ func TestBasicAuthMiddleware(t *testing.T) {
 		assert.Equal(t, rr.Code, http.StatusUnauthorized)
 	})
 
-	t.Run("UnauthorizedNoCredential", func(t *testing.T) {
+	t.Run("UnauthorizedIncorrectPassword", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/", nil)
+		req.SetBasicAuth(testUser.Username, "incorrect")
 
 		rr := httptest.NewRecorder()
 		mw.ServeHTTP(rr, req)
 
 		assert.Equal(t, rr.Code, http.StatusUnauthorized)
 	})
-}
 
-func TestRecoverPanicMiddleware(t *testing.T) {
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		panic("the middleware should recover from this panic")
-	})
-	mw := api.RecoverPanicMiddleware(handler)
-
-	req := httptest.NewRequest("GET", "/", nil)
-	rr := httptest.NewRecorder()
+	t.Run("UnauthorizedNoCredential", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
 
-	assert.NotPanics(t, func() {
+		rr := httptest.NewRecorder()
 		mw.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		ctx := context.Background()
+		usernameKey := repo.UsernameKey(testUser.Username)
+		// Forget the failures of the previous subtests.
+		require.NoError(t, authRepo.Lockouts.RecordSuccess(ctx, usernameKey))
+
+		attempt := func(password string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "198.51.100.7:1234"
+			req.SetBasicAuth(testUser.Username, password)
+
+			rr := httptest.NewRecorder()
+			mw.ServeHTTP(rr, req)
+			return rr
+		}
+
+		// The first failures are free.
+		for range 2 {
+			rr := attempt("incorrect")
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Empty(t, rr.Header().Get("Retry-After"))
+		}
+
+		rr := attempt("incorrect")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+		// The correct password is rejected until the delay has passed.
+		rr = attempt(testUserPassword)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+		time.Sleep(time.Second)
+		rr = attempt(testUserPassword)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// A successful login resets the failures.
+		rr = attempt("incorrect")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Empty(t, rr.Header().Get("Retry-After"))
+		require.NoError(t, authRepo.Lockouts.Clear(ctx, usernameKey))
 	})
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 }
@@ -0,0 +1,53 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/auth/middleware"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAuthMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	clients := repo.NewClientRepository(map[string]string{"service": "s3cret:with/symbols"})
+	mw := middleware.ClientAuthMiddleware(handler, clients)
+
+	tests := []struct {
+		name     string
+		id       string
+		secret   string
+		expected int
+	}{
+		// Credentials are form-encoded, as defined by RFC 6749.
+		{"Authorized", "service", "s3cret%3Awith%2Fsymbols", http.StatusOK},
+		{"IncorrectSecret", "service", "incorrect", http.StatusUnauthorized},
+		{"UnknownClient", "unknown", "s3cret%3Awith%2Fsymbols", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.SetBasicAuth(tt.id, tt.secret)
+
+			rr := httptest.NewRecorder()
+			mw.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+
+	t.Run("UnauthorizedNoCredentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"error":"invalid_client"`)
+		assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+	})
+}
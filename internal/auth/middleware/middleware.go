@@ -1,20 +1,27 @@
 package middleware
 
 import (
-	"crypto/sha256"
-	"crypto/subtle"
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/r3d5un/islandwind/internal/api"
-	"github.com/r3d5un/islandwind/internal/auth/config"
 	"github.com/r3d5un/islandwind/internal/auth/repo"
 	"github.com/r3d5un/islandwind/internal/logging"
 )
 
-func AccessTokenMiddleware(next http.Handler, tokens repo.TokenService) http.Handler {
+// AccessTokenMiddleware authenticates the bearer token of the request, and adds the principal
+// the token was issued to the request context. Bearer tokens starting with
+// repo.APITokenPrefix are authenticated as personal access tokens, and other bearer tokens as
+// JWT access tokens.
+func AccessTokenMiddleware(
+	next http.Handler,
+	tokens repo.TokenService,
+	apiTokens repo.APITokenService,
+) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -24,6 +31,7 @@ func AccessTokenMiddleware(next http.Handler, tokens repo.TokenService) http.Han
 				errors.New("missing authorization header"),
 				"missing authorization header",
 			)
+			return
 		}
 
 		accessTokenString := strings.Split(authHeader, " ")
@@ -42,17 +50,41 @@ func AccessTokenMiddleware(next http.Handler, tokens repo.TokenService) http.Han
 			return
 		}
 
-		valid, err := tokens.Validate(r.Context(), repo.AccessTokenType, accessTokenString[1])
-		if err != nil || !valid {
+		if repo.IsAPIToken(accessTokenString[1]) {
+			principal, err := apiTokens.Authenticate(r.Context(), accessTokenString[1])
+			switch {
+			case errors.Is(err, repo.ErrUnauthorized):
+				api.UnauthorizedResponse(w, r)
+				return
+			case err != nil:
+				api.ServerErrorResponse(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), *principal)))
+			return
+		}
+
+		principal, err := tokens.Authenticate(r.Context(), accessTokenString[1])
+		if err != nil {
 			api.UnauthorizedResponse(w, r)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), *principal)))
 	})
 }
 
-func BasicAuthMiddleware(next http.Handler, cfg config.BasicAuthConfig) http.Handler {
+// BasicAuthMiddleware authenticates the username and password of the request against the
+// users, and adds the authenticated user to the request context. Failed attempts are counted
+// per username and client IP address, and attempts are rejected while either is throttled.
+// Failed and rejected attempts are recorded as audit events.
+func BasicAuthMiddleware(
+	next http.Handler,
+	users repo.UserService,
+	lockouts repo.LockoutService,
+	audit repo.AuditService,
+	clientIPHeader string,
+) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		logger := logging.LoggerFromContext(ctx)
@@ -60,27 +92,107 @@ func BasicAuthMiddleware(next http.Handler, cfg config.BasicAuthConfig) http.Han
 		logger.LogAttrs(ctx, slog.LevelInfo, "authenticating request")
 		username, password, ok := r.BasicAuth()
 		if ok {
-			usernameHash := sha256.Sum256([]byte(username))
-			passwordHash := sha256.Sum256([]byte(password))
-			expectedUsernameHash := sha256.Sum256([]byte(cfg.Username))
-			expectedPasswordHash := sha256.Sum256([]byte(cfg.Password))
-			usernameMatch := subtle.ConstantTimeCompare(
-				usernameHash[:],
-				expectedUsernameHash[:],
-			) == 1
-			passwordMatch := subtle.ConstantTimeCompare(
-				passwordHash[:],
-				expectedPasswordHash[:],
-			) == 1
-
-			if usernameMatch && passwordMatch {
+			usernameKey := repo.UsernameKey(username)
+			keys := []repo.LoginAttemptKey{
+				usernameKey,
+				repo.ClientIPKey(api.ClientIP(r, clientIPHeader)),
+			}
+
+			retryAfter, err := lockouts.Check(ctx, keys...)
+			if err != nil {
+				api.ServerErrorResponse(w, r, err)
+				return
+			}
+			if retryAfter > 0 {
+				audit.Record(ctx, loginFailure(username, "throttled"))
+				api.RateLimitExceededResponse(w, r, retryAfter)
+				return
+			}
+
+			user, err := users.Authenticate(ctx, username, password)
+			switch {
+			case err == nil:
 				logger.LogAttrs(ctx, slog.LevelInfo, "request authenticated")
-				next.ServeHTTP(w, r)
+				if err := lockouts.RecordSuccess(ctx, usernameKey); err != nil {
+					api.ServerErrorResponse(w, r, err)
+					return
+				}
+				principal := api.Principal{
+					Subject: user.ID.String(),
+					Scopes:  user.Role.Scopes(),
+				}
+				next.ServeHTTP(w, r.WithContext(withPrincipal(ctx, principal)))
+				return
+			case !errors.Is(err, repo.ErrInvalidCredentials):
+				api.ServerErrorResponse(w, r, err)
 				return
 			}
+
+			audit.Record(ctx, loginFailure(username, err.Error()))
+			retryAfter, err = lockouts.RecordFailure(ctx, keys...)
+			if err != nil {
+				api.ServerErrorResponse(w, r, err)
+				return
+			}
+			api.SetRetryAfter(w, retryAfter)
 		}
 
-		w.Header().Set("WWW-Authentication", `Basic realm="restricted", charset="UTF-8"`)
+		w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
 		api.UnauthorizedResponse(w, r)
 	})
 }
+
+// ClientAuthMiddleware authenticates the client credentials of the request with HTTP basic
+// authentication, as OAuth 2.0 clients do. The client ID and secret are form-encoded, as
+// defined by RFC 6749.
+func ClientAuthMiddleware(next http.Handler, clients repo.ClientService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		clientID, clientSecret, ok := r.BasicAuth()
+		if ok {
+			id, idErr := url.QueryUnescape(clientID)
+			secret, secretErr := url.QueryUnescape(clientSecret)
+			if idErr == nil && secretErr == nil && clients.Authenticate(id, secret) {
+				ctx, _ = logging.ContextLogger(ctx, slog.Group("client", slog.String("id", id)))
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="clients", charset="UTF-8"`)
+		api.OAuthErrorResponse(
+			w,
+			r,
+			http.StatusUnauthorized,
+			api.OAuthInvalidClient,
+			"client authentication failed",
+		)
+	})
+}
+
+// loginFailure returns the audit event of a failed login with the username. The actor is left
+// unknown, as the username may not exist.
+func loginFailure(username string, reason string) repo.AuditEventInput {
+	return repo.AuditEventInput{
+		Type:    repo.AuditLogin,
+		Outcome: repo.AuditFailure,
+		Details: map[string]any{"username": username, "reason": reason},
+	}
+}
+
+// DeviceMiddleware adds the user agent and client IP address of the request to the context,
+// so that they are recorded with the audit events of the request.
+func DeviceMiddleware(next http.Handler, clientIPHeader string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		device := repo.NewDevice(r.UserAgent(), api.ClientIP(r, clientIPHeader))
+		next.ServeHTTP(w, r.WithContext(repo.ContextWithDevice(r.Context(), device)))
+	})
+}
+
+// withPrincipal adds the principal to the context, and to the logger of the context, so that
+// the log entries of the request show who acted.
+func withPrincipal(ctx context.Context, principal api.Principal) context.Context {
+	ctx, _ = logging.ContextLogger(ctx, slog.Group("principal", slog.String("sub", principal.Subject)))
+	return api.ContextWithPrincipal(ctx, principal)
+}
@@ -10,12 +10,21 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/r3d5un/islandwind/internal/auth/mail"
 	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 	database "github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/testsuite"
 )
 
-var authRepo repo.Repository
+const testUserPassword string = "correct horse battery staple"
+
+var (
+	authRepo repo.Repository
+	// testUser is the user tokens are issued to.
+	testUser *repo.User
+)
 
 func TestMain(m *testing.M) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -36,12 +45,43 @@ func TestMain(m *testing.M) {
 		logger.Error("unable to create database connection pool", slog.String("error", err.Error()))
 		return
 	}
+	keySet, err := keys.Generate()
+	if err != nil {
+		logger.Error("unable to generate signing keys", slog.String("error", err.Error()))
+		return
+	}
 	authRepo = repo.NewRepository(
 		db,
 		new(cfg.TimeoutDuration()),
-		config.Config{SigningSecret: "islandwind", TokenIssuer: "islandwind"},
+		config.Config{
+			RefreshSigningSecret:   "islandwind",
+			ChallengeSigningSecret: "islandwind-challenge",
+			TokenIssuer:            "islandwind",
+			Lockout: config.LockoutConfig{
+				FreeAttempts:     2,
+				BaseDelaySeconds: 1,
+				MaxDelaySeconds:  4,
+				Threshold:        5,
+				IPThreshold:      1000,
+				DurationSeconds:  60,
+				ResetSeconds:     3600,
+			},
+		},
+		keySet,
+		mail.NewMailbox(),
+		nil,
 	)
 
+	testUser, err = authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "test",
+		Password: testUserPassword,
+		Role:     scope.RoleAdmin,
+	})
+	if err != nil {
+		logger.Error("unable to create test user", slog.String("error", err.Error()))
+		return
+	}
+
 	exitCode := m.Run()
 
 	defer os.Exit(exitCode)
@@ -1,24 +1,38 @@
 package middleware_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/r3d5un/islandwind/internal/api"
 	"github.com/r3d5un/islandwind/internal/auth/middleware"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestAccessTokenMiddleware(t *testing.T) {
+	ctx := context.Background()
+
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := api.PrincipalFromContext(r.Context())
+		if !ok || principal.Subject != testUser.ID.String() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	})
 
-	accessToken, err := authRepo.Tokens.CreateAccessToken()
+	accessToken, err := authRepo.Tokens.CreateAccessToken(api.Principal{
+		Subject: testUser.ID.String(),
+		Scopes:  testUser.Role.Scopes(),
+	})
 	assert.NoError(t, err)
 
-	mw := middleware.AccessTokenMiddleware(handler, authRepo.Tokens)
+	mw := middleware.AccessTokenMiddleware(handler, authRepo.Tokens, authRepo.APITokens)
 
 	t.Run("Authorize", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/", nil)
@@ -48,4 +62,36 @@ func TestAccessTokenMiddleware(t *testing.T) {
 
 		assert.Equal(t, rr.Code, http.StatusUnauthorized)
 	})
+
+	t.Run("APIToken", func(t *testing.T) {
+		apiToken, token, err := authRepo.APITokens.Create(ctx, testUser.ID, repo.APITokenInput{
+			Name:   "ci",
+			Scopes: []string{scope.BlogWrite},
+		})
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		err = authRepo.APITokens.Revoke(ctx, testUser.ID, apiToken.ID)
+		assert.NoError(t, err)
+
+		rr = httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("UnknownAPIToken", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %sunknown", repo.APITokenPrefix))
+
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
 }
@@ -0,0 +1,352 @@
+// Package oidc implements an OpenID Connect relying party using the authorization code flow
+// with PKCE.
+//
+// The provider is found with OpenID Connect Discovery, and ID tokens are verified with the keys
+// the provider publishes. The keys are fetched again when a token is signed by an unknown key,
+// so that the provider can rotate its keys. Only Ed25519 and RSA keys are supported.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+)
+
+const (
+	// maxResponseSize limits the responses read from the provider.
+	maxResponseSize int64 = 1 << 20
+	// keyRefreshInterval limits how often the keys are fetched for tokens signed by unknown
+	// keys, so that forged tokens cannot make the relying party flood the provider.
+	keyRefreshInterval time.Duration = time.Minute
+	// verifierBytes is the number of random bytes of PKCE code verifiers, giving 43 characters
+	// as recommended by RFC 7636.
+	verifierBytes int = 32
+)
+
+var (
+	// ErrDiscovery is returned when the discovery document or the keys of the provider cannot
+	// be read, or are invalid.
+	ErrDiscovery = errors.New("oidc discovery failed")
+	// ErrTokenRequest is returned when the provider rejects the authorization code.
+	ErrTokenRequest = errors.New("oidc token request failed")
+	// ErrInvalidIDToken is returned when an ID token cannot be verified.
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Metadata is the part of the provider metadata of OpenID Connect Discovery used by the
+// relying party.
+type Metadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// Discover reads the metadata of the provider from the well-known discovery document of the
+// issuer. The issuer of the metadata must match the issuer exactly.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Metadata, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	var metadata Metadata
+	if err := getJSON(ctx, client, u, &metadata); err != nil {
+		return nil, err
+	}
+	switch {
+	case metadata.Issuer != issuer:
+		return nil, fmt.Errorf("%w: issuer %q does not match", ErrDiscovery, metadata.Issuer)
+	case metadata.AuthorizationEndpoint == "", metadata.TokenEndpoint == "",
+		metadata.JWKSURI == "":
+		return nil, fmt.Errorf("%w: missing endpoints", ErrDiscovery)
+	// Providers not listing the methods may still support PKCE.
+	case len(metadata.CodeChallengeMethodsSupported) > 0 &&
+		!slices.Contains(metadata.CodeChallengeMethodsSupported, "S256"):
+		return nil, fmt.Errorf("%w: S256 code challenges not supported", ErrDiscovery)
+	}
+
+	return &metadata, nil
+}
+
+// Client is the relying party of a provider.
+type Client struct {
+	cfg      config.OIDCConfig
+	metadata Metadata
+	client   *http.Client
+
+	mu       sync.Mutex
+	keys     *keys.Set
+	keysRead time.Time
+}
+
+// NewClient discovers the provider of the issuer, and reads its keys. If client is nil,
+// http.DefaultClient is used.
+func NewClient(
+	ctx context.Context,
+	cfg config.OIDCConfig,
+	client *http.Client,
+) (*Client, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	metadata, err := Discover(ctx, client, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	c := Client{cfg: cfg, metadata: *metadata, client: client}
+	if _, err := c.readKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// NewVerifier returns a new PKCE code verifier.
+func NewVerifier() string {
+	b := make([]byte, verifierBytes)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Challenge returns the S256 code challenge of the PKCE code verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL of the authorization endpoint to send the user to. The state is
+// returned to the callback, and the nonce is returned in the ID token.
+func (c *Client) AuthCodeURL(state string, nonce string, verifier string) string {
+	scopes := []string{"openid"}
+	for _, s := range c.cfg.Scopes {
+		if s != "openid" && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(c.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return c.metadata.AuthorizationEndpoint + sep + query.Encode()
+}
+
+// tokenResponse is the part of the token response used by the relying party.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems the authorization code at the token endpoint, authenticating with the
+// client secret, and returns the ID token.
+func (c *Client) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, c.metadata.TokenEndpoint, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// The credentials are form-encoded before basic authentication, as required by RFC 6749.
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: status %d", ErrTokenRequest, resp.StatusCode)
+	}
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf(
+			"%w: %s: %s", ErrTokenRequest, body.Error, body.ErrorDescription,
+		)
+	case body.IDToken == "":
+		return "", fmt.Errorf("%w: no id token", ErrTokenRequest)
+	}
+
+	return body.IDToken, nil
+}
+
+// IDToken is a verified ID token.
+type IDToken struct {
+	Subject string
+	Claims  jwt.MapClaims
+}
+
+// String returns the claim if it is a string.
+func (t IDToken) String(claim string) string {
+	s, _ := t.Claims[claim].(string)
+	return s
+}
+
+// Strings returns the claim if it is a string or an array of strings.
+func (t IDToken) Strings(claim string) []string {
+	switch v := t.Claims[claim].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// Verify verifies the signature, issuer, audience, expiry and nonce of the ID token.
+func (c *Client) Verify(ctx context.Context, input string, nonce string) (*IDToken, error) {
+	keySet, err := c.keySet(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	token, err := jwt.Parse(
+		input,
+		keySet.Keyfunc,
+		jwt.WithValidMethods(keySet.Algorithms()),
+		jwt.WithIssuer(c.metadata.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	idToken := IDToken{Claims: claims}
+	idToken.Subject, _ = claims.GetSubject()
+	if idToken.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.String("nonce")), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	// Tokens issued to several audiences must name the client as the authorized party.
+	if audience, _ := claims.GetAudience(); len(audience) > 1 &&
+		idToken.String("azp") != c.cfg.ClientID {
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+	}
+
+	return &idToken, nil
+}
+
+// keySet returns the keys of the provider, reading them again if the token is signed by an
+// unknown key and the keys have not been read recently.
+func (c *Client) keySet(ctx context.Context, input string) (*keys.Set, error) {
+	c.mu.Lock()
+	keySet, keysRead := c.keys, c.keysRead
+	c.mu.Unlock()
+
+	token, _, err := jwt.NewParser().ParseUnverified(input, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := keySet.Keyfunc(token); !errors.Is(err, keys.ErrUnknownKey) ||
+		time.Since(keysRead) < keyRefreshInterval {
+		return keySet, nil
+	}
+
+	return c.readKeys(ctx)
+}
+
+func (c *Client) readKeys(ctx context.Context) (*keys.Set, error) {
+	var jwks keys.JWKS
+	if err := getJSON(ctx, c.client, c.metadata.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	// Keys not used for signatures, or of unsupported types, are skipped.
+	var verification []*keys.Key
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := keys.ParseJWK(jwk)
+		if err != nil {
+			continue
+		}
+		verification = append(verification, key)
+	}
+	if len(verification) == 0 {
+		return nil, fmt.Errorf("%w: no supported keys", ErrDiscovery)
+	}
+	keySet := keys.NewVerificationSet(verification...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys, c.keysRead = keySet, time.Now()
+
+	return keySet, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned status %d", ErrDiscovery, u, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/oidc"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL string = "http://localhost:4000/api/v1/auth/oidc/callback"
+
+// authorize follows the authorization URL to the provider, returning the query of the
+// redirect back to the callback.
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	client := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := resp.Location()
+	require.NoError(t, err)
+	require.Equal(t, redirectURL, location.Scheme+"://"+location.Host+location.Path)
+	return location.Query()
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	provider := testsuite.NewOIDCProvider("islandwind", "a client secret")
+	t.Cleanup(provider.Close)
+	provider.SetUser(map[string]any{
+		"sub":                "00u1",
+		"preferred_username": "judy",
+		"groups":             []string{"staff", "blog-editors"},
+	})
+
+	client, err := oidc.NewClient(ctx, config.OIDCConfig{
+		Issuer:       provider.Issuer,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "profile"},
+	}, nil)
+	require.NoError(t, err)
+
+	t.Run("Login", func(t *testing.T) {
+		verifier := oidc.NewVerifier()
+		authURL := client.AuthCodeURL("a state", "a nonce", verifier)
+		query, err := url.Parse(authURL)
+		require.NoError(t, err)
+		assert.Equal(t, "openid profile", query.Query().Get("scope"))
+		assert.Equal(t, oidc.Challenge(verifier), query.Query().Get("code_challenge"))
+
+		callback := authorize(t, authURL)
+		assert.Equal(t, "a state", callback.Get("state"))
+
+		idToken, err := client.Exchange(ctx, callback.Get("code"), verifier)
+		require.NoError(t, err)
+
+		verified, err := client.Verify(ctx, idToken, "a nonce")
+		require.NoError(t, err)
+		assert.Equal(t, "00u1", verified.Subject)
+		assert.Equal(t, "judy", verified.String("preferred_username"))
+		assert.Equal(t, []string{"staff", "blog-editors"}, verified.Strings("groups"))
+
+		_, err = client.Verify(ctx, idToken, "another nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+
+		// Authorization codes are single-use.
+		_, err = client.Exchange(ctx, callback.Get("code"), verifier)
+		assert.ErrorIs(t, err, oidc.ErrTokenRequest)
+	})
+
+	t.Run("ExchangeInvalidVerifier", func(t *testing.T) {
+		callback := authorize(t, client.AuthCodeURL("a state", "a nonce", oidc.NewVerifier()))
+
+		_, err := client.Exchange(ctx, callback.Get("code"), oidc.NewVerifier())
+		assert.ErrorIs(t, err, oidc.ErrTokenRequest)
+	})
+
+	t.Run("VerifyInvalidAudience", func(t *testing.T) {
+		now := time.Now()
+		idToken, err := provider.SignIDToken(jwt.MapClaims{
+			"iss":   provider.Issuer,
+			"sub":   "00u1",
+			"aud":   "another client",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": "a nonce",
+		})
+		require.NoError(t, err)
+
+		_, err = client.Verify(ctx, idToken, "a nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("VerifyExpired", func(t *testing.T) {
+		now := time.Now()
+		idToken, err := provider.SignIDToken(jwt.MapClaims{
+			"iss":   provider.Issuer,
+			"sub":   "00u1",
+			"aud":   provider.ClientID,
+			"iat":   now.Add(-2 * time.Hour).Unix(),
+			"exp":   now.Add(-time.Hour).Unix(),
+			"nonce": "a nonce",
+		})
+		require.NoError(t, err)
+
+		_, err = client.Verify(ctx, idToken, "a nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("DiscoverIssuerMismatch", func(t *testing.T) {
+		_, err := oidc.Discover(ctx, http.DefaultClient, provider.Issuer+"/")
+		assert.ErrorIs(t, err, oidc.ErrDiscovery)
+	})
+}
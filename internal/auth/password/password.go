@@ -0,0 +1,136 @@
+// Package password hashes and verifies passwords with argon2id. Hashes are encoded in the PHC
+// string format, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>, so that the parameters can
+// be raised later without invalidating existing hashes.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	// ErrMismatch is returned when the password does not match the hash.
+	ErrMismatch = errors.New("password does not match")
+	// ErrInvalidHash is returned when the hash is not an encoded argon2id hash.
+	ErrInvalidHash = errors.New("invalid password hash")
+	// ErrIncompatibleVersion is returned when the hash was created by another version of
+	// argon2.
+	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+)
+
+// Params are the argon2id parameters used when hashing passwords.
+type Params struct {
+	// Memory is the amount of memory used in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Parallelism is the number of threads used.
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams follows the second recommended option of RFC 9106, using 64 MiB of memory.
+var DefaultParams = Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var encoding = base64.RawStdEncoding
+
+// Hash returns the encoded argon2id hash of the password using the DefaultParams.
+func Hash(password string) (string, error) {
+	return HashWithParams(password, DefaultParams)
+}
+
+// HashWithParams returns the encoded argon2id hash of the password using the given params.
+func HashWithParams(password string, p Params) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Memory,
+		p.Iterations,
+		p.Parallelism,
+		encoding.EncodeToString(salt),
+		encoding.EncodeToString(key),
+	), nil
+}
+
+// Verify compares the password with the encoded hash in constant time. ErrMismatch is returned
+// if the password does not match.
+func Verify(password string, encoded string) error {
+	p, salt, key, err := decode(encoded)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether the encoded hash was created with parameters other than the
+// DefaultParams.
+func NeedsRehash(encoded string) bool {
+	p, _, _, err := decode(encoded)
+	if err != nil {
+		return true
+	}
+	return p != DefaultParams
+}
+
+func decode(encoded string) (Params, []byte, []byte, error) {
+	var p Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return p, nil, nil, ErrIncompatibleVersion
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := encoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := encoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
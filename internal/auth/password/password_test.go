@@ -0,0 +1,60 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/auth/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParams keeps the tests fast. The parameters are encoded in the hash, so verifying does
+// not depend on the DefaultParams.
+var testParams = password.Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestHash(t *testing.T) {
+	t.Run("Verify", func(t *testing.T) {
+		hash, err := password.HashWithParams("correct horse battery staple", testParams)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+		assert.NoError(t, password.Verify("correct horse battery staple", hash))
+		assert.ErrorIs(t, password.Verify("incorrect", hash), password.ErrMismatch)
+	})
+
+	t.Run("UniqueSalt", func(t *testing.T) {
+		first, err := password.HashWithParams("password", testParams)
+		require.NoError(t, err)
+		second, err := password.HashWithParams("password", testParams)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("InvalidHash", func(t *testing.T) {
+		for _, hash := range []string{
+			"",
+			"password",
+			"$2a$10$abcdefghijklmnopqrstuv",
+			"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+		} {
+			assert.ErrorIs(t, password.Verify("password", hash), password.ErrInvalidHash, hash)
+		}
+
+		err := password.Verify("password", "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5")
+		assert.ErrorIs(t, err, password.ErrIncompatibleVersion)
+	})
+
+	t.Run("NeedsRehash", func(t *testing.T) {
+		hash, err := password.HashWithParams("password", testParams)
+		require.NoError(t, err)
+		assert.True(t, password.NeedsRehash(hash))
+	})
+}
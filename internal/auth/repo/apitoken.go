@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// APITokenPrefix starts every personal access token, distinguishing them from JWTs, which
+// always start with "eyJ", and making leaked tokens easy to find with secret scanners.
+const APITokenPrefix string = "iwpat_"
+
+// apiTokenBytes is the number of random bytes of a personal access token.
+const apiTokenBytes int = 32
+
+// maxAPITokens limits the number of personal access tokens listed for a user.
+const maxAPITokens int = 100
+
+// ErrInvalidAPIToken is returned when a personal access token is unknown, expired or revoked,
+// or the user it was issued to is disabled. It wraps ErrUnauthorized.
+var ErrInvalidAPIToken = fmt.Errorf("%w: invalid api token", ErrUnauthorized)
+
+// APIToken is a personal access token, without the token itself.
+type APIToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"userId"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+func newAPITokenFromRow(row *data.APIToken) *APIToken {
+	return &APIToken{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		Name:       row.Name,
+		Scopes:     row.Scopes,
+		CreatedAt:  row.CreatedAt,
+		ExpiresAt:  db.NullToPtr(row.ExpiresAt),
+		LastUsedAt: db.NullToPtr(row.LastUsedAt),
+		RevokedAt:  db.NullToPtr(row.RevokedAt),
+	}
+}
+
+type APITokenInput struct {
+	Name string `json:"name"`
+	// Scopes of the token. Callers must ensure the user is allowed the scopes.
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional. Tokens without an expiry are valid until revoked.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type APITokenService interface {
+	// Create issues a personal access token to the user. The token is returned only here, as
+	// only its hash is stored.
+	Create(ctx context.Context, userID uuid.UUID, input APITokenInput) (*APIToken, string, error)
+	// List lists the personal access tokens of the user, including expired and revoked tokens.
+	List(ctx context.Context, userID uuid.UUID) ([]*APIToken, error)
+	// Revoke revokes the personal access token of the user. ErrRecordNotFound is returned if
+	// the user has no unrevoked token with the ID.
+	Revoke(ctx context.Context, userID uuid.UUID, ID uuid.UUID) error
+	// Authenticate validates the personal access token, and returns the principal it was
+	// issued to. The scopes of the principal are limited to the current role of the user.
+	Authenticate(ctx context.Context, token string) (*api.Principal, error)
+}
+
+type APITokenRepository struct {
+	models *data.Models
+}
+
+func NewAPITokenRepository(models *data.Models) APITokenService {
+	return &APITokenRepository{models: models}
+}
+
+// IsAPIToken reports whether the bearer token is a personal access token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *APITokenRepository) Create(
+	ctx context.Context,
+	userID uuid.UUID,
+	input APITokenInput,
+) (*APIToken, string, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"apiToken",
+		slog.String("userId", userID.String()),
+		slog.String("name", input.Name),
+		slog.Any("scopes", input.Scopes),
+	))
+
+	b := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "creating api token")
+	row, err := r.models.APITokens.Insert(ctx, data.APITokenInput{
+		UserID:    userID,
+		Name:      input.Name,
+		TokenHash: hashAPIToken(token),
+		Scopes:    input.Scopes,
+		ExpiresAt: db.PtrToNull(input.ExpiresAt),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "api token created", slog.String("id", row.ID.String()))
+
+	return newAPITokenFromRow(row), token, nil
+}
+
+func (r *APITokenRepository) List(ctx context.Context, userID uuid.UUID) ([]*APIToken, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"apiTokens",
+		slog.String("userId", userID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading api tokens")
+	rows, _, err := r.models.APITokens.SelectMany(ctx, data.APITokenFilter{
+		UserID:   sql.Null[uuid.UUID]{V: userID, Valid: true},
+		PageSize: maxAPITokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*APIToken, len(rows))
+	for i, row := range rows {
+		tokens[i] = newAPITokenFromRow(row)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "api tokens retrieved")
+
+	return tokens, nil
+}
+
+func (r *APITokenRepository) Revoke(ctx context.Context, userID uuid.UUID, ID uuid.UUID) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"apiToken",
+		slog.String("id", ID.String()),
+		slog.String("userId", userID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "revoking api token")
+	row, err := r.models.APITokens.Revoke(ctx, ID, userID)
+	if err != nil {
+		return err
+	}
+	ensure.Equal(row.ID, ID, "api token ID must match")
+	logger.LogAttrs(ctx, slog.LevelInfo, "api token revoked")
+
+	return nil
+}
+
+func (r *APITokenRepository) Authenticate(
+	ctx context.Context,
+	token string,
+) (*api.Principal, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if !IsAPIToken(token) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	row, err := r.models.APITokens.SelectByHash(ctx, hashAPIToken(token))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+	logger = logger.With(slog.Group(
+		"apiToken",
+		slog.String("id", row.ID.String()),
+		slog.String("userId", row.UserID.String()),
+	))
+
+	switch {
+	case row.RevokedAt.Valid:
+		logger.LogAttrs(ctx, slog.LevelInfo, "api token revoked")
+		return nil, ErrInvalidAPIToken
+	case row.ExpiresAt.Valid && !row.ExpiresAt.V.After(time.Now()):
+		logger.LogAttrs(ctx, slog.LevelInfo, "api token expired")
+		return nil, ErrInvalidAPIToken
+	}
+
+	user, err := r.models.Users.SelectOne(ctx, row.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		logger.LogAttrs(ctx, slog.LevelInfo, "user disabled")
+		return nil, ErrInvalidAPIToken
+	}
+
+	// The role of the user may have changed since the token was created, so the token is only
+	// granted the scopes it was created with that the role still grants.
+	roleScopes := user.Role.Scopes()
+	scopes := make([]string, 0, len(row.Scopes))
+	for _, s := range row.Scopes {
+		if slices.Contains(roleScopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	if err := r.models.APITokens.Touch(ctx, row.ID); err != nil {
+		// Failing to record the use of the token should not fail the request.
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"unable to record api token use",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return &api.Principal{Subject: row.UserID.String(), Scopes: scopes}, nil
+}
@@ -0,0 +1,101 @@
+package repo_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenRepository(t *testing.T) {
+	ctx := context.Background()
+
+	author, err := authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "ci",
+		Password: testUserPassword,
+		Role:     scope.RoleEditor,
+	})
+	require.NoError(t, err)
+
+	apiToken, token, err := authRepo.APITokens.Create(ctx, author.ID, repo.APITokenInput{
+		Name:   "publishing",
+		Scopes: []string{scope.BlogWrite, scope.BlogDelete},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, repo.APITokenPrefix))
+
+	t.Run("Authenticate", func(t *testing.T) {
+		principal, err := authRepo.APITokens.Authenticate(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, author.ID.String(), principal.Subject)
+		assert.Equal(t, []string{scope.BlogWrite, scope.BlogDelete}, principal.Scopes)
+		assert.Empty(t, principal.SessionID)
+
+		tokens, err := authRepo.APITokens.List(ctx, author.ID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, apiToken.ID, tokens[0].ID)
+		assert.NotNil(t, tokens[0].LastUsedAt)
+	})
+
+	t.Run("AuthenticateRoleChanged", func(t *testing.T) {
+		_, err := authRepo.Users.SetRole(ctx, author.ID, scope.RoleAuthor)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_, err := authRepo.Users.SetRole(ctx, author.ID, scope.RoleEditor)
+			require.NoError(t, err)
+		})
+
+		// The token loses the scopes the new role does not grant.
+		principal, err := authRepo.APITokens.Authenticate(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, []string{scope.BlogWrite}, principal.Scopes)
+	})
+
+	t.Run("AuthenticateUnknown", func(t *testing.T) {
+		_, err := authRepo.APITokens.Authenticate(ctx, repo.APITokenPrefix+"unknown")
+		assert.ErrorIs(t, err, repo.ErrInvalidAPIToken)
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+	})
+
+	t.Run("AuthenticateExpired", func(t *testing.T) {
+		_, expired, err := authRepo.APITokens.Create(ctx, author.ID, repo.APITokenInput{
+			Name:      "expired",
+			Scopes:    []string{scope.BlogWrite},
+			ExpiresAt: new(time.Now().Add(-time.Minute)),
+		})
+		require.NoError(t, err)
+
+		_, err = authRepo.APITokens.Authenticate(ctx, expired)
+		assert.ErrorIs(t, err, repo.ErrInvalidAPIToken)
+	})
+
+	t.Run("AuthenticateDisabledUser", func(t *testing.T) {
+		_, err := authRepo.Users.SetDisabled(ctx, author.ID, true)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_, err := authRepo.Users.SetDisabled(ctx, author.ID, false)
+			require.NoError(t, err)
+		})
+
+		_, err = authRepo.APITokens.Authenticate(ctx, token)
+		assert.ErrorIs(t, err, repo.ErrInvalidAPIToken)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		err := authRepo.APITokens.Revoke(ctx, testUser.ID, apiToken.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+
+		err = authRepo.APITokens.Revoke(ctx, author.ID, apiToken.ID)
+		require.NoError(t, err)
+
+		_, err = authRepo.APITokens.Authenticate(ctx, token)
+		assert.ErrorIs(t, err, repo.ErrInvalidAPIToken)
+	})
+}
@@ -0,0 +1,227 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// AuditEventType is what happened in an audit event.
+type AuditEventType string
+
+const (
+	// AuditLogin is a session started with a password, and a TOTP code if enabled.
+	AuditLogin AuditEventType = "login"
+	// AuditRefresh is a refresh token replaced by a new token of the session.
+	AuditRefresh AuditEventType = "refresh"
+	// AuditLogout is a session ended by its user.
+	AuditLogout AuditEventType = "logout"
+	// AuditRevoke is a token revoked by a client as defined by RFC 7009.
+	AuditRevoke AuditEventType = "revoke"
+	// AuditRefreshTokenDelete is refresh tokens deleted by an administrator.
+	AuditRefreshTokenDelete AuditEventType = "refresh_token_delete"
+	// AuditPasswordReset is a password reset with a token sent by email.
+	AuditPasswordReset AuditEventType = "password_reset"
+)
+
+func (t AuditEventType) Valid() bool {
+	switch t {
+	case AuditLogin,
+		AuditRefresh,
+		AuditLogout,
+		AuditRevoke,
+		AuditRefreshTokenDelete,
+		AuditPasswordReset:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuditOutcome is whether the action of an audit event succeeded.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+func (o AuditOutcome) Valid() bool {
+	return o == AuditSuccess || o == AuditFailure
+}
+
+// AuditEvent is a security relevant event, recording who did what from where.
+type AuditEvent struct {
+	ID         uuid.UUID      `json:"id"`
+	OccurredAt time.Time      `json:"occurredAt"`
+	Type       AuditEventType `json:"type"`
+	Outcome    AuditOutcome   `json:"outcome"`
+	// ActorID is the user acting, or the user the event concerns if the actor is unknown.
+	ActorID   *uuid.UUID `json:"actorId"`
+	ClientIP  *string    `json:"clientIp"`
+	UserAgent *string    `json:"userAgent"`
+	SessionID *uuid.UUID `json:"sessionId"`
+	// TokenIDs are the IDs of the tokens the event concerns.
+	TokenIDs []uuid.UUID `json:"tokenIds"`
+	// Details are additional event specific fields, e.g. the reason of a failure.
+	Details map[string]any `json:"details"`
+}
+
+func newAuditEventFromRow(row *data.AuditEvent) *AuditEvent {
+	return &AuditEvent{
+		ID:         row.ID,
+		OccurredAt: row.OccurredAt,
+		Type:       AuditEventType(row.EventType),
+		Outcome:    AuditOutcome(row.Outcome),
+		ActorID:    db.NullUUIDToPtr(row.ActorID),
+		ClientIP:   db.NullToPtr(row.ClientIP),
+		UserAgent:  db.NullToPtr(row.UserAgent),
+		SessionID:  db.NullUUIDToPtr(row.SessionID),
+		TokenIDs:   row.TokenIDs,
+		Details:    row.Details,
+	}
+}
+
+// AuditEventInput is an event to record. The client and, unless the ActorID is set, the actor
+// are read from the context.
+type AuditEventInput struct {
+	Type      AuditEventType `json:"type"`
+	Outcome   AuditOutcome   `json:"outcome"`
+	ActorID   *uuid.UUID     `json:"actorId"`
+	SessionID *uuid.UUID     `json:"sessionId"`
+	TokenIDs  []uuid.UUID    `json:"tokenIds"`
+	Details   map[string]any `json:"details"`
+}
+
+// withResult sets the outcome of the event from the error of the action, adding the error as
+// the reason of failures.
+func (e AuditEventInput) withResult(err error) AuditEventInput {
+	if err == nil {
+		e.Outcome = AuditSuccess
+		return e
+	}
+
+	e.Outcome = AuditFailure
+	details := make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details["reason"] = err.Error()
+	e.Details = details
+	return e
+}
+
+type deviceContextKey struct{}
+
+// ContextWithDevice adds the client of the request to the context, so that it is recorded with
+// the audit events of the request.
+func ContextWithDevice(ctx context.Context, device Device) context.Context {
+	return context.WithValue(ctx, deviceContextKey{}, device)
+}
+
+// DeviceFromContext returns the client added to the context by ContextWithDevice.
+func DeviceFromContext(ctx context.Context) (Device, bool) {
+	device, ok := ctx.Value(deviceContextKey{}).(Device)
+	return device, ok
+}
+
+type AuditService interface {
+	// Record stores the event. Failing to store the event is logged rather than returned, so
+	// that the action audited is not failed after the fact.
+	Record(ctx context.Context, input AuditEventInput)
+	// List lists the events matching the filter in the order they occurred.
+	List(
+		ctx context.Context,
+		filter data.AuditEventFilter,
+	) ([]*AuditEvent, *data.Metadata, error)
+	// Prune deletes the events that occurred before the time, returning the number of events
+	// deleted.
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditRepository stores the audit events in the append-only auth.audit_event table.
+type AuditRepository struct {
+	models *data.Models
+}
+
+func NewAuditRepository(models *data.Models) AuditService {
+	return &AuditRepository{models: models}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, input AuditEventInput) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"auditEvent",
+		slog.String("type", string(input.Type)),
+		slog.String("outcome", string(input.Outcome)),
+	))
+
+	row := data.AuditEventInput{
+		EventType: string(input.Type),
+		Outcome:   string(input.Outcome),
+		ActorID:   db.NewNullUUID(input.ActorID),
+		SessionID: db.NewNullUUID(input.SessionID),
+		TokenIDs:  input.TokenIDs,
+		Details:   input.Details,
+	}
+	if !row.ActorID.Valid {
+		if principal, ok := api.PrincipalFromContext(ctx); ok {
+			if id, err := uuid.Parse(principal.Subject); err == nil {
+				row.ActorID = uuid.NullUUID{UUID: id, Valid: true}
+			}
+		}
+	}
+	if device, ok := DeviceFromContext(ctx); ok {
+		row.ClientIP = sql.Null[string]{V: device.ClientIP, Valid: device.ClientIP != ""}
+		row.UserAgent = sql.Null[string]{V: device.UserAgent, Valid: device.UserAgent != ""}
+	}
+
+	// The event is recorded even if the request was cancelled after the action.
+	if _, err := r.models.AuditEvents.Insert(context.WithoutCancel(ctx), row); err != nil {
+		logger.LogAttrs(
+			ctx, slog.LevelError, "unable to record audit event", slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (r *AuditRepository) List(
+	ctx context.Context,
+	filter data.AuditEventFilter,
+) ([]*AuditEvent, *data.Metadata, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Any("filter", filter))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading audit events")
+	rows, metadata, err := r.models.AuditEvents.SelectMany(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	ensure.NotNil(metadata, "audit event metadata must not be nil")
+
+	events := make([]*AuditEvent, len(rows))
+	for i, row := range rows {
+		events[i] = newAuditEventFromRow(row)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "audit events retrieved")
+
+	return events, metadata, nil
+}
+
+func (r *AuditRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Time("before", before))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "pruning audit events")
+	deleted, err := r.models.AuditEvents.DeleteBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "audit events pruned", slog.Int64("deleted", deleted))
+
+	return deleted, nil
+}
@@ -0,0 +1,131 @@
+package repo_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	device := repo.Device{UserAgent: "audit-test", ClientIP: "192.0.2.21"}
+	ctx := repo.ContextWithDevice(context.Background(), device)
+
+	// listSession lists the events of the session in the order they occurred.
+	listSession := func(t *testing.T, sessionID uuid.UUID) []*repo.AuditEvent {
+		events, metadata, err := authRepo.Audit.List(ctx, data.AuditEventFilter{
+			SessionID: sql.Null[uuid.UUID]{V: sessionID, Valid: true},
+			PageSize:  10,
+		})
+		require.NoError(t, err)
+		require.Equal(t, len(events), metadata.ResponseLength)
+		return events
+	}
+
+	_, refreshToken, err := authRepo.Tokens.CreateSession(
+		ctx, api.Principal{Subject: testUser.ID.String()}, device,
+	)
+	require.NoError(t, err)
+	sessions, err := authRepo.Tokens.ListSessions(ctx, testUser.ID)
+	require.NoError(t, err)
+	var sessionID uuid.UUID
+	for _, session := range sessions {
+		if session.UserAgent != nil && *session.UserAgent == device.UserAgent {
+			sessionID = session.ID
+		}
+	}
+	require.NotEqual(t, uuid.Nil, sessionID)
+
+	t.Run("Login", func(t *testing.T) {
+		events := listSession(t, sessionID)
+		require.Len(t, events, 1)
+		assert.Equal(t, repo.AuditLogin, events[0].Type)
+		assert.Equal(t, repo.AuditSuccess, events[0].Outcome)
+		assert.Equal(t, testUser.ID, *events[0].ActorID)
+		assert.Equal(t, device.ClientIP, *events[0].ClientIP)
+		assert.Equal(t, device.UserAgent, *events[0].UserAgent)
+		assert.Len(t, events[0].TokenIDs, 1)
+	})
+
+	var replaced *string
+	t.Run("Refresh", func(t *testing.T) {
+		_, replaced, err = authRepo.Tokens.Refresh(ctx, *refreshToken, device)
+		require.NoError(t, err)
+
+		events := listSession(t, sessionID)
+		require.Len(t, events, 2)
+		assert.Equal(t, repo.AuditRefresh, events[1].Type)
+		assert.Equal(t, repo.AuditSuccess, events[1].Outcome)
+		// The replaced token and its replacement.
+		require.Len(t, events[1].TokenIDs, 2)
+		assert.Equal(t, events[0].TokenIDs[0], events[1].TokenIDs[0])
+
+		// The events of a token are found by its ID.
+		byToken, _, err := authRepo.Audit.List(ctx, data.AuditEventFilter{
+			TokenID:  sql.Null[uuid.UUID]{V: events[1].TokenIDs[1], Valid: true},
+			PageSize: 10,
+		})
+		require.NoError(t, err)
+		require.Len(t, byToken, 1)
+		assert.Equal(t, events[1].ID, byToken[0].ID)
+	})
+
+	t.Run("RefreshReused", func(t *testing.T) {
+		_, _, err := authRepo.Tokens.Refresh(ctx, *refreshToken, device)
+		require.ErrorIs(t, err, repo.ErrTokenReused)
+
+		events := listSession(t, sessionID)
+		require.Len(t, events, 3)
+		assert.Equal(t, repo.AuditRefresh, events[2].Type)
+		assert.Equal(t, repo.AuditFailure, events[2].Outcome)
+		assert.Contains(t, events[2].Details["reason"], "reused")
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		require.NoError(t, authRepo.Tokens.InvalidateRefreshToken(ctx, *replaced))
+
+		events := listSession(t, sessionID)
+		require.Len(t, events, 4)
+		assert.Equal(t, repo.AuditLogout, events[3].Type)
+		assert.Equal(t, repo.AuditSuccess, events[3].Outcome)
+		assert.Equal(t, testUser.ID, *events[3].ActorID)
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		var seen []uuid.UUID
+		filter := data.AuditEventFilter{
+			SessionID: sql.Null[uuid.UUID]{V: sessionID, Valid: true},
+			PageSize:  3,
+		}
+		for {
+			events, metadata, err := authRepo.Audit.List(ctx, filter)
+			require.NoError(t, err)
+			if len(events) == 0 {
+				break
+			}
+			for _, event := range events {
+				seen = append(seen, event.ID)
+			}
+			filter.LastSeen = metadata.LastSeen
+		}
+		assert.Len(t, seen, 4)
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		_, err := authRepo.Audit.Prune(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Len(t, listSession(t, sessionID), 4)
+
+		deleted, err := authRepo.Audit.Prune(ctx, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, deleted, int64(4))
+		assert.Empty(t, listSession(t, sessionID))
+	})
+}
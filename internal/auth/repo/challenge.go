@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+const (
+	// totpChallengeType is the typ claim of TOTP challenge tokens.
+	totpChallengeType string = "totp_challenge"
+	// totpChallengeDuration is the time users have to enter their TOTP code after the password.
+	totpChallengeDuration time.Duration = 5 * time.Minute
+)
+
+// ErrInvalidChallenge is returned when a TOTP challenge token is invalid, expired or used. It
+// wraps ErrUnauthorized.
+var ErrInvalidChallenge = fmt.Errorf("%w: invalid challenge", ErrUnauthorized)
+
+// TOTPChallenge is issued when a user with two-factor authentication has given the correct
+// password, and is exchanged for tokens together with a TOTP or recovery code.
+type TOTPChallenge struct {
+	// ID is the jti claim of the challenge token.
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// Label is the session label given when logging in.
+	Label *string
+}
+
+// CreateTOTPChallenge signs the challenge with the challenge signing secret, and stores its ID
+// so that the challenge can only be exchanged for tokens once. The challenge is not signed with
+// the key set, as services verifying access tokens with the published keys would accept it.
+func (r *TokenRepository) CreateTOTPChallenge(
+	ctx context.Context,
+	challenge TOTPChallenge,
+) (*string, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", challenge.UserID.String()),
+	))
+
+	now := time.Now().UTC()
+	exp := now.Add(totpChallengeDuration)
+	challenge.ID = uuid.New()
+	claims := r.newClaims(challenge.ID, challenge.UserID, nil, exp, now)
+	claims["typ"] = totpChallengeType
+	if challenge.Label != nil {
+		claims["label"] = *challenge.Label
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "creating totp challenge")
+	_, err := r.models.TOTPChallenges.Insert(ctx, data.TOTPChallengeInput{
+		ID:        challenge.ID,
+		UserID:    challenge.UserID,
+		ExpiresAt: exp,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).
+		SignedString(r.challengeSigningSecret)
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp challenge created")
+
+	return &signed, nil
+}
+
+func (r *TokenRepository) ParseTOTPChallenge(input string) (*TOTPChallenge, error) {
+	token, err := r.parseToken(input, totpChallengeTokenType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidChallenge, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidChallenge
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	jti, err := r.jtiFromToken(*token)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	challenge := TOTPChallenge{ID: *jti, UserID: userID}
+	if label, ok := claims["label"].(string); ok {
+		challenge.Label = &label
+	}
+
+	return &challenge, nil
+}
+
+func (r *TokenRepository) ConsumeTOTPChallenge(ctx context.Context, challenge TOTPChallenge) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"challenge",
+		slog.String("id", challenge.ID.String()),
+		slog.String("userId", challenge.UserID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "consuming totp challenge")
+	row, err := r.models.TOTPChallenges.Use(ctx, challenge.ID)
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		return ErrInvalidChallenge
+	case err != nil:
+		return err
+	}
+	if row.UserID != challenge.UserID {
+		return ErrInvalidChallenge
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp challenge consumed")
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// ClientService authenticates the services calling the token introspection and revocation
+// endpoints.
+type ClientService interface {
+	// Authenticate reports whether the secret is the secret of the client.
+	Authenticate(clientID string, clientSecret string) bool
+}
+
+// ClientRepository holds the client credentials from the configuration.
+type ClientRepository struct {
+	// secrets are the SHA-256 hashes of the secrets by client ID, so that secrets of different
+	// lengths are compared in constant time.
+	secrets map[string][32]byte
+}
+
+func NewClientRepository(credentials map[string]string) ClientService {
+	secrets := make(map[string][32]byte, len(credentials))
+	for id, secret := range credentials {
+		secrets[id] = sha256.Sum256([]byte(secret))
+	}
+	return &ClientRepository{secrets: secrets}
+}
+
+func (r *ClientRepository) Authenticate(clientID string, clientSecret string) bool {
+	expected, ok := r.secrets[clientID]
+	given := sha256.Sum256([]byte(clientSecret))
+	return subtle.ConstantTimeCompare(expected[:], given[:]) == 1 && ok
+}
@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+type DenylistService interface {
+	// Deny adds the access token to the denylist until it expires.
+	Deny(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error
+	// DenySession adds the session to the denylist, denying every access token issued for it,
+	// until the last of those tokens expires.
+	DenySession(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) error
+	// IsDenied reports whether the access token, or the session it was issued for, is on the
+	// denylist. Access tokens without a session are checked with uuid.Nil as the session ID.
+	IsDenied(ctx context.Context, jti uuid.UUID, sessionID uuid.UUID) (bool, error)
+	// Sync loads the denylist into memory, and keeps it in sync with the tokens denied by
+	// other instances until the context is done or the connection to the database fails.
+	Sync(ctx context.Context) error
+	// Prune removes the expired access tokens from the denylist.
+	Prune(ctx context.Context) error
+}
+
+// DenylistRepository stores the denylist of access tokens and sessions in the database, and
+// keeps a copy in memory so that access tokens are checked without a query. The copy is only used while Sync runs, as tokens
+// denied by other instances are otherwise missed, and the database is queried instead.
+type DenylistRepository struct {
+	models *data.Models
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	synced  bool
+	entries map[uuid.UUID]time.Time
+	// sessions are the denied sessions, keyed by session ID.
+	sessions map[uuid.UUID]time.Time
+}
+
+func NewDenylistRepository(models *data.Models) DenylistService {
+	return &DenylistRepository{
+		models:   models,
+		now:      time.Now,
+		entries:  make(map[uuid.UUID]time.Time),
+		sessions: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (r *DenylistRepository) Deny(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	if err := r.models.Denylist.Insert(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	// The token is denied on this instance right away, without waiting for the notification.
+	r.add(jti, expiresAt)
+
+	return nil
+}
+
+func (r *DenylistRepository) DenySession(
+	ctx context.Context,
+	sessionID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	if err := r.models.Denylist.InsertSession(ctx, sessionID, expiresAt); err != nil {
+		return err
+	}
+	r.addSession(sessionID, expiresAt)
+
+	return nil
+}
+
+func (r *DenylistRepository) IsDenied(
+	ctx context.Context,
+	jti uuid.UUID,
+	sessionID uuid.UUID,
+) (bool, error) {
+	r.mu.RLock()
+	_, denied := r.entries[jti]
+	if !denied && sessionID != uuid.Nil {
+		_, denied = r.sessions[sessionID]
+	}
+	synced := r.synced
+	r.mu.RUnlock()
+
+	if denied || synced {
+		return denied, nil
+	}
+	return r.models.Denylist.Exists(ctx, jti, sessionID)
+}
+
+func (r *DenylistRepository) Sync(ctx context.Context) error {
+	logger := logging.LoggerFromContext(ctx)
+	defer r.setSynced(false)
+
+	return r.models.Denylist.Listen(
+		ctx,
+		func(ctx context.Context) error {
+			rows, err := r.models.Denylist.SelectActive(ctx, r.now())
+			if err != nil {
+				return err
+			}
+
+			sessionRows, err := r.models.Denylist.SelectActiveSessions(ctx, r.now())
+			if err != nil {
+				return err
+			}
+
+			entries := make(map[uuid.UUID]time.Time, len(rows))
+			for _, row := range rows {
+				entries[row.JTI] = row.ExpiresAt
+			}
+			sessions := make(map[uuid.UUID]time.Time, len(sessionRows))
+			for _, row := range sessionRows {
+				sessions[row.SessionID] = row.ExpiresAt
+			}
+			r.mu.Lock()
+			// Tokens and sessions denied by this instance while the denylist was read are kept.
+			for jti, expiresAt := range r.entries {
+				entries[jti] = expiresAt
+			}
+			for sessionID, expiresAt := range r.sessions {
+				sessions[sessionID] = expiresAt
+			}
+			r.entries = entries
+			r.sessions = sessions
+			r.synced = true
+			r.mu.Unlock()
+			logger.LogAttrs(
+				ctx,
+				slog.LevelInfo,
+				"denylist loaded",
+				slog.Int("count", len(entries)),
+				slog.Int("sessions", len(sessions)),
+			)
+
+			return nil
+		},
+		func(denied data.DeniedAccessToken) {
+			r.add(denied.JTI, denied.ExpiresAt)
+		},
+		func(denied data.DeniedSession) {
+			r.addSession(denied.SessionID, denied.ExpiresAt)
+		},
+	)
+}
+
+func (r *DenylistRepository) Prune(ctx context.Context) error {
+	logger := logging.LoggerFromContext(ctx)
+	now := r.now()
+
+	r.mu.Lock()
+	pruned := 0
+	for jti, expiresAt := range r.entries {
+		if !expiresAt.After(now) {
+			delete(r.entries, jti)
+			pruned++
+		}
+	}
+	for sessionID, expiresAt := range r.sessions {
+		if !expiresAt.After(now) {
+			delete(r.sessions, sessionID)
+			pruned++
+		}
+	}
+	r.mu.Unlock()
+	logger.LogAttrs(ctx, slog.LevelInfo, "denylist pruned", slog.Int("pruned", pruned))
+
+	// Every instance deletes the expired rows, which is harmless as deleting them is idempotent.
+	if _, err := r.models.Denylist.DeleteExpired(ctx, now); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *DenylistRepository) add(jti uuid.UUID, expiresAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[jti] = expiresAt
+}
+
+func (r *DenylistRepository) addSession(sessionID uuid.UUID, expiresAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[sessionID] = expiresAt
+}
+
+func (r *DenylistRepository) setSynced(synced bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.synced = synced
+}
@@ -0,0 +1,107 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenylist(t *testing.T) {
+	ctx := context.Background()
+
+	// The other instance shares the database, and learns of the tokens denied by authRepo
+	// while syncing.
+	other := newTestRepository()
+	syncCtx, cancel := context.WithCancel(ctx)
+	synced := make(chan error, 1)
+	go func() { synced <- other.Denylist.Sync(syncCtx) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.ErrorIs(t, <-synced, context.Canceled)
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		jti := uuid.New()
+		denied, err := authRepo.Denylist.IsDenied(ctx, jti, uuid.Nil)
+		require.NoError(t, err)
+		assert.False(t, denied)
+
+		require.NoError(t, authRepo.Denylist.Deny(ctx, jti, time.Now().Add(time.Minute)))
+		// Denying twice is not an error.
+		require.NoError(t, authRepo.Denylist.Deny(ctx, jti, time.Now().Add(time.Minute)))
+
+		denied, err = authRepo.Denylist.IsDenied(ctx, jti, uuid.Nil)
+		require.NoError(t, err)
+		assert.True(t, denied)
+		assert.Eventually(t, func() bool {
+			denied, err := other.Denylist.IsDenied(ctx, jti, uuid.Nil)
+			return err == nil && denied
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("AuthenticateRevoked", func(t *testing.T) {
+		accessToken, err := authRepo.Tokens.CreateAccessToken(
+			api.Principal{Subject: testUser.ID.String()},
+		)
+		require.NoError(t, err)
+		_, err = other.Tokens.Authenticate(ctx, *accessToken)
+		require.NoError(t, err)
+
+		require.NoError(t, authRepo.Tokens.Revoke(ctx, *accessToken, repo.AccessTokenHint))
+
+		_, err = authRepo.Tokens.Authenticate(ctx, *accessToken)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+		assert.Eventually(t, func() bool {
+			_, err := other.Tokens.Authenticate(ctx, *accessToken)
+			return errors.Is(err, repo.ErrTokenRevoked)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("DenySession", func(t *testing.T) {
+		jti, sessionID := uuid.New(), uuid.New()
+		require.NoError(
+			t, authRepo.Denylist.DenySession(ctx, sessionID, time.Now().Add(time.Minute)),
+		)
+
+		denied, err := authRepo.Denylist.IsDenied(ctx, jti, sessionID)
+		require.NoError(t, err)
+		assert.True(t, denied)
+		denied, err = authRepo.Denylist.IsDenied(ctx, jti, uuid.Nil)
+		require.NoError(t, err)
+		assert.False(t, denied)
+		assert.Eventually(t, func() bool {
+			denied, err := other.Denylist.IsDenied(ctx, jti, sessionID)
+			return err == nil && denied
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// A new instance reads the denied sessions from the database.
+		denied, err = newTestRepository().Denylist.IsDenied(ctx, jti, sessionID)
+		require.NoError(t, err)
+		assert.True(t, denied)
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		expired, active := uuid.New(), uuid.New()
+		require.NoError(t, authRepo.Denylist.Deny(ctx, expired, time.Now().Add(-time.Second)))
+		require.NoError(t, authRepo.Denylist.Deny(ctx, active, time.Now().Add(time.Minute)))
+
+		require.NoError(t, authRepo.Denylist.Prune(ctx))
+
+		// A new instance reads the denylist from the database.
+		fresh := newTestRepository()
+		denied, err := fresh.Denylist.IsDenied(ctx, expired, uuid.Nil)
+		require.NoError(t, err)
+		assert.False(t, denied)
+		denied, err = fresh.Denylist.IsDenied(ctx, active, uuid.Nil)
+		require.NoError(t, err)
+		assert.True(t, denied)
+	})
+}
@@ -0,0 +1,414 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/mail"
+	"github.com/r3d5un/islandwind/internal/auth/password"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// EmailTokenPurpose is what a token sent by email is used for.
+type EmailTokenPurpose string
+
+const (
+	PasswordResetPurpose     EmailTokenPurpose = "password_reset"
+	EmailVerificationPurpose EmailTokenPurpose = "email_verification"
+)
+
+// emailTokenBytes is the number of random bytes of a token sent by email.
+const emailTokenBytes int = 32
+
+// passwordResetTimeout limits the time spent sending a password reset email in the background.
+const passwordResetTimeout time.Duration = time.Minute
+
+var (
+	// ErrInvalidEmailToken is returned when a token sent by email is unknown, used or expired,
+	// or the email address it verifies has changed since.
+	ErrInvalidEmailToken = errors.New("invalid or expired token")
+	// ErrNoEmail is returned when verifying the email address of a user without an address.
+	ErrNoEmail = errors.New("user has no email address")
+	// ErrEmailVerified is returned when verifying an email address that is already verified.
+	ErrEmailVerified = errors.New("email address already verified")
+)
+
+type EmailService interface {
+	// SetEmail changes the email address of the user, and sends a verification email to the
+	// address, unless it is unchanged and verified.
+	SetEmail(ctx context.Context, ID uuid.UUID, email string) (*User, error)
+	// SendVerification sends a new verification email to the address of the user, replacing
+	// the earlier verification emails. ErrNoEmail or ErrEmailVerified is returned if the user
+	// has no unverified address.
+	SendVerification(ctx context.Context, ID uuid.UUID) error
+	// VerifyEmail verifies the email address the token was sent to, returning the user.
+	VerifyEmail(ctx context.Context, token string) (*User, error)
+	// RequestPasswordReset sends a password reset email to the enabled user with the verified
+	// address. The email is sent in the background, and nothing is sent if there is no such
+	// user, so that callers cannot find out which addresses are registered from the response
+	// or the time it takes. Errors sending the email are logged rather than returned.
+	RequestPasswordReset(ctx context.Context, email string) error
+	// Wait blocks until the password reset emails being sent in the background are sent.
+	Wait()
+	// ResetPassword replaces the password of the user the token was sent to, and invalidates
+	// all refresh tokens issued to the user.
+	ResetPassword(ctx context.Context, token string, newPassword string) error
+}
+
+// EmailRepository sends the single-use tokens verifying email addresses and resetting
+// passwords. Only the hashes of the tokens are stored.
+type EmailRepository struct {
+	models *data.Models
+	mailer mail.Mailer
+	cfg    config.MailConfig
+	audit  AuditService
+	// pending tracks the password reset emails being sent in the background.
+	pending sync.WaitGroup
+}
+
+func NewEmailRepository(
+	models *data.Models,
+	mailer mail.Mailer,
+	cfg config.MailConfig,
+	audit AuditService,
+) EmailService {
+	return &EmailRepository{models: models, mailer: mailer, cfg: cfg, audit: audit}
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a random token, which is stored in place of
+// the token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *EmailRepository) SetEmail(
+	ctx context.Context,
+	ID uuid.UUID,
+	email string,
+) (*User, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "setting email")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	row, err := r.models.Users.SetEmailTx(ctx, tx, ID, sql.Null[string]{V: email, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	if !row.EmailVerifiedAt.Valid {
+		if err := r.sendVerification(ctx, tx, row); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "email set")
+
+	return newUserFromRow(row), nil
+}
+
+func (r *EmailRepository) SendVerification(ctx context.Context, ID uuid.UUID) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "sending verification email")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	row, err := r.models.Users.SelectOneTx(ctx, tx, ID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !row.Email.Valid:
+		return ErrNoEmail
+	case row.EmailVerifiedAt.Valid:
+		return ErrEmailVerified
+	}
+	if err := r.sendVerification(ctx, tx, row); err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "verification email sent")
+
+	return nil
+}
+
+func (r *EmailRepository) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "verifying email")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	emailToken, err := r.useToken(ctx, tx, EmailVerificationPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With(slog.Group("user", slog.String("id", emailToken.UserID.String())))
+
+	row, err := r.models.Users.VerifyEmailTx(ctx, tx, emailToken.UserID, emailToken.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			logger.LogAttrs(ctx, slog.LevelInfo, "email changed since the token was sent")
+			return nil, ErrInvalidEmailToken
+		}
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "email verified")
+
+	return newUserFromRow(row), nil
+}
+
+func (r *EmailRepository) RequestPasswordReset(ctx context.Context, email string) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "requesting password reset")
+	// The request outlives the response, which is returned before the user is looked up.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetTimeout)
+	r.pending.Go(func() {
+		defer cancel()
+		if err := r.requestPasswordReset(ctx, email); err != nil {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"unable to send password reset email",
+				slog.String("error", err.Error()),
+			)
+		}
+	})
+
+	return nil
+}
+
+func (r *EmailRepository) Wait() {
+	r.pending.Wait()
+}
+
+// requestPasswordReset looks up the user with the address, and sends the password reset email.
+func (r *EmailRepository) requestPasswordReset(ctx context.Context, email string) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	row, err := r.models.Users.SelectByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			logger.LogAttrs(ctx, slog.LevelInfo, "no user with the email")
+			return nil
+		}
+		return err
+	}
+	logger = logger.With(slog.Group("user", slog.String("id", row.ID.String())))
+	if row.Disabled || !row.EmailVerifiedAt.Valid {
+		logger.LogAttrs(ctx, slog.LevelInfo, "user disabled or email unverified")
+		return nil
+	}
+
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	validFor := r.cfg.PasswordResetDuration()
+	token, err := r.issueToken(ctx, tx, row, PasswordResetPurpose, validFor)
+	if err != nil {
+		return err
+	}
+	err = r.send(ctx, mail.PasswordResetTemplate, row, "/reset-password", token, validFor)
+	if err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "password reset email sent")
+
+	return nil
+}
+
+func (r *EmailRepository) ResetPassword(
+	ctx context.Context,
+	token string,
+	newPassword string,
+) (err error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	event := AuditEventInput{Type: AuditPasswordReset}
+	defer func() { r.audit.Record(ctx, event.withResult(err)) }()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "resetting password")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	emailToken, err := r.useToken(ctx, tx, PasswordResetPurpose, token)
+	if err != nil {
+		return err
+	}
+	event.ActorID = &emailToken.UserID
+	logger = logger.With(slog.Group("user", slog.String("id", emailToken.UserID.String())))
+
+	row, err := r.models.Users.SelectOneTx(ctx, tx, emailToken.UserID)
+	if err != nil {
+		return err
+	}
+	if row.Disabled {
+		logger.LogAttrs(ctx, slog.LevelInfo, "user disabled")
+		return ErrInvalidEmailToken
+	}
+
+	hash, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	_, err = r.models.Users.UpdateTx(ctx, tx, data.UserPatch{
+		ID:           row.ID,
+		PasswordHash: sql.Null[string]{V: hash, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := r.models.RefreshTokens.InvalidateByUserTx(ctx, tx, row.ID); err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "password reset")
+
+	return nil
+}
+
+// sendVerification issues a verification token for the address of the user, and sends it. The
+// email is sent before the transaction is committed, so that no token is stored if sending
+// fails.
+func (r *EmailRepository) sendVerification(
+	ctx context.Context,
+	tx pgx.Tx,
+	row *data.User,
+) error {
+	validFor := r.cfg.EmailVerificationDuration()
+	token, err := r.issueToken(ctx, tx, row, EmailVerificationPurpose, validFor)
+	if err != nil {
+		return err
+	}
+	return r.send(ctx, mail.EmailVerificationTemplate, row, "/verify-email", token, validFor)
+}
+
+// issueToken stores the hash of a new token sent to the address of the user, replacing the
+// earlier tokens of the user for the purpose.
+func (r *EmailRepository) issueToken(
+	ctx context.Context,
+	tx pgx.Tx,
+	row *data.User,
+	purpose EmailTokenPurpose,
+	validFor time.Duration,
+) (string, error) {
+	b := make([]byte, emailTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	_, err := r.models.EmailTokens.ReplaceTx(ctx, tx, data.EmailTokenInput{
+		UserID:    row.ID,
+		Purpose:   string(purpose),
+		TokenHash: hashToken(token),
+		Email:     row.Email.V,
+		ExpiresAt: time.Now().UTC().Add(validFor),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// send emails the user a link to the path of the web client, carrying the token.
+func (r *EmailRepository) send(
+	ctx context.Context,
+	tmpl mail.Template,
+	row *data.User,
+	path string,
+	token string,
+	validFor time.Duration,
+) error {
+	link := strings.TrimSuffix(r.cfg.BaseURL, "/") + path + "?" +
+		url.Values{"token": {token}}.Encode()
+	msg, err := mail.Render(tmpl, row.Email.V, mail.LinkData{
+		Username: row.Username,
+		Link:     link,
+		ValidFor: validFor,
+	})
+	if err != nil {
+		return err
+	}
+	return r.mailer.Send(ctx, msg)
+}
+
+// useToken marks the token as used, returning ErrInvalidEmailToken if it is unknown, used or
+// expired.
+func (r *EmailRepository) useToken(
+	ctx context.Context,
+	tx pgx.Tx,
+	purpose EmailTokenPurpose,
+	token string,
+) (*data.EmailToken, error) {
+	emailToken, err := r.models.EmailTokens.UseTx(ctx, tx, string(purpose), hashToken(token))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, ErrInvalidEmailToken
+		}
+		return nil, err
+	}
+	return emailToken, nil
+}
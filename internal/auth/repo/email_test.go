@@ -0,0 +1,134 @@
+package repo_test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var emailLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+// lastEmailToken returns the token of the link in the last email sent to the address.
+func lastEmailToken(t *testing.T, to string) string {
+	t.Helper()
+
+	messages := mailbox.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != to {
+			continue
+		}
+		link, err := url.Parse(emailLinkPattern.FindString(messages[i].Text))
+		require.NoError(t, err)
+		return link.Query().Get("token")
+	}
+	require.Failf(t, "no email sent", "no email was sent to %s", to)
+	return ""
+}
+
+func TestEmailRepository(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	const initialPassword string = "an initial password"
+	user, err := authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "grace",
+		Password: initialPassword,
+	})
+	require.NoError(t, err)
+
+	var verificationToken string
+
+	t.Run("SetEmail", func(t *testing.T) {
+		updated, err := authRepo.Emails.SetEmail(ctx, user.ID, "grace@example.com")
+		require.NoError(t, err)
+		require.NotNil(t, updated.Email)
+		assert.Equal(t, "grace@example.com", *updated.Email)
+		assert.Nil(t, updated.EmailVerifiedAt)
+
+		verificationToken = lastEmailToken(t, "grace@example.com")
+		assert.NotEmpty(t, verificationToken)
+	})
+
+	t.Run("RequestPasswordResetUnverified", func(t *testing.T) {
+		sent := len(mailbox.Messages())
+		require.NoError(t, authRepo.Emails.RequestPasswordReset(ctx, "grace@example.com"))
+		authRepo.Emails.Wait()
+		// Passwords are only reset by email to verified addresses.
+		assert.Len(t, mailbox.Messages(), sent)
+	})
+
+	t.Run("SendVerification", func(t *testing.T) {
+		require.NoError(t, authRepo.Emails.SendVerification(ctx, user.ID))
+
+		// The new email replaces the earlier one.
+		_, err := authRepo.Emails.VerifyEmail(ctx, verificationToken)
+		assert.ErrorIs(t, err, repo.ErrInvalidEmailToken)
+		verificationToken = lastEmailToken(t, "grace@example.com")
+	})
+
+	t.Run("VerifyEmail", func(t *testing.T) {
+		verified, err := authRepo.Emails.VerifyEmail(ctx, verificationToken)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, verified.ID)
+		assert.NotNil(t, verified.EmailVerifiedAt)
+
+		// Tokens are single-use.
+		_, err = authRepo.Emails.VerifyEmail(ctx, verificationToken)
+		assert.ErrorIs(t, err, repo.ErrInvalidEmailToken)
+
+		err = authRepo.Emails.SendVerification(ctx, user.ID)
+		assert.ErrorIs(t, err, repo.ErrEmailVerified)
+	})
+
+	t.Run("SetEmailUnchanged", func(t *testing.T) {
+		sent := len(mailbox.Messages())
+		updated, err := authRepo.Emails.SetEmail(ctx, user.ID, "Grace@example.com")
+		require.NoError(t, err)
+		// Changing the case of a verified address keeps it verified.
+		assert.NotNil(t, updated.EmailVerifiedAt)
+		assert.Len(t, mailbox.Messages(), sent)
+	})
+
+	t.Run("RequestPasswordResetUnknown", func(t *testing.T) {
+		sent := len(mailbox.Messages())
+		require.NoError(t, authRepo.Emails.RequestPasswordReset(ctx, "nobody@example.com"))
+		authRepo.Emails.Wait()
+		assert.Len(t, mailbox.Messages(), sent)
+	})
+
+	t.Run("ResetPassword", func(t *testing.T) {
+		require.NoError(t, authRepo.Emails.RequestPasswordReset(ctx, "GRACE@example.com"))
+		authRepo.Emails.Wait()
+		token := lastEmailToken(t, "Grace@example.com")
+
+		const newPassword string = "a password reset by email"
+		require.NoError(t, authRepo.Emails.ResetPassword(ctx, token, newPassword))
+		_, err := authRepo.Users.Authenticate(ctx, "grace", newPassword)
+		require.NoError(t, err)
+
+		err = authRepo.Emails.ResetPassword(ctx, token, initialPassword)
+		assert.ErrorIs(t, err, repo.ErrInvalidEmailToken)
+	})
+
+	t.Run("VerifyEmailChanged", func(t *testing.T) {
+		_, err := authRepo.Emails.SetEmail(ctx, user.ID, "grace@example.org")
+		require.NoError(t, err)
+		token := lastEmailToken(t, "grace@example.org")
+		_, err = authRepo.Emails.SetEmail(ctx, user.ID, "grace@example.net")
+		require.NoError(t, err)
+
+		// Changing the address invalidates the token sent to the earlier address.
+		_, err = authRepo.Emails.VerifyEmail(ctx, token)
+		assert.ErrorIs(t, err, repo.ErrInvalidEmailToken)
+	})
+}
@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// Token type hints and token types of RFC 7662 and RFC 7009.
+const (
+	AccessTokenHint  string = "access_token"
+	RefreshTokenHint string = "refresh_token"
+)
+
+// Introspection describes a token as defined by RFC 7662. Only Active is set for inactive
+// tokens, so that nothing is disclosed about them.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+}
+
+// parseAny parses the token as the type of the hint first, and then as the other type. The
+// returned hint is the type the token was parsed as.
+func (r *TokenRepository) parseAny(input string, hint string) (*jwt.Token, string, error) {
+	order := []string{AccessTokenHint, RefreshTokenHint}
+	if hint == RefreshTokenHint {
+		order = []string{RefreshTokenHint, AccessTokenHint}
+	}
+
+	var err error
+	for _, tokenHint := range order {
+		tokenType := AccessTokenType
+		if tokenHint == RefreshTokenHint {
+			tokenType = RefreshTokenType
+		}
+
+		var token *jwt.Token
+		token, err = r.parseToken(input, tokenType)
+		if err == nil {
+			return token, tokenHint, nil
+		}
+	}
+
+	return nil, "", err
+}
+
+func (r *TokenRepository) Introspect(
+	ctx context.Context,
+	input string,
+	hint string,
+) (*Introspection, error) {
+	logger := logging.LoggerFromContext(ctx)
+	inactive := &Introspection{Active: false}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "introspecting token", slog.String("hint", hint))
+	token, tokenHint, err := r.parseAny(input, hint)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelInfo, "token inactive", slog.String("reason", err.Error()))
+		return inactive, nil
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	jti, err := r.jtiFromToken(*token)
+	if err != nil {
+		return inactive, nil
+	}
+	logger = logger.With(slog.String("jti", jti.String()), slog.String("tokenType", tokenHint))
+
+	switch tokenHint {
+	case AccessTokenHint:
+		denied, err := r.denylist.IsDenied(ctx, *jti, sessionFromClaims(claims))
+		if err != nil {
+			return nil, err
+		}
+		if denied {
+			logger.LogAttrs(ctx, slog.LevelInfo, "token inactive", slog.String("reason", "revoked"))
+			return inactive, nil
+		}
+	case RefreshTokenHint:
+		// Refresh tokens are only valid while stored, which also rules out other tokens signed
+		// with the refresh secret, e.g. TOTP challenges.
+		active, err := r.refreshTokenActive(ctx, *jti)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			logger.LogAttrs(ctx, slog.LevelInfo, "token inactive", slog.String("reason", "revoked"))
+			return inactive, nil
+		}
+	}
+
+	exp, _ := claims.GetExpirationTime()
+	iat, _ := claims.GetIssuedAt()
+	sub, _ := claims.GetSubject()
+	scopeClaim, _ := claims["scope"].(string)
+	logger.LogAttrs(ctx, slog.LevelInfo, "token active", slog.String("sub", sub))
+
+	return &Introspection{
+		Active:    true,
+		Scope:     scopeClaim,
+		TokenType: tokenHint,
+		Exp:       exp.Unix(),
+		Iat:       iat.Unix(),
+		Sub:       sub,
+		Iss:       r.Issuer,
+		JTI:       jti.String(),
+	}, nil
+}
+
+// refreshTokenActive reports whether the refresh token is stored, not invalidated, and issued to
+// a user who is not disabled.
+func (r *TokenRepository) refreshTokenActive(ctx context.Context, jti uuid.UUID) (bool, error) {
+	row, err := r.models.RefreshTokens.SelectOne(ctx, jti)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if row.Invalidated || !row.UserID.Valid {
+		return false, nil
+	}
+
+	user, err := r.models.Users.SelectOne(ctx, row.UserID.UUID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !user.Disabled, nil
+}
+
+func (r *TokenRepository) Revoke(ctx context.Context, input string, hint string) (err error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "revoking token", slog.String("hint", hint))
+	token, tokenHint, err := r.parseAny(input, hint)
+	if err != nil {
+		// Invalid and expired tokens cannot be used, so there is nothing to revoke.
+		logger.LogAttrs(ctx, slog.LevelInfo, "token invalid", slog.String("reason", err.Error()))
+		return nil
+	}
+	jti, err := r.jtiFromToken(*token)
+	if err != nil {
+		return nil
+	}
+	logger = logger.With(slog.String("jti", jti.String()), slog.String("tokenType", tokenHint))
+
+	event := AuditEventInput{
+		Type:     AuditRevoke,
+		TokenIDs: []uuid.UUID{*jti},
+		Details:  map[string]any{"tokenType": tokenHint},
+	}
+	if sub, err := token.Claims.GetSubject(); err == nil {
+		if userID, err := uuid.Parse(sub); err == nil {
+			event.ActorID = &userID
+		}
+	}
+	defer func() { r.audit.Record(ctx, event.withResult(err)) }()
+
+	switch tokenHint {
+	case AccessTokenHint:
+		exp, err := token.Claims.GetExpirationTime()
+		if err != nil {
+			return ErrVerifyingToken
+		}
+		if err := r.denylist.Deny(ctx, *jti, exp.Time); err != nil {
+			return err
+		}
+	case RefreshTokenHint:
+		_, err := r.models.RefreshTokens.Update(ctx, data.RefreshTokenPatch{
+			ID:          *jti,
+			Invalidated: db.NewNullBool(new(true)),
+		})
+		if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			return err
+		}
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "token revoked")
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospection(t *testing.T) {
+	ctx := context.Background()
+
+	accessToken, refreshToken, err := authRepo.Tokens.CreateSession(
+		ctx,
+		api.Principal{Subject: testUser.ID.String(), Scopes: []string{scope.BlogWrite}},
+		repo.Device{},
+	)
+	require.NoError(t, err)
+
+	t.Run("IntrospectAccessToken", func(t *testing.T) {
+		introspection, err := authRepo.Tokens.Introspect(ctx, *accessToken, "")
+		require.NoError(t, err)
+		assert.True(t, introspection.Active)
+		assert.Equal(t, repo.AccessTokenHint, introspection.TokenType)
+		assert.Equal(t, testUser.ID.String(), introspection.Sub)
+		assert.Equal(t, scope.BlogWrite, introspection.Scope)
+		assert.NotEmpty(t, introspection.JTI)
+		assert.Greater(t, introspection.Exp, introspection.Iat)
+	})
+
+	t.Run("IntrospectRefreshToken", func(t *testing.T) {
+		// The hint only changes the order in which the types are tried.
+		for _, hint := range []string{"", repo.AccessTokenHint, repo.RefreshTokenHint} {
+			introspection, err := authRepo.Tokens.Introspect(ctx, *refreshToken, hint)
+			require.NoError(t, err)
+			assert.True(t, introspection.Active)
+			assert.Equal(t, repo.RefreshTokenHint, introspection.TokenType)
+			assert.Equal(t, testUser.ID.String(), introspection.Sub)
+		}
+	})
+
+	t.Run("IntrospectInvalidToken", func(t *testing.T) {
+		challenge, err := authRepo.Tokens.CreateTOTPChallenge(
+			ctx,
+			repo.TOTPChallenge{UserID: testUser.ID},
+		)
+		require.NoError(t, err)
+
+		for _, token := range []string{"invalid", *challenge} {
+			introspection, err := authRepo.Tokens.Introspect(ctx, token, "")
+			require.NoError(t, err)
+			assert.Equal(t, &repo.Introspection{Active: false}, introspection)
+		}
+	})
+
+	t.Run("RevokeAccessToken", func(t *testing.T) {
+		require.NoError(t, authRepo.Tokens.Revoke(ctx, *accessToken, repo.AccessTokenHint))
+		// Revoking twice is not an error.
+		require.NoError(t, authRepo.Tokens.Revoke(ctx, *accessToken, repo.AccessTokenHint))
+
+		introspection, err := authRepo.Tokens.Introspect(ctx, *accessToken, "")
+		require.NoError(t, err)
+		assert.False(t, introspection.Active)
+	})
+
+	t.Run("RevokeRefreshToken", func(t *testing.T) {
+		// The token is found even with the wrong hint.
+		require.NoError(t, authRepo.Tokens.Revoke(ctx, *refreshToken, repo.AccessTokenHint))
+
+		introspection, err := authRepo.Tokens.Introspect(ctx, *refreshToken, "")
+		require.NoError(t, err)
+		assert.False(t, introspection.Active)
+
+		_, _, err = authRepo.Tokens.Refresh(ctx, *refreshToken, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+	})
+
+	t.Run("RevokeInvalidToken", func(t *testing.T) {
+		assert.NoError(t, authRepo.Tokens.Revoke(ctx, "invalid", ""))
+	})
+}
@@ -0,0 +1,294 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// maxLoginAttemptKeyLength is the length of the key column in characters. Longer keys, e.g.
+// usernames that cannot exist, are truncated.
+const maxLoginAttemptKeyLength int = 256
+
+// maxLoginAttempts limits the number of login attempts listed.
+const maxLoginAttempts int = 100
+
+// LoginAttemptKind is what failed login attempts are counted by.
+type LoginAttemptKind string
+
+const (
+	// LoginAttemptUsername counts the failed password attempts of a username.
+	LoginAttemptUsername LoginAttemptKind = "username"
+	// LoginAttemptIP counts the failed attempts of a client IP address.
+	LoginAttemptIP LoginAttemptKind = "ip"
+	// LoginAttemptTOTP counts the failed second factor attempts of a user.
+	LoginAttemptTOTP LoginAttemptKind = "totp"
+)
+
+func (k LoginAttemptKind) Valid() bool {
+	switch k {
+	case LoginAttemptUsername, LoginAttemptIP, LoginAttemptTOTP:
+		return true
+	default:
+		return false
+	}
+}
+
+// LoginAttemptKey identifies what failed login attempts are counted for.
+type LoginAttemptKey struct {
+	Kind LoginAttemptKind `json:"kind"`
+	Key  string           `json:"key"`
+}
+
+// NewLoginAttemptKey returns the key of the kind. Usernames differing only in case share the
+// key. As keys are taken from requests, invalid UTF-8 is replaced.
+func NewLoginAttemptKey(kind LoginAttemptKind, key string) LoginAttemptKey {
+	key = strings.ToValidUTF8(key, "\uFFFD")
+	if kind == LoginAttemptUsername {
+		key = strings.ToLower(key)
+	}
+	if runes := []rune(key); len(runes) > maxLoginAttemptKeyLength {
+		key = string(runes[:maxLoginAttemptKeyLength])
+	}
+	return LoginAttemptKey{Kind: kind, Key: key}
+}
+
+// UsernameKey returns the key counting the failed attempts of the username.
+func UsernameKey(username string) LoginAttemptKey {
+	return NewLoginAttemptKey(LoginAttemptUsername, username)
+}
+
+// ClientIPKey returns the key counting the failed attempts of the client IP address.
+func ClientIPKey(ip string) LoginAttemptKey {
+	return NewLoginAttemptKey(LoginAttemptIP, ip)
+}
+
+// TOTPKey returns the key counting the failed second factor attempts of the user.
+func TOTPKey(userID uuid.UUID) LoginAttemptKey {
+	return NewLoginAttemptKey(LoginAttemptTOTP, userID.String())
+}
+
+func (k LoginAttemptKey) row() data.LoginAttemptKey {
+	return data.LoginAttemptKey{Kind: string(k.Kind), Key: k.Key}
+}
+
+// LoginAttempt are the failed login attempts of a key.
+type LoginAttempt struct {
+	LoginAttemptKey
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt"`
+	// BlockedUntil is the time until which attempts are rejected, if they are.
+	BlockedUntil *time.Time `json:"blockedUntil"`
+	// Locked is true while the key is locked out after reaching the lockout threshold.
+	Locked bool `json:"locked"`
+}
+
+func newLoginAttemptFromRow(row *data.LoginAttempt, now time.Time) *LoginAttempt {
+	a := LoginAttempt{
+		LoginAttemptKey: LoginAttemptKey{Kind: LoginAttemptKind(row.Kind), Key: row.Key},
+		Failures:        row.Failures,
+		LastFailureAt:   row.LastFailureAt,
+	}
+	if row.BlockedUntil.Valid && row.BlockedUntil.V.After(now) {
+		a.BlockedUntil = &row.BlockedUntil.V
+		a.Locked = row.Locked
+	}
+	return &a
+}
+
+type LoginAttemptFilter struct {
+	Kind *LoginAttemptKind `json:"kind"`
+	Key  *string           `json:"key"`
+	// Blocked limits the attempts to those currently blocked.
+	Blocked bool `json:"blocked"`
+}
+
+type LockoutService interface {
+	// Check returns how long until attempts of the keys are allowed, or zero if they are
+	// allowed now.
+	Check(ctx context.Context, keys ...LoginAttemptKey) (time.Duration, error)
+	// RecordFailure counts a failed attempt for each of the keys, and returns how long until
+	// the next attempt of the keys is allowed.
+	RecordFailure(ctx context.Context, keys ...LoginAttemptKey) (time.Duration, error)
+	// RecordSuccess resets the failed attempts of the key.
+	RecordSuccess(ctx context.Context, key LoginAttemptKey) error
+	// List lists the keys with failed attempts, with the most recent failures first.
+	List(ctx context.Context, filter LoginAttemptFilter) ([]*LoginAttempt, error)
+	// Clear resets the failed attempts of the key, lifting any lockout. ErrRecordNotFound is
+	// returned if the key has no failed attempts.
+	Clear(ctx context.Context, key LoginAttemptKey) error
+}
+
+// LockoutRepository stores failed login attempts in the database, so that attempts are
+// throttled across all instances of the application.
+type LockoutRepository struct {
+	models *data.Models
+	cfg    config.LockoutConfig
+	now    func() time.Time
+}
+
+func NewLockoutRepository(models *data.Models, cfg config.LockoutConfig) LockoutService {
+	return &LockoutRepository{models: models, cfg: cfg, now: time.Now}
+}
+
+// delay returns how long attempts are blocked after the failures, and whether the key is locked
+// out. Client IP addresses are only locked out, and not delayed, as many users may share an
+// address.
+func (r *LockoutRepository) delay(kind LoginAttemptKind, failures int) (time.Duration, bool) {
+	threshold := r.cfg.Threshold
+	if kind == LoginAttemptIP {
+		threshold = r.cfg.IPThreshold
+	}
+	if threshold > 0 && failures >= threshold {
+		return r.cfg.Duration(), true
+	}
+
+	if kind == LoginAttemptIP || failures <= r.cfg.FreeAttempts || r.cfg.BaseDelay() <= 0 {
+		return 0, false
+	}
+	// The delay doubles with each failure, and the shift is limited to avoid overflowing.
+	delay := r.cfg.BaseDelay() << min(failures-r.cfg.FreeAttempts-1, 30)
+	if r.cfg.MaxDelay() > 0 {
+		delay = min(delay, r.cfg.MaxDelay())
+	}
+	return delay, false
+}
+
+func (r *LockoutRepository) Check(
+	ctx context.Context,
+	keys ...LoginAttemptKey,
+) (time.Duration, error) {
+	now := r.now()
+
+	rowKeys := make([]data.LoginAttemptKey, len(keys))
+	for i, key := range keys {
+		rowKeys[i] = key.row()
+	}
+	rows, err := r.models.LoginAttempts.SelectBlocked(ctx, rowKeys, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, row := range rows {
+		wait = max(wait, row.BlockedUntil.V.Sub(now))
+	}
+	return wait, nil
+}
+
+func (r *LockoutRepository) RecordFailure(
+	ctx context.Context,
+	keys ...LoginAttemptKey,
+) (time.Duration, error) {
+	logger := logging.LoggerFromContext(ctx)
+	now := r.now()
+
+	// Failures older than the reset window are forgotten. Without a window, failures are kept
+	// until the next successful attempt.
+	var resetBefore time.Time
+	if r.cfg.Reset() > 0 {
+		resetBefore = now.Add(-r.cfg.Reset())
+	}
+
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer rollback()
+
+	var wait time.Duration
+	for _, key := range keys {
+		row, err := r.models.LoginAttempts.RecordFailureTx(ctx, tx, key.row(), now, resetBefore)
+		if err != nil {
+			return 0, err
+		}
+
+		delay, locked := r.delay(key.Kind, row.Failures)
+		if delay > 0 {
+			row, err = r.models.LoginAttempts.BlockTx(ctx, tx, key.row(), now.Add(delay), locked)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if locked {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelWarn,
+				"login attempts locked out",
+				slog.Any("key", key),
+				slog.Int("failures", row.Failures),
+			)
+		}
+		if row.BlockedUntil.Valid {
+			wait = max(wait, row.BlockedUntil.V.Sub(now))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return wait, nil
+}
+
+func (r *LockoutRepository) RecordSuccess(ctx context.Context, key LoginAttemptKey) error {
+	_, err := r.models.LoginAttempts.Delete(ctx, key.row())
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (r *LockoutRepository) List(
+	ctx context.Context,
+	filter LoginAttemptFilter,
+) ([]*LoginAttempt, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Any("filter", filter))
+	now := r.now()
+
+	rowFilter := data.LoginAttemptFilter{
+		Key:      db.PtrToNull(filter.Key),
+		PageSize: maxLoginAttempts,
+	}
+	if filter.Kind != nil {
+		rowFilter.Kind = sql.Null[string]{V: string(*filter.Kind), Valid: true}
+	}
+	if filter.Blocked {
+		rowFilter.BlockedAfter = sql.Null[time.Time]{V: now, Valid: true}
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading login attempts")
+	rows, err := r.models.LoginAttempts.SelectMany(ctx, rowFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]*LoginAttempt, len(rows))
+	for i, row := range rows {
+		attempts[i] = newLoginAttemptFromRow(row, now)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "login attempts retrieved")
+
+	return attempts, nil
+}
+
+func (r *LockoutRepository) Clear(ctx context.Context, key LoginAttemptKey) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Any("key", key))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "clearing login attempts")
+	if _, err := r.models.LoginAttempts.Delete(ctx, key.row()); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "login attempts cleared")
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+
+	usernameKey := repo.UsernameKey("Lockout")
+	ipKey := repo.ClientIPKey("203.0.113.9")
+
+	t.Run("Check", func(t *testing.T) {
+		wait, err := authRepo.Lockouts.Check(ctx, usernameKey, ipKey)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("RecordFailure", func(t *testing.T) {
+		// The test configuration allows two free failures, doubles the delay from one second
+		// up to four seconds, and locks out after five failures.
+		expected := []time.Duration{0, 0, time.Second, 2 * time.Second, time.Minute}
+		for i, delay := range expected {
+			wait, err := authRepo.Lockouts.RecordFailure(ctx, usernameKey, ipKey)
+			require.NoError(t, err)
+			assert.InDelta(t, delay, wait, float64(100*time.Millisecond), "failure %d", i+1)
+		}
+
+		wait, err := authRepo.Lockouts.Check(ctx, usernameKey, ipKey)
+		require.NoError(t, err)
+		assert.Greater(t, wait, 59*time.Second)
+
+		// Usernames differing in case share the lockout, and client IP addresses are not
+		// delayed below their threshold.
+		wait, err = authRepo.Lockouts.Check(ctx, repo.UsernameKey("lockout"))
+		require.NoError(t, err)
+		assert.Greater(t, wait, 59*time.Second)
+		wait, err = authRepo.Lockouts.Check(ctx, ipKey)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		attempts, err := authRepo.Lockouts.List(ctx, repo.LoginAttemptFilter{
+			Kind:    new(repo.LoginAttemptUsername),
+			Key:     new(usernameKey.Key),
+			Blocked: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, attempts, 1)
+		assert.Equal(t, 5, attempts[0].Failures)
+		assert.True(t, attempts[0].Locked)
+		assert.NotNil(t, attempts[0].BlockedUntil)
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		require.NoError(t, authRepo.Lockouts.Clear(ctx, usernameKey))
+
+		wait, err := authRepo.Lockouts.Check(ctx, usernameKey, ipKey)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+
+		err = authRepo.Lockouts.Clear(ctx, usernameKey)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("RecordSuccess", func(t *testing.T) {
+		for range 3 {
+			_, err := authRepo.Lockouts.RecordFailure(ctx, usernameKey)
+			require.NoError(t, err)
+		}
+		require.NoError(t, authRepo.Lockouts.RecordSuccess(ctx, usernameKey))
+		// Succeeding without failures is not an error.
+		require.NoError(t, authRepo.Lockouts.RecordSuccess(ctx, usernameKey))
+
+		wait, err := authRepo.Lockouts.RecordFailure(ctx, usernameKey)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+}
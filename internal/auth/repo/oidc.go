@@ -0,0 +1,275 @@
+package repo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/oidc"
+	"github.com/r3d5un/islandwind/internal/auth/password"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+const (
+	// oidcLoginDuration is the time users have to log in with the provider.
+	oidcLoginDuration time.Duration = 10 * time.Minute
+	// maxOIDCUsernameLength matches the maximum length of usernames created by administrators.
+	maxOIDCUsernameLength int = 128
+)
+
+var (
+	// ErrInvalidOIDCLogin is returned when the callback of a login does not match the login,
+	// the login has expired, or the provider does not confirm the login. It wraps
+	// ErrUnauthorized.
+	ErrInvalidOIDCLogin = fmt.Errorf("%w: invalid oidc login", ErrUnauthorized)
+	// ErrNoRoleMapped is returned when none of the claim values of a user map to a role, and
+	// no default role is configured.
+	ErrNoRoleMapped = errors.New("no role mapped from the claims of the user")
+	// ErrUsernameTaken is returned when a user logging in with the provider for the first time
+	// has the username of an existing user, which is not linked to the provider.
+	ErrUsernameTaken = errors.New("username already taken")
+)
+
+// OIDCLogin is a login started with the provider.
+type OIDCLogin struct {
+	// AuthURL is the URL of the provider to send the user to.
+	AuthURL string
+	// LoginToken is kept by the browser of the user until the provider redirects back, binding
+	// the callback to the browser that started the login. It is a random token identifying the
+	// stored login.
+	LoginToken string
+}
+
+type OIDCService interface {
+	// Begin starts a login with the provider.
+	Begin(ctx context.Context) (*OIDCLogin, error)
+	// Complete verifies the callback of the provider against the login token, and returns the
+	// user the provider authenticated. Users are created on their first login, and their role
+	// is updated from the claims of the ID token on every login.
+	Complete(ctx context.Context, loginToken string, state string, code string) (*User, error)
+}
+
+// OIDCRepository logs users in with an OpenID Connect provider. The state, nonce and PKCE code
+// verifier of a login are stored until the login is completed, so that any instance can
+// complete the login without the verifier leaving the server.
+type OIDCRepository struct {
+	models *data.Models
+	client *oidc.Client
+	cfg    config.Config
+	audit  AuditService
+}
+
+func NewOIDCRepository(
+	models *data.Models,
+	client *oidc.Client,
+	cfg config.Config,
+	audit AuditService,
+) OIDCService {
+	return &OIDCRepository{models: models, client: client, cfg: cfg, audit: audit}
+}
+
+func (r *OIDCRepository) Begin(ctx context.Context) (*OIDCLogin, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "starting oidc login")
+	state, nonce, verifier := rand.Text(), rand.Text(), oidc.NewVerifier()
+
+	loginToken := rand.Text()
+	_, err := r.models.OIDCLogins.Insert(ctx, data.OIDCLoginInput{
+		TokenHash: hashToken(loginToken),
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: time.Now().UTC().Add(oidcLoginDuration),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLogin{
+		AuthURL:    r.client.AuthCodeURL(state, nonce, verifier),
+		LoginToken: loginToken,
+	}, nil
+}
+
+func (r *OIDCRepository) Complete(
+	ctx context.Context,
+	loginToken string,
+	state string,
+	code string,
+) (user *User, err error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	// Successful logins are recorded when the session is created.
+	event := AuditEventInput{Type: AuditLogin, Details: map[string]any{"method": "oidc"}}
+	defer func() {
+		if err != nil {
+			r.audit.Record(ctx, event.withResult(err))
+		}
+	}()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "completing oidc login")
+	// The login is consumed before it is verified, so that each login is completed once.
+	login, err := r.models.OIDCLogins.Consume(ctx, hashToken(loginToken))
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		return nil, fmt.Errorf("%w: unknown or expired login", ErrInvalidOIDCLogin)
+	case err != nil:
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(login.State), []byte(state)) != 1 {
+		return nil, fmt.Errorf("%w: state mismatch", ErrInvalidOIDCLogin)
+	}
+
+	rawIDToken, err := r.client.Exchange(ctx, code, login.Verifier)
+	if err != nil {
+		if errors.Is(err, oidc.ErrTokenRequest) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOIDCLogin, err)
+		}
+		return nil, err
+	}
+	idToken, err := r.client.Verify(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOIDCLogin, err)
+	}
+	event.Details["subject"] = idToken.Subject
+	logger = logger.With(slog.Group("identity", slog.String("subject", idToken.Subject)))
+
+	role, err := r.role(idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	issuer := idToken.String("iss")
+	var row *data.User
+	identity, err := r.models.Identities.TouchTx(ctx, tx, issuer, idToken.Subject)
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		logger.LogAttrs(ctx, slog.LevelInfo, "creating user of new identity")
+		row, err = r.createUser(ctx, tx, idToken, role)
+		if err != nil {
+			return nil, err
+		}
+		_, err = r.models.Identities.InsertTx(ctx, tx, data.UserIdentityInput{
+			UserID:  row.ID,
+			Issuer:  issuer,
+			Subject: idToken.Subject,
+		})
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		row, err = r.models.Users.SelectOneTx(ctx, tx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	event.ActorID = &row.ID
+	logger = logger.With(slog.Group("user", slog.String("id", row.ID.String())))
+	if row.Disabled {
+		logger.LogAttrs(ctx, slog.LevelInfo, "user disabled")
+		return nil, ErrUnauthorized
+	}
+
+	row, err = r.models.Users.UpdateTx(ctx, tx, data.UserPatch{
+		ID:          row.ID,
+		LastLoginAt: sql.Null[time.Time]{V: time.Now().UTC(), Valid: true},
+		Role:        sql.Null[scope.Role]{V: role, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "oidc login completed")
+
+	return newUserFromRow(row), nil
+}
+
+// role returns the most privileged role mapped from the role claim of the ID token, or the
+// default role if none are mapped.
+func (r *OIDCRepository) role(idToken *oidc.IDToken) (scope.Role, error) {
+	roles, err := r.cfg.OIDC.Roles()
+	if err != nil {
+		return "", err
+	}
+
+	var mapped []scope.Role
+	for _, value := range idToken.Strings(r.cfg.OIDC.RoleClaim) {
+		if role, ok := roles[value]; ok {
+			mapped = append(mapped, role)
+		}
+	}
+	for _, role := range scope.Roles {
+		if slices.Contains(mapped, role) {
+			return role, nil
+		}
+	}
+	if r.cfg.OIDC.DefaultRole != "" {
+		return scope.Role(r.cfg.OIDC.DefaultRole), nil
+	}
+
+	return "", ErrNoRoleMapped
+}
+
+// createUser creates the user of an identity logging in for the first time. The user is given
+// a random password, which can be replaced by resetting the password.
+func (r *OIDCRepository) createUser(
+	ctx context.Context,
+	tx pgx.Tx,
+	idToken *oidc.IDToken,
+	role scope.Role,
+) (*data.User, error) {
+	username := idToken.String(r.cfg.OIDC.UsernameClaim)
+	if username == "" || utf8.RuneCountInString(username) > maxOIDCUsernameLength ||
+		strings.ContainsFunc(username, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsControl(r) || r == ':'
+		}) {
+		return nil, fmt.Errorf(
+			"%w: invalid %s claim", ErrInvalidOIDCLogin, r.cfg.OIDC.UsernameClaim,
+		)
+	}
+
+	hash, err := password.Hash(rand.Text())
+	if err != nil {
+		return nil, err
+	}
+	row, err := r.models.Users.InsertTx(ctx, tx, data.UserInput{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUniqueConstraintViolation) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	return row, nil
+}
@@ -0,0 +1,150 @@
+package repo_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authorize follows the authorization URL to the OIDC provider, returning the query of the
+// redirect back to the callback.
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	client := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := resp.Location()
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestOIDCRepository(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	// login logs in as the user with the provider.
+	login := func(t *testing.T, claims map[string]any) (*repo.User, error) {
+		t.Helper()
+
+		oidcProvider.SetUser(claims)
+		started, err := authRepo.OIDC.Begin(ctx)
+		require.NoError(t, err)
+		callback := authorize(t, started.AuthURL)
+		return authRepo.OIDC.Complete(
+			ctx, started.LoginToken, callback.Get("state"), callback.Get("code"),
+		)
+	}
+
+	var user *repo.User
+
+	t.Run("FirstLogin", func(t *testing.T) {
+		var err error
+		user, err = login(t, map[string]any{
+			"sub":                "00u1",
+			"preferred_username": "ivan",
+			"groups":             []string{"staff", "blog-authors", "blog-editors"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ivan", user.Username)
+		// The most privileged of the mapped roles is given.
+		assert.Equal(t, scope.RoleEditor, user.Role)
+		assert.NotNil(t, user.LastLoginAt)
+	})
+
+	t.Run("RoleUpdated", func(t *testing.T) {
+		// The username of linked users is not updated from the claims.
+		updated, err := login(t, map[string]any{
+			"sub":                "00u1",
+			"preferred_username": "ivan.renamed",
+			"groups":             []string{"blog-authors"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, updated.ID)
+		assert.Equal(t, "ivan", updated.Username)
+		assert.Equal(t, scope.RoleAuthor, updated.Role)
+	})
+
+	t.Run("NoRoleMapped", func(t *testing.T) {
+		_, err := login(t, map[string]any{
+			"sub":                "00u2",
+			"preferred_username": "mallory",
+			"groups":             []string{"staff"},
+		})
+		assert.ErrorIs(t, err, repo.ErrNoRoleMapped)
+	})
+
+	t.Run("UsernameTaken", func(t *testing.T) {
+		_, err := login(t, map[string]any{
+			"sub":                "00u3",
+			"preferred_username": testUser.Username,
+			"groups":             []string{"blog-editors"},
+		})
+		assert.ErrorIs(t, err, repo.ErrUsernameTaken)
+	})
+
+	t.Run("InvalidUsername", func(t *testing.T) {
+		_, err := login(t, map[string]any{
+			"sub":    "00u4",
+			"groups": []string{"blog-editors"},
+		})
+		assert.ErrorIs(t, err, repo.ErrInvalidOIDCLogin)
+	})
+
+	t.Run("StateMismatch", func(t *testing.T) {
+		oidcProvider.SetUser(map[string]any{"sub": "00u1", "groups": []string{"blog-authors"}})
+		started, err := authRepo.OIDC.Begin(ctx)
+		require.NoError(t, err)
+		callback := authorize(t, started.AuthURL)
+
+		_, err = authRepo.OIDC.Complete(
+			ctx, started.LoginToken, "another state", callback.Get("code"),
+		)
+		assert.ErrorIs(t, err, repo.ErrInvalidOIDCLogin)
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+	})
+
+	t.Run("InvalidLoginToken", func(t *testing.T) {
+		oidcProvider.SetUser(map[string]any{"sub": "00u1", "groups": []string{"blog-authors"}})
+		started, err := authRepo.OIDC.Begin(ctx)
+		require.NoError(t, err)
+		callback := authorize(t, started.AuthURL)
+
+		_, err = authRepo.OIDC.Complete(
+			ctx, started.LoginToken+"x", callback.Get("state"), callback.Get("code"),
+		)
+		assert.ErrorIs(t, err, repo.ErrInvalidOIDCLogin)
+	})
+
+	t.Run("CodeReused", func(t *testing.T) {
+		oidcProvider.SetUser(map[string]any{"sub": "00u1", "groups": []string{"blog-authors"}})
+		started, err := authRepo.OIDC.Begin(ctx)
+		require.NoError(t, err)
+		callback := authorize(t, started.AuthURL)
+
+		_, err = authRepo.OIDC.Complete(
+			ctx, started.LoginToken, callback.Get("state"), callback.Get("code"),
+		)
+		require.NoError(t, err)
+		_, err = authRepo.OIDC.Complete(
+			ctx, started.LoginToken, callback.Get("state"), callback.Get("code"),
+		)
+		assert.ErrorIs(t, err, repo.ErrInvalidOIDCLogin)
+	})
+}
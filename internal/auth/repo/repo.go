@@ -6,30 +6,69 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/r3d5un/islandwind/internal/auth/config"
 	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/r3d5un/islandwind/internal/auth/mail"
+	"github.com/r3d5un/islandwind/internal/auth/oidc"
 )
 
 type Repository struct {
 	db     *pgxpool.Pool
 	models data.Models
 	cfg    config.Config
+	// Keys sign and verify access tokens.
+	Keys   *keys.Set
 	Tokens TokenService
+	Users  UserService
+	// APITokens are the personal access tokens of users.
+	APITokens APITokenService
+	// Lockouts throttle failed login attempts.
+	Lockouts LockoutService
+	// Denylist holds the revoked access tokens, and must be kept in sync by running Sync.
+	Denylist DenylistService
+	// Audit records security relevant events.
+	Audit AuditService
+	// Emails verify the email addresses of users, and reset passwords by email.
+	Emails EmailService
+	// OIDC logs users in with an OpenID Connect provider. It is nil if no provider is
+	// configured.
+	OIDC OIDCService
 }
 
 func NewRepository(
 	db *pgxpool.Pool,
 	timeout *time.Duration,
 	cfg config.Config,
+	keySet *keys.Set,
+	mailer mail.Mailer,
+	oidcClient *oidc.Client,
 ) Repository {
 	models := data.NewModels(db, timeout)
-	return Repository{
+	denylist := NewDenylistRepository(&models)
+	audit := NewAuditRepository(&models)
+	repository := Repository{
 		db:     db,
 		models: models,
 		cfg:    cfg,
+		Keys:   keySet,
 		Tokens: NewTokenRepository(
-			[]byte(cfg.SigningSecret),
+			keySet,
 			[]byte(cfg.RefreshSigningSecret),
+			[]byte(cfg.ChallengeSigningSecret),
 			cfg.TokenIssuer,
 			&models,
+			denylist,
+			audit,
 		),
+		Users:     NewUserRepository(&models, cfg.TokenIssuer),
+		APITokens: NewAPITokenRepository(&models),
+		Lockouts:  NewLockoutRepository(&models, cfg.Lockout),
+		Denylist:  denylist,
+		Audit:     audit,
+		Emails:    NewEmailRepository(&models, mailer, cfg.Mail, audit),
 	}
+	if oidcClient != nil {
+		repository.OIDC = NewOIDCRepository(&models, oidcClient, cfg, audit)
+	}
+
+	return repository
 }
@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+const (
+	// maxSessions limits the number of sessions listed for a user.
+	maxSessions int = 100
+	// maxUserAgentLength is the length of the user agent columns in bytes.
+	maxUserAgentLength int = 512
+)
+
+// Device describes the client using a session. It is captured at login, and updated when the
+// session is refreshed.
+type Device struct {
+	UserAgent string `json:"userAgent"`
+	ClientIP  string `json:"clientIp"`
+	// Label is a name for the session chosen by the user, e.g. "work laptop". The label is kept
+	// when the session is refreshed without a label.
+	Label *string `json:"label"`
+}
+
+// NewDevice returns the device of the user agent and client IP address of a request. The user
+// agent is truncated to fit the column, as clients control its length.
+func NewDevice(userAgent string, clientIP string) Device {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+		for !utf8.ValidString(userAgent) {
+			userAgent = userAgent[:len(userAgent)-1]
+		}
+	}
+	return Device{UserAgent: userAgent, ClientIP: clientIP}
+}
+
+func (d Device) input(previous *data.RefreshToken) data.RefreshTokenInput {
+	input := data.RefreshTokenInput{
+		UserAgent: sql.Null[string]{V: d.UserAgent, Valid: d.UserAgent != ""},
+		ClientIP:  sql.Null[string]{V: d.ClientIP, Valid: d.ClientIP != ""},
+		Label:     db.PtrToNull(d.Label),
+	}
+	if previous != nil {
+		if !input.UserAgent.Valid {
+			input.UserAgent = previous.UserAgent
+		}
+		if !input.ClientIP.Valid {
+			input.ClientIP = previous.ClientIP
+		}
+		if !input.Label.Valid {
+			input.Label = previous.Label
+		}
+	}
+	return input
+}
+
+// Session is a login of a user, lasting until it is revoked or the refresh token expires
+// without being refreshed.
+type Session struct {
+	ID        uuid.UUID `json:"id"`
+	UserAgent *string   `json:"userAgent"`
+	ClientIP  *string   `json:"clientIp"`
+	Label     *string   `json:"label"`
+	// IssuedAt is when the current refresh token of the session was issued.
+	IssuedAt   time.Time  `json:"issuedAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	Expiration time.Time  `json:"expiration"`
+}
+
+func newSessionFromRow(row *data.RefreshToken) *Session {
+	return &Session{
+		ID:         row.SessionID,
+		UserAgent:  db.NullToPtr(row.UserAgent),
+		ClientIP:   db.NullToPtr(row.ClientIP),
+		Label:      db.NullToPtr(row.Label),
+		IssuedAt:   row.IssuedAt,
+		LastUsedAt: db.NullToPtr(row.LastUsedAt),
+		Expiration: row.Expiration,
+	}
+}
+
+func (r *TokenRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"sessions",
+		slog.String("userId", userID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading sessions")
+	// Each session has a single valid refresh token, the latest replacement.
+	rows, _, err := r.models.RefreshTokens.SelectMany(ctx, data.RefreshTokenFilter{
+		UserID:       sql.Null[uuid.UUID]{V: userID, Valid: true},
+		Invalidated:  sql.Null[bool]{V: false, Valid: true},
+		ExpirationTo: sql.Null[time.Time]{V: time.Now().UTC(), Valid: true},
+		PageSize:     maxSessions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = newSessionFromRow(row)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "sessions retrieved")
+
+	return sessions, nil
+}
+
+func (r *TokenRepository) RevokeSession(
+	ctx context.Context,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"session",
+		slog.String("id", sessionID.String()),
+		slog.String("userId", userID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "revoking session")
+	revoked, err := r.models.RefreshTokens.InvalidateSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if len(revoked) == 0 {
+		return db.ErrRecordNotFound
+	}
+	if err := r.denySessions(ctx, revoked...); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "session revoked")
+	r.audit.Record(ctx, AuditEventInput{
+		Type:      AuditLogout,
+		Outcome:   AuditSuccess,
+		ActorID:   &userID,
+		SessionID: &sessionID,
+	})
+
+	return nil
+}
+
+func (r *TokenRepository) RevokeOtherSessions(
+	ctx context.Context,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+) (int64, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"session",
+		slog.String("id", sessionID.String()),
+		slog.String("userId", userID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "revoking other sessions")
+	revoked, err := r.models.RefreshTokens.InvalidateOtherSessions(ctx, userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.denySessions(ctx, revoked...); err != nil {
+		return 0, err
+	}
+	affected := int64(len(revoked))
+	logger.LogAttrs(ctx, slog.LevelInfo, "sessions revoked", slog.Int64("revoked", affected))
+	// The session is the one kept, and the other sessions of the user are ended.
+	r.audit.Record(ctx, AuditEventInput{
+		Type:      AuditLogout,
+		Outcome:   AuditSuccess,
+		ActorID:   &userID,
+		SessionID: &sessionID,
+		Details:   map[string]any{"otherSessions": true, "revoked": affected},
+	})
+
+	return affected, nil
+}
@@ -0,0 +1,105 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	principal := api.Principal{Subject: testUser.ID.String(), Scopes: testUser.Role.Scopes()}
+
+	accessToken, refreshToken, err := authRepo.Tokens.CreateSession(ctx, principal, repo.Device{
+		UserAgent: "curl/8.0",
+		ClientIP:  "192.0.2.1",
+		Label:     new("laptop"),
+	})
+	require.NoError(t, err)
+
+	current, err := authRepo.Tokens.Authenticate(ctx, *accessToken)
+	require.NoError(t, err)
+	currentID, err := uuid.Parse(current.SessionID)
+	require.NoError(t, err)
+
+	otherAccess, other, err := authRepo.Tokens.CreateSession(ctx, principal, repo.Device{})
+	require.NoError(t, err)
+
+	var refreshedAccess *string
+
+	t.Run("Refresh", func(t *testing.T) {
+		// Refreshing keeps the session, and the label when none is given.
+		a, r, err := authRepo.Tokens.Refresh(ctx, *refreshToken, repo.Device{ClientIP: "192.0.2.2"})
+		require.NoError(t, err)
+		refreshToken = r
+		refreshedAccess = a
+
+		refreshed, err := authRepo.Tokens.Authenticate(ctx, *a)
+		require.NoError(t, err)
+		assert.Equal(t, current.SessionID, refreshed.SessionID)
+
+		session := findSession(t, currentID)
+		require.NotNil(t, session)
+		assert.Equal(t, "192.0.2.2", *session.ClientIP)
+		assert.Equal(t, "curl/8.0", *session.UserAgent)
+		assert.Equal(t, "laptop", *session.Label)
+		assert.NotNil(t, session.LastUsedAt)
+	})
+
+	t.Run("RevokeSessionOfOtherUser", func(t *testing.T) {
+		err := authRepo.Tokens.RevokeSession(ctx, uuid.New(), currentID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("RevokeOtherSessions", func(t *testing.T) {
+		revoked, err := authRepo.Tokens.RevokeOtherSessions(ctx, testUser.ID, currentID)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, revoked, int64(1))
+
+		// The access tokens of the other sessions are rejected before they expire.
+		_, err = authRepo.Tokens.Authenticate(ctx, *otherAccess)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
+		_, err = authRepo.Tokens.Authenticate(ctx, *accessToken)
+		assert.NoError(t, err)
+
+		_, _, err = authRepo.Tokens.Refresh(ctx, *other, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+		assert.NotNil(t, findSession(t, currentID))
+	})
+
+	t.Run("RevokeSession", func(t *testing.T) {
+		err := authRepo.Tokens.RevokeSession(ctx, testUser.ID, currentID)
+		require.NoError(t, err)
+
+		// Access tokens issued for the session before and after refreshing are rejected.
+		_, err = authRepo.Tokens.Authenticate(ctx, *accessToken)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
+		_, err = authRepo.Tokens.Authenticate(ctx, *refreshedAccess)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
+
+		_, _, err = authRepo.Tokens.Refresh(ctx, *refreshToken, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+		assert.Nil(t, findSession(t, currentID))
+
+		err = authRepo.Tokens.RevokeSession(ctx, testUser.ID, currentID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+}
+
+func findSession(t *testing.T, id uuid.UUID) *repo.Session {
+	t.Helper()
+	sessions, err := authRepo.Tokens.ListSessions(context.Background(), testUser.ID)
+	require.NoError(t, err)
+	for _, session := range sessions {
+		if session.ID == id {
+			return session
+		}
+	}
+	return nil
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
 	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/keys"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 	"github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/ensure"
 	"github.com/r3d5un/islandwind/internal/logging"
@@ -22,6 +26,12 @@ type RefreshToken struct {
 	IssuedAt      time.Time  `json:"issuedAt"`
 	Invalidated   bool       `json:"invalidated"`
 	InvalidatedBy *uuid.UUID `json:"invalidatedBy"`
+	UserID        *uuid.UUID `json:"userId"`
+	SessionID     uuid.UUID  `json:"sessionId"`
+	UserAgent     *string    `json:"userAgent"`
+	ClientIP      *string    `json:"clientIp"`
+	Label         *string    `json:"label"`
+	LastUsedAt    *time.Time `json:"lastUsedAt"`
 }
 
 func newRefreshTokenFromRow(row *data.RefreshToken) *RefreshToken {
@@ -32,6 +42,12 @@ func newRefreshTokenFromRow(row *data.RefreshToken) *RefreshToken {
 		IssuedAt:      row.IssuedAt,
 		Invalidated:   row.Invalidated,
 		InvalidatedBy: db.NullUUIDToPtr(row.InvalidatedBy),
+		UserID:        db.NullUUIDToPtr(row.UserID),
+		SessionID:     row.SessionID,
+		UserAgent:     db.NullToPtr(row.UserAgent),
+		ClientIP:      db.NullToPtr(row.ClientIP),
+		Label:         db.NullToPtr(row.Label),
+		LastUsedAt:    db.NullToPtr(row.LastUsedAt),
 	}
 }
 
@@ -58,26 +74,81 @@ var (
 	ErrInvalidIssuedAt = errors.New("token iat timestamp invalid")
 	ErrIssuerMismatch  = errors.New("token issuer does not match requirements")
 	ErrUnauthorized    = errors.New("token unauthorized")
+	ErrMissingSubject  = errors.New("token sub claim missing")
+	// ErrTokenReused is returned when a replaced refresh token is presented again. It wraps
+	// ErrUnauthorized.
+	ErrTokenReused = fmt.Errorf("%w: refresh token reused", ErrUnauthorized)
+	// ErrTokenRevoked is returned when a revoked access token is presented. It wraps
+	// ErrUnauthorized.
+	ErrTokenRevoked = fmt.Errorf("%w: access token revoked", ErrUnauthorized)
 )
 
+// accessTokenDuration is how long access tokens are valid. Revoked sessions stay on the
+// denylist for as long, outliving every access token issued for them.
+const accessTokenDuration time.Duration = 5 * time.Minute
+
 type TokenType int
 
 const (
 	AccessTokenType TokenType = iota
 	RefreshTokenType
+	// totpChallengeTokenType is signed with the challenge signing secret, which is only known
+	// to the auth module.
+	totpChallengeTokenType
 )
 
 type TokenService interface {
-	CreateAccessToken() (accessToken *string, err error)
+	// CreateAccessToken creates a signed access token issued to the principal, carrying the
+	// scopes of the principal in the scope claim, and the session in the sid claim.
+	CreateAccessToken(principal api.Principal) (accessToken *string, err error)
+	// CreateSession starts a new session for the principal on the device, returning the
+	// access token and refresh token of the session.
+	CreateSession(
+		ctx context.Context,
+		principal api.Principal,
+		device Device,
+	) (accessToken *string, refreshToken *string, err error)
 	Validate(ctx context.Context, tokenType TokenType, input string) (valid bool, err error)
+	// Introspect describes the access or refresh token as defined by RFC 7662. The hint,
+	// AccessTokenHint or RefreshTokenHint, is the type tried first. Invalid, expired and
+	// revoked tokens are described as inactive, without an error.
+	Introspect(ctx context.Context, input string, hint string) (*Introspection, error)
+	// Revoke revokes the access or refresh token as defined by RFC 7009. Access tokens are
+	// denied until they expire, and refresh tokens are invalidated. Invalid tokens are ignored.
+	Revoke(ctx context.Context, input string, hint string) error
+	// Authenticate validates the access token, and returns the principal it was issued to.
+	Authenticate(ctx context.Context, input string) (*api.Principal, error)
 	InvalidateRefreshToken(ctx context.Context, input string) error
-	CreateRefreshToken(ctx context.Context) (refreshToken *string, err error)
+	// CreateRefreshToken creates and stores a signed refresh token issued to the user,
+	// starting a new session without device metadata.
+	CreateRefreshToken(ctx context.Context, userID uuid.UUID) (refreshToken *string, err error)
+	// Refresh replaces the refresh token, updating the session with the device refreshing it.
 	Refresh(
 		ctx context.Context,
 		refreshTokenInput string,
+		device Device,
 	) (accessToken *string, refreshToken *string, err error)
+	// CreateTOTPChallenge issues a short-lived challenge token to a user who has given the
+	// correct password, to be exchanged for tokens with a TOTP or recovery code.
+	CreateTOTPChallenge(
+		ctx context.Context,
+		challenge TOTPChallenge,
+	) (challengeToken *string, err error)
+	// ParseTOTPChallenge verifies the challenge token, or returns ErrInvalidChallenge.
+	ParseTOTPChallenge(input string) (*TOTPChallenge, error)
+	// ConsumeTOTPChallenge marks the challenge as used once the code has been accepted, so
+	// that it cannot log in again. ErrInvalidChallenge is returned if it is used or expired.
+	ConsumeTOTPChallenge(ctx context.Context, challenge TOTPChallenge) error
+	// ListSessions lists the valid sessions of the user.
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error)
+	// RevokeSession invalidates the session of the user. ErrRecordNotFound is returned if the
+	// user has no valid session with the ID.
+	RevokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error
+	// RevokeOtherSessions invalidates all sessions of the user except the given session,
+	// returning the number of sessions revoked.
+	RevokeOtherSessions(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) (int64, error)
 	Update(ctx context.Context, input RefreshTokenPatch) (*RefreshToken, error)
-	DeleteExpired(ctx context.Context) error
+	TokenCleaner
 	List(
 		ctx context.Context,
 		filter data.RefreshTokenFilter,
@@ -85,11 +156,22 @@ type TokenService interface {
 	Delete(ctx context.Context, filter data.RefreshTokenFilter) (int64, error)
 }
 
+// TokenRepository issues access tokens signed by the asymmetric keys of the key set, so that
+// other services can verify them using the published keys. Refresh tokens are only verified
+// by the repository, and are signed with HS512 using the refresh signing secret. TOTP challenges
+// are signed with HS512 using the challenge signing secret.
 type TokenRepository struct {
-	signingSecret        []byte
+	keys                 *keys.Set
 	refreshSigningSecret []byte
-	Issuer               string `json:"issuer"`
-	models               *data.Models
+	// challengeSigningSecret signs TOTP challenges, which must not verify against the
+	// published keys of the key set.
+	challengeSigningSecret []byte
+	Issuer                 string `json:"issuer"`
+	models                 *data.Models
+	// denylist holds the revoked access tokens and sessions.
+	denylist DenylistService
+	// audit records logins, refreshes, logouts and deletions.
+	audit AuditService
 }
 
 func (r *TokenRepository) LogValue() slog.Value {
@@ -99,16 +181,22 @@ func (r *TokenRepository) LogValue() slog.Value {
 }
 
 func NewTokenRepository(
-	accessTokenSecret []byte,
+	keySet *keys.Set,
 	refreshTokenSecret []byte,
+	challengeSecret []byte,
 	issuer string,
 	models *data.Models,
+	denylist DenylistService,
+	audit AuditService,
 ) TokenService {
 	return &TokenRepository{
-		signingSecret:        accessTokenSecret,
-		refreshSigningSecret: refreshTokenSecret,
-		Issuer:               issuer,
-		models:               models,
+		keys:                   keySet,
+		refreshSigningSecret:   refreshTokenSecret,
+		challengeSigningSecret: challengeSecret,
+		Issuer:                 issuer,
+		models:                 models,
+		denylist:               denylist,
+		audit:                  audit,
 	}
 }
 
@@ -166,23 +254,34 @@ func (r *TokenRepository) Delete(
 		slog.Any("filter", filter),
 	))
 
+	event := AuditEventInput{Type: AuditRefreshTokenDelete}
+	if filter.ID.Valid {
+		event.TokenIDs = []uuid.UUID{filter.ID.V}
+	}
+
 	logger.LogAttrs(ctx, slog.LevelInfo, "deleting refresh tokens")
 	affected, err := r.models.RefreshTokens.DeleteMany(ctx, filter)
 	if err != nil {
+		r.audit.Record(ctx, event.withResult(err))
 		return 0, err
 	}
 	ensure.NotNil(affected, "affected row count cannot be nil without errors")
 	logger.LogAttrs(
 		ctx, slog.LevelInfo, "refresh tokens deleted", slog.Int64("affected", *affected),
 	)
+	event.Details = map[string]any{"deleted": *affected}
+	r.audit.Record(ctx, event.withResult(nil))
 
 	return *affected, nil
 }
 
 // CreateAccessToken create a new signed JWT token string.
-func (r *TokenRepository) CreateAccessToken() (*string, error) {
-	token := r.newToken(uuid.New(), time.Now().UTC().Add(time.Minute*5), time.Now().UTC())
-	tokenString, err := token.SignedString(r.signingSecret)
+func (r *TokenRepository) CreateAccessToken(principal api.Principal) (*string, error) {
+	userID, err := uuid.Parse(principal.Subject)
+	if err != nil {
+		return nil, ErrMissingSubject
+	}
+	tokenString, err := r.signAccessToken(userID, principal.Scopes, principal.SessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -190,6 +289,27 @@ func (r *TokenRepository) CreateAccessToken() (*string, error) {
 	return &tokenString, nil
 }
 
+// signAccessToken signs an access token for the user. The sid claim is omitted if the session
+// is empty.
+func (r *TokenRepository) signAccessToken(
+	userID uuid.UUID,
+	scopes []string,
+	sessionID string,
+) (string, error) {
+	claims := r.newClaims(
+		uuid.New(),
+		userID,
+		scopes,
+		time.Now().UTC().Add(accessTokenDuration),
+		time.Now().UTC(),
+	)
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+
+	return r.keys.Sign(claims)
+}
+
 func (r *TokenRepository) parseToken(input string, tokenType TokenType) (*jwt.Token, error) {
 	var token *jwt.Token
 	var err error
@@ -210,13 +330,12 @@ func (r *TokenRepository) parseToken(input string, tokenType TokenType) (*jwt.To
 				return nil, err
 			}
 		}
-	case AccessTokenType:
-		token, err = jwt.Parse(input, func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, ErrParsingToken
-			}
-			return r.signingSecret, nil
-		})
+	case totpChallengeTokenType:
+		token, err = jwt.Parse(
+			input,
+			func(*jwt.Token) (any, error) { return r.challengeSigningSecret, nil },
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS512.Alg()}),
+		)
 		if err != nil {
 			switch {
 			case errors.Is(err, jwt.ErrTokenSignatureInvalid):
@@ -225,6 +344,27 @@ func (r *TokenRepository) parseToken(input string, tokenType TokenType) (*jwt.To
 				return nil, err
 			}
 		}
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, ErrVerifyingToken
+		}
+		if typ, _ := claims["typ"].(string); typ != totpChallengeType {
+			return nil, ErrUnauthorized
+		}
+	case AccessTokenType:
+		token, err = jwt.Parse(
+			input,
+			r.keys.Keyfunc,
+			jwt.WithValidMethods(r.keys.Algorithms()),
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, keys.ErrUnknownKey):
+				return nil, ErrUnauthorized
+			default:
+				return nil, err
+			}
+		}
 	default:
 		return nil, errors.New("unknown token type")
 	}
@@ -263,8 +403,50 @@ func (r *TokenRepository) Validate(
 	return true, nil
 }
 
-func (r *TokenRepository) InvalidateRefreshToken(ctx context.Context, input string) error {
+func (r *TokenRepository) Authenticate(ctx context.Context, input string) (*api.Principal, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "authenticating access token")
+	token, err := r.parseToken(input, AccessTokenType)
+	if err != nil {
+		logger.LogAttrs(
+			ctx, slog.LevelError, "error parsing token", slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	subject, err := token.Claims.GetSubject()
+	if err != nil {
+		return nil, ErrVerifyingToken
+	}
+	jti, err := r.jtiFromToken(*token)
+	if err != nil {
+		return nil, ErrVerifyingToken
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	// Tokens without a scope claim are valid, but grant no scopes.
+	scopeClaim, _ := claims["scope"].(string)
+	sessionID, _ := claims["sid"].(string)
+	denied, err := r.denylist.IsDenied(ctx, *jti, sessionFromClaims(claims))
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		logger.LogAttrs(ctx, slog.LevelInfo, "access token revoked", slog.String("jti", jti.String()))
+		return nil, ErrTokenRevoked
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "access token authenticated", slog.String("sub", subject))
+
+	return &api.Principal{
+		Subject:   subject,
+		Scopes:    scope.Parse(scopeClaim),
+		SessionID: sessionID,
+	}, nil
+}
+
+func (r *TokenRepository) InvalidateRefreshToken(ctx context.Context, input string) (err error) {
 	logger := logging.LoggerFromContext(ctx)
+	event := AuditEventInput{Type: AuditLogout}
+	defer func() { r.audit.Record(ctx, event.withResult(err)) }()
 
 	token, err := r.parseToken(input, RefreshTokenType)
 	if err != nil {
@@ -285,37 +467,60 @@ func (r *TokenRepository) InvalidateRefreshToken(ctx context.Context, input stri
 		return ErrVerifyingToken
 	}
 
+	event.TokenIDs = []uuid.UUID{*tokenID}
+
 	logger = logger.With(slog.String("jti", tokenID.String()))
 	logger.LogAttrs(ctx, slog.LevelInfo, "invalidating token")
-	_, err = r.models.RefreshTokens.Update(
+	row, err := r.models.RefreshTokens.Update(
 		ctx,
 		data.RefreshTokenPatch{ID: *tokenID, Invalidated: sql.NullBool{Valid: true, Bool: true}},
 	)
 	if err != nil {
 		return err
 	}
+	event.ActorID = db.NullUUIDToPtr(row.UserID)
+	event.SessionID = &row.SessionID
 	logger.LogAttrs(ctx, slog.LevelInfo, "token invalidated")
 
-	return nil
+	return r.denySessions(ctx, row.SessionID)
 }
 
-func (r *TokenRepository) newRefreshToken(ctx context.Context) (*jwt.Token, error) {
-	row, err := r.models.RefreshTokens.Insert(ctx, data.RefreshTokenInput{
-		Issuer:     r.Issuer,
-		Expiration: time.Now().UTC().Add(time.Minute * 60),
-		IssuedAt:   time.Now().UTC(),
-	})
-	if err != nil {
-		return nil, err
-	}
-	return new(r.newToken(row.ID, row.Expiration, row.IssuedAt)), nil
+// newRefreshTokenInput returns the input of a refresh token of the session. The device metadata
+// missing from the device is copied from the previous token of the session, if any.
+func (r *TokenRepository) newRefreshTokenInput(
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+	device Device,
+	previous *data.RefreshToken,
+) data.RefreshTokenInput {
+	now := time.Now().UTC()
+
+	input := device.input(previous)
+	input.Issuer = r.Issuer
+	input.Expiration = now.Add(time.Minute * 60)
+	input.IssuedAt = now
+	input.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+	input.SessionID = sessionID
+	input.LastUsedAt = sql.Null[time.Time]{V: now, Valid: true}
+
+	return input
 }
 
-func (r *TokenRepository) CreateRefreshToken(ctx context.Context) (*string, error) {
-	logger := logging.LoggerFromContext(ctx)
+// signRefreshToken signs a refresh token for the stored refresh token.
+func (r *TokenRepository) signRefreshToken(row *data.RefreshToken) (string, error) {
+	return jwt.NewWithClaims(
+		jwt.SigningMethodHS512,
+		r.newClaims(row.ID, row.UserID.UUID, nil, row.Expiration, row.IssuedAt),
+	).SignedString(r.refreshSigningSecret)
+}
+
+func (r *TokenRepository) CreateRefreshToken(ctx context.Context, userID uuid.UUID) (*string, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.String("sub", userID.String()))
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "creating refresh token")
-	token, err := r.newRefreshToken(ctx)
+	row, err := r.models.RefreshTokens.Insert(
+		ctx, r.newRefreshTokenInput(userID, uuid.New(), Device{}, nil),
+	)
 	if err != nil {
 		logger.LogAttrs(
 			ctx,
@@ -325,7 +530,7 @@ func (r *TokenRepository) CreateRefreshToken(ctx context.Context) (*string, erro
 		)
 		return nil, err
 	}
-	tokenString, err := token.SignedString(r.refreshSigningSecret)
+	tokenString, err := r.signRefreshToken(row)
 	if err != nil {
 		return nil, err
 	}
@@ -334,13 +539,64 @@ func (r *TokenRepository) CreateRefreshToken(ctx context.Context) (*string, erro
 	return &tokenString, nil
 }
 
+func (r *TokenRepository) CreateSession(
+	ctx context.Context,
+	principal api.Principal,
+	device Device,
+) (*string, *string, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.String("sub", principal.Subject))
+
+	userID, err := uuid.Parse(principal.Subject)
+	if err != nil {
+		return nil, nil, ErrMissingSubject
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "creating session")
+	row, err := r.models.RefreshTokens.Insert(
+		ctx, r.newRefreshTokenInput(userID, uuid.New(), device, nil),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger = logger.With(slog.String("sid", row.SessionID.String()))
+	logger.LogAttrs(ctx, slog.LevelInfo, "session created")
+	r.audit.Record(ctx, AuditEventInput{
+		Type:      AuditLogin,
+		Outcome:   AuditSuccess,
+		ActorID:   &userID,
+		SessionID: &row.SessionID,
+		TokenIDs:  []uuid.UUID{row.ID},
+	})
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "signing access and refresh tokens")
+	access, err := r.signAccessToken(userID, principal.Scopes, row.SessionID.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh, err := r.signRefreshToken(row)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "tokens signed")
+
+	return &access, &refresh, nil
+}
+
 // Refresh accepts a refresh token string, then produces a new access token and refresh token. The new refresh token
 // invalidates and replaces the old refresh token.
+//
+// The chain of replacements starting at a token issued by logging in is a token family. A
+// replaced token is only presented again if it was stolen, or if the legitimate client failed to
+// store its replacement. As the server cannot tell which client is legitimate, the whole family
+// is invalidated and ErrTokenReused is returned, logging both clients out.
 func (r *TokenRepository) Refresh(
 	ctx context.Context,
 	refreshTokenInput string,
+	device Device,
 ) (accessToken *string, refreshToken *string, err error) {
 	logger := logging.LoggerFromContext(ctx)
+	event := AuditEventInput{Type: AuditRefresh}
+	defer func() { r.audit.Record(ctx, event.withResult(err)) }()
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "validating refresh token")
 	token, err := r.parseToken(refreshTokenInput, RefreshTokenType)
@@ -358,6 +614,7 @@ func (r *TokenRepository) Refresh(
 	if err != nil {
 		return nil, nil, ErrVerifyingToken
 	}
+	event.TokenIDs = []uuid.UUID{*id}
 	row, err := r.models.RefreshTokens.SelectOne(ctx, *id)
 	if err != nil {
 		switch {
@@ -367,37 +624,79 @@ func (r *TokenRepository) Refresh(
 			return nil, nil, err
 		}
 	}
+	event.ActorID = db.NullUUIDToPtr(row.UserID)
+	event.SessionID = &row.SessionID
 	if row.Invalidated {
+		// Tokens invalidated by logging out, or by invalidating all tokens of the user, have
+		// no replacement.
+		if row.InvalidatedBy.Valid {
+			return nil, nil, r.revokeFamily(ctx, row)
+		}
+		return nil, nil, ErrUnauthorized
+	}
+	// Tokens issued before user accounts existed cannot be refreshed.
+	if !row.UserID.Valid {
 		return nil, nil, ErrUnauthorized
 	}
 	logger.LogAttrs(ctx, slog.LevelInfo, "refresh token validated")
 
+	// The scopes are read from the current role of the user, so that role changes take effect
+	// when the access token is refreshed.
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading user")
+	user, err := r.models.Users.SelectOne(ctx, row.UserID.UUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			return nil, nil, ErrUnauthorized
+		default:
+			return nil, nil, err
+		}
+	}
+	if user.Disabled {
+		return nil, nil, ErrUnauthorized
+	}
+
 	logger.LogAttrs(ctx, slog.LevelInfo, "replacing refresh token")
-	newRefreshToken, err := r.newRefreshToken(ctx)
+	tx, rollback, err := r.models.BeginTx(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	id, err = r.jtiFromToken(*newRefreshToken)
+	defer rollback()
+
+	newRow, err := r.models.RefreshTokens.InsertTx(
+		ctx, tx, r.newRefreshTokenInput(row.UserID.UUID, row.SessionID, device, row),
+	)
 	if err != nil {
 		return nil, nil, err
 	}
-	_, err = r.models.RefreshTokens.Update(ctx, data.RefreshTokenPatch{
-		ID:            row.ID,
-		Invalidated:   sql.NullBool{Valid: true, Bool: true},
-		InvalidatedBy: uuid.NullUUID{Valid: true, UUID: *id},
-	})
+	event.TokenIDs = append(event.TokenIDs, newRow.ID)
+	_, err = r.models.RefreshTokens.RotateTx(ctx, tx, row.ID, newRow.ID)
 	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			// The token was replaced by a concurrent refresh since it was read, so it has
+			// been presented twice.
+			rollback()
+			return nil, nil, r.revokeFamily(ctx, row)
+		default:
+			return nil, nil, err
+		}
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
 		return nil, nil, err
 	}
 	logger.LogAttrs(ctx, slog.LevelInfo, "refresh token replaced")
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "signing access and refresh tokens")
-	newAccessToken := r.newToken(uuid.New(), time.Now().UTC().Add(time.Minute*5), time.Now().UTC())
-	access, err := newAccessToken.SignedString(r.signingSecret)
+	access, err := r.signAccessToken(
+		row.UserID.UUID, user.Role.Scopes(), row.SessionID.String(),
+	)
 	if err != nil {
 		return nil, nil, err
 	}
-	refresh, err := newRefreshToken.SignedString(r.signingSecret)
+	refresh, err := r.signRefreshToken(newRow)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -406,11 +705,76 @@ func (r *TokenRepository) Refresh(
 	return &access, &refresh, nil
 }
 
-func (r *TokenRepository) newToken(jti uuid.UUID, exp time.Time, iat time.Time) jwt.Token {
-	return *jwt.NewWithClaims(
-		jwt.SigningMethodHS512,
-		jwt.MapClaims{"jti": jti.String(), "exp": exp.Unix(), "iat": iat.Unix(), "iss": r.Issuer},
+// revokeFamily invalidates the reused refresh token and all tokens descending from it in a
+// single transaction, and logs the reuse as a security event. ErrTokenReused is returned
+// unless the family could not be invalidated.
+func (r *TokenRepository) revokeFamily(ctx context.Context, reused *data.RefreshToken) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"securityEvent",
+		slog.String("type", "refresh_token_reuse"),
+		slog.String("jti", reused.ID.String()),
+		slog.String("sub", reused.UserID.UUID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelWarn, "refresh token reused, revoking token family")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	revoked, err := r.models.RefreshTokens.InvalidateFamilyTx(ctx, tx, reused.ID)
+	if err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.LogAttrs(
+		ctx, slog.LevelWarn, "token family revoked", slog.Int64("revoked", revoked),
 	)
+	if err := r.denySessions(ctx, reused.SessionID); err != nil {
+		return err
+	}
+
+	return ErrTokenReused
+}
+
+// denySessions adds the sessions to the denylist, so that the access tokens issued for them
+// are rejected before they expire.
+func (r *TokenRepository) denySessions(ctx context.Context, sessionIDs ...uuid.UUID) error {
+	expiresAt := time.Now().UTC().Add(accessTokenDuration)
+	for _, sessionID := range sessionIDs {
+		if err := r.denylist.DenySession(ctx, sessionID, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newClaims returns the claims of a token. The scope claim is omitted if no scopes are given.
+func (r *TokenRepository) newClaims(
+	jti uuid.UUID,
+	sub uuid.UUID,
+	scopes []string,
+	exp time.Time,
+	iat time.Time,
+) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"jti": jti.String(),
+		"sub": sub.String(),
+		"exp": exp.Unix(),
+		"iat": iat.Unix(),
+		"iss": r.Issuer,
+	}
+	if len(scopes) > 0 {
+		claims["scope"] = scope.Format(scopes)
+	}
+
+	return claims
 }
 
 func (r *TokenRepository) verifyClaims(token *jwt.Token) (bool, error) {
@@ -443,9 +807,25 @@ func (r *TokenRepository) verifyClaims(token *jwt.Token) (bool, error) {
 		return false, ErrIssuerMismatch
 	}
 
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return false, ErrMissingSubject
+	}
+
 	return true, nil
 }
 
+// sessionFromClaims returns the session in the sid claim, or uuid.Nil if the token has no
+// session.
+func sessionFromClaims(claims jwt.MapClaims) uuid.UUID {
+	claim, _ := claims["sid"].(string)
+	sessionID, err := uuid.Parse(claim)
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionID
+}
+
 func (r *TokenRepository) jtiFromToken(token jwt.Token) (*uuid.UUID, error) {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
@@ -462,20 +842,3 @@ func (r *TokenRepository) jtiFromToken(token jwt.Token) (*uuid.UUID, error) {
 	}
 	return &id, nil
 }
-
-func (r *TokenRepository) DeleteExpired(ctx context.Context) error {
-	logger := logging.LoggerFromContext(ctx)
-
-	logger.LogAttrs(ctx, slog.LevelInfo, "deleting expired tokens")
-	affectedRows, err := r.models.RefreshTokens.DeleteMany(ctx, data.RefreshTokenFilter{
-		ExpirationFrom: sql.Null[time.Time]{V: time.Now().UTC(), Valid: true},
-	})
-	if err != nil {
-		return err
-	}
-	logger.LogAttrs(
-		ctx, slog.LevelInfo, "tokens deleted", slog.Int64("rowsAffected", *affectedRows),
-	)
-
-	return nil
-}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// ExpiredTokenResult contains the refresh tokens deleted by a run of DeleteExpired.
+type ExpiredTokenResult struct {
+	// Locked is false if another instance held the cleanup lock, and nothing was deleted.
+	Locked  bool  `json:"locked"`
+	Deleted int64 `json:"deleted"`
+}
+
+type TokenCleaner interface {
+	// DeleteExpired deletes a batch of at most batchSize expired refresh tokens, oldest first.
+	// Only one instance of the application deletes tokens at a time.
+	DeleteExpired(ctx context.Context, batchSize int) (*ExpiredTokenResult, error)
+}
+
+func (r *TokenRepository) DeleteExpired(
+	ctx context.Context,
+	batchSize int,
+) (*ExpiredTokenResult, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "deleting expired tokens", slog.Int("batchSize", batchSize))
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	locked, err := db.TryAdvisoryXactLock(ctx, tx, db.AuthRefreshTokenCleanupLock)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		logger.LogAttrs(ctx, slog.LevelInfo, "cleanup lock held by another instance")
+		return &ExpiredTokenResult{Locked: false}, nil
+	}
+
+	deleted, err := r.models.RefreshTokens.DeleteExpiredTx(
+		ctx, tx, time.Now().UTC(), batchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "tokens deleted", slog.Int64("rowsAffected", deleted))
+
+	return &ExpiredTokenResult{Locked: true, Deleted: deleted}, nil
+}
@@ -6,8 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/r3d5un/islandwind/internal/api"
 	"github.com/r3d5un/islandwind/internal/auth/data"
 	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,7 +21,10 @@ func TestTokenRepository(t *testing.T) {
 	var tokens []*repo.RefreshToken
 
 	t.Run("CreateAccessToken", func(t *testing.T) {
-		newJWT, err := authRepo.Tokens.CreateAccessToken()
+		newJWT, err := authRepo.Tokens.CreateAccessToken(api.Principal{
+			Subject: testUser.ID.String(),
+			Scopes:  []string{scope.BlogWrite, scope.MediaRead},
+		})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, newJWT)
 
@@ -32,8 +37,15 @@ func TestTokenRepository(t *testing.T) {
 		assert.True(t, ok)
 	})
 
+	t.Run("Authenticate", func(t *testing.T) {
+		principal, err := authRepo.Tokens.Authenticate(ctx, accessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, testUser.ID.String(), principal.Subject)
+		assert.Equal(t, []string{scope.BlogWrite, scope.MediaRead}, principal.Scopes)
+	})
+
 	t.Run("CreateRefreshToken", func(t *testing.T) {
-		token, err := authRepo.Tokens.CreateRefreshToken(ctx)
+		token, err := authRepo.Tokens.CreateRefreshToken(ctx, testUser.ID)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
 
@@ -41,19 +53,61 @@ func TestTokenRepository(t *testing.T) {
 	})
 
 	t.Run("Refresh", func(t *testing.T) {
-		a, r, err := authRepo.Tokens.Refresh(ctx, refreshToken)
+		a, r, err := authRepo.Tokens.Refresh(ctx, refreshToken, repo.Device{})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, a)
 		assert.NotEmpty(t, r)
+
+		principal, err := authRepo.Tokens.Authenticate(ctx, *a)
+		assert.NoError(t, err)
+		assert.Equal(t, testUser.ID.String(), principal.Subject)
+		// The refreshed access token carries the scopes of the role of the user.
+		assert.Equal(t, scope.RoleAdmin.Scopes(), principal.Scopes)
+
+		// The replacing refresh token can be refreshed in turn.
+		_, _, err = authRepo.Tokens.Refresh(ctx, *r, repo.Device{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("RefreshReuse", func(t *testing.T) {
+		first, err := authRepo.Tokens.CreateRefreshToken(ctx, testUser.ID)
+		assert.NoError(t, err)
+		_, second, err := authRepo.Tokens.Refresh(ctx, *first, repo.Device{})
+		assert.NoError(t, err)
+		access, third, err := authRepo.Tokens.Refresh(ctx, *second, repo.Device{})
+		assert.NoError(t, err)
+
+		// Presenting a replaced token revokes the whole family, including the latest token,
+		// and the access tokens of the session.
+		_, _, err = authRepo.Tokens.Refresh(ctx, *first, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrTokenReused)
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+
+		_, _, err = authRepo.Tokens.Refresh(ctx, *third, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+		_, err = authRepo.Tokens.Authenticate(ctx, *access)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
+
+		// Tokens of other families are not affected.
+		other, err := authRepo.Tokens.CreateRefreshToken(ctx, testUser.ID)
+		assert.NoError(t, err)
+		_, _, err = authRepo.Tokens.Refresh(ctx, *other, repo.Device{})
+		assert.NoError(t, err)
 	})
 
 	t.Run("InvalidateRefreshToken", func(t *testing.T) {
-		token, err := authRepo.Tokens.CreateRefreshToken(ctx)
+		access, token, err := authRepo.Tokens.CreateSession(
+			ctx, api.Principal{Subject: testUser.ID.String()}, repo.Device{},
+		)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
 
 		err = authRepo.Tokens.InvalidateRefreshToken(ctx, *token)
 		assert.NoError(t, err)
+
+		// Logging out ends the session, rejecting its access tokens.
+		_, err = authRepo.Tokens.Authenticate(ctx, *access)
+		assert.ErrorIs(t, err, repo.ErrTokenRevoked)
 	})
 
 	t.Run("ListRefreshTokens", func(t *testing.T) {
@@ -86,8 +140,9 @@ func TestTokenRepository(t *testing.T) {
 	})
 
 	t.Run("DeleteExpired", func(t *testing.T) {
-		err := authRepo.Tokens.DeleteExpired(ctx)
+		result, err := authRepo.Tokens.DeleteExpired(ctx, 100)
 		assert.NoError(t, err)
+		assert.True(t, result.Locked)
 	})
 
 }
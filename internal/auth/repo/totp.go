@@ -0,0 +1,394 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+const (
+	// totpPeriod is the lifetime of a TOTP code in seconds, as expected by authenticator apps.
+	totpPeriod int64 = 30
+	// totpSkew is the number of periods before and after the current period accepted, allowing
+	// for clock drift.
+	totpSkew int64 = 1
+	// totpSecretSize is the size of TOTP secrets in bytes, as recommended by RFC 4226.
+	totpSecretSize uint = 20
+	// qrCodeSize is the width and height of provisioning QR codes in pixels.
+	qrCodeSize int = 256
+	// recoveryCodeCount is the number of recovery codes issued to a user.
+	recoveryCodeCount int = 10
+	// recoveryCodeBytes is the number of random bytes of a recovery code, encoded as 16 base32
+	// characters.
+	recoveryCodeBytes int = 10
+)
+
+var (
+	// ErrTOTPNotEnrolled is returned when the user has not started TOTP enrolment.
+	ErrTOTPNotEnrolled = errors.New("totp not enrolled")
+	// ErrTOTPEnabled is returned when enrolling a user who already has TOTP enabled.
+	ErrTOTPEnabled = errors.New("totp already enabled")
+	// ErrTOTPNotEnabled is returned when TOTP is required to be enabled, but is not.
+	ErrTOTPNotEnabled = errors.New("totp not enabled")
+	// ErrInvalidTOTPCode is returned when a TOTP or recovery code is invalid or already used.
+	ErrInvalidTOTPCode = errors.New("invalid totp code")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is the secret of a pending TOTP enrolment. The URI is the otpauth URI read by
+// authenticator apps, and the secret may be entered manually instead.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (r *UserRepository) EnrollTOTP(ctx context.Context, ID uuid.UUID) (*TOTPEnrollment, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	row, err := r.models.Users.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if row.TOTPEnabled {
+		return nil, ErrTOTPEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      r.totpIssuer,
+		AccountName: row.Username,
+		SecretSize:  totpSecretSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "starting totp enrolment")
+	_, err = r.models.Users.Update(ctx, data.UserPatch{
+		ID:         ID,
+		TOTPSecret: sql.Null[string]{V: key.Secret(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp enrolment started")
+
+	return &TOTPEnrollment{Secret: key.Secret(), URI: key.URL()}, nil
+}
+
+func (r *UserRepository) TOTPQRCode(ctx context.Context, ID uuid.UUID) ([]byte, error) {
+	row, err := r.models.Users.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	// The secret is only shown while enrolling, so that a stolen access token cannot be used
+	// to copy the authenticator of the user.
+	switch {
+	case row.TOTPEnabled:
+		return nil, ErrTOTPEnabled
+	case !row.TOTPSecret.Valid:
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	secret, err := totpEncoding.DecodeString(row.TOTPSecret.V)
+	if err != nil {
+		return nil, err
+	}
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      r.totpIssuer,
+		AccountName: row.Username,
+		Secret:      secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *UserRepository) ConfirmTOTP(
+	ctx context.Context,
+	ID uuid.UUID,
+	code string,
+) ([]string, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	row, err := r.models.Users.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case row.TOTPEnabled:
+		return nil, ErrTOTPEnabled
+	case !row.TOTPSecret.Valid:
+		return nil, ErrTOTPNotEnrolled
+	}
+	if err := r.verifyTOTPCode(ctx, row, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "enabling totp")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	_, err = r.models.Users.UpdateTx(ctx, tx, data.UserPatch{
+		ID:          ID,
+		TOTPEnabled: sql.Null[bool]{V: true, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.models.RecoveryCodes.ReplaceTx(ctx, tx, ID, hashes); err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp enabled")
+
+	return codes, nil
+}
+
+func (r *UserRepository) RegenerateRecoveryCodes(
+	ctx context.Context,
+	ID uuid.UUID,
+	code string,
+) ([]string, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	if _, err := r.VerifySecondFactor(ctx, ID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "replacing recovery codes")
+	if err := r.models.RecoveryCodes.Replace(ctx, ID, hashes); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "recovery codes replaced")
+
+	return codes, nil
+}
+
+func (r *UserRepository) DisableTOTP(ctx context.Context, ID uuid.UUID, code string) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	if _, err := r.VerifySecondFactor(ctx, ID, code); err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "disabling totp")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	if err := r.models.Users.ClearTOTPTx(ctx, tx, ID); err != nil {
+		return err
+	}
+	if err := r.models.RecoveryCodes.DeleteByUserTx(ctx, tx, ID); err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "totp disabled")
+
+	return nil
+}
+
+func (r *UserRepository) VerifySecondFactor(
+	ctx context.Context,
+	ID uuid.UUID,
+	code string,
+) (*User, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	row, err := r.models.Users.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if row.Disabled {
+		logger.LogAttrs(ctx, slog.LevelInfo, "user disabled")
+		return nil, ErrInvalidCredentials
+	}
+	if !row.TOTPEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	if isTOTPCode(code) {
+		if err := r.verifyTOTPCode(ctx, row, code); err != nil {
+			return nil, err
+		}
+		return newUserFromRow(row), nil
+	}
+
+	used, err := r.models.RecoveryCodes.Use(ctx, ID, hashRecoveryCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		logger.LogAttrs(ctx, slog.LevelInfo, "recovery code invalid")
+		return nil, ErrInvalidTOTPCode
+	}
+	logger.LogAttrs(ctx, slog.LevelWarn, "recovery code used")
+
+	return newUserFromRow(row), nil
+}
+
+// verifyTOTPCode checks the code against the TOTP secret of the user, and records the time step
+// of the code so that it cannot be replayed.
+func (r *UserRepository) verifyTOTPCode(ctx context.Context, row *data.User, code string) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", row.ID.String()),
+	))
+
+	step, ok := validateTOTPCode(row.TOTPSecret.V, code, time.Now())
+	if !ok {
+		logger.LogAttrs(ctx, slog.LevelInfo, "totp code invalid")
+		return ErrInvalidTOTPCode
+	}
+	advanced, err := r.models.Users.AdvanceTOTPStep(ctx, row.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		logger.LogAttrs(ctx, slog.LevelInfo, "totp code replayed", slog.Int64("step", step))
+		return ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// validateTOTPCode returns the time step the code is valid for, within the allowed skew.
+func validateTOTPCode(secret string, code string, now time.Time) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totp.GenerateCodeCustom(
+			secret,
+			time.Unix(step*totpPeriod, 0),
+			totp.ValidateOpts{
+				Period:    uint(totpPeriod),
+				Digits:    otp.DigitsSix,
+				Algorithm: otp.AlgorithmSHA1,
+			},
+		)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// isTOTPCode reports whether the code is shaped like a TOTP code rather than a recovery code.
+func isTOTPCode(code string) bool {
+	if len(code) != otp.DigitsSix.Length() {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCodes returns new recovery codes, formatted as groups of four characters, and
+// their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(b))
+
+		groups := make([]string, 0, len(encoded)/4)
+		for j := 0; j < len(encoded); j += 4 {
+			groups = append(groups, encoded[j:j+4])
+		}
+		codes[i] = strings.Join(groups, "-")
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes the recovery code, ignoring case, dashes and whitespace so that codes
+// can be entered as the user wrote them down.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp/totp"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	ctx := context.Background()
+
+	user, err := authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "totp",
+		Password: testUserPassword,
+		Role:     scope.RoleAdmin,
+	})
+	require.NoError(t, err)
+
+	var enrollment *repo.TOTPEnrollment
+	var recoveryCodes []string
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		_, err := authRepo.Users.VerifySecondFactor(ctx, user.ID, "123456")
+		assert.ErrorIs(t, err, repo.ErrTOTPNotEnabled)
+
+		_, err = authRepo.Users.TOTPQRCode(ctx, user.ID)
+		assert.ErrorIs(t, err, repo.ErrTOTPNotEnrolled)
+	})
+
+	t.Run("Enroll", func(t *testing.T) {
+		enrollment, err = authRepo.Users.EnrollTOTP(ctx, user.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, enrollment.Secret)
+		assert.Contains(t, enrollment.URI, "otpauth://totp/")
+
+		img, err := authRepo.Users.TOTPQRCode(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\x89PNG"), img[:4])
+	})
+
+	t.Run("ConfirmInvalidCode", func(t *testing.T) {
+		_, err := authRepo.Users.ConfirmTOTP(ctx, user.ID, "000000")
+		assert.ErrorIs(t, err, repo.ErrInvalidTOTPCode)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		// The code of the previous period is accepted, and is not replayed by the next
+		// subtest.
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now().Add(-30*time.Second))
+		require.NoError(t, err)
+
+		recoveryCodes, err = authRepo.Users.ConfirmTOTP(ctx, user.ID, code)
+		require.NoError(t, err)
+		assert.Len(t, recoveryCodes, 10)
+
+		read, err := authRepo.Users.Read(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, read.TOTPEnabled)
+
+		_, err = authRepo.Users.EnrollTOTP(ctx, user.ID)
+		assert.ErrorIs(t, err, repo.ErrTOTPEnabled)
+		_, err = authRepo.Users.TOTPQRCode(ctx, user.ID)
+		assert.ErrorIs(t, err, repo.ErrTOTPEnabled)
+	})
+
+	t.Run("VerifyTOTPCode", func(t *testing.T) {
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		require.NoError(t, err)
+
+		verified, err := authRepo.Users.VerifySecondFactor(ctx, user.ID, code)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, verified.ID)
+
+		// Codes cannot be replayed.
+		_, err = authRepo.Users.VerifySecondFactor(ctx, user.ID, code)
+		assert.ErrorIs(t, err, repo.ErrInvalidTOTPCode)
+	})
+
+	t.Run("VerifyRecoveryCode", func(t *testing.T) {
+		_, err := authRepo.Users.VerifySecondFactor(ctx, user.ID, "AAAA-BBBB-CCCC-DDDD")
+		assert.ErrorIs(t, err, repo.ErrInvalidTOTPCode)
+
+		_, err = authRepo.Users.VerifySecondFactor(ctx, user.ID, recoveryCodes[0])
+		require.NoError(t, err)
+
+		// Recovery codes are used once.
+		_, err = authRepo.Users.VerifySecondFactor(ctx, user.ID, recoveryCodes[0])
+		assert.ErrorIs(t, err, repo.ErrInvalidTOTPCode)
+	})
+
+	t.Run("RegenerateRecoveryCodes", func(t *testing.T) {
+		regenerated, err := authRepo.Users.RegenerateRecoveryCodes(ctx, user.ID, recoveryCodes[1])
+		require.NoError(t, err)
+		assert.Len(t, regenerated, 10)
+
+		// The previous codes are replaced.
+		_, err = authRepo.Users.VerifySecondFactor(ctx, user.ID, recoveryCodes[2])
+		assert.ErrorIs(t, err, repo.ErrInvalidTOTPCode)
+
+		recoveryCodes = regenerated
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		err := authRepo.Users.DisableTOTP(ctx, user.ID, recoveryCodes[0])
+		require.NoError(t, err)
+
+		read, err := authRepo.Users.Read(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, read.TOTPEnabled)
+
+		_, err = authRepo.Users.VerifySecondFactor(ctx, user.ID, recoveryCodes[1])
+		assert.ErrorIs(t, err, repo.ErrTOTPNotEnabled)
+	})
+
+	t.Run("Challenge", func(t *testing.T) {
+		challenge, err := authRepo.Tokens.CreateTOTPChallenge(ctx, repo.TOTPChallenge{
+			UserID: user.ID,
+			Label:  new("laptop"),
+		})
+		require.NoError(t, err)
+
+		parsed, err := authRepo.Tokens.ParseTOTPChallenge(*challenge)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, parsed.UserID)
+		assert.Equal(t, "laptop", *parsed.Label)
+
+		// Challenges are consumed once.
+		require.NoError(t, authRepo.Tokens.ConsumeTOTPChallenge(ctx, *parsed))
+		err = authRepo.Tokens.ConsumeTOTPChallenge(ctx, *parsed)
+		assert.ErrorIs(t, err, repo.ErrInvalidChallenge)
+
+		// Challenge tokens are not access or refresh tokens, and refresh tokens are not
+		// challenges.
+		_, err = authRepo.Tokens.Authenticate(ctx, *challenge)
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+		_, _, err = authRepo.Tokens.Refresh(ctx, *challenge, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+
+		// Services verifying access tokens with the published keys must reject challenges.
+		_, err = jwt.Parse(
+			*challenge,
+			authRepo.Keys.Keyfunc,
+			jwt.WithValidMethods(authRepo.Keys.Algorithms()),
+		)
+		assert.Error(t, err)
+
+		refreshToken, err := authRepo.Tokens.CreateRefreshToken(ctx, user.ID)
+		require.NoError(t, err)
+		_, err = authRepo.Tokens.ParseTOTPChallenge(*refreshToken)
+		assert.ErrorIs(t, err, repo.ErrInvalidChallenge)
+
+		accessToken, err := authRepo.Tokens.CreateAccessToken(
+			api.Principal{Subject: user.ID.String()},
+		)
+		require.NoError(t, err)
+		_, err = authRepo.Tokens.ParseTOTPChallenge(*accessToken)
+		assert.ErrorIs(t, err, repo.ErrInvalidChallenge)
+	})
+}
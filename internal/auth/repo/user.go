@@ -0,0 +1,419 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/auth/data"
+	"github.com/r3d5un/islandwind/internal/auth/password"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// ErrInvalidCredentials is returned when the username or password is incorrect, or the user
+// is disabled. The cases are not distinguished, so that callers cannot enumerate usernames.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrInsecureInitialPassword is returned when the initial user would be created with an empty
+// password, or a password equal to the username.
+var ErrInsecureInitialPassword = errors.New("initial user password is empty or equal to username")
+
+type User struct {
+	ID          uuid.UUID  `json:"id"`
+	Username    string     `json:"username"`
+	Disabled    bool       `json:"disabled"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+	Role        scope.Role `json:"role"`
+	// TOTPEnabled is set when the user has two-factor authentication enabled.
+	TOTPEnabled bool `json:"totpEnabled"`
+	// Email is the address password reset emails are sent to, once verified.
+	Email           *string    `json:"email"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+}
+
+func newUserFromRow(row *data.User) *User {
+	return &User{
+		ID:              row.ID,
+		Username:        row.Username,
+		Disabled:        row.Disabled,
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+		LastLoginAt:     db.NullToPtr(row.LastLoginAt),
+		Role:            row.Role,
+		TOTPEnabled:     row.TOTPEnabled,
+		Email:           db.NullToPtr(row.Email),
+		EmailVerifiedAt: db.NullToPtr(row.EmailVerifiedAt),
+	}
+}
+
+type UserInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Role defaults to scope.RoleAuthor if empty.
+	Role scope.Role `json:"role"`
+}
+
+func (i UserInput) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", i.Username),
+		slog.String("role", string(i.Role)),
+	)
+}
+
+type UserService interface {
+	Create(ctx context.Context, input UserInput) (*User, error)
+	Read(ctx context.Context, ID uuid.UUID) (*User, error)
+	List(ctx context.Context, filter data.UserFilter) ([]*User, *data.Metadata, error)
+	// SetDisabled disables or enables the user. Disabling a user invalidates all refresh
+	// tokens issued to the user.
+	SetDisabled(ctx context.Context, ID uuid.UUID, disabled bool) (*User, error)
+	// SetRole changes the role of the user. Refresh tokens issued to the user remain valid,
+	// and access tokens issued after the change carry the scopes of the new role.
+	SetRole(ctx context.Context, ID uuid.UUID, role scope.Role) (*User, error)
+	// ChangePassword replaces the password of the user, and invalidates all refresh tokens
+	// issued to the user. If the current password is given, it must match the stored
+	// password, otherwise ErrInvalidCredentials is returned.
+	ChangePassword(
+		ctx context.Context,
+		ID uuid.UUID,
+		currentPassword *string,
+		newPassword string,
+	) error
+	// Authenticate returns the enabled user with the username and password, or
+	// ErrInvalidCredentials.
+	Authenticate(ctx context.Context, username string, password string) (*User, error)
+	// EnsureInitialUser creates an admin with the username and password if no users exist, so
+	// that the first login is possible on a new installation. ErrInsecureInitialPassword is
+	// returned instead if the password is empty or equal to the username.
+	EnsureInitialUser(ctx context.Context, username string, password string) (bool, error)
+	// EnrollTOTP starts TOTP enrolment, generating a new secret for the user. The secret is
+	// not used to log in until the enrolment is confirmed. ErrTOTPEnabled is returned if the
+	// user already has TOTP enabled.
+	EnrollTOTP(ctx context.Context, ID uuid.UUID) (*TOTPEnrollment, error)
+	// TOTPQRCode returns the provisioning URI of the pending enrolment as a QR code PNG.
+	TOTPQRCode(ctx context.Context, ID uuid.UUID) ([]byte, error)
+	// ConfirmTOTP enables TOTP if the code is valid for the pending enrolment, and returns the
+	// recovery codes of the user.
+	ConfirmTOTP(ctx context.Context, ID uuid.UUID, code string) ([]string, error)
+	// RegenerateRecoveryCodes replaces the recovery codes of the user, if the TOTP or recovery
+	// code is valid.
+	RegenerateRecoveryCodes(ctx context.Context, ID uuid.UUID, code string) ([]string, error)
+	// DisableTOTP disables TOTP and deletes the recovery codes of the user, if the TOTP or
+	// recovery code is valid.
+	DisableTOTP(ctx context.Context, ID uuid.UUID, code string) error
+	// VerifySecondFactor returns the enabled user if the TOTP or recovery code is valid, or
+	// ErrInvalidTOTPCode. Each code is accepted only once.
+	VerifySecondFactor(ctx context.Context, ID uuid.UUID, code string) (*User, error)
+}
+
+type UserRepository struct {
+	models *data.Models
+	// totpIssuer names the service in the provisioning URIs of authenticator apps.
+	totpIssuer string
+	// dummyHash is verified against when the username does not exist, so that the response
+	// time does not reveal whether a username exists.
+	dummyHash func() (string, error)
+}
+
+func NewUserRepository(models *data.Models, totpIssuer string) UserService {
+	return &UserRepository{
+		models:     models,
+		totpIssuer: totpIssuer,
+		dummyHash: sync.OnceValues(func() (string, error) {
+			return password.Hash(uuid.NewString())
+		}),
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, input UserInput) (*User, error) {
+	if input.Role == "" {
+		input.Role = scope.RoleAuthor
+	}
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("username", input.Username),
+		slog.String("role", string(input.Role)),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "hashing password")
+	hash, err := password.Hash(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "creating user")
+	row, err := r.models.Users.Insert(ctx, data.UserInput{
+		Username:     input.Username,
+		PasswordHash: hash,
+		Role:         input.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user created", slog.String("id", row.ID.String()))
+
+	return newUserFromRow(row), nil
+}
+
+func (r *UserRepository) Read(ctx context.Context, ID uuid.UUID) (*User, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading user")
+	row, err := r.models.Users.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	ensure.Equal(row.ID, ID, "user ID must match")
+	logger.LogAttrs(ctx, slog.LevelInfo, "user retrieved")
+
+	return newUserFromRow(row), nil
+}
+
+func (r *UserRepository) List(
+	ctx context.Context,
+	filter data.UserFilter,
+) ([]*User, *data.Metadata, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"users",
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading users")
+	rows, metadata, err := r.models.Users.SelectMany(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	ensure.NotNil(metadata, "user metadata must not be nil")
+
+	users := make([]*User, len(rows))
+	for i, row := range rows {
+		users[i] = newUserFromRow(row)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "users retrieved")
+
+	return users, metadata, nil
+}
+
+func (r *UserRepository) SetDisabled(
+	ctx context.Context,
+	ID uuid.UUID,
+	disabled bool,
+) (*User, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+		slog.Bool("disabled", disabled),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "updating user")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	row, err := r.models.Users.UpdateTx(ctx, tx, data.UserPatch{
+		ID:       ID,
+		Disabled: sql.Null[bool]{V: disabled, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		if _, err := r.models.RefreshTokens.InvalidateByUserTx(ctx, tx, ID); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user updated")
+
+	return newUserFromRow(row), nil
+}
+
+func (r *UserRepository) SetRole(
+	ctx context.Context,
+	ID uuid.UUID,
+	role scope.Role,
+) (*User, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+		slog.String("role", string(role)),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "updating role")
+	row, err := r.models.Users.Update(ctx, data.UserPatch{
+		ID:   ID,
+		Role: sql.Null[scope.Role]{V: role, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "role updated")
+
+	return newUserFromRow(row), nil
+}
+
+func (r *UserRepository) ChangePassword(
+	ctx context.Context,
+	ID uuid.UUID,
+	currentPassword *string,
+	newPassword string,
+) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "changing password")
+	tx, rollback, err := r.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	if currentPassword != nil {
+		row, err := r.models.Users.SelectOneTx(ctx, tx, ID)
+		if err != nil {
+			return err
+		}
+		if err := password.Verify(*currentPassword, row.PasswordHash); err != nil {
+			logger.LogAttrs(
+				ctx, slog.LevelInfo, "current password invalid", slog.String("error", err.Error()),
+			)
+			return ErrInvalidCredentials
+		}
+	}
+
+	hash, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	_, err = r.models.Users.UpdateTx(ctx, tx, data.UserPatch{
+		ID:           ID,
+		PasswordHash: sql.Null[string]{V: hash, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := r.models.RefreshTokens.InvalidateByUserTx(ctx, tx, ID); err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "password changed")
+
+	return nil
+}
+
+func (r *UserRepository) Authenticate(
+	ctx context.Context,
+	username string,
+	input string,
+) (*User, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"user",
+		slog.String("username", username),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "authenticating user")
+	row, err := r.models.Users.SelectByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, db.ErrRecordNotFound) {
+			return nil, err
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "user not found")
+		hash, err := r.dummyHash()
+		if err != nil {
+			return nil, err
+		}
+		_ = password.Verify(input, hash)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := password.Verify(input, row.PasswordHash); err != nil {
+		logger.LogAttrs(
+			ctx, slog.LevelInfo, "password invalid", slog.String("error", err.Error()),
+		)
+		return nil, ErrInvalidCredentials
+	}
+	if row.Disabled {
+		logger.LogAttrs(ctx, slog.LevelInfo, "user disabled")
+		return nil, ErrInvalidCredentials
+	}
+
+	patch := data.UserPatch{
+		ID:          row.ID,
+		LastLoginAt: sql.Null[time.Time]{V: time.Now().UTC(), Valid: true},
+	}
+	if password.NeedsRehash(row.PasswordHash) {
+		logger.LogAttrs(ctx, slog.LevelInfo, "rehashing password")
+		hash, err := password.Hash(input)
+		if err != nil {
+			return nil, err
+		}
+		patch.PasswordHash = sql.Null[string]{V: hash, Valid: true}
+	}
+	row, err = r.models.Users.Update(ctx, patch)
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "user authenticated", slog.String("id", row.ID.String()))
+
+	return newUserFromRow(row), nil
+}
+
+func (r *UserRepository) EnsureInitialUser(
+	ctx context.Context,
+	username string,
+	password string,
+) (bool, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "checking for existing users")
+	count, err := r.models.Users.Count(ctx)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if password == "" || password == username {
+		return false, ErrInsecureInitialPassword
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "no users found, creating initial user")
+	_, err = r.Create(ctx, UserInput{
+		Username: username,
+		Password: password,
+		Role:     scope.RoleAdmin,
+	})
+	switch {
+	case errors.Is(err, db.ErrUniqueConstraintViolation):
+		// Another instance created the user first.
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
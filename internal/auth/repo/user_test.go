@@ -0,0 +1,134 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/config"
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	database "github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	const initialPassword string = "an initial password"
+	user, err := authRepo.Users.Create(ctx, repo.UserInput{
+		Username: "Carol",
+		Password: initialPassword,
+	})
+	require.NoError(t, err)
+	// Users are authors unless another role is given.
+	assert.Equal(t, scope.RoleAuthor, user.Role)
+
+	t.Run("Authenticate", func(t *testing.T) {
+		authenticated, err := authRepo.Users.Authenticate(ctx, "carol", initialPassword)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, authenticated.ID)
+		assert.NotNil(t, authenticated.LastLoginAt)
+
+		_, err = authRepo.Users.Authenticate(ctx, "carol", "incorrect")
+		assert.ErrorIs(t, err, repo.ErrInvalidCredentials)
+
+		_, err = authRepo.Users.Authenticate(ctx, "nobody", initialPassword)
+		assert.ErrorIs(t, err, repo.ErrInvalidCredentials)
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		err := authRepo.Users.ChangePassword(ctx, user.ID, new("incorrect"), "a new password")
+		assert.ErrorIs(t, err, repo.ErrInvalidCredentials)
+
+		refreshToken, err := authRepo.Tokens.CreateRefreshToken(ctx, user.ID)
+		require.NoError(t, err)
+
+		err = authRepo.Users.ChangePassword(ctx, user.ID, new(initialPassword), "a new password")
+		require.NoError(t, err)
+
+		_, err = authRepo.Users.Authenticate(ctx, "carol", initialPassword)
+		assert.ErrorIs(t, err, repo.ErrInvalidCredentials)
+		_, err = authRepo.Users.Authenticate(ctx, "carol", "a new password")
+		assert.NoError(t, err)
+
+		// Changing the password logs the user out everywhere.
+		_, _, err = authRepo.Tokens.Refresh(ctx, *refreshToken, repo.Device{})
+		assert.ErrorIs(t, err, repo.ErrUnauthorized)
+	})
+
+	t.Run("SetDisabled", func(t *testing.T) {
+		disabled, err := authRepo.Users.SetDisabled(ctx, user.ID, true)
+		require.NoError(t, err)
+		assert.True(t, disabled.Disabled)
+
+		_, err = authRepo.Users.Authenticate(ctx, "carol", "a new password")
+		assert.ErrorIs(t, err, repo.ErrInvalidCredentials)
+
+		enabled, err := authRepo.Users.SetDisabled(ctx, user.ID, false)
+		require.NoError(t, err)
+		assert.False(t, enabled.Disabled)
+	})
+
+	t.Run("SetRole", func(t *testing.T) {
+		updated, err := authRepo.Users.SetRole(ctx, user.ID, scope.RoleEditor)
+		require.NoError(t, err)
+		assert.Equal(t, scope.RoleEditor, updated.Role)
+	})
+
+	t.Run("EnsureInitialUser", func(t *testing.T) {
+		// Users exist, so no user is created.
+		created, err := authRepo.Users.EnsureInitialUser(ctx, "admin", "password")
+		require.NoError(t, err)
+		assert.False(t, created)
+	})
+}
+
+func TestEnsureInitialUser(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	// The shared test database has users, so the initial user is created in a database of its
+	// own.
+	dbContainer, shutdown, err := database.NewPostgresTestcontainer(ctx)
+	require.NoError(t, err)
+	t.Cleanup(shutdown)
+	db, cfg, err := database.NewTestPool(ctx, dbContainer)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	users := repo.NewRepository(
+		db, new(cfg.TimeoutDuration()), config.Config{}, nil, mailbox, nil,
+	).Users
+
+	t.Run("InsecurePassword", func(t *testing.T) {
+		for _, password := range []string{"", "admin"} {
+			created, err := users.EnsureInitialUser(ctx, "admin", password)
+			assert.ErrorIs(t, err, repo.ErrInsecureInitialPassword)
+			assert.False(t, created)
+		}
+	})
+
+	t.Run("Created", func(t *testing.T) {
+		created, err := users.EnsureInitialUser(ctx, "admin", testUserPassword)
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		user, err := users.Authenticate(ctx, "admin", testUserPassword)
+		require.NoError(t, err)
+		assert.Equal(t, scope.RoleAdmin, user.Role)
+
+		created, err = users.EnsureInitialUser(ctx, "admin", testUserPassword)
+		require.NoError(t, err)
+		assert.False(t, created)
+	})
+}
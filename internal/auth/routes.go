@@ -10,109 +10,514 @@ import (
 	"github.com/r3d5un/islandwind/internal/api"
 	"github.com/r3d5un/islandwind/internal/auth/handlers"
 	"github.com/r3d5un/islandwind/internal/auth/middleware"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
 	"github.com/rs/cors"
 )
 
-type authType int
-
-const (
-	basicAuth authType = iota
-	accessToken
-	noAuth
-)
-
 func (m *Module) addRoutes(ctx context.Context) {
-	routes := []struct {
-		Path     string `json:"path"`
-		handler  http.HandlerFunc
-		Method   string `json:"method"`
-		authType authType
-	}{
+	type route struct {
+		Path    string `json:"path"`
+		handler http.HandlerFunc
+		Method  string `json:"method"`
+		// Scopes are required of the access token authenticating the request. Routes
+		// without scopes are public, or authenticate the request themselves.
+		Scopes []string `json:"scopes"`
+	}
+	routes := []route{
 		// healthcheck
 		{
 			"/api/v1/auth/healthcheck",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			noAuth,
+			nil,
 		},
 		{
 			"/api/v1/auth/healthcheck",
 			m.healthcheckHandler,
 			http.MethodGet,
-			noAuth,
+			nil,
+		},
+		// keys verifying access tokens, published for other services
+		{
+			"/.well-known/jwks.json",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/.well-known/jwks.json",
+			handlers.JWKSHandler(m.repo.Keys),
+			http.MethodGet,
+			nil,
 		},
 		// login
 		{
 			"/api/v1/auth/login",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			noAuth,
+			nil,
 		},
 		{
 			"/api/v1/auth/login",
-			handlers.LoginHandler(m.repo.Tokens),
-			http.MethodPost,
 			// Basic authentication should only be used for logging in. Other resources
 			// should be accessible with access tokens.
-			basicAuth,
+			middleware.BasicAuthMiddleware(
+				handlers.LoginHandler(
+					m.repo.Tokens,
+					m.repo.Users,
+					m.cfg.Server.ClientIPHeader,
+				),
+				m.repo.Users,
+				m.repo.Lockouts,
+				m.repo.Audit,
+				m.cfg.Server.ClientIPHeader,
+			).ServeHTTP,
+			http.MethodPost,
+			nil,
+		},
+		{
+			"/api/v1/auth/login/totp",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/login/totp",
+			handlers.TOTPLoginHandler(
+				m.repo.Tokens,
+				m.repo.Users,
+				m.repo.Lockouts,
+				m.repo.Audit,
+				m.cfg.Server.ClientIPHeader,
+			),
+			http.MethodPost,
+			// The challenge token issued by the login route authenticates the request.
+			nil,
 		},
 		// logout
 		{
 			"/api/v1/auth/logout",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			noAuth,
+			nil,
 		},
 		{
 			"/api/v1/auth/logout",
 			handlers.LogoutHandler(m.repo.Tokens),
 			http.MethodPost,
-			// Basic authentication should only be used for logging in. Other resources
-			// should be accessible with access tokens.
-			noAuth,
+			nil,
 		},
 		// refresh
 		{
 			"/api/v1/auth/refresh",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			noAuth,
+			nil,
 		},
 		{
 			"/api/v1/auth/refresh",
-			handlers.RefreshHandler(m.repo.Tokens),
+			handlers.RefreshHandler(m.repo.Tokens, m.cfg.Server.ClientIPHeader),
 			http.MethodPost,
 			// The RefreshHandler authenticates and validates the request as part of the
 			// refresh process. No extra auth required.
-			noAuth,
+			nil,
+		},
+		// token introspection and revocation for other services
+		{
+			"/api/v1/auth/introspect",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/introspect",
+			middleware.ClientAuthMiddleware(
+				handlers.IntrospectHandler(m.repo.Tokens),
+				m.clients,
+			).ServeHTTP,
+			http.MethodPost,
+			// The ClientAuthMiddleware authenticates the client credentials of the request.
+			nil,
+		},
+		{
+			"/api/v1/auth/revoke",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/revoke",
+			middleware.ClientAuthMiddleware(
+				handlers.RevokeHandler(m.repo.Tokens),
+				m.clients,
+			).ServeHTTP,
+			http.MethodPost,
+			nil,
+		},
+		// sessions
+		{
+			"/api/v1/auth/session",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/session",
+			handlers.ListSessionsHandler(m.repo.Tokens),
+			http.MethodGet,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/session",
+			handlers.RevokeOtherSessionsHandler(m.repo.Tokens),
+			http.MethodDelete,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/session/{id}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/session/{id}",
+			handlers.RevokeSessionHandler(m.repo.Tokens),
+			http.MethodDelete,
+			[]string{scope.AuthSelf},
+		},
+		// two-factor authentication
+		{
+			"/api/v1/auth/totp",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/totp",
+			handlers.EnrollTOTPHandler(m.repo.Users),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/totp",
+			handlers.DisableTOTPHandler(m.repo.Users),
+			http.MethodDelete,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/totp/qr",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/totp/qr",
+			handlers.TOTPQRCodeHandler(m.repo.Users),
+			http.MethodGet,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/totp/confirm",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/totp/confirm",
+			handlers.ConfirmTOTPHandler(m.repo.Users),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/totp/recoveryCodes",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/totp/recoveryCodes",
+			handlers.RegenerateRecoveryCodesHandler(m.repo.Users),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		// personal access tokens
+		{
+			"/api/v1/auth/apiToken",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/apiToken",
+			handlers.PostAPITokenHandler(m.repo.APITokens),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/apiToken",
+			handlers.ListAPITokensHandler(m.repo.APITokens),
+			http.MethodGet,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/apiToken/{id}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/apiToken/{id}",
+			handlers.RevokeAPITokenHandler(m.repo.APITokens),
+			http.MethodDelete,
+			[]string{scope.AuthSelf},
 		},
 		{
 			"/api/v1/auth/refreshToken",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			noAuth,
+			nil,
 		},
 		{
 			"/api/v1/auth/refreshToken",
 			handlers.ListRefreshTokenHandler(m.repo.Tokens),
 			http.MethodGet,
-			accessToken,
+			[]string{scope.AuthTokens},
 		},
 		{
 			"/api/v1/auth/refreshToken",
 			handlers.PatchRefreshTokenHandler(m.repo.Tokens),
 			http.MethodPatch,
-			accessToken,
+			[]string{scope.AuthTokens},
 		},
 		{
 			"/api/v1/auth/refreshToken",
 			handlers.DeleteRefreshTokenHandler(m.repo.Tokens),
 			http.MethodDelete,
-			accessToken,
+			[]string{scope.AuthTokens},
+		},
+		// failed login attempts and lockouts
+		{
+			"/api/v1/auth/lockout",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/lockout",
+			handlers.ListLoginAttemptsHandler(m.repo.Lockouts),
+			http.MethodGet,
+			[]string{scope.AuthUsers},
+		},
+		{
+			"/api/v1/auth/lockout/{kind}/{key}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/lockout/{kind}/{key}",
+			handlers.ClearLoginAttemptsHandler(m.repo.Lockouts),
+			http.MethodDelete,
+			[]string{scope.AuthUsers},
+		},
+		// audit events
+		{
+			"/api/v1/auth/audit",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/audit",
+			handlers.ListAuditEventsHandler(m.repo.Audit),
+			http.MethodGet,
+			[]string{scope.AuthAudit},
+		},
+		// users
+		{
+			"/api/v1/auth/user",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user",
+			handlers.PostUserHandler(m.repo.Users),
+			http.MethodPost,
+			[]string{scope.AuthUsers},
+		},
+		{
+			"/api/v1/auth/user",
+			handlers.ListUserHandler(m.repo.Users),
+			http.MethodGet,
+			[]string{scope.AuthUsers},
+		},
+		{
+			"/api/v1/auth/user/{id}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}",
+			handlers.GetUserHandler(m.repo.Users),
+			http.MethodGet,
+			[]string{scope.AuthUsers},
+		},
+		{
+			"/api/v1/auth/user/{id}/disable",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}/disable",
+			handlers.DisableUserHandler(m.repo.Users, true),
+			http.MethodPost,
+			[]string{scope.AuthUsers},
+		},
+		{
+			"/api/v1/auth/user/{id}/enable",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}/enable",
+			handlers.DisableUserHandler(m.repo.Users, false),
+			http.MethodPost,
+			[]string{scope.AuthUsers},
+		},
+		{
+			"/api/v1/auth/user/{id}/role",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}/role",
+			handlers.SetRoleHandler(m.repo.Users),
+			http.MethodPost,
+			[]string{scope.AuthUsers},
+		},
+		// Users may change their own password. Changing the password of others requires the
+		// auth:users scope, which is checked by the handler.
+		{
+			"/api/v1/auth/user/{id}/password",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}/password",
+			handlers.ChangePasswordHandler(m.repo.Users),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		// Users may change and verify their own email address. Doing so for others requires the
+		// auth:users scope, which is checked by the handlers.
+		{
+			"/api/v1/auth/user/{id}/email",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}/email",
+			handlers.SetEmailHandler(m.repo.Emails),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		{
+			"/api/v1/auth/user/{id}/email/verification",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/user/{id}/email/verification",
+			handlers.SendEmailVerificationHandler(m.repo.Emails),
+			http.MethodPost,
+			[]string{scope.AuthSelf},
+		},
+		// The tokens sent by email authenticate the requests to verify email addresses and
+		// reset passwords. No extra auth required.
+		{
+			"/api/v1/auth/email/verify",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/email/verify",
+			handlers.VerifyEmailHandler(m.repo.Emails),
+			http.MethodPost,
+			nil,
+		},
+		{
+			"/api/v1/auth/password/reset",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/password/reset",
+			handlers.RequestPasswordResetHandler(m.repo.Emails),
+			http.MethodPost,
+			nil,
+		},
+		{
+			"/api/v1/auth/password/reset/confirm",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/auth/password/reset/confirm",
+			handlers.ResetPasswordHandler(m.repo.Emails),
+			http.MethodPost,
+			nil,
 		},
 	}
 
-	corsMiddleware := cors.New(cors.Options{
+	// Users log in with the OpenID Connect provider, if configured, by visiting the login
+	// route. The login and callback routes are navigated to by the browser, and the callback
+	// authenticates the request with the login cookie and the provider. No extra auth required.
+	if m.repo.OIDC != nil {
+		routes = append(routes,
+			route{
+				"/api/v1/auth/oidc/login",
+				api.CorsPreflightHandler(),
+				http.MethodOptions,
+				nil,
+			},
+			route{
+				"/api/v1/auth/oidc/login",
+				handlers.OIDCLoginHandler(m.repo.OIDC),
+				http.MethodGet,
+				nil,
+			},
+			route{
+				"/api/v1/auth/oidc/callback",
+				api.CorsPreflightHandler(),
+				http.MethodOptions,
+				nil,
+			},
+			route{
+				"/api/v1/auth/oidc/callback",
+				handlers.OIDCCallbackHandler(
+					m.repo.OIDC,
+					m.repo.Tokens,
+					m.cfg.Auth.OIDC.PostLoginURL,
+					m.cfg.Server.ClientIPHeader,
+				),
+				http.MethodGet,
+				nil,
+			},
+		)
+	}
+
+	corsOptions := cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{
 			http.MethodPost,
@@ -122,8 +527,14 @@ func (m *Module) addRoutes(ctx context.Context) {
 			http.MethodOptions,
 			http.MethodHead,
 		},
-		AllowedHeaders: []string{"Authorization", "Content-Type"},
-	})
+		AllowedHeaders: []string{"Authorization", "Content-Type", handlers.CSRFTokenHeader},
+	}
+	// Browsers only send the refresh token cookie cross-origin to origins allowed explicitly.
+	if len(m.cfg.Auth.AllowedOrigins) > 0 {
+		corsOptions.AllowedOrigins = m.cfg.Auth.AllowedOrigins
+		corsOptions.AllowCredentials = true
+	}
+	corsMiddleware := cors.New(corsOptions)
 
 	m.logger.LogAttrs(ctx, slog.LevelInfo, "adding routes")
 	for _, route := range routes {
@@ -146,22 +557,16 @@ func (m *Module) addRoutes(ctx context.Context) {
 			func(next http.Handler) http.Handler {
 				return corsMiddleware.Handler(next)
 			},
-			// Require authentication for write requests
+			// Record the client of the request with its audit events
 			func(next http.Handler) http.Handler {
-				switch route.authType {
-				case noAuth:
+				return middleware.DeviceMiddleware(next, m.cfg.Server.ClientIPHeader)
+			},
+			// Require an access token granting the scopes of the route
+			func(next http.Handler) http.Handler {
+				if len(route.Scopes) == 0 {
 					return next
-				case basicAuth:
-					handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-						next.ServeHTTP(w, r)
-					})
-					return middleware.BasicAuthMiddleware(handlerFunc, m.cfg.BasicAuth)
-				case accessToken:
-					fallthrough
-				default:
-					return m.AccessTokenMiddleware(next)
-
 				}
+				return m.AccessTokenMiddleware(api.RequireScope(route.Scopes...)(next))
 			},
 		)
 
@@ -0,0 +1,99 @@
+// Package scope defines the scopes carried by access tokens, and the roles granting them.
+//
+// Route tables declare the scopes required to call a route, and the scopes of a user are
+// determined by their role when tokens are issued.
+package scope
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// BlogWrite allows creating, updating and restoring blog posts, and reading revisions.
+	BlogWrite string = "blog:write"
+	// BlogDelete allows deleting and purging blog posts.
+	BlogDelete string = "blog:delete"
+	// BlogModerate allows moderating, editing and deleting comments.
+	BlogModerate string = "blog:moderate"
+	// MediaRead allows listing media assets and reading their metadata.
+	MediaRead string = "media:read"
+	// MediaWrite allows uploading media assets.
+	MediaWrite string = "media:write"
+	// MediaDelete allows deleting media assets.
+	MediaDelete string = "media:delete"
+	// AuthSelf allows users to manage their own account, e.g. changing their password.
+	AuthSelf string = "auth:self"
+	// AuthTokens allows listing, invalidating and deleting the refresh tokens of all users.
+	AuthTokens string = "auth:tokens"
+	// AuthUsers allows managing all users.
+	AuthUsers string = "auth:users"
+	// AuthAudit allows reading the audit events of all users.
+	AuthAudit string = "auth:audit"
+)
+
+// Role is a named set of scopes assigned to users.
+type Role string
+
+const (
+	// RoleAdmin is granted all scopes.
+	RoleAdmin Role = "admin"
+	// RoleEditor manages all blog content and media, but not users or tokens.
+	RoleEditor Role = "editor"
+	// RoleAuthor writes blog posts and uploads media, but cannot delete or moderate content.
+	RoleAuthor Role = "author"
+)
+
+// Roles lists all roles, from the most to the least privileged.
+var Roles = []Role{RoleAdmin, RoleEditor, RoleAuthor}
+
+var roleScopes = map[Role][]string{
+	RoleAdmin: {
+		BlogWrite,
+		BlogDelete,
+		BlogModerate,
+		MediaRead,
+		MediaWrite,
+		MediaDelete,
+		AuthSelf,
+		AuthTokens,
+		AuthUsers,
+		AuthAudit,
+	},
+	RoleEditor: {
+		BlogWrite,
+		BlogDelete,
+		BlogModerate,
+		MediaRead,
+		MediaWrite,
+		MediaDelete,
+		AuthSelf,
+	},
+	RoleAuthor: {
+		BlogWrite,
+		MediaRead,
+		MediaWrite,
+		AuthSelf,
+	},
+}
+
+// Valid reports whether the role is one of the defined roles.
+func (r Role) Valid() bool {
+	_, ok := roleScopes[r]
+	return ok
+}
+
+// Scopes returns the scopes granted by the role. Unknown roles are granted no scopes.
+func (r Role) Scopes() []string {
+	return slices.Clone(roleScopes[r])
+}
+
+// Format encodes the scopes as the space-delimited scope claim of RFC 8693.
+func Format(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// Parse decodes a space-delimited scope claim.
+func Parse(claim string) []string {
+	return strings.Fields(claim)
+}
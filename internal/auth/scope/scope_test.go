@@ -0,0 +1,32 @@
+package scope_test
+
+import (
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole(t *testing.T) {
+	for _, role := range scope.Roles {
+		assert.True(t, role.Valid(), role)
+		// Every user can manage their own account.
+		assert.Contains(t, role.Scopes(), scope.AuthSelf, role)
+	}
+
+	assert.False(t, scope.Role("owner").Valid())
+	assert.Empty(t, scope.Role("owner").Scopes())
+
+	assert.Contains(t, scope.RoleAdmin.Scopes(), scope.AuthUsers)
+	assert.NotContains(t, scope.RoleEditor.Scopes(), scope.AuthUsers)
+	assert.Contains(t, scope.RoleAdmin.Scopes(), scope.AuthAudit)
+	assert.NotContains(t, scope.RoleEditor.Scopes(), scope.AuthAudit)
+	assert.NotContains(t, scope.RoleAuthor.Scopes(), scope.BlogDelete)
+}
+
+func TestFormat(t *testing.T) {
+	scopes := []string{scope.BlogWrite, scope.MediaRead}
+	assert.Equal(t, "blog:write media:read", scope.Format(scopes))
+	assert.Equal(t, scopes, scope.Parse(" blog:write  media:read "))
+	assert.Empty(t, scope.Parse(""))
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+)
+
+// tokenCleanupMetrics are published at /debug/vars. Runs counts the runs of the worker,
+// LockHeld the runs skipped because another instance held the cleanup lock, Deleted the
+// refresh tokens deleted, and Errors the failed runs.
+var tokenCleanupMetrics = expvar.NewMap("auth.refreshTokenCleanup")
+
+// tokenCleanup periodically deletes expired refresh tokens, so that the refresh token table does
+// not grow without bound. Only the instance holding the cleanup advisory lock deletes tokens.
+type tokenCleanup struct {
+	*db.PeriodicWorker
+	tokens    repo.TokenCleaner
+	batchSize int
+	logger    *slog.Logger
+}
+
+// newTokenCleanup returns the worker deleting expired refresh tokens. The worker is disabled if
+// the interval or the batch size is not positive.
+func newTokenCleanup(
+	tokens repo.TokenCleaner,
+	interval time.Duration,
+	batchSize int,
+	logger *slog.Logger,
+) *tokenCleanup {
+	w := &tokenCleanup{
+		tokens:    tokens,
+		batchSize: batchSize,
+		logger: logger.With(slog.Group(
+			"tokenCleanup",
+			slog.Duration("interval", interval),
+			slog.Int("batchSize", batchSize),
+		)),
+	}
+	if batchSize <= 0 {
+		interval = 0
+	}
+	w.PeriodicWorker = db.NewPeriodicWorker("refresh token cleanup", interval, w.run, w.logger)
+
+	return w
+}
+
+// run deletes batches of expired tokens until a batch is not full, so that a backlog of expired
+// tokens does not have to wait for several intervals. Each batch is deleted in its own
+// transaction, keeping the rows locked for a short time.
+func (w *tokenCleanup) run(ctx context.Context) {
+	tokenCleanupMetrics.Add("Runs", 1)
+
+	var deleted int64
+	for ctx.Err() == nil {
+		result, err := w.tokens.DeleteExpired(ctx, w.batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			tokenCleanupMetrics.Add("Errors", 1)
+			w.logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"unable to delete expired refresh tokens",
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		if !result.Locked {
+			tokenCleanupMetrics.Add("LockHeld", 1)
+			return
+		}
+		tokenCleanupMetrics.Add("Deleted", result.Deleted)
+		deleted += result.Deleted
+		if result.Deleted < int64(w.batchSize) {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		w.logger.LogAttrs(
+			ctx, slog.LevelInfo, "expired refresh tokens deleted", slog.Int64("deleted", deleted),
+		)
+	}
+}
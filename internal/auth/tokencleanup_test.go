@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/auth/repo"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTokenCleaner struct {
+	mu sync.Mutex
+	// expired is the number of expired tokens left to delete.
+	expired int64
+	locked  bool
+	calls   int
+}
+
+func (f *fakeTokenCleaner) DeleteExpired(
+	ctx context.Context,
+	batchSize int,
+) (*repo.ExpiredTokenResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if !f.locked {
+		return &repo.ExpiredTokenResult{Locked: false}, nil
+	}
+	deleted := min(f.expired, int64(batchSize))
+	f.expired -= deleted
+	return &repo.ExpiredTokenResult{Locked: true, Deleted: deleted}, nil
+}
+
+func (f *fakeTokenCleaner) state() (int64, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.expired, f.calls
+}
+
+// tokenCleanupMetric returns the current value of the metric.
+func tokenCleanupMetric(name string) int64 {
+	if v, ok := tokenCleanupMetrics.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestTokenCleanup(t *testing.T) {
+	logger := testsuite.NewTestLogger()
+
+	t.Run("DeletesInBatches", func(t *testing.T) {
+		tokens := &fakeTokenCleaner{expired: 25, locked: true}
+		deleted := tokenCleanupMetric("Deleted")
+		worker := newTokenCleanup(tokens, time.Hour, 10, &logger)
+
+		// The last batch is not full, so the worker waits for the next interval.
+		worker.run(context.Background())
+		expired, calls := tokens.state()
+		assert.Zero(t, expired)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, int64(25), tokenCleanupMetric("Deleted")-deleted)
+	})
+
+	t.Run("LockHeld", func(t *testing.T) {
+		tokens := &fakeTokenCleaner{expired: 25}
+		lockHeld := tokenCleanupMetric("LockHeld")
+		worker := newTokenCleanup(tokens, time.Hour, 10, &logger)
+
+		worker.run(context.Background())
+		expired, calls := tokens.state()
+		assert.Equal(t, int64(25), expired)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, int64(1), tokenCleanupMetric("LockHeld")-lockHeld)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		for _, batchSize := range []int{0, 10} {
+			tokens := &fakeTokenCleaner{expired: 25, locked: true}
+			interval := time.Millisecond
+			if batchSize > 0 {
+				interval = 0
+			}
+			worker := newTokenCleanup(tokens, interval, batchSize, &logger)
+
+			worker.Start(context.Background())
+			worker.Stop()
+			_, calls := tokens.state()
+			assert.Zero(t, calls)
+		}
+	})
+}
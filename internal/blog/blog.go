@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/blog/handlers"
 	"github.com/r3d5un/islandwind/internal/blog/repo"
 	"github.com/r3d5un/islandwind/internal/cache"
 	"github.com/r3d5un/islandwind/internal/config"
@@ -24,6 +26,9 @@ type Module struct {
 	repo   repo.Repository
 	mux    *http.ServeMux
 	auth   AuthMiddlewareService
+
+	scheduler      *publishScheduler
+	commentLimiter handlers.CommentRateLimiter
 }
 
 type AuthMiddlewareService interface {
@@ -52,6 +57,18 @@ func NewModule(
 		),
 		auth: authModule,
 	}
+	module.scheduler = newPublishScheduler(
+		module.repo.Posts,
+		cfg.Blog.PublishInterval(),
+		logger,
+	)
+
+	if cfg.Blog.CommentRateLimit > 0 && cfg.Blog.CommentRateWindow() > 0 {
+		module.commentLimiter = api.NewRateLimiter(
+			cfg.Blog.CommentRateLimit,
+			cfg.Blog.CommentRateWindow(),
+		)
+	}
 
 	return &module, nil
 }
@@ -59,8 +76,10 @@ func NewModule(
 func (m *Module) Start(ctx context.Context, mux *http.ServeMux) {
 	m.mux = mux
 	m.addRoutes(ctx)
+	m.scheduler.Start(ctx)
 }
 
 func (m *Module) Shutdown() {
 	m.logger.LogAttrs(context.Background(), slog.LevelInfo, "shutting down module")
+	m.scheduler.Stop()
 }
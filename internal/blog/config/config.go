@@ -0,0 +1,44 @@
+package config
+
+import "time"
+
+type Config struct {
+	// PublishIntervalSeconds is how often the scheduler checks for blog posts that are due to
+	// be published or unpublished.
+	//
+	// Set through the ISLANDWIND_BLOG_PUBLISHINTERVALSECONDS environment variable.
+	PublishIntervalSeconds int `json:"publishIntervalSeconds"`
+	// SiteURL is the base URL of the website presenting the blog, used to link to posts.
+	//
+	// Set through the ISLANDWIND_BLOG_SITEURL environment variable.
+	SiteURL string `json:"siteUrl"`
+	// FeedTitle is the title of the RSS, Atom and JSON feeds.
+	//
+	// Set through the ISLANDWIND_BLOG_FEEDTITLE environment variable.
+	FeedTitle string `json:"feedTitle"`
+	// FeedDescription is the description of the RSS, Atom and JSON feeds.
+	//
+	// Set through the ISLANDWIND_BLOG_FEEDDESCRIPTION environment variable.
+	FeedDescription string `json:"feedDescription"`
+	// FeedSize is the number of most recent posts included in the feeds.
+	//
+	// Set through the ISLANDWIND_BLOG_FEEDSIZE environment variable.
+	FeedSize int `json:"feedSize"`
+	// CommentRateLimit is the number of comments a client may submit within the
+	// CommentRateWindowSeconds window.
+	//
+	// Set through the ISLANDWIND_BLOG_COMMENTRATELIMIT environment variable.
+	CommentRateLimit int `json:"commentRateLimit"`
+	// CommentRateWindowSeconds is the window the CommentRateLimit applies to.
+	//
+	// Set through the ISLANDWIND_BLOG_COMMENTRATEWINDOWSECONDS environment variable.
+	CommentRateWindowSeconds int `json:"commentRateWindowSeconds"`
+}
+
+func (c Config) PublishInterval() time.Duration {
+	return time.Duration(c.PublishIntervalSeconds) * time.Second
+}
+
+func (c Config) CommentRateWindow() time.Duration {
+	return time.Duration(c.CommentRateWindowSeconds) * time.Second
+}
@@ -0,0 +1,340 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// CommentStatus is the moderation status of a comment. New comments are pending until they
+// are approved or rejected by a moderator.
+type CommentStatus string
+
+const (
+	CommentPending  CommentStatus = "pending"
+	CommentApproved CommentStatus = "approved"
+	CommentRejected CommentStatus = "rejected"
+)
+
+// Comment is the database record of a reader comment on a blog post.
+type Comment struct {
+	ID     uuid.UUID `json:"id"     db:"id"`
+	PostID uuid.UUID `json:"postId" db:"post_id"`
+	// ParentID is the ID of the comment replied to. Null for top-level comments.
+	ParentID    sql.Null[uuid.UUID] `json:"parentId"    db:"parent_id"`
+	AuthorName  string              `json:"authorName"  db:"author_name"`
+	AuthorEmail sql.Null[string]    `json:"authorEmail" db:"author_email"`
+	Content     string              `json:"content"     db:"content"`
+	Status      CommentStatus       `json:"status"      db:"status"`
+	CreatedAt   time.Time           `json:"createdAt"   db:"created_at"`
+	UpdatedAt   time.Time           `json:"updatedAt"   db:"updated_at"`
+	ModeratedAt sql.Null[time.Time] `json:"moderatedAt" db:"moderated_at"`
+}
+
+var commentColumns = builder.ColumnsFrom(Comment{})
+
+// CommentInput is the input type used by the CommentModel for creating new comment records.
+// New comments are always pending.
+type CommentInput struct {
+	PostID      uuid.UUID           `json:"postId"`
+	ParentID    sql.Null[uuid.UUID] `json:"parentId"`
+	AuthorName  string              `json:"authorName"`
+	AuthorEmail sql.Null[string]    `json:"authorEmail"`
+	Content     string              `json:"content"`
+}
+
+// CommentPatch is the input type used by the CommentModel for updating comment records. The
+// moderated_at timestamp is set whenever the status is changed.
+type CommentPatch struct {
+	ID         uuid.UUID               `json:"id"`
+	AuthorName sql.Null[string]        `json:"authorName"`
+	Content    sql.Null[string]        `json:"content"`
+	Status     sql.Null[CommentStatus] `json:"status"`
+}
+
+type CommentFilter struct {
+	ID       sql.Null[uuid.UUID]     `json:"id"`
+	PostID   sql.Null[uuid.UUID]     `json:"postId"`
+	ParentID sql.Null[uuid.UUID]     `json:"parentId"`
+	Status   sql.Null[CommentStatus] `json:"status"`
+
+	LastSeen uuid.UUID `json:"lastSeen"`
+	PageSize int       `json:"pageSize"`
+}
+
+type CommentModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *CommentModel) insert(
+	ctx context.Context,
+	q db.Queryable,
+	input CommentInput,
+) (*Comment, error) {
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"post_id":      {V: input.PostID, Valid: true},
+			"parent_id":    {V: input.ParentID.V, Valid: input.ParentID.Valid},
+			"author_name":  {V: input.AuthorName, Valid: true},
+			"author_email": {V: input.AuthorEmail.V, Valid: input.AuthorEmail.Valid},
+			"content":      {V: input.Content, Valid: true},
+		}).
+		Returning(commentColumns...).
+		Into("blog.comment")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("postId", input.PostID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	c, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment inserted", slog.String("id", c.ID.String()))
+
+	return &c, nil
+}
+
+func (m *CommentModel) Insert(ctx context.Context, input CommentInput) (*Comment, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *CommentModel) InsertTx(ctx context.Context, tx pgx.Tx, input CommentInput) (*Comment, error) {
+	return m.insert(ctx, tx, input)
+}
+
+func (m *CommentModel) selectOne(ctx context.Context, q db.Queryable, id uuid.UUID) (*Comment, error) {
+	stmt, args := builder.From("blog.comment").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Select(commentColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("args", args),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	c, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment selected", slog.String("id", c.ID.String()))
+
+	return &c, nil
+}
+
+func (m *CommentModel) SelectOne(ctx context.Context, id uuid.UUID) (*Comment, error) {
+	return m.selectOne(ctx, m.DB, id)
+}
+
+func (m *CommentModel) SelectOneTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*Comment, error) {
+	return m.selectOne(ctx, tx, id)
+}
+
+func (m *CommentModel) selectMany(
+	ctx context.Context,
+	q db.Queryable,
+	filter CommentFilter,
+) ([]*Comment, *Metadata, error) {
+	stmt, args := builder.
+		From("blog.comment").
+		Where(
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("post_id", builder.Equal, filter.PostID),
+			builder.NewNullPredicate("parent_id", builder.Equal, filter.ParentID),
+			builder.NewNullPredicate("status", builder.Equal, filter.Status),
+			builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen),
+		).
+		OrderBy(builder.OrderBy{Column: "id", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(commentColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	comments := make([]*Comment, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		c, err := m.scan(rows)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		comments[i] = &c
+		i++
+	}
+	comments = comments[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(comments),
+	}
+	if len(comments) > 0 {
+		metadata.LastSeen = comments[metadata.ResponseLength-1].ID
+		metadata.Next = true
+	}
+
+	return comments, &metadata, nil
+}
+
+func (m *CommentModel) SelectMany(
+	ctx context.Context,
+	filter CommentFilter,
+) ([]*Comment, *Metadata, error) {
+	return m.selectMany(ctx, m.DB, filter)
+}
+
+func (m *CommentModel) SelectManyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	filter CommentFilter,
+) ([]*Comment, *Metadata, error) {
+	return m.selectMany(ctx, tx, filter)
+}
+
+func (m *CommentModel) update(
+	ctx context.Context,
+	q db.Queryable,
+	patch CommentPatch,
+) (*Comment, error) {
+	// Changing the status is a moderation decision, which is timestamped.
+	moderatedAt := builder.NewAssignment("", nil)
+	if patch.Status.Valid {
+		moderatedAt = builder.NewAssignment("moderated_at = NOW()", nil)
+	}
+
+	stmt, args, err := builder.
+		Update("blog.comment").
+		Where(builder.NewGenericPredicate("id", builder.Equal, patch.ID)).
+		Returning(commentColumns...).
+		Set(
+			builder.NewNullAssignment("author_name", patch.AuthorName),
+			builder.NewNullAssignment("content", patch.Content),
+			builder.NewNullAssignment("status", patch.Status),
+			moderatedAt,
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", patch.ID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	c, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment updated", slog.String("id", c.ID.String()))
+
+	return &c, nil
+}
+
+func (m *CommentModel) Update(ctx context.Context, patch CommentPatch) (*Comment, error) {
+	return m.update(ctx, m.DB, patch)
+}
+
+func (m *CommentModel) UpdateTx(ctx context.Context, tx pgx.Tx, patch CommentPatch) (*Comment, error) {
+	return m.update(ctx, tx, patch)
+}
+
+func (m *CommentModel) delete(ctx context.Context, q db.Queryable, id uuid.UUID) error {
+	stmt, args := builder.From("blog.comment").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	if res.RowsAffected() == 0 {
+		return db.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Delete removes the comment and, through the cascading foreign key, all replies to it.
+func (m *CommentModel) Delete(ctx context.Context, id uuid.UUID) error {
+	return m.delete(ctx, m.DB, id)
+}
+
+func (m *CommentModel) DeleteTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	return m.delete(ctx, tx, id)
+}
+
+func (m *CommentModel) scan(row pgx.Row) (Comment, error) {
+	var c Comment
+	err := row.Scan(
+		&c.ID,
+		&c.PostID,
+		&c.ParentID,
+		&c.AuthorName,
+		&c.AuthorEmail,
+		&c.Content,
+		&c.Status,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.ModeratedAt,
+	)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,107 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	post, err := models.Posts.Insert(ctx, data.PostInput{
+		Title:     "Commented",
+		Slug:      "commented",
+		Content:   "Some example content",
+		Published: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, models.Posts.Delete(ctx, post.ID))
+	})
+
+	var comment *data.Comment
+
+	t.Run("Insert", func(t *testing.T) {
+		comment, err = models.Comments.Insert(ctx, data.CommentInput{
+			PostID:      post.ID,
+			AuthorName:  "Reader",
+			AuthorEmail: sql.Null[string]{V: "reader@example.com", Valid: true},
+			Content:     "First!",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, data.CommentPending, comment.Status)
+		assert.False(t, comment.ModeratedAt.Valid)
+	})
+
+	t.Run("SelectOne", func(t *testing.T) {
+		selected, err := models.Comments.SelectOne(ctx, comment.ID)
+		require.NoError(t, err)
+		assert.Equal(t, comment, selected)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		updated, err := models.Comments.Update(ctx, data.CommentPatch{
+			ID:     comment.ID,
+			Status: sql.Null[data.CommentStatus]{V: data.CommentApproved, Valid: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, data.CommentApproved, updated.Status)
+		assert.True(t, updated.ModeratedAt.Valid)
+		assert.Equal(t, comment.Content, updated.Content)
+	})
+
+	t.Run("SelectMany", func(t *testing.T) {
+		reply, err := models.Comments.Insert(ctx, data.CommentInput{
+			PostID:     post.ID,
+			ParentID:   sql.Null[uuid.UUID]{V: comment.ID, Valid: true},
+			AuthorName: "Author",
+			Content:    "Thanks!",
+		})
+		require.NoError(t, err)
+
+		approved, metadata, err := models.Comments.SelectMany(ctx, data.CommentFilter{
+			PostID:   sql.Null[uuid.UUID]{V: post.ID, Valid: true},
+			Status:   sql.Null[data.CommentStatus]{V: data.CommentApproved, Valid: true},
+			PageSize: 10,
+		})
+		require.NoError(t, err)
+		require.Len(t, approved, 1)
+		assert.Equal(t, comment.ID, approved[0].ID)
+		assert.Equal(t, comment.ID, metadata.LastSeen)
+
+		replies, _, err := models.Comments.SelectMany(ctx, data.CommentFilter{
+			ParentID: sql.Null[uuid.UUID]{V: comment.ID, Valid: true},
+			PageSize: 10,
+		})
+		require.NoError(t, err)
+		require.Len(t, replies, 1)
+		assert.Equal(t, reply.ID, replies[0].ID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, models.Comments.Delete(ctx, comment.ID))
+
+		// Replies are removed with the parent comment.
+		remaining, _, err := models.Comments.SelectMany(ctx, data.CommentFilter{
+			PostID:   sql.Null[uuid.UUID]{V: post.ID, Valid: true},
+			PageSize: 10,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, remaining)
+
+		assert.ErrorIs(t, models.Comments.Delete(ctx, comment.ID), db.ErrRecordNotFound)
+	})
+}
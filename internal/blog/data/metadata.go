@@ -10,6 +10,12 @@ import (
 type Metadata struct {
 	// LastSeen is a [uuid.UUID] from the last item of the result the metadata object describes.
 	LastSeen uuid.UUID `json:"lastSeen,omitzero"`
+	// LastRank is the search rank of the last item of ranked results, continuing the results
+	// together with LastSeen.
+	LastRank *float32 `json:"lastRank,omitempty"`
+	// LastRevision is the revision number of the last item of revision results, continuing the
+	// revisions of a blog post in place of LastSeen.
+	LastRevision int `json:"lastRevision,omitempty"`
 	// Next is true when there are more results in the dataset.
 	Next bool `json:"next"`
 	// ResponseLength is the number of results returned.
@@ -12,14 +12,24 @@ import (
 )
 
 type Models struct {
-	db    *pgxpool.Pool
-	Posts PostModel
+	db            *pgxpool.Pool
+	Posts         PostModel
+	PostRevisions PostRevisionModel
+	Tags          TagModel
+	PostTags      PostTagModel
+	PostSlugs     PostSlugModel
+	Comments      CommentModel
 }
 
 func NewModels(pool *pgxpool.Pool, timeout *time.Duration) Models {
 	return Models{
-		db:    pool,
-		Posts: PostModel{DB: pool, Timeout: timeout},
+		db:            pool,
+		Posts:         PostModel{DB: pool, Timeout: timeout},
+		PostRevisions: PostRevisionModel{DB: pool, Timeout: timeout},
+		Tags:          TagModel{DB: pool, Timeout: timeout},
+		PostTags:      PostTagModel{DB: pool, Timeout: timeout},
+		PostSlugs:     PostSlugModel{DB: pool, Timeout: timeout},
+		Comments:      CommentModel{DB: pool, Timeout: timeout},
 	}
 }
 
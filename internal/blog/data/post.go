@@ -3,12 +3,16 @@ package data
 import (
 	"context"
 	"database/sql"
+	"html"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oapi-codegen/nullable"
 	"github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/db/builder"
 	"github.com/r3d5un/islandwind/internal/logging"
@@ -16,34 +20,68 @@ import (
 
 // Post is the database record for a blog post.
 type Post struct {
-	ID        uuid.UUID    `json:"id"        db:"id"`
-	Title     string       `json:"title"     db:"title"`
-	Content   string       `json:"content"   db:"content"`
-	Published bool         `json:"published" db:"published"`
-	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time    `json:"updatedAt" db:"updated_at"`
-	Deleted   bool         `json:"deleted"   db:"deleted"`
-	DeletedAt sql.NullTime `json:"deletedAt" db:"deleted_at"`
+	ID        uuid.UUID `json:"id"        db:"id"`
+	Title     string    `json:"title"     db:"title"`
+	Slug      string    `json:"slug"      db:"slug"`
+	Content   string    `json:"content"   db:"content"`
+	Published bool      `json:"published" db:"published"`
+	// PublishAt is when the post is scheduled to be published. Cleared once published.
+	PublishAt sql.NullTime `json:"publishAt" db:"publish_at"`
+	// UnpublishAt is when the post is scheduled to be unpublished. Cleared once unpublished.
+	UnpublishAt sql.NullTime `json:"unpublishAt" db:"unpublish_at"`
+	CreatedAt   time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time    `json:"updatedAt" db:"updated_at"`
+	Deleted     bool         `json:"deleted"   db:"deleted"`
+	DeletedAt   sql.NullTime `json:"deletedAt" db:"deleted_at"`
+	// Rank is the full-text search rank of the post. Only set when listing posts with a
+	// search query.
+	Rank sql.Null[float32] `json:"rank"`
+	// Snippet is an excerpt of the content with the search terms highlighted with <mark>
+	// elements. The rest of the excerpt is HTML escaped. Only set when listing posts with a
+	// search query.
+	Snippet sql.Null[string] `json:"snippet"`
 }
 
 var postColumns = builder.ColumnsFrom(Post{})
 
+const (
+	// searchColumn is the tsvector column kept up to date by the
+	// trigger_blog_post_search_vector trigger.
+	searchColumn string = "search_vector"
+	// searchConfig is the text search configuration used by the search_vector trigger.
+	searchConfig = builder.English
+	// snippetStart and snippetStop delimit the search terms in snippets returned by
+	// ts_headline. Private use characters are used rather than HTML, so that the snippet can
+	// be escaped before the search terms are highlighted. They are removed from the content.
+	snippetStart string = "\uE000"
+	snippetStop  string = "\uE001"
+	// snippetOptions are the ts_headline options used for search result snippets.
+	snippetOptions string = "StartSel=" + snippetStart + ", StopSel=" + snippetStop +
+		", MaxWords=35, MinWords=15, MaxFragments=2"
+)
+
 // PostInput is the input type used by the BlogModel for creating new blog post records.
 type PostInput struct {
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Published bool   `json:"published"`
+	Title       string       `json:"title"`
+	Slug        string       `json:"slug"`
+	Content     string       `json:"content"`
+	Published   bool         `json:"published"`
+	PublishAt   sql.NullTime `json:"publishAt"`
+	UnpublishAt sql.NullTime `json:"unpublishAt"`
 }
 
 // PostPatch is used for updating any existing blog post records. All fields except
 // the ID is optional, but if populated will update the record when given to
-// BlogModel.Update.
+// BlogModel.Update. The schedule fields can be explicitly set to null to cancel a schedule.
 type PostPatch struct {
-	ID        uuid.UUID        `json:"id"`
-	Title     sql.Null[string] `json:"title"`
-	Content   sql.Null[string] `json:"content"`
-	Published sql.Null[bool]   `json:"published"`
-	Deleted   sql.Null[bool]   `json:"deleted"`
+	ID          uuid.UUID                    `json:"id"`
+	Title       sql.Null[string]             `json:"title"`
+	Slug        sql.Null[string]             `json:"slug"`
+	Content     sql.Null[string]             `json:"content"`
+	Published   sql.Null[bool]               `json:"published"`
+	PublishAt   nullable.Nullable[time.Time] `json:"publishAt"`
+	UnpublishAt nullable.Nullable[time.Time] `json:"unpublishAt"`
+	Deleted     sql.Null[bool]               `json:"deleted"`
 }
 
 type PostModel struct {
@@ -54,9 +92,16 @@ type PostModel struct {
 func (m *PostModel) insert(ctx context.Context, q db.Queryable, input PostInput) (*Post, error) {
 	stmt, args, err := builder.
 		Insert(builder.Tuple{
-			"title":     {V: input.Content, Valid: true},
+			"title":     {V: input.Title, Valid: true},
+			"slug":      {V: input.Slug, Valid: true},
 			"content":   {V: input.Content, Valid: true},
 			"published": {V: input.Published, Valid: true},
+			"publish_at": {
+				V: input.PublishAt.Time, Valid: input.PublishAt.Valid,
+			},
+			"unpublish_at": {
+				V: input.UnpublishAt.Time, Valid: input.UnpublishAt.Valid,
+			},
 		}).
 		Returning(
 			postColumns...,
@@ -127,9 +172,47 @@ func (m *PostModel) SelectOneTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*
 	return m.selectOne(ctx, tx, id)
 }
 
+func (m *PostModel) selectOneBySlug(
+	ctx context.Context,
+	q db.Queryable,
+	slug string,
+) (*Post, error) {
+	stmt, args := builder.From("blog.post").
+		Where(builder.NewGenericPredicate("slug", builder.Equal, slug)).
+		Select(postColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("args", args),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	p, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post selected", slog.Any("post", p))
+
+	return &p, nil
+}
+
+func (m *PostModel) SelectOneBySlug(ctx context.Context, slug string) (*Post, error) {
+	return m.selectOneBySlug(ctx, m.DB, slug)
+}
+
+func (m *PostModel) SelectOneBySlugTx(ctx context.Context, tx pgx.Tx, slug string) (*Post, error) {
+	return m.selectOneBySlug(ctx, tx, slug)
+}
+
 type PostFilter struct {
 	ID            sql.Null[uuid.UUID] `json:"id"`
 	Title         sql.Null[string]    `json:"title"`
+	Slug          sql.Null[string]    `json:"slug"`
 	Published     sql.Null[bool]      `json:"published"`
 	CreatedAtFrom sql.Null[time.Time] `json:"createdAtFrom"`
 	CreatedAtTo   sql.Null[time.Time] `json:"createdAtTo"`
@@ -138,6 +221,18 @@ type PostFilter struct {
 	Deleted       sql.Null[bool]      `json:"deleted"`
 	DeletedAtFrom sql.Null[time.Time] `json:"deletedAtFrom"`
 	DeletedAtTo   sql.Null[time.Time] `json:"deletedAtTo"`
+	// Query is a full-text search query in web search syntax. When set, results are ordered
+	// by rank and contain highlighted snippets.
+	Query sql.Null[string] `json:"query"`
+	// Tags limits the result to posts with the given tag names. TagMatch decides whether the
+	// posts must have any or all of the tags, and defaults to any.
+	Tags     []string `json:"tags"`
+	TagMatch TagMatch `json:"tagMatch"`
+	// Descending orders the posts newest first. LastSeen is then the ID to continue before.
+	Descending bool `json:"descending"`
+	// LastRank is the rank of the LastSeen post when continuing ranked results. Posts of the
+	// same rank are ordered by ID.
+	LastRank sql.Null[float32] `json:"lastRank"`
 
 	LastSeen uuid.UUID `json:"lastSeen"`
 	PageSize int       `json:"pageSize"`
@@ -148,11 +243,51 @@ func (m *PostModel) selectMany(
 	q db.Queryable,
 	filter PostFilter,
 ) ([]*Post, *Metadata, error) {
+	orderBy := []builder.OrderBy{
+		{Column: "created_at", Order: builder.Asc},
+		{Column: "id", Order: builder.Asc},
+	}
+	lastSeen := builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen)
+	if filter.Descending {
+		orderBy = []builder.OrderBy{
+			{Column: "created_at", Order: builder.Desc},
+			{Column: "id", Order: builder.Desc},
+		}
+		lastSeen = builder.NewNullPredicate(
+			"id",
+			builder.Less,
+			sql.Null[uuid.UUID]{V: filter.LastSeen, Valid: filter.LastSeen != uuid.Nil},
+		)
+	}
+	rankColumns := []string{"NULL::REAL AS rank", "NULL::TEXT AS snippet"}
+	if filter.Query.Valid {
+		tsQuery := builder.TextSearchQuery(searchColumn, searchConfig)
+		rank := "ts_rank(" + searchColumn + ", " + tsQuery + ")"
+		rankColumns = []string{
+			rank + " AS rank",
+			"ts_headline('" + string(searchConfig) + "', " +
+				"translate(content, '" + snippetStart + snippetStop + "', ''), " + tsQuery +
+				", '" + snippetOptions + "') AS snippet",
+		}
+		// Ranked results are continued from the rank and ID of the last seen post, as the
+		// posts are not ordered by ID alone.
+		idOrder, idCondition := builder.Asc, builder.Greater
+		if filter.Descending {
+			idOrder, idCondition = builder.Desc, builder.Less
+		}
+		orderBy = []builder.OrderBy{
+			{Column: "rank", Order: builder.Desc},
+			{Column: "id", Order: idOrder},
+		}
+		lastSeen = newRankPredicate(rank, idCondition, filter.LastRank, filter.LastSeen)
+	}
+
 	stmt, args := builder.
 		From("blog.post").
 		Where(
 			builder.NewNullPredicate("id", builder.Equal, filter.ID),
 			builder.NewNullPredicate("title", builder.Equal, filter.Title),
+			builder.NewNullPredicate("slug", builder.Equal, filter.Slug),
 			builder.NewNullPredicate("published", builder.Equal, filter.Published),
 			builder.NewNullPredicate("deleted", builder.Equal, filter.Deleted),
 			builder.NewNullPredicate("deleted_at", builder.GreaterOrEqual, filter.DeletedAtFrom),
@@ -161,14 +296,13 @@ func (m *PostModel) selectMany(
 			builder.NewNullPredicate("created_to", builder.Less, filter.CreatedAtFrom),
 			builder.NewNullPredicate("updated_at", builder.GreaterOrEqual, filter.UpdatedAtFrom),
 			builder.NewNullPredicate("updated_at", builder.Less, filter.UpdatedAtFrom),
-			builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen),
-		).
-		OrderBy(
-			builder.OrderBy{Column: "created_at", Order: builder.Asc},
-			builder.OrderBy{Column: "id", Order: builder.Asc},
+			lastSeen,
+			builder.NewTextSearchPredicate(searchColumn, searchConfig, filter.Query),
+			newTagPredicate(filter.Tags, filter.TagMatch),
 		).
+		OrderBy(orderBy...).
 		Limit(filter.PageSize).
-		Select(postColumns...)
+		Select(append(slices.Clone(postColumns), rankColumns...)...)
 
 	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
 	defer cancel()
@@ -188,10 +322,17 @@ func (m *PostModel) selectMany(
 	posts := make([]*Post, filter.PageSize)
 	i := 0
 	for rows.Next() {
-		p, err := m.scan(rows)
+		var rank sql.Null[float32]
+		var snippet sql.Null[string]
+		p, err := m.scan(rows, &rank, &snippet)
 		if err != nil {
 			return nil, nil, db.HandleError(ctx, err)
 		}
+		p.Rank = rank
+		if snippet.Valid {
+			snippet.V = highlightSnippet(snippet.V)
+		}
+		p.Snippet = snippet
 		posts[i] = &p
 		i++
 	}
@@ -204,13 +345,45 @@ func (m *PostModel) selectMany(
 		ResponseLength: len(posts),
 	}
 	if len(posts) > 0 {
-		metadata.LastSeen = posts[metadata.ResponseLength-1].ID
+		last := posts[metadata.ResponseLength-1]
+		metadata.LastSeen = last.ID
 		metadata.Next = true
+		if last.Rank.Valid {
+			metadata.LastRank = &last.Rank.V
+		}
 	}
 
 	return posts, &metadata, nil
 }
 
+// snippetHighlighter replaces the delimiters of the search terms in escaped snippets.
+var snippetHighlighter = strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>")
+
+// highlightSnippet escapes the snippet, as the content of posts may contain HTML, and then
+// highlights the search terms with <mark> elements.
+func highlightSnippet(snippet string) string {
+	return snippetHighlighter.Replace(html.EscapeString(snippet))
+}
+
+// newRankPredicate continues ranked results after the post with the last rank and the last
+// seen ID, in the order of the condition. No predicate is created without a last seen post.
+func newRankPredicate(
+	rank string,
+	cond builder.PredicateCondition,
+	lastRank sql.Null[float32],
+	lastSeen uuid.UUID,
+) builder.Predicate {
+	if !lastRank.Valid || lastSeen == uuid.Nil {
+		return builder.NewPredicate("", nil)
+	}
+
+	return builder.NewPredicate(
+		"("+rank+" < @last_rank::REAL OR ("+rank+" = @last_rank::REAL AND id "+
+			string(cond)+" @last_seen))",
+		pgx.NamedArgs{"last_rank": lastRank.V, "last_seen": lastSeen},
+	)
+}
+
 func (m *PostModel) SelectMany(
 	ctx context.Context,
 	filter PostFilter,
@@ -233,8 +406,11 @@ func (m *PostModel) update(ctx context.Context, q db.Queryable, patch PostPatch)
 		Returning(postColumns...).
 		Set(
 			builder.NewNullAssignment("title", patch.Title),
+			builder.NewNullAssignment("slug", patch.Slug),
 			builder.NewNullAssignment("content", patch.Content),
 			builder.NewNullAssignment("published", patch.Published),
+			builder.NewNullableAssignment("publish_at", patch.PublishAt),
+			builder.NewNullableAssignment("unpublish_at", patch.UnpublishAt),
 			builder.NewNullAssignment("deleted", patch.Deleted),
 			builder.NewAssignment(
 				`deleted_at = CASE
@@ -308,18 +484,85 @@ func (m *PostModel) DeleteTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error
 	return m.delete(ctx, tx, id)
 }
 
-func (m *PostModel) scan(row pgx.Row) (Post, error) {
+// publishDue publishes all posts with a publish_at in the past, and clears their publish_at.
+// Returns the IDs of the published posts.
+func (m *PostModel) publishDue(ctx context.Context, q db.Queryable) ([]uuid.UUID, error) {
+	const stmt string = `
+UPDATE blog.post
+SET published  = TRUE,
+    publish_at = NULL
+WHERE publish_at <= NOW()
+  AND NOT deleted
+RETURNING id;
+`
+
+	return m.updateDue(ctx, q, stmt)
+}
+
+func (m *PostModel) PublishDueTx(ctx context.Context, tx pgx.Tx) ([]uuid.UUID, error) {
+	return m.publishDue(ctx, tx)
+}
+
+// unpublishDue unpublishes all posts with an unpublish_at in the past, and clears their
+// unpublish_at. Returns the IDs of the unpublished posts.
+func (m *PostModel) unpublishDue(ctx context.Context, q db.Queryable) ([]uuid.UUID, error) {
+	const stmt string = `
+UPDATE blog.post
+SET published    = FALSE,
+    unpublish_at = NULL
+WHERE unpublish_at <= NOW()
+RETURNING id;
+`
+
+	return m.updateDue(ctx, q, stmt)
+}
+
+func (m *PostModel) UnpublishDueTx(ctx context.Context, tx pgx.Tx) ([]uuid.UUID, error) {
+	return m.unpublishDue(ctx, tx)
+}
+
+func (m *PostModel) updateDue(ctx context.Context, q db.Queryable, stmt string) ([]uuid.UUID, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "scheduled posts updated", slog.Int("count", len(ids)))
+
+	return ids, nil
+}
+
+// scan reads a row selected with postColumns. Any extra destinations are scanned from the
+// columns following postColumns in the order they are given.
+func (m *PostModel) scan(row pgx.Row, extra ...any) (Post, error) {
 	var p Post
-	err := row.Scan(
+	dest := []any{
 		&p.ID,
 		&p.Title,
+		&p.Slug,
 		&p.Content,
 		&p.Published,
+		&p.PublishAt,
+		&p.UnpublishAt,
 		&p.CreatedAt,
 		&p.UpdatedAt,
 		&p.Deleted,
 		&p.DeletedAt,
-	)
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return p, err
 	}
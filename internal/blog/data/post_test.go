@@ -3,6 +3,7 @@ package data_test
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"testing"
 	"time"
 
@@ -23,6 +24,7 @@ func TestBlogModel(t *testing.T) {
 	t.Run("Insert", func(t *testing.T) {
 		inserted, err := models.Posts.Insert(ctx, data.PostInput{
 			Title:     "Test",
+			Slug:      "test",
 			Content:   "Some example content",
 			Published: true,
 		})
@@ -37,6 +39,7 @@ func TestBlogModel(t *testing.T) {
 	t.Run("Select", func(t *testing.T) {
 		inserted, err := models.Posts.Insert(ctx, data.PostInput{
 			Title:     "Test",
+			Slug:      "test",
 			Content:   "Some example content",
 			Published: true,
 		})
@@ -56,6 +59,7 @@ func TestBlogModel(t *testing.T) {
 	t.Run("SelectMany", func(t *testing.T) {
 		inserted, err := models.Posts.Insert(ctx, data.PostInput{
 			Title:     "Test",
+			Slug:      "test",
 			Content:   "Some example content",
 			Published: true,
 		})
@@ -77,9 +81,106 @@ func TestBlogModel(t *testing.T) {
 		assert.Equal(t, selected[len(selected)-1].ID, metadata.LastSeen)
 	})
 
+	t.Run("Search", func(t *testing.T) {
+		inserted, err := models.Posts.Insert(ctx, data.PostInput{
+			Title:     "Searching",
+			Slug:      "searching",
+			Content:   "Indexing content with PostgreSQL full-text search",
+			Published: true,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, inserted)
+		t.Cleanup(func() {
+			require.NoError(t, models.Posts.Delete(ctx, inserted.ID))
+		})
+
+		selected, metadata, err := models.Posts.SelectMany(ctx, data.PostFilter{
+			PageSize: 10,
+			Query:    sql.Null[string]{V: "postgresql search", Valid: true},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, selected)
+		assert.NotEmpty(t, metadata)
+		assert.Equal(t, inserted.ID, selected[0].ID)
+		assert.True(t, selected[0].Rank.Valid)
+		assert.Contains(t, selected[0].Snippet.V, "<mark>")
+	})
+
+	t.Run("SearchSnippetEscaped", func(t *testing.T) {
+		inserted, err := models.Posts.Insert(ctx, data.PostInput{
+			Title:     "Escaping",
+			Slug:      "escaping",
+			Content:   "Escaping <script>alert('snippet')</script> markup in snippets",
+			Published: true,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, models.Posts.Delete(ctx, inserted.ID))
+		})
+
+		selected, _, err := models.Posts.SelectMany(ctx, data.PostFilter{
+			PageSize: 10,
+			Query:    sql.Null[string]{V: "escaping markup", Valid: true},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, selected)
+		assert.Equal(t, inserted.ID, selected[0].ID)
+		assert.Contains(t, selected[0].Snippet.V, "<mark>")
+		assert.NotContains(t, selected[0].Snippet.V, "<script>")
+		assert.NotContains(t, selected[0].Snippet.V, "</script>")
+	})
+
+	t.Run("SearchPaging", func(t *testing.T) {
+		for i, content := range []string{
+			"Paging ranked results",
+			"Paging ranked results, paging by rank",
+			"Paging ranked results, paging by rank, paging by ID",
+			"Paging ranked results",
+		} {
+			inserted, err := models.Posts.Insert(ctx, data.PostInput{
+				Title:     "Paging",
+				Slug:      fmt.Sprintf("paging-%d", i),
+				Content:   content,
+				Published: true,
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, models.Posts.Delete(ctx, inserted.ID))
+			})
+		}
+		filter := data.PostFilter{
+			PageSize: 10,
+			Query:    sql.Null[string]{V: "paging", Valid: true},
+		}
+		all, _, err := models.Posts.SelectMany(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, all, 4)
+
+		// Paging through the results one post at a time yields the same posts in the same
+		// order, including posts of the same rank.
+		filter.PageSize = 1
+		var paged []*data.Post
+		for range len(all) + 1 {
+			selected, metadata, err := models.Posts.SelectMany(ctx, filter)
+			require.NoError(t, err)
+			if len(selected) == 0 {
+				break
+			}
+			require.NotNil(t, metadata.LastRank)
+			paged = append(paged, selected...)
+			filter.LastSeen = metadata.LastSeen
+			filter.LastRank = sql.Null[float32]{V: *metadata.LastRank, Valid: true}
+		}
+		require.Len(t, paged, len(all))
+		for i := range all {
+			assert.Equal(t, all[i].ID, paged[i].ID)
+		}
+	})
+
 	t.Run("Update", func(t *testing.T) {
 		inserted, err := models.Posts.Insert(ctx, data.PostInput{
 			Title:     "Test",
+			Slug:      "test",
 			Content:   "Some example content",
 			Published: true,
 		})
@@ -104,6 +205,7 @@ func TestBlogModel(t *testing.T) {
 	t.Run("Purge", func(t *testing.T) {
 		inserted, err := models.Posts.Insert(ctx, data.PostInput{
 			Title:     "Test",
+			Slug:      "test",
 			Content:   "Some example content",
 			Published: true,
 		})
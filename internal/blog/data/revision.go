@@ -0,0 +1,243 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// PostRevision is the database record of a blog post title and content as they were before an
+// update.
+type PostRevision struct {
+	ID        uuid.UUID `json:"id"        db:"id"`
+	PostID    uuid.UUID `json:"postId"    db:"post_id"`
+	Revision  int       `json:"revision"  db:"revision"`
+	Title     string    `json:"title"     db:"title"`
+	Content   string    `json:"content"   db:"content"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+var postRevisionColumns = builder.ColumnsFrom(PostRevision{})
+
+type PostRevisionModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// snapshot stores the current title and content of a blog post as a new revision. The post
+// row is locked for the remainder of the transaction.
+//
+// The row is locked before the revision number is read. Under READ COMMITTED, a single
+// statement waiting for the lock would still compute the revision number from its snapshot
+// taken before the wait, and concurrent updates would compute the same number.
+func (m *PostRevisionModel) snapshot(
+	ctx context.Context,
+	q db.Queryable,
+	postID uuid.UUID,
+) (*PostRevision, error) {
+	const lockStmt string = `
+SELECT id
+FROM blog.post
+WHERE id = $1::UUID
+FOR UPDATE;
+`
+	const stmt string = `
+INSERT INTO blog.post_revision (post_id, revision, title, content)
+SELECT p.id,
+       COALESCE((SELECT MAX(r.revision) FROM blog.post_revision r WHERE r.post_id = p.id), 0) + 1,
+       p.title,
+       p.content
+FROM blog.post p
+WHERE p.id = $1::UUID
+RETURNING
+    id,
+    post_id,
+    revision,
+    title,
+    content,
+    created_at;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(lockStmt+stmt)),
+		slog.String("postId", postID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	var locked uuid.UUID
+	if err := q.QueryRow(ctx, lockStmt, postID).Scan(&locked); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	r, err := m.scan(q.QueryRow(ctx, stmt, postID))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post revision inserted", slog.Any("revision", r))
+
+	return &r, nil
+}
+
+func (m *PostRevisionModel) SnapshotTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	postID uuid.UUID,
+) (*PostRevision, error) {
+	return m.snapshot(ctx, tx, postID)
+}
+
+func (m *PostRevisionModel) selectOne(
+	ctx context.Context,
+	q db.Queryable,
+	id uuid.UUID,
+) (*PostRevision, error) {
+	stmt, args := builder.From("blog.post_revision").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Select(postRevisionColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("args", args),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	r, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post revision selected", slog.Any("revision", r))
+
+	return &r, nil
+}
+
+func (m *PostRevisionModel) SelectOne(ctx context.Context, id uuid.UUID) (*PostRevision, error) {
+	return m.selectOne(ctx, m.DB, id)
+}
+
+func (m *PostRevisionModel) SelectOneTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+) (*PostRevision, error) {
+	return m.selectOne(ctx, tx, id)
+}
+
+type PostRevisionFilter struct {
+	ID            sql.Null[uuid.UUID] `json:"id"`
+	PostID        sql.Null[uuid.UUID] `json:"postId"`
+	CreatedAtFrom sql.Null[time.Time] `json:"createdAtFrom"`
+	CreatedAtTo   sql.Null[time.Time] `json:"createdAtTo"`
+
+	// LastRevision continues the revisions of a blog post after the revision number. Revision
+	// numbers only increase per blog post, so results should be filtered by PostID.
+	LastRevision int `json:"lastRevision"`
+	PageSize     int `json:"pageSize"`
+}
+
+func (m *PostRevisionModel) selectMany(
+	ctx context.Context,
+	q db.Queryable,
+	filter PostRevisionFilter,
+) ([]*PostRevision, *Metadata, error) {
+	stmt, args := builder.
+		From("blog.post_revision").
+		Where(
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("post_id", builder.Equal, filter.PostID),
+			builder.NewNullPredicate("created_at", builder.GreaterOrEqual, filter.CreatedAtFrom),
+			builder.NewNullPredicate("created_at", builder.Less, filter.CreatedAtTo),
+			builder.NewGenericPredicate("revision", builder.Greater, filter.LastRevision),
+		).
+		OrderBy(builder.OrderBy{Column: "revision", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(postRevisionColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	revisions := make([]*PostRevision, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		r, err := m.scan(rows)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		revisions[i] = &r
+		i++
+	}
+	revisions = revisions[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(revisions),
+	}
+	if len(revisions) > 0 {
+		metadata.LastRevision = revisions[metadata.ResponseLength-1].Revision
+		metadata.Next = true
+	}
+
+	return revisions, &metadata, nil
+}
+
+func (m *PostRevisionModel) SelectMany(
+	ctx context.Context,
+	filter PostRevisionFilter,
+) ([]*PostRevision, *Metadata, error) {
+	return m.selectMany(ctx, m.DB, filter)
+}
+
+func (m *PostRevisionModel) SelectManyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	filter PostRevisionFilter,
+) ([]*PostRevision, *Metadata, error) {
+	return m.selectMany(ctx, tx, filter)
+}
+
+func (m *PostRevisionModel) scan(row pgx.Row) (PostRevision, error) {
+	var r PostRevision
+	err := row.Scan(
+		&r.ID,
+		&r.PostID,
+		&r.Revision,
+		&r.Title,
+		&r.Content,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
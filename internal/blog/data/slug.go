@@ -0,0 +1,221 @@
+package data
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// PostSlug is the database record of a slug previously used by a blog post.
+type PostSlug struct {
+	Slug      string    `json:"slug"      db:"slug"`
+	PostID    uuid.UUID `json:"postId"    db:"post_id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+var postSlugColumns = builder.ColumnsFrom(PostSlug{})
+
+type PostSlugModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// selectTaken returns the slugs equal to, or suffixed variants of, the given slug that are
+// used by other blog posts, either currently or in their slug history. Slugs in the history of
+// the excluded post are not considered taken, so a post can reclaim its own previous slugs.
+//
+// Within a transaction, concurrent calls for the same slug are serialized with an advisory
+// lock held until the transaction ends.
+func (m *PostSlugModel) selectTaken(
+	ctx context.Context,
+	q db.Queryable,
+	slug string,
+	excludePostID uuid.UUID,
+) ([]string, error) {
+	const lockStmt string = `SELECT pg_advisory_xact_lock(hashtext($1));`
+	const stmt string = `
+SELECT slug
+FROM blog.post
+WHERE (slug = $1 OR slug LIKE $1 || '-%')
+  AND id <> $2::UUID
+UNION
+SELECT slug
+FROM blog.post_slug_history
+WHERE (slug = $1 OR slug LIKE $1 || '-%')
+  AND post_id <> $2::UUID;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("slug", slug),
+		slog.String("excludePostId", excludePostID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := q.Exec(ctx, lockStmt, slug); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	rows, err := q.Query(ctx, stmt, slug, excludePostID)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "taken slugs selected", slog.Int("count", len(taken)))
+
+	return taken, nil
+}
+
+func (m *PostSlugModel) SelectTakenTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	slug string,
+	excludePostID uuid.UUID,
+) ([]string, error) {
+	return m.selectTaken(ctx, tx, slug, excludePostID)
+}
+
+// insert records a previous slug of a blog post. If the slug already exists in the history, it
+// is moved to the given post.
+func (m *PostSlugModel) insert(
+	ctx context.Context,
+	q db.Queryable,
+	slug string,
+	postID uuid.UUID,
+) (*PostSlug, error) {
+	const stmt string = `
+INSERT INTO blog.post_slug_history (slug, post_id)
+VALUES ($1, $2::UUID)
+ON CONFLICT (slug) DO UPDATE SET post_id    = EXCLUDED.post_id,
+                                 created_at = NOW()
+RETURNING
+    slug,
+    post_id,
+    created_at;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("slug", slug),
+		slog.String("postId", postID.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	s, err := m.scan(q.QueryRow(ctx, stmt, slug, postID))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post slug inserted", slog.Any("slug", s))
+
+	return &s, nil
+}
+
+func (m *PostSlugModel) Insert(ctx context.Context, slug string, postID uuid.UUID) (*PostSlug, error) {
+	return m.insert(ctx, m.DB, slug, postID)
+}
+
+func (m *PostSlugModel) InsertTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	slug string,
+	postID uuid.UUID,
+) (*PostSlug, error) {
+	return m.insert(ctx, tx, slug, postID)
+}
+
+func (m *PostSlugModel) selectOne(ctx context.Context, q db.Queryable, slug string) (*PostSlug, error) {
+	stmt, args := builder.From("blog.post_slug_history").
+		Where(builder.NewGenericPredicate("slug", builder.Equal, slug)).
+		Select(postSlugColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("args", args),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	s, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post slug selected", slog.Any("slug", s))
+
+	return &s, nil
+}
+
+func (m *PostSlugModel) SelectOne(ctx context.Context, slug string) (*PostSlug, error) {
+	return m.selectOne(ctx, m.DB, slug)
+}
+
+func (m *PostSlugModel) SelectOneTx(ctx context.Context, tx pgx.Tx, slug string) (*PostSlug, error) {
+	return m.selectOne(ctx, tx, slug)
+}
+
+func (m *PostSlugModel) delete(ctx context.Context, q db.Queryable, slug string) error {
+	stmt, args := builder.From("blog.post_slug_history").
+		Where(builder.NewGenericPredicate("slug", builder.Equal, slug)).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("slug", slug),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := q.Exec(ctx, stmt, args); err != nil {
+		return db.HandleError(ctx, err)
+	}
+
+	return nil
+}
+
+func (m *PostSlugModel) Delete(ctx context.Context, slug string) error {
+	return m.delete(ctx, m.DB, slug)
+}
+
+func (m *PostSlugModel) DeleteTx(ctx context.Context, tx pgx.Tx, slug string) error {
+	return m.delete(ctx, tx, slug)
+}
+
+func (m *PostSlugModel) scan(row pgx.Row) (PostSlug, error) {
+	var s PostSlug
+	err := row.Scan(
+		&s.Slug,
+		&s.PostID,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		return s, err
+	}
+	return s, nil
+}
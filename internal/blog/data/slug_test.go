@@ -0,0 +1,59 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostSlugModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	t.Run("History", func(t *testing.T) {
+		inserted, err := models.Posts.Insert(ctx, data.PostInput{
+			Title:     "Slugged",
+			Slug:      "slugged",
+			Content:   "Some slugged content",
+			Published: true,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, models.Posts.Delete(ctx, inserted.ID))
+		})
+
+		selected, err := models.Posts.SelectOneBySlug(ctx, "slugged")
+		require.NoError(t, err)
+		assert.Equal(t, *inserted, *selected)
+
+		history, err := models.PostSlugs.Insert(ctx, "slugged-old", inserted.ID)
+		require.NoError(t, err)
+		assert.Equal(t, inserted.ID, history.PostID)
+
+		tx, rollback, err := models.BeginTx(ctx)
+		require.NoError(t, err)
+		defer rollback()
+
+		taken, err := models.PostSlugs.SelectTakenTx(ctx, tx, "slugged", uuid.Nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"slugged", "slugged-old"}, taken)
+
+		taken, err = models.PostSlugs.SelectTakenTx(ctx, tx, "slugged", inserted.ID)
+		require.NoError(t, err)
+		assert.Empty(t, taken)
+
+		require.NoError(t, models.PostSlugs.Delete(ctx, "slugged-old"))
+		_, err = models.PostSlugs.SelectOne(ctx, "slugged-old")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+}
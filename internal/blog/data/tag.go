@@ -0,0 +1,356 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// Tag is the database record for a blog post tag.
+type Tag struct {
+	ID        uuid.UUID `json:"id"        db:"id"`
+	Name      string    `json:"name"      db:"name"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	// PostCount is the number of published, non-deleted posts with the tag. Only set when
+	// listing tags.
+	PostCount sql.Null[int64] `json:"postCount"`
+}
+
+var tagColumns = builder.ColumnsFrom(Tag{})
+
+// TagMatch decides whether a post must have any or all of the tags in a PostFilter.
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any"
+	TagMatchAll TagMatch = "all"
+)
+
+type TagModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// upsertMany inserts the tags that do not already exist and returns all the tags with the
+// given names.
+func (m *TagModel) upsertMany(ctx context.Context, q db.Queryable, names []string) ([]*Tag, error) {
+	const stmt string = `
+INSERT INTO blog.tag (name)
+SELECT UNNEST($1::VARCHAR[])
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING
+    id,
+    name,
+    created_at;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("names", names),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, names)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	tags := make([]*Tag, 0, len(names))
+	for rows.Next() {
+		t, err := m.scan(rows)
+		if err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		tags = append(tags, &t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "tags upserted", slog.Int("count", len(tags)))
+
+	return tags, nil
+}
+
+func (m *TagModel) UpsertMany(ctx context.Context, names []string) ([]*Tag, error) {
+	return m.upsertMany(ctx, m.DB, names)
+}
+
+func (m *TagModel) UpsertManyTx(ctx context.Context, tx pgx.Tx, names []string) ([]*Tag, error) {
+	return m.upsertMany(ctx, tx, names)
+}
+
+type TagFilter struct {
+	ID   sql.Null[uuid.UUID] `json:"id"`
+	Name sql.Null[string]    `json:"name"`
+
+	LastSeen uuid.UUID `json:"lastSeen"`
+	PageSize int       `json:"pageSize"`
+}
+
+func (m *TagModel) selectMany(
+	ctx context.Context,
+	q db.Queryable,
+	filter TagFilter,
+) ([]*Tag, *Metadata, error) {
+	columns := make([]string, len(tagColumns))
+	for i, column := range tagColumns {
+		columns[i] = "t." + column
+	}
+
+	stmt, args := builder.
+		From("blog.tag t").
+		LeftJoin("blog.post_tag pt ON pt.tag_id = t.id").
+		LeftJoin("blog.post p ON p.id = pt.post_id AND p.published AND NOT p.deleted").
+		Where(
+			builder.NewNullPredicate("t.id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("t.name", builder.Equal, filter.Name),
+			builder.NewGenericPredicate("t.id", builder.Greater, filter.LastSeen),
+		).
+		GroupBy(columns...).
+		OrderBy(builder.OrderBy{Column: "t.id", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(append(slices.Clone(columns), "COUNT(p.id) AS post_count")...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	tags := make([]*Tag, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		var postCount sql.Null[int64]
+		t, err := m.scan(rows, &postCount)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		t.PostCount = postCount
+		tags[i] = &t
+		i++
+	}
+	tags = tags[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(tags),
+	}
+	if len(tags) > 0 {
+		metadata.LastSeen = tags[metadata.ResponseLength-1].ID
+		metadata.Next = true
+	}
+
+	return tags, &metadata, nil
+}
+
+func (m *TagModel) SelectMany(ctx context.Context, filter TagFilter) ([]*Tag, *Metadata, error) {
+	return m.selectMany(ctx, m.DB, filter)
+}
+
+func (m *TagModel) SelectManyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	filter TagFilter,
+) ([]*Tag, *Metadata, error) {
+	return m.selectMany(ctx, tx, filter)
+}
+
+// scan reads a row selected with tagColumns. Any extra destinations are scanned from the
+// columns following tagColumns in the order they are given.
+func (m *TagModel) scan(row pgx.Row, extra ...any) (Tag, error) {
+	var t Tag
+	dest := []any{
+		&t.ID,
+		&t.Name,
+		&t.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// PostTag is the database record linking a blog post to a tag.
+type PostTag struct {
+	PostID uuid.UUID `json:"postId" db:"post_id"`
+	TagID  uuid.UUID `json:"tagId"  db:"tag_id"`
+	// Name is the name of the tag, joined from blog.tag.
+	Name string `json:"name" db:"name"`
+}
+
+type PostTagModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+// replace removes all tags from a blog post and adds the given ones.
+func (m *PostTagModel) replace(
+	ctx context.Context,
+	q db.Queryable,
+	postID uuid.UUID,
+	tagIDs []uuid.UUID,
+) error {
+	deleteStmt, deleteArgs := builder.From("blog.post_tag").
+		Where(builder.NewGenericPredicate("post_id", builder.Equal, postID)).
+		Delete()
+	const insertStmt string = `
+INSERT INTO blog.post_tag (post_id, tag_id)
+SELECT $1::UUID, UNNEST($2::UUID[])
+ON CONFLICT DO NOTHING;
+`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("delete", logging.MinifySQL(deleteStmt)),
+		slog.String("insert", logging.MinifySQL(insertStmt)),
+		slog.String("postId", postID.String()),
+		slog.Any("tagIds", tagIDs),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	if _, err := q.Exec(ctx, deleteStmt, deleteArgs); err != nil {
+		return db.HandleError(ctx, err)
+	}
+	if len(tagIDs) > 0 {
+		if _, err := q.Exec(ctx, insertStmt, postID, tagIDs); err != nil {
+			return db.HandleError(ctx, err)
+		}
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post tags replaced")
+
+	return nil
+}
+
+func (m *PostTagModel) Replace(ctx context.Context, postID uuid.UUID, tagIDs []uuid.UUID) error {
+	return m.replace(ctx, m.DB, postID, tagIDs)
+}
+
+func (m *PostTagModel) ReplaceTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	postID uuid.UUID,
+	tagIDs []uuid.UUID,
+) error {
+	return m.replace(ctx, tx, postID, tagIDs)
+}
+
+// selectByPostIDs selects the tags of all the given blog posts in a single query, ordered by
+// tag name.
+func (m *PostTagModel) selectByPostIDs(
+	ctx context.Context,
+	q db.Queryable,
+	postIDs []uuid.UUID,
+) ([]*PostTag, error) {
+	stmt, args := builder.
+		From("blog.post_tag pt").
+		Join("blog.tag t ON t.id = pt.tag_id").
+		Where(builder.NewPredicate(
+			"pt.post_id = ANY(@post_ids)",
+			pgx.NamedArgs{"post_ids": postIDs},
+		)).
+		OrderBy(
+			builder.OrderBy{Column: "pt.post_id", Order: builder.Asc},
+			builder.OrderBy{Column: "t.name", Order: builder.Asc},
+		).
+		Select("pt.post_id", "pt.tag_id", "t.name")
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Int("posts", len(postIDs)),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+
+	postTags := make([]*PostTag, 0)
+	for rows.Next() {
+		var pt PostTag
+		if err := rows.Scan(&pt.PostID, &pt.TagID, &pt.Name); err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		postTags = append(postTags, &pt)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "post tags selected", slog.Int("count", len(postTags)))
+
+	return postTags, nil
+}
+
+func (m *PostTagModel) SelectByPostIDs(
+	ctx context.Context,
+	postIDs []uuid.UUID,
+) ([]*PostTag, error) {
+	return m.selectByPostIDs(ctx, m.DB, postIDs)
+}
+
+func (m *PostTagModel) SelectByPostIDsTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	postIDs []uuid.UUID,
+) ([]*PostTag, error) {
+	return m.selectByPostIDs(ctx, tx, postIDs)
+}
+
+// newTagPredicate limits posts to those tagged with any or all of the given tag names. Returns
+// an empty predicate when no tags are given.
+func newTagPredicate(tags []string, match TagMatch) builder.Predicate {
+	if len(tags) == 0 {
+		return builder.NewPredicate("", nil)
+	}
+
+	text := `id IN (
+    SELECT pt.post_id
+    FROM blog.post_tag pt
+             JOIN blog.tag t ON t.id = pt.tag_id
+    WHERE t.name = ANY(@predicate_tags)`
+	args := pgx.NamedArgs{"predicate_tags": tags}
+	if match == TagMatchAll {
+		text += `
+    GROUP BY pt.post_id
+    HAVING COUNT(DISTINCT t.id) = @predicate_tag_count`
+		args["predicate_tag_count"] = len(tags)
+	}
+
+	return builder.NewPredicate(text+")", args)
+}
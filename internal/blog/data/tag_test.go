@@ -0,0 +1,79 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	t.Run("UpsertMany", func(t *testing.T) {
+		tags, err := models.Tags.UpsertMany(ctx, []string{"upsert-a", "upsert-b"})
+		require.NoError(t, err)
+		require.Len(t, tags, 2)
+
+		again, err := models.Tags.UpsertMany(ctx, []string{"upsert-a"})
+		require.NoError(t, err)
+		require.Len(t, again, 1)
+		assert.Equal(t, tags[0].ID, again[0].ID)
+	})
+
+	t.Run("ReplaceAndSelect", func(t *testing.T) {
+		inserted, err := models.Posts.Insert(ctx, data.PostInput{
+			Title:     "Tagged",
+			Slug:      "tagged",
+			Content:   "Some tagged content",
+			Published: true,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, models.Posts.Delete(ctx, inserted.ID))
+		})
+
+		tags, err := models.Tags.UpsertMany(ctx, []string{"replace-a", "replace-b"})
+		require.NoError(t, err)
+		require.NoError(t, models.PostTags.Replace(
+			ctx, inserted.ID, []uuid.UUID{tags[0].ID, tags[1].ID},
+		))
+
+		postTags, err := models.PostTags.SelectByPostIDs(ctx, []uuid.UUID{inserted.ID})
+		require.NoError(t, err)
+		require.Len(t, postTags, 2)
+		assert.Equal(t, "replace-a", postTags[0].Name)
+
+		selected, _, err := models.Tags.SelectMany(ctx, data.TagFilter{
+			PageSize: 1,
+			Name:     sql.Null[string]{V: "replace-b", Valid: true},
+		})
+		require.NoError(t, err)
+		require.Len(t, selected, 1)
+		assert.Equal(t, int64(1), selected[0].PostCount.V)
+
+		posts, _, err := models.Posts.SelectMany(ctx, data.PostFilter{
+			PageSize: 10,
+			Tags:     []string{"replace-a", "replace-b"},
+			TagMatch: data.TagMatchAll,
+		})
+		require.NoError(t, err)
+		require.Len(t, posts, 1)
+		assert.Equal(t, inserted.ID, posts[0].ID)
+
+		require.NoError(t, models.PostTags.Replace(ctx, inserted.ID, nil))
+		postTags, err = models.PostTags.SelectByPostIDs(ctx, []uuid.UUID{inserted.ID})
+		require.NoError(t, err)
+		assert.Empty(t, postTags)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/url"
 
 	"github.com/google/uuid"
 	"github.com/r3d5un/islandwind/internal/api"
@@ -115,6 +116,55 @@ func GetBlogpostHandler(
 	}
 }
 
+// BlogpostBySlugPath is the path of the route serving GetBlogpostBySlugHandler, without the
+// slug. Used to redirect requests for previous slugs.
+const BlogpostBySlugPath string = "/api/v1/blog/post/by-slug/"
+
+func GetBlogpostBySlugHandler(
+	blogposts repo.PostReader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		slug := r.PathValue("slug")
+		if slug == "" {
+			api.InvalidParameterResponse(ctx, w, r, "slug", errors.New("slug must be provided"))
+			return
+		}
+
+		blogpost, err := blogposts.ReadBySlug(ctx, slug)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(blogpost, "blogpost cannot be nil without errors")
+
+		if blogpost.Slug != slug {
+			http.Redirect(
+				w, r, BlogpostBySlugPath+url.PathEscape(blogpost.Slug), http.StatusMovedPermanently,
+			)
+			return
+		}
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			BlogpostResponse{
+				Data: *blogpost,
+			},
+			nil,
+		)
+	}
+}
+
 func ListBlogpostHandler(
 	blogposts repo.PostReader,
 ) http.HandlerFunc {
@@ -136,7 +186,22 @@ func ListBlogpostHandler(
 		filters.Deleted = api.ReadQueryNull(api.ParseQueryBoolean(qs, "deleted", v))
 		filters.DeletedAtFrom = api.ReadQueryNull(api.ParseQueryDate(qs, "deleted_at_from", v))
 		filters.DeletedAtTo = api.ReadQueryNull(api.ParseQueryDate(qs, "deleted_at_to", v))
+		filters.Query = api.ReadQueryNull(api.ParseQueryString(qs, "q", v))
+		filters.Tags = api.ReadQueryStrings(qs, "tag")
+		filters.TagMatch = data.TagMatch(qs.Get("tag_match"))
+		switch filters.TagMatch {
+		case "":
+			filters.TagMatch = data.TagMatchAny
+		case data.TagMatchAny, data.TagMatchAll:
+		default:
+			v.AddError("tag_match", "must be either any or all")
+		}
 		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+		filters.LastRank = api.ReadQueryNull(api.ParseQueryFloat32(qs, "last_rank", v))
+		// Search results are ordered by rank, so they are continued from the last rank.
+		if filters.Query.Valid && filters.LastSeen != uuid.Nil && !filters.LastRank.Valid {
+			v.AddError("last_rank", "must be provided with last_seen when searching")
+		}
 
 		if !v.Valid() {
 			api.ValidationFailedResponse(ctx, w, r, v.Errors)
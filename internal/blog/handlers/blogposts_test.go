@@ -68,6 +68,43 @@ func TestBlogpostHandlers(t *testing.T) {
 		assert.Equal(t, post, resp)
 	})
 
+	t.Run("GetBlogpostBySlugHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		assert.NoError(t, err)
+		req.SetPathValue("slug", post.Data.Slug)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.GetBlogpostBySlugHandler(blogReaderWriter)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.BlogpostResponse
+		err = json.Unmarshal(rr.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, post.Data.ID, resp.Data.ID)
+	})
+
+	t.Run("GetBlogpostBySlugHandlerRedirect", func(t *testing.T) {
+		previousSlug := post.Data.Slug
+		updated, err := blogReaderWriter.Update(
+			context.Background(),
+			repo.PostPatch{ID: post.Data.ID, Title: new("Renamed Example Title")},
+		)
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		assert.NoError(t, err)
+		req.SetPathValue("slug", previousSlug)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.GetBlogpostBySlugHandler(blogReaderWriter)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, "/api/v1/blog/post/by-slug/"+updated.Slug, rr.Header().Get("Location"))
+	})
+
 	t.Run("ListBlogpostHandler", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "", nil)
 		assert.NoError(t, err)
@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+const (
+	maxCommentAuthorNameLength  int = 128
+	maxCommentAuthorEmailLength int = 320
+	maxCommentContentLength     int = 5000
+)
+
+type CommentResponse struct {
+	Data repo.Comment `json:"data"`
+}
+
+type CommentListResponse struct {
+	Metadata data.Metadata   `json:"metadata"`
+	Data     []*repo.Comment `json:"data"`
+}
+
+type CommentRequestBody struct {
+	Data CommentSubmission `json:"data"`
+}
+
+// CommentSubmission is a comment submitted by a reader.
+type CommentSubmission struct {
+	repo.CommentInput
+	// Website is a honeypot field. It is hidden from readers, so a value indicates that the
+	// comment was submitted by a bot.
+	Website string `json:"website"`
+}
+
+type CommentPatchRequestBody struct {
+	Data CommentPatch `json:"data"`
+}
+
+type CommentPatch struct {
+	AuthorName *string `json:"authorName"`
+	Content    *string `json:"content"`
+}
+
+// CommentRateLimiter limits the number of comments submitted by a client.
+type CommentRateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+// SubmitCommentHandler adds a comment to the blog post in the path. Comments are held for
+// moderation, so the handler responds with 202 Accepted. Submissions filling in the honeypot
+// field receive the same response, but are discarded.
+func SubmitCommentHandler(
+	comments repo.CommentWriter,
+	limiter CommentRateLimiter,
+	clientIPHeader string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		postID, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		if limiter != nil {
+			if ok, retryAfter := limiter.Allow(api.ClientIP(r, clientIPHeader)); !ok {
+				api.RateLimitExceededResponse(w, r, retryAfter)
+				return
+			}
+		}
+
+		var body CommentRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+		input := body.Data.CommentInput
+		input.AuthorName = strings.TrimSpace(input.AuthorName)
+		input.Content = strings.TrimSpace(input.Content)
+
+		v := validator.New()
+		validateCommentInput(v, input)
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		if body.Data.Website != "" {
+			logging.LoggerFromContext(ctx).LogAttrs(
+				ctx, slog.LevelInfo, "honeypot filled, discarding comment",
+			)
+			now := time.Now().UTC()
+			api.RespondWithJSON(w, r, http.StatusAccepted, CommentResponse{Data: repo.Comment{
+				ID:          uuid.Must(uuid.NewV7()),
+				PostID:      *postID,
+				ParentID:    input.ParentID,
+				AuthorName:  input.AuthorName,
+				AuthorEmail: input.AuthorEmail,
+				Content:     input.Content,
+				Status:      data.CommentPending,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}}, nil)
+			return
+		}
+
+		comment, err := comments.Submit(ctx, *postID, input)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound), errors.Is(err, repo.ErrCommentsClosed):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, repo.ErrInvalidParentComment):
+				v.AddError("parentId", "must be an approved comment on the same blog post")
+				api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(comment, "comment cannot be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusAccepted, CommentResponse{Data: *comment}, nil)
+	}
+}
+
+func validateCommentInput(v *validator.Validator, input repo.CommentInput) {
+	validateCommentAuthorName(v, input.AuthorName)
+	validateCommentContent(v, input.Content)
+	if input.AuthorEmail != nil {
+		_, err := mail.ParseAddress(*input.AuthorEmail)
+		v.Check(err == nil, "authorEmail", "must be a valid email address")
+		v.Check(
+			len(*input.AuthorEmail) <= maxCommentAuthorEmailLength,
+			"authorEmail",
+			fmt.Sprintf("must not be more than %d bytes long", maxCommentAuthorEmailLength),
+		)
+	}
+}
+
+func validateCommentAuthorName(v *validator.Validator, name string) {
+	v.Check(name != "", "authorName", "must be provided")
+	v.Check(
+		utf8.RuneCountInString(name) <= maxCommentAuthorNameLength,
+		"authorName",
+		fmt.Sprintf("must not be more than %d characters long", maxCommentAuthorNameLength),
+	)
+}
+
+func validateCommentContent(v *validator.Validator, content string) {
+	v.Check(content != "", "content", "must be provided")
+	v.Check(
+		utf8.RuneCountInString(content) <= maxCommentContentLength,
+		"content",
+		fmt.Sprintf("must not be more than %d characters long", maxCommentContentLength),
+	)
+}
+
+// ListApprovedCommentsHandler lists the approved comments on the blog post given by the
+// post_id query parameter.
+func ListApprovedCommentsHandler(comments repo.CommentReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.CommentFilter{}
+
+		postID := api.ReadOptionalQueryUUID(qs, "post_id", v)
+		v.Check(postID != nil, "post_id", "must be provided")
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 25, v)
+		filters.ParentID = api.ReadQueryNull(api.ParseQueryUUID(qs, "parent_id", v))
+		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, metadata, err := comments.ListApproved(ctx, *postID, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			CommentListResponse{
+				Data:     list,
+				Metadata: *metadata,
+			},
+			nil,
+		)
+	}
+}
+
+// ListCommentsHandler lists comments for moderators. By default, the moderation queue of
+// pending comments is listed.
+func ListCommentsHandler(comments repo.CommentReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.CommentFilter{}
+
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 25, v)
+		filters.ID = api.ReadQueryNull(api.ParseQueryUUID(qs, "id", v))
+		filters.PostID = api.ReadQueryNull(api.ParseQueryUUID(qs, "post_id", v))
+		filters.ParentID = api.ReadQueryNull(api.ParseQueryUUID(qs, "parent_id", v))
+		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+
+		status := data.CommentStatus(qs.Get("status"))
+		switch status {
+		case "":
+			filters.Status = sql.Null[data.CommentStatus]{V: data.CommentPending, Valid: true}
+		case "all":
+		case data.CommentPending, data.CommentApproved, data.CommentRejected:
+			filters.Status = sql.Null[data.CommentStatus]{V: status, Valid: true}
+		default:
+			v.AddError("status", "must be one of pending, approved, rejected or all")
+		}
+
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, metadata, err := comments.List(ctx, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			CommentListResponse{
+				Data:     list,
+				Metadata: *metadata,
+			},
+			nil,
+		)
+	}
+}
+
+func GetCommentHandler(comments repo.CommentReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		comment, err := comments.Read(ctx, *id)
+		if err != nil {
+			commentErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(comment, "comment cannot be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, CommentResponse{Data: *comment}, nil)
+	}
+}
+
+// ModerateCommentHandler sets the status of the comment in the path, approving or rejecting
+// it.
+func ModerateCommentHandler(
+	comments repo.CommentWriter,
+	status data.CommentStatus,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		comment, err := comments.Moderate(ctx, *id, status)
+		if err != nil {
+			commentErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(comment, "comment cannot be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, CommentResponse{Data: *comment}, nil)
+	}
+}
+
+func PatchCommentHandler(comments repo.CommentWriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		var body CommentPatchRequestBody
+		if err := api.ReadJSON(r, &body); err != nil {
+			api.BadRequestResponse(w, r, err, "unable to parse JSON request body")
+			return
+		}
+
+		v := validator.New()
+		v.Check(
+			body.Data.AuthorName != nil || body.Data.Content != nil,
+			"data",
+			"authorName or content must be provided",
+		)
+		if body.Data.AuthorName != nil {
+			validateCommentAuthorName(v, strings.TrimSpace(*body.Data.AuthorName))
+		}
+		if body.Data.Content != nil {
+			validateCommentContent(v, strings.TrimSpace(*body.Data.Content))
+		}
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		comment, err := comments.Update(ctx, repo.CommentPatch{
+			ID:         *id,
+			AuthorName: trimSpacePtr(body.Data.AuthorName),
+			Content:    trimSpacePtr(body.Data.Content),
+		})
+		if err != nil {
+			commentErrorResponse(ctx, w, r, err)
+			return
+		}
+		ensure.NotNil(comment, "comment cannot be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, CommentResponse{Data: *comment}, nil)
+	}
+}
+
+func trimSpacePtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	return new(strings.TrimSpace(*s))
+}
+
+// DeleteCommentHandler deletes the comment in the path, including all replies to it.
+func DeleteCommentHandler(comments repo.CommentWriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		if err := comments.Delete(ctx, *id); err != nil {
+			commentErrorResponse(ctx, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func commentErrorResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		api.NotFoundResponse(ctx, w, r)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
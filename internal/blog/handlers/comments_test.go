@@ -0,0 +1,210 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/blog/handlers"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type denyLimiter struct{}
+
+func (denyLimiter) Allow(string) (bool, time.Duration) {
+	return false, time.Minute
+}
+
+func newCommentRequest(t *testing.T, postID string, submission handlers.CommentSubmission) *http.Request {
+	t.Helper()
+
+	body, err := json.Marshal(handlers.CommentRequestBody{Data: submission})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "", strings.NewReader(string(body)))
+	require.NoError(t, err)
+	req.SetPathValue("id", postID)
+
+	return req
+}
+
+func TestCommentHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	post, err := blogReaderWriter.Create(ctx, repo.PostInput{
+		Title:     "Commented Post",
+		Content:   "Some sample content",
+		Published: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, blogReaderWriter.Delete(ctx, post.ID))
+	})
+
+	var comment handlers.CommentResponse
+
+	t.Run("SubmitCommentHandler", func(t *testing.T) {
+		req := newCommentRequest(t, post.ID.String(), handlers.CommentSubmission{
+			CommentInput: repo.CommentInput{
+				AuthorName:  "Reader",
+				AuthorEmail: new("reader@example.com"),
+				Content:     "Nice post!",
+			},
+		})
+
+		rr := httptest.NewRecorder()
+		handlers.SubmitCommentHandler(commentReaderWriter, nil, "").ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &comment))
+		assert.Equal(t, data.CommentPending, comment.Data.Status)
+	})
+
+	t.Run("SubmitCommentHandlerHoneypot", func(t *testing.T) {
+		req := newCommentRequest(t, post.ID.String(), handlers.CommentSubmission{
+			CommentInput: repo.CommentInput{AuthorName: "Bot", Content: "Buy now"},
+			Website:      "https://spam.example.com",
+		})
+
+		rr := httptest.NewRecorder()
+		handlers.SubmitCommentHandler(commentReaderWriter, nil, "").ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		var resp handlers.CommentResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		_, err := commentReaderWriter.Read(ctx, resp.Data.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("SubmitCommentHandlerRateLimited", func(t *testing.T) {
+		req := newCommentRequest(t, post.ID.String(), handlers.CommentSubmission{
+			CommentInput: repo.CommentInput{AuthorName: "Reader", Content: "Again!"},
+		})
+
+		rr := httptest.NewRecorder()
+		handlers.SubmitCommentHandler(commentReaderWriter, denyLimiter{}, "").ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("SubmitCommentHandlerInvalidParent", func(t *testing.T) {
+		// Replies to comments pending moderation are not accepted.
+		req := newCommentRequest(t, post.ID.String(), handlers.CommentSubmission{
+			CommentInput: repo.CommentInput{
+				ParentID:   &comment.Data.ID,
+				AuthorName: "Reader",
+				Content:    "Reply",
+			},
+		})
+
+		rr := httptest.NewRecorder()
+		handlers.SubmitCommentHandler(commentReaderWriter, nil, "").ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("ListApprovedCommentsHandlerPending", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/?post_id="+post.ID.String(), nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListApprovedCommentsHandler(commentReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.CommentListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Empty(t, resp.Data)
+	})
+
+	t.Run("ListCommentsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/?post_id="+post.ID.String(), nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListCommentsHandler(commentReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.CommentListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+		assert.Equal(t, comment.Data.ID, resp.Data[0].ID)
+	})
+
+	t.Run("ModerateCommentHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", comment.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.ModerateCommentHandler(commentReaderWriter, data.CommentApproved).
+			ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.CommentResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, data.CommentApproved, resp.Data.Status)
+		assert.NotNil(t, resp.Data.ModeratedAt)
+	})
+
+	t.Run("ListApprovedCommentsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/?post_id="+post.ID.String(), nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListApprovedCommentsHandler(commentReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.CommentListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+		assert.Equal(t, comment.Data.ID, resp.Data[0].ID)
+		assert.Nil(t, resp.Data[0].AuthorEmail)
+	})
+
+	t.Run("PatchCommentHandler", func(t *testing.T) {
+		body, err := json.Marshal(handlers.CommentPatchRequestBody{
+			Data: handlers.CommentPatch{Content: new("Nice post, edited")},
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPatch, "", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.SetPathValue("id", comment.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.PatchCommentHandler(commentReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp handlers.CommentResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "Nice post, edited", resp.Data.Content)
+	})
+
+	t.Run("DeleteCommentHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", comment.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.DeleteCommentHandler(commentReaderWriter).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = httptest.NewRecorder()
+		handlers.GetCommentHandler(commentReaderWriter).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
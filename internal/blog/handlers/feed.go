@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/blog/config"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/feed"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// FeedHandler serves the most recent published blog posts as a syndication feed in the given
+// format. Conditional requests using If-None-Match or If-Modified-Since are answered with 304
+// Not Modified.
+func FeedHandler(
+	blogposts repo.PostReader,
+	format feed.Format,
+	cfg config.Config,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.LoggerFromContext(ctx)
+
+		posts, _, err := blogposts.List(ctx, data.PostFilter{
+			Published:  sql.Null[bool]{V: true, Valid: true},
+			Deleted:    sql.Null[bool]{V: false, Valid: true},
+			Descending: true,
+			PageSize:   cfg.FeedSize,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		siteURL := strings.TrimSuffix(cfg.SiteURL, "/")
+		f := feed.Feed{
+			Title:       cfg.FeedTitle,
+			Description: cfg.FeedDescription,
+			Link:        siteURL,
+			FeedURL:     requestURL(r),
+			Items:       make([]feed.Item, len(posts)),
+		}
+		for i, post := range posts {
+			f.Items[i] = feed.Item{
+				ID:          "urn:uuid:" + post.ID.String(),
+				Title:       post.Title,
+				Link:        siteURL + "/blog/" + post.Slug,
+				ContentHTML: post.ContentHTML,
+				Tags:        post.Tags,
+				Published:   post.CreatedAt,
+				Updated:     post.UpdatedAt,
+			}
+			if post.UpdatedAt.After(f.Updated) {
+				f.Updated = post.UpdatedAt
+			}
+		}
+
+		etag := f.ETag(format)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if !f.Updated.IsZero() {
+			w.Header().Set("Last-Modified", f.Updated.UTC().Format(http.TimeFormat))
+		}
+		if notModified(r, etag, f.Updated) {
+			logger.LogAttrs(ctx, slog.LevelInfo, "feed not modified", slog.String("etag", etag))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		body, err := f.Encode(format)
+		if err != nil {
+			api.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "unable to write feed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes precedence over
+// If-Modified-Since, as described in RFC 9110.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if strings.TrimSpace(inm) == "*" {
+			return true
+		}
+		tags := strings.Split(inm, ",")
+		for i, tag := range tags {
+			tags[i] = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		}
+		return slices.Contains(tags, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// requestURL reconstructs the absolute URL of the request, respecting X-Forwarded-Proto when
+// running behind a reverse proxy.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
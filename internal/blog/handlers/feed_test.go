@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/blog/config"
+	"github.com/r3d5un/islandwind/internal/blog/handlers"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/feed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedHandler(t *testing.T) {
+	cfg := config.Config{
+		SiteURL:   "https://example.com",
+		FeedTitle: "islandwind",
+		FeedSize:  20,
+	}
+
+	post, err := blogReaderWriter.Create(context.Background(), repo.PostInput{
+		Title:     "Feed Example",
+		Content:   "Some **markdown** content",
+		Published: true,
+		Tags:      []string{"feeds"},
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		_ = blogReaderWriter.Delete(context.Background(), post.ID)
+	})
+
+	var etag string
+
+	t.Run("JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/blog/feed.json", nil)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.FeedHandler(blogReaderWriter, feed.JSON, cfg)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, feed.JSON.ContentType(), rr.Header().Get("Content-Type"))
+		assert.NotEmpty(t, rr.Header().Get("Last-Modified"))
+		etag = rr.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+		assert.Contains(t, rr.Body.String(), "https://example.com/blog/"+post.Slug)
+		assert.Contains(t, rr.Body.String(), `<strong>markdown</strong>`)
+	})
+
+	t.Run("IfNoneMatch", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/blog/feed.json", nil)
+		req.Header.Set("If-None-Match", etag)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.FeedHandler(blogReaderWriter, feed.JSON, cfg)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.Bytes())
+	})
+
+	t.Run("IfModifiedSince", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/blog/feed.rss", nil)
+
+		rr := httptest.NewRecorder()
+		handler := handlers.FeedHandler(blogReaderWriter, feed.RSS, cfg)
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/blog/feed.rss", nil)
+		req.Header.Set("If-Modified-Since", rr.Header().Get("Last-Modified"))
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+	})
+}
@@ -15,7 +15,11 @@ import (
 	"github.com/r3d5un/islandwind/internal/testsuite"
 )
 
-var blogReaderWriter repo.PostReaderWriter
+var (
+	blogReaderWriter    repo.PostReaderWriter
+	tagReader           repo.TagReader
+	commentReaderWriter repo.CommentReaderWriter
+)
 
 func TestMain(m *testing.M) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -46,6 +50,8 @@ func TestMain(m *testing.M) {
 
 	repository := repo.NewRepository(db, postgresCache, new(cfg.TimeoutDuration()))
 	blogReaderWriter = repository.Posts
+	tagReader = repository.Tags
+	commentReaderWriter = repository.Comments
 
 	exitCode := m.Run()
 
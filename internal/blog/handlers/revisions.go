@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/diff"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+type RevisionListResponse struct {
+	Metadata data.Metadata        `json:"metadata"`
+	Data     []*repo.PostRevision `json:"data"`
+}
+
+type RevisionDiffResponse struct {
+	Data repo.RevisionDiff `json:"data"`
+}
+
+func ListRevisionsHandler(revisions repo.PostRevisionReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		postID, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.PostRevisionFilter{
+			PostID: sql.Null[uuid.UUID]{V: *postID, Valid: true},
+		}
+
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 25, v)
+		filters.CreatedAtFrom = api.ReadQueryNull(api.ParseQueryDate(qs, "created_at_from", v))
+		filters.CreatedAtTo = api.ReadQueryNull(api.ParseQueryDate(qs, "created_at_to", v))
+		filters.LastRevision = api.ReadRequiredQueryInt(qs, "last_revision", 0, v)
+
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, metadata, err := revisions.ListRevisions(ctx, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			RevisionListResponse{
+				Data:     list,
+				Metadata: *metadata,
+			},
+			nil,
+		)
+	}
+}
+
+func DiffRevisionsHandler(revisions repo.PostRevisionReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		postID, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		v := validator.New()
+		qs := r.URL.Query()
+		from := api.ReadOptionalQueryUUID(qs, "from", v)
+		to := api.ReadOptionalQueryUUID(qs, "to", v)
+		v.Check(from != nil, "from", "must be provided")
+
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		revisionDiff, err := revisions.DiffRevisions(ctx, *postID, *from, to)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, diff.ErrTooLarge):
+				api.ErrorResponse(
+					w, r, http.StatusUnprocessableEntity, "revisions are too large to compare",
+				)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(revisionDiff, "revision diff should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			RevisionDiffResponse{
+				Data: *revisionDiff,
+			},
+			nil,
+		)
+	}
+}
+
+func RestoreRevisionHandler(revisions repo.PostRevisionWriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		postID, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+		revisionID, err := api.ReadPathParamID(ctx, "revisionId", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "revisionId", err)
+			return
+		}
+
+		blogpost, err := revisions.RestoreRevision(ctx, *postID, *revisionID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, db.ErrUniqueConstraintViolation):
+				api.ConstraintViolationResponse(w, r, err, "blogpost title already exists")
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(blogpost, "blogpost cannot be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			BlogpostResponse{
+				Data: *blogpost,
+			},
+			nil,
+		)
+	}
+}
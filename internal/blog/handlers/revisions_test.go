@@ -0,0 +1,233 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/handlers"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	post, err := blogReaderWriter.Create(ctx, repo.PostInput{
+		Title:   "Revision Handler Title",
+		Content: "First line\nSecond line",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, blogReaderWriter.Purge(context.Background(), post.ID))
+	})
+
+	_, err = blogReaderWriter.Update(
+		ctx,
+		repo.PostPatch{ID: post.ID, Content: new("First line\nChanged line")},
+	)
+	require.NoError(t, err)
+
+	var revisionID uuid.UUID
+
+	t.Run("ListRevisionsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", post.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.ListRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.RevisionListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+		assert.Equal(t, 1, resp.Data[0].Revision)
+		assert.Equal(t, "First line\nSecond line", resp.Data[0].Content)
+		assert.Equal(t, 1, resp.Metadata.LastRevision)
+		revisionID = resp.Data[0].ID
+	})
+
+	t.Run("ListRevisionsHandlerLastRevision", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodGet, "?"+url.Values{"last_revision": {"1"}}.Encode(), nil,
+		)
+		require.NoError(t, err)
+		req.SetPathValue("id", post.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.ListRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.RevisionListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Empty(t, resp.Data)
+	})
+
+	t.Run("ListRevisionsHandlerInvalidID", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", "not-a-uuid")
+
+		rr := httptest.NewRecorder()
+		handlers.ListRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Contains(t, rr.Body.String(), "id is not a valid parameter")
+	})
+
+	t.Run("DiffRevisionsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodGet, "?"+url.Values{"from": {revisionID.String()}}.Encode(), nil,
+		)
+		require.NoError(t, err)
+		req.SetPathValue("id", post.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.DiffRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.RevisionDiffResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.True(t, resp.Data.Changed)
+		assert.Nil(t, resp.Data.To)
+		assert.Equal(t, []diff.Line{
+			{Op: diff.Equal, Text: "First line", OldNumber: 1, NewNumber: 1},
+			{Op: diff.Delete, Text: "Second line", OldNumber: 2},
+			{Op: diff.Insert, Text: "Changed line", NewNumber: 2},
+		}, resp.Data.Content)
+	})
+
+	t.Run("DiffRevisionsHandlerValidation", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			query url.Values
+			field string
+		}{
+			{name: "MissingFrom", query: url.Values{}, field: "from"},
+			{name: "InvalidFrom", query: url.Values{"from": {"not-a-uuid"}}, field: "from"},
+			{
+				name:  "InvalidTo",
+				query: url.Values{"from": {revisionID.String()}, "to": {"not-a-uuid"}},
+				field: "to",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodGet, "?"+tc.query.Encode(), nil)
+				require.NoError(t, err)
+				req.SetPathValue("id", post.ID.String())
+
+				rr := httptest.NewRecorder()
+				handlers.DiffRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+				assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+				assert.Contains(t, rr.Body.String(), tc.field)
+			})
+		}
+	})
+
+	t.Run("DiffRevisionsHandlerInvalidID", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodGet, "?"+url.Values{"from": {revisionID.String()}}.Encode(), nil,
+		)
+		require.NoError(t, err)
+		req.SetPathValue("id", "not-a-uuid")
+
+		rr := httptest.NewRecorder()
+		handlers.DiffRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Contains(t, rr.Body.String(), "id is not a valid parameter")
+	})
+
+	t.Run("DiffRevisionsHandlerUnknownRevision", func(t *testing.T) {
+		req, err := http.NewRequest(
+			http.MethodGet, "?"+url.Values{"from": {uuid.New().String()}}.Encode(), nil,
+		)
+		require.NoError(t, err)
+		req.SetPathValue("id", post.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.DiffRevisionsHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("RestoreRevisionHandlerInvalidID", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			postID     string
+			revisionID string
+			param      string
+		}{
+			{
+				name:       "PostID",
+				postID:     "not-a-uuid",
+				revisionID: revisionID.String(),
+				param:      "id",
+			},
+			{
+				name:       "RevisionID",
+				postID:     post.ID.String(),
+				revisionID: "not-a-uuid",
+				param:      "revisionId",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodPost, "", nil)
+				require.NoError(t, err)
+				req.SetPathValue("id", tc.postID)
+				req.SetPathValue("revisionId", tc.revisionID)
+
+				rr := httptest.NewRecorder()
+				handlers.RestoreRevisionHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+				assert.Equal(t, http.StatusNotFound, rr.Code)
+				assert.Contains(t, rr.Body.String(), tc.param+" is not a valid parameter")
+			})
+		}
+	})
+
+	t.Run("RestoreRevisionHandlerUnknownRevision", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", post.ID.String())
+		req.SetPathValue("revisionId", uuid.New().String())
+
+		rr := httptest.NewRecorder()
+		handlers.RestoreRevisionHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("RestoreRevisionHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", post.ID.String())
+		req.SetPathValue("revisionId", revisionID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.RestoreRevisionHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.BlogpostResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "First line\nSecond line", resp.Data.Content)
+	})
+}
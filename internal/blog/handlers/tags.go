@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+type TagListResponse struct {
+	Metadata data.Metadata `json:"metadata"`
+	Data     []*repo.Tag   `json:"data"`
+}
+
+func ListTagsHandler(tags repo.TagReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.TagFilter{}
+
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 100, v)
+		filters.ID = api.ReadQueryNull(api.ParseQueryUUID(qs, "id", v))
+		filters.Name = api.ReadQueryNull(api.ParseQueryString(qs, "name", v))
+		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, metadata, err := tags.List(ctx, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			TagListResponse{
+				Data:     list,
+				Metadata: *metadata,
+			},
+			nil,
+		)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/handlers"
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	posts := make([]*repo.Post, 0, 2)
+	for _, input := range []repo.PostInput{
+		{Title: "Tagged Handler Go", Published: true, Tags: []string{"Handler-Go"}},
+		{
+			Title:     "Tagged Handler Go And SQL",
+			Published: true,
+			Tags:      []string{"handler-go", "handler-sql"},
+		},
+	} {
+		post, err := blogReaderWriter.Create(ctx, input)
+		require.NoError(t, err)
+		posts = append(posts, post)
+	}
+	t.Cleanup(func() {
+		for _, post := range posts {
+			assert.NoError(t, blogReaderWriter.Purge(context.Background(), post.ID))
+		}
+	})
+
+	listTags := func(t *testing.T, query url.Values) (int, handlers.TagListResponse) {
+		req, err := http.NewRequest(http.MethodGet, "?"+query.Encode(), nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListTagsHandler(tagReader).ServeHTTP(rr, req)
+
+		var resp handlers.TagListResponse
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		}
+		return rr.Code, resp
+	}
+
+	listPosts := func(t *testing.T, query url.Values) (int, []uuid.UUID) {
+		req, err := http.NewRequest(http.MethodGet, "?"+query.Encode(), nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListBlogpostHandler(blogReaderWriter).ServeHTTP(rr, req)
+
+		var resp handlers.BlogpostListResponse
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		}
+		ids := make([]uuid.UUID, len(resp.Data))
+		for i, post := range resp.Data {
+			ids[i] = post.ID
+		}
+		return rr.Code, ids
+	}
+
+	t.Run("ListTagsHandler", func(t *testing.T) {
+		status, resp := listTags(t, url.Values{"name": {"handler-go"}})
+		assert.Equal(t, http.StatusOK, status)
+		require.Len(t, resp.Data, 1)
+		assert.Equal(t, "handler-go", resp.Data[0].Name)
+		assert.Equal(t, int64(2), resp.Data[0].PostCount)
+	})
+
+	t.Run("ListTagsHandlerInvalidID", func(t *testing.T) {
+		status, _ := listTags(t, url.Values{"id": {"not-a-uuid"}})
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+
+	t.Run("ListBlogpostHandlerTag", func(t *testing.T) {
+		status, ids := listPosts(t, url.Values{"tag": {"Handler-SQL"}})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []uuid.UUID{posts[1].ID}, ids)
+	})
+
+	t.Run("ListBlogpostHandlerTagMatchAny", func(t *testing.T) {
+		status, ids := listPosts(t, url.Values{"tag": {"handler-go", "handler-sql"}})
+		assert.Equal(t, http.StatusOK, status)
+		assert.ElementsMatch(t, []uuid.UUID{posts[0].ID, posts[1].ID}, ids)
+	})
+
+	t.Run("ListBlogpostHandlerTagMatchAll", func(t *testing.T) {
+		status, ids := listPosts(t, url.Values{
+			"tag":       {"handler-go", "handler-sql"},
+			"tag_match": {"all"},
+		})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []uuid.UUID{posts[1].ID}, ids)
+	})
+
+	t.Run("ListBlogpostHandlerInvalidTagMatch", func(t *testing.T) {
+		status, _ := listPosts(t, url.Values{"tag": {"handler-go"}, "tag_match": {"some"}})
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+}
@@ -6,36 +6,58 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oapi-codegen/nullable"
 	"github.com/r3d5un/islandwind/internal/blog/data"
 	"github.com/r3d5un/islandwind/internal/cache"
 	"github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/ensure"
 	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/markdown"
 )
 
 type Post struct {
-	ID        uuid.UUID  `json:"id"`
-	Title     string     `json:"title"`
-	Content   string     `json:"content"`
-	Published bool       `json:"published"`
-	CreatedAt time.Time  `json:"createdAt"`
-	UpdatedAt time.Time  `json:"updatedAt"`
-	Deleted   bool       `json:"deleted"`
-	DeletedAt *time.Time `json:"deletedAt"`
+	ID      uuid.UUID `json:"id"`
+	Title   string    `json:"title"`
+	Slug    string    `json:"slug"`
+	Content string    `json:"content"`
+	// ContentHTML is the content rendered from markdown to sanitized HTML.
+	ContentHTML string `json:"contentHtml"`
+	// TOC is the table of contents of the rendered content.
+	TOC       []markdown.Heading `json:"toc"`
+	Published bool               `json:"published"`
+	// PublishAt is when the post is scheduled to be published, if scheduled.
+	PublishAt *time.Time `json:"publishAt"`
+	// UnpublishAt is when the post is scheduled to be unpublished, if scheduled.
+	UnpublishAt *time.Time `json:"unpublishAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	Deleted     bool       `json:"deleted"`
+	DeletedAt   *time.Time `json:"deletedAt"`
+	Rank        *float32   `json:"rank,omitempty"`
+	Snippet     *string    `json:"snippet,omitempty"`
+	Tags        []string   `json:"tags"`
 }
 
 type PostInput struct {
 	Title     string `json:"title"`
 	Content   string `json:"content"`
 	Published bool   `json:"published"`
+	// PublishAt schedules the post to be published at the given time.
+	PublishAt *time.Time `json:"publishAt"`
+	// UnpublishAt schedules the post to be unpublished at the given time.
+	UnpublishAt *time.Time `json:"unpublishAt"`
+	Tags        []string   `json:"tags"`
 }
 
 func (p *PostInput) row() data.PostInput {
 	return data.PostInput{
-		Title:     p.Title,
-		Content:   p.Content,
-		Published: p.Published,
+		Title:       p.Title,
+		Content:     p.Content,
+		Published:   p.Published,
+		PublishAt:   db.NewNullTime(p.PublishAt),
+		UnpublishAt: db.NewNullTime(p.UnpublishAt),
 	}
 }
 
@@ -45,20 +67,31 @@ type PostPatch struct {
 	Content   *string   `json:"content"`
 	Published *bool     `json:"published"`
 	Deleted   *bool     `json:"deleted"`
+	// PublishAt schedules the post to be published. Set to null to cancel the schedule.
+	PublishAt nullable.Nullable[time.Time] `json:"publishAt,omitempty"`
+	// UnpublishAt schedules the post to be unpublished. Set to null to cancel the schedule.
+	UnpublishAt nullable.Nullable[time.Time] `json:"unpublishAt,omitempty"`
+	// Tags replaces the tags of the post when not nil. An empty list removes all tags.
+	Tags *[]string `json:"tags"`
 }
 
 func (p *PostPatch) row() data.PostPatch {
 	return data.PostPatch{
-		ID:        p.ID,
-		Title:     db.PtrToNull(p.Title),
-		Content:   db.PtrToNull(p.Content),
-		Published: db.PtrToNull(p.Published),
-		Deleted:   db.PtrToNull(p.Deleted),
+		ID:          p.ID,
+		Title:       db.PtrToNull(p.Title),
+		Content:     db.PtrToNull(p.Content),
+		Published:   db.PtrToNull(p.Published),
+		PublishAt:   p.PublishAt,
+		UnpublishAt: p.UnpublishAt,
+		Deleted:     db.PtrToNull(p.Deleted),
 	}
 }
 
 type PostReader interface {
 	Read(ctx context.Context, ID uuid.UUID) (*Post, error)
+	// ReadBySlug reads a blog post by its current or a previous slug. Callers can compare the
+	// slug of the returned post to detect that the slug has changed.
+	ReadBySlug(ctx context.Context, slug string) (*Post, error)
 	List(ctx context.Context, filter data.PostFilter) ([]*Post, *data.Metadata, error)
 }
 
@@ -73,6 +106,9 @@ type PostWriter interface {
 type PostReaderWriter interface {
 	PostReader
 	PostWriter
+	PostRevisionReader
+	PostRevisionWriter
+	PostScheduler
 }
 
 type BlogpostService struct {
@@ -114,6 +150,25 @@ func (svc *BlogpostService) Read(ctx context.Context, ID uuid.UUID) (*Post, erro
 	return blogpost, nil
 }
 
+func (svc *BlogpostService) ReadBySlug(ctx context.Context, slug string) (*Post, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"post",
+		slog.String("slug", slug),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading blog post by slug")
+	blogpost, err := svc.blogpostStore.ReadBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	ensure.NotNil(blogpost, "blog post cannot be nil without errors")
+	logger.LogAttrs(
+		ctx, slog.LevelInfo, "blog post retrieved", slog.String("currentSlug", blogpost.Slug),
+	)
+
+	return blogpost, nil
+}
+
 func (svc *BlogpostService) List(
 	ctx context.Context,
 	filter data.PostFilter,
@@ -124,6 +179,7 @@ func (svc *BlogpostService) List(
 	))
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "reading blog posts")
+	filter.Tags = NormalizeTags(filter.Tags)
 	posts, metadata, err := svc.blogpostStore.List(ctx, filter)
 	if err != nil {
 		return nil, nil, err
@@ -141,11 +197,22 @@ func (svc *BlogpostService) Create(ctx context.Context, input PostInput) (*Post,
 	))
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "creating blog post")
-	blogpost, err := svc.blogpostStore.Create(ctx, input)
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	blogpost, err := svc.blogpostStore.Create(ctx, tx, input)
 	if err != nil {
 		return nil, err
 	}
 	ensure.NotNil(blogpost, "blog post cannot be nil without errors")
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	logger.LogAttrs(ctx, slog.LevelInfo, "blog post created")
 
 	return blogpost, nil
@@ -158,17 +225,67 @@ func (svc *BlogpostService) Update(ctx context.Context, patch PostPatch) (*Post,
 	))
 
 	logger.LogAttrs(ctx, slog.LevelInfo, "updating blog post")
-	post, err := svc.blogpostStore.Update(ctx, patch)
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	post, err := svc.update(ctx, tx, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	svc.invalidate(ctx, post.ID)
+	logger.LogAttrs(ctx, slog.LevelInfo, "blog post updated")
+
+	return post, nil
+}
+
+// update applies the patch as part of the given transaction. If the patch changes the title or
+// content, the current title and content are stored as a revision before they are overwritten.
+// Callers must invalidate the cached post once the transaction is committed.
+func (svc *BlogpostService) update(ctx context.Context, tx pgx.Tx, patch PostPatch) (*Post, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if patch.Title != nil || patch.Content != nil {
+		revision, err := svc.models.PostRevisions.SnapshotTx(ctx, tx, patch.ID)
+		if err != nil {
+			return nil, err
+		}
+		logger.LogAttrs(
+			ctx, slog.LevelInfo, "blog post revision recorded", slog.Int("revision", revision.Revision),
+		)
+	}
+
+	post, err := svc.blogpostStore.Update(ctx, tx, patch)
 	if err != nil {
 		return nil, err
 	}
 	ensure.NotNil(post, "blog post cannot be nil without errors")
 	ensure.Equal(post.ID, patch.ID, "blog post ID must match")
-	logger.LogAttrs(ctx, slog.LevelInfo, "blog post updated")
 
 	return post, nil
 }
 
+// invalidate removes the blog post from the cache. Called after committing changes to the post,
+// so that concurrent reads cannot cache the post as it was before the changes.
+func (svc *BlogpostService) invalidate(ctx context.Context, ID uuid.UUID) {
+	if err := svc.cache.Delete(ID); err != nil {
+		logging.LoggerFromContext(ctx).LogAttrs(
+			ctx,
+			slog.LevelError,
+			"unable to invalidate cache",
+			slog.String("id", ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
 func (svc *BlogpostService) Delete(ctx context.Context, ID uuid.UUID) error {
 	logger := logging.LoggerFromContext(ctx).With(slog.Group(
 		"blogpost",
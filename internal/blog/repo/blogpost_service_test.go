@@ -3,12 +3,17 @@ package repo_test
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/oapi-codegen/nullable"
 	"github.com/r3d5un/islandwind/internal/blog/data"
 	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/markdown"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,6 +60,31 @@ func TestPostRepository(t *testing.T) {
 		assert.Equal(t, *created, *read)
 	})
 
+	t.Run("RenderedContent", func(t *testing.T) {
+		created, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{
+				Title:     "Example Title",
+				Content:   "## Introduction\n\nSome *placeholder* content\n\n<script>alert(1)</script>",
+				Published: true,
+			},
+		)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, created.ID))
+		})
+
+		read, err := blog.Posts.Read(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Contains(t, read.ContentHTML, `<h2 id="introduction">`)
+		assert.Contains(t, read.ContentHTML, "<em>placeholder</em>")
+		assert.NotContains(t, read.ContentHTML, "<script>")
+		assert.Equal(
+			t, []markdown.Heading{{Level: 2, ID: "introduction", Text: "Introduction"}}, read.TOC,
+		)
+	})
+
 	t.Run("List", func(t *testing.T) {
 		created, err := blog.Posts.Create(
 			ctx,
@@ -110,6 +140,267 @@ func TestPostRepository(t *testing.T) {
 		assert.False(t, updated.Deleted)
 	})
 
+	t.Run("Revisions", func(t *testing.T) {
+		created, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{
+				Title:     "Example Title",
+				Content:   "Some placeholder content",
+				Published: true,
+			},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, created.ID))
+		})
+
+		updated, err := blog.Posts.Update(
+			ctx,
+			repo.PostPatch{ID: created.ID, Content: new("Some updated content")},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "Some updated content", updated.Content)
+
+		revisions, metadata, err := blog.Posts.ListRevisions(
+			ctx,
+			data.PostRevisionFilter{
+				PageSize: 10,
+				PostID:   sql.Null[uuid.UUID]{V: created.ID, Valid: true},
+			},
+		)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, 1, metadata.ResponseLength)
+		assert.Equal(t, created.Content, revisions[0].Content)
+
+		revisionDiff, err := blog.Posts.DiffRevisions(ctx, created.ID, revisions[0].ID, nil)
+		require.NoError(t, err)
+		assert.True(t, revisionDiff.Changed)
+
+		restored, err := blog.Posts.RestoreRevision(ctx, created.ID, revisions[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.Content, restored.Content)
+	})
+
+	t.Run("ConcurrentRevisions", func(t *testing.T) {
+		created, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{Title: "Concurrent Title", Content: "Some content", Published: true},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, created.ID))
+		})
+
+		// Concurrent updates are serialised on the post, each recording the next revision.
+		const updates int = 8
+		var wg sync.WaitGroup
+		errs := make([]error, updates)
+		for i := range updates {
+			wg.Go(func() {
+				_, errs[i] = blog.Posts.Update(
+					ctx,
+					repo.PostPatch{ID: created.ID, Content: new(fmt.Sprintf("Update %d", i))},
+				)
+			})
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+
+		revisions, _, err := blog.Posts.ListRevisions(
+			ctx,
+			data.PostRevisionFilter{
+				PageSize: updates * 2,
+				PostID:   sql.Null[uuid.UUID]{V: created.ID, Valid: true},
+			},
+		)
+		require.NoError(t, err)
+		numbers := make([]int, 0, len(revisions))
+		for _, revision := range revisions {
+			numbers = append(numbers, revision.Revision)
+		}
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, numbers)
+
+		// Pages continue after the last revision number.
+		filter := data.PostRevisionFilter{
+			PageSize: 3,
+			PostID:   sql.Null[uuid.UUID]{V: created.ID, Valid: true},
+		}
+		paged := make([]int, 0, updates)
+		for {
+			page, metadata, err := blog.Posts.ListRevisions(ctx, filter)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			for _, revision := range page {
+				paged = append(paged, revision.Revision)
+			}
+			filter.LastRevision = metadata.LastRevision
+		}
+		assert.Equal(t, numbers, paged)
+	})
+
+	t.Run("Slugs", func(t *testing.T) {
+		first, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{Title: "Crème Brûlée", Content: "Some placeholder content"},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, first.ID))
+		})
+		assert.Equal(t, "creme-brulee", first.Slug)
+
+		second, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{Title: "Creme Brulee", Content: "Some placeholder content"},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, second.ID))
+		})
+		assert.Equal(t, "creme-brulee-2", second.Slug)
+
+		updated, err := blog.Posts.Update(
+			ctx,
+			repo.PostPatch{ID: first.ID, Title: new("Tarte Tatin")},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "tarte-tatin", updated.Slug)
+
+		read, err := blog.Posts.ReadBySlug(ctx, "tarte-tatin")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, read.ID)
+
+		moved, err := blog.Posts.ReadBySlug(ctx, "creme-brulee")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, moved.ID)
+		assert.Equal(t, "tarte-tatin", moved.Slug)
+
+		third, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{Title: "Crème brûlée!", Content: "Some placeholder content"},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, third.ID))
+		})
+		assert.Equal(t, "creme-brulee-3", third.Slug, "previous slugs must not be reused")
+
+		_, err = blog.Posts.ReadBySlug(ctx, "does-not-exist")
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("Schedule", func(t *testing.T) {
+		created, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{
+				Title:     "Scheduled Title",
+				Content:   "Some placeholder content",
+				Published: false,
+				PublishAt: new(time.Now().Add(-time.Minute)),
+			},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, created.ID))
+		})
+		require.NotNil(t, created.PublishAt)
+
+		result, err := blog.Posts.PublishScheduled(ctx)
+		require.NoError(t, err)
+		assert.True(t, result.Locked)
+		assert.Contains(t, result.Published, created.ID)
+
+		read, err := blog.Posts.Read(ctx, created.ID)
+		require.NoError(t, err)
+		assert.True(t, read.Published)
+		assert.Nil(t, read.PublishAt)
+
+		updated, err := blog.Posts.Update(
+			ctx,
+			repo.PostPatch{
+				ID:          created.ID,
+				UnpublishAt: nullable.NewNullableWithValue(time.Now().Add(-time.Second)),
+			},
+		)
+		require.NoError(t, err)
+		require.NotNil(t, updated.UnpublishAt)
+
+		result, err = blog.Posts.PublishScheduled(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, result.Unpublished, created.ID)
+
+		read, err = blog.Posts.Read(ctx, created.ID)
+		require.NoError(t, err)
+		assert.False(t, read.Published)
+		assert.Nil(t, read.UnpublishAt)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		created, err := blog.Posts.Create(
+			ctx,
+			repo.PostInput{
+				Title:     "Example Title",
+				Content:   "Some placeholder content",
+				Published: true,
+				Tags:      []string{"Postgres", " go ", "go", ""},
+			},
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, blog.Posts.Purge(ctx, created.ID))
+		})
+		assert.Equal(t, []string{"go", "postgres"}, created.Tags)
+
+		list, _, err := blog.Posts.List(
+			ctx,
+			data.PostFilter{
+				PageSize: 10,
+				Tags:     []string{"go", "rust"},
+				TagMatch: data.TagMatchAny,
+			},
+		)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, created.ID, list[0].ID)
+		assert.Equal(t, created.Tags, list[0].Tags)
+
+		list, _, err = blog.Posts.List(
+			ctx,
+			data.PostFilter{
+				PageSize: 10,
+				Tags:     []string{"go", "rust"},
+				TagMatch: data.TagMatchAll,
+			},
+		)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+
+		updated, err := blog.Posts.Update(
+			ctx,
+			repo.PostPatch{ID: created.ID, Tags: &[]string{"rust"}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"rust"}, updated.Tags)
+
+		updated, err = blog.Posts.Update(ctx, repo.PostPatch{ID: created.ID, Published: new(true)})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"rust"}, updated.Tags)
+
+		tags, metadata, err := blog.Tags.List(
+			ctx,
+			data.TagFilter{PageSize: 10, Name: sql.Null[string]{V: "rust", Valid: true}},
+		)
+		require.NoError(t, err)
+		require.Len(t, tags, 1)
+		assert.Equal(t, 1, metadata.ResponseLength)
+		assert.Equal(t, int64(1), tags[0].PostCount)
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		created, err := blog.Posts.Create(
 			ctx,
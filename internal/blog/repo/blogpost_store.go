@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -12,6 +13,7 @@ import (
 	"github.com/r3d5un/islandwind/internal/cache"
 	"github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/markdown"
 )
 
 type blogpostStore struct {
@@ -23,26 +25,41 @@ func newBlogpostStore(models *data.Models, cache cache.Cache) blogpostStore {
 	return blogpostStore{models: models, cache: cache}
 }
 
-func (s *blogpostStore) Create(ctx context.Context, input PostInput) (*Post, error) {
-	row, err := s.models.Posts.Insert(ctx, input.row())
+// Create inserts the post as part of the transaction. The post is not cached, as the transaction
+// may still roll back, and is cached when first read instead.
+func (s *blogpostStore) Create(ctx context.Context, tx pgx.Tx, input PostInput) (*Post, error) {
+	var err error
+	postRow := input.row()
+	postRow.Slug, err = uniqueSlug(ctx, s.models, tx, uuid.Nil, input.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := s.models.Posts.InsertTx(ctx, tx, postRow)
+	if err != nil {
+		return nil, err
+	}
+	blogpost, err := s.newPostFromRow(*row)
+	if err != nil {
+		return nil, err
+	}
+
+	blogpost.Tags, err = replaceTags(ctx, s.models, tx, blogpost.ID, input.Tags)
 	if err != nil {
 		return nil, err
 	}
-	blogpost := s.newPostFromRow(*row)
-	s.cache.Set(blogpost.ID, blogpost)
 
 	return blogpost, nil
 }
 
 func (s *blogpostStore) Read(ctx context.Context, ID uuid.UUID) (*Post, error) {
 	var blogpost Post
-	var err error
 	logger := logging.LoggerFromContext(ctx)
 
-	if err := s.cache.Get(ID, blogpost); err == nil {
-		return &blogpost, nil
-	}
+	err := s.cache.Get(ID, &blogpost)
 	switch {
+	case err == nil:
+		return &blogpost, nil
 	case errors.Is(err, cache.ErrCacheMiss):
 		logger.LogAttrs(ctx, slog.LevelInfo, "cache miss")
 	default:
@@ -53,11 +70,50 @@ func (s *blogpostStore) Read(ctx context.Context, ID uuid.UUID) (*Post, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.newPost(*row, &blogpost)
+	if err := s.newPost(*row, &blogpost); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.models.PostTags.SelectByPostIDs(ctx, []uuid.UUID{ID})
+	if err != nil {
+		return nil, err
+	}
+	blogpost.Tags = s.tagNames(tagsByPost(tags), ID)
+	s.cache.Set(ID, blogpost)
 
 	return &blogpost, nil
 }
 
+// ReadBySlug reads a blog post by its current slug. If no post currently uses the slug, the
+// slug history is searched, and the post is returned with its current slug.
+func (s *blogpostStore) ReadBySlug(ctx context.Context, slug string) (*Post, error) {
+	row, err := s.models.Posts.SelectOneBySlug(ctx, slug)
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		logging.LoggerFromContext(ctx).
+			LogAttrs(ctx, slog.LevelInfo, "slug not in use, searching slug history")
+		history, err := s.models.PostSlugs.SelectOne(ctx, slug)
+		if err != nil {
+			return nil, err
+		}
+		return s.Read(ctx, history.PostID)
+	case err != nil:
+		return nil, err
+	}
+	blogpost, err := s.newPostFromRow(*row)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.models.PostTags.SelectByPostIDs(ctx, []uuid.UUID{blogpost.ID})
+	if err != nil {
+		return nil, err
+	}
+	blogpost.Tags = s.tagNames(tagsByPost(tags), blogpost.ID)
+
+	return blogpost, nil
+}
+
 func (s *blogpostStore) List(
 	ctx context.Context,
 	filter data.PostFilter,
@@ -66,21 +122,77 @@ func (s *blogpostStore) List(
 	if err != nil {
 		return nil, nil, err
 	}
-	blogposts := s.newBlogpostListFromRows(rows)
+	blogposts, err := s.newBlogpostListFromRows(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uuid.UUID, len(blogposts))
+	for i, blogpost := range blogposts {
+		ids[i] = blogpost.ID
+	}
+	tags, err := s.models.PostTags.SelectByPostIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	postTags := tagsByPost(tags)
+	for _, blogpost := range blogposts {
+		blogpost.Tags = s.tagNames(postTags, blogpost.ID)
+	}
 
-	return blogposts, metadata, err
+	return blogposts, metadata, nil
 }
 
-func (s *blogpostStore) Update(ctx context.Context, patch PostPatch) (*Post, error) {
-	row, err := s.models.Posts.Update(ctx, patch.row())
+// Update applies the patch as part of the transaction. The post is not removed from the cache,
+// as concurrent reads could cache the post again before the transaction commits.
+func (s *blogpostStore) Update(ctx context.Context, tx pgx.Tx, patch PostPatch) (*Post, error) {
+	postPatch := patch.row()
+
+	var previousSlug string
+	if patch.Title != nil {
+		current, err := s.models.Posts.SelectOneTx(ctx, tx, patch.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Title != *patch.Title {
+			newSlug, err := uniqueSlug(ctx, s.models, tx, patch.ID, *patch.Title)
+			if err != nil {
+				return nil, err
+			}
+			if newSlug != current.Slug {
+				previousSlug = current.Slug
+				postPatch.Slug = sql.Null[string]{V: newSlug, Valid: true}
+			}
+		}
+	}
+
+	row, err := s.models.Posts.UpdateTx(ctx, tx, postPatch)
+	if err != nil {
+		return nil, err
+	}
+	blogpost, err := s.newPostFromRow(*row)
 	if err != nil {
 		return nil, err
 	}
-	blogpost := s.newPostFromRow(*row)
 
-	if err := s.cache.Delete(blogpost.ID); err != nil {
-		logging.LoggerFromContext(ctx).
-			Error("unable to invalidate cache", slog.String("error", err.Error()))
+	if previousSlug != "" {
+		if err := retireSlug(ctx, s.models, tx, blogpost.ID, previousSlug, blogpost.Slug); err != nil {
+			return nil, err
+		}
+	}
+
+	switch patch.Tags {
+	case nil:
+		tags, err := s.models.PostTags.SelectByPostIDsTx(ctx, tx, []uuid.UUID{blogpost.ID})
+		if err != nil {
+			return nil, err
+		}
+		blogpost.Tags = s.tagNames(tagsByPost(tags), blogpost.ID)
+	default:
+		blogpost.Tags, err = replaceTags(ctx, s.models, tx, blogpost.ID, *patch.Tags)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return blogpost, nil
@@ -110,7 +222,23 @@ func (s *blogpostStore) Restore(ctx context.Context, ID uuid.UUID) (*Post, error
 	if err != nil {
 		return nil, err
 	}
-	return s.newPostFromRow(*row), nil
+	blogpost, err := s.newPostFromRow(*row)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.models.PostTags.SelectByPostIDs(ctx, []uuid.UUID{ID})
+	if err != nil {
+		return nil, err
+	}
+	blogpost.Tags = s.tagNames(tagsByPost(tags), ID)
+
+	if err := s.cache.Delete(ID); err != nil {
+		logging.LoggerFromContext(ctx).
+			Error("unable to invalidate cache", slog.String("error", err.Error()))
+	}
+
+	return blogpost, nil
 }
 
 func (s *blogpostStore) Purge(ctx context.Context, tx pgx.Tx, ID uuid.UUID) error {
@@ -126,34 +254,83 @@ func (s *blogpostStore) Purge(ctx context.Context, tx pgx.Tx, ID uuid.UUID) erro
 	return nil
 }
 
-func (s *blogpostStore) newPostFromRow(row data.Post) *Post {
-	return &Post{
-		ID:        row.ID,
-		Title:     row.Title,
-		Content:   row.Content,
-		Published: row.Published,
-		CreatedAt: row.CreatedAt,
-		UpdatedAt: row.UpdatedAt,
-		Deleted:   row.Deleted,
-		DeletedAt: db.NullTimeToPtr(row.DeletedAt),
+// tagNames returns the tag names of a blog post, or an empty list if the post has no tags.
+func (s *blogpostStore) tagNames(tags map[uuid.UUID][]string, ID uuid.UUID) []string {
+	names, ok := tags[ID]
+	if !ok {
+		return []string{}
+	}
+	return names
+}
+
+func (s *blogpostStore) newPostFromRow(row data.Post) (*Post, error) {
+	post := &Post{
+		ID:          row.ID,
+		Title:       row.Title,
+		Slug:        row.Slug,
+		Content:     row.Content,
+		Published:   row.Published,
+		PublishAt:   utcTimePtr(row.PublishAt),
+		UnpublishAt: utcTimePtr(row.UnpublishAt),
+		CreatedAt:   row.CreatedAt.UTC(),
+		UpdatedAt:   row.UpdatedAt.UTC(),
+		Deleted:     row.Deleted,
+		DeletedAt:   utcTimePtr(row.DeletedAt),
+		Rank:        db.NullToPtr(row.Rank),
+		Snippet:     db.NullToPtr(row.Snippet),
+	}
+	if err := s.render(post); err != nil {
+		return nil, err
 	}
+	return post, nil
 }
 
-func (s *blogpostStore) newBlogpostListFromRows(rows []*data.Post) []*Post {
+func (s *blogpostStore) newBlogpostListFromRows(rows []*data.Post) ([]*Post, error) {
+	var err error
 	blogposts := make([]*Post, len(rows))
 	for i, row := range rows {
-		blogposts[i] = s.newPostFromRow(*row)
+		blogposts[i], err = s.newPostFromRow(*row)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return blogposts
+	return blogposts, nil
 }
 
-func (s *blogpostStore) newPost(row data.Post, post *Post) {
+func (s *blogpostStore) newPost(row data.Post, post *Post) error {
 	post.ID = row.ID
 	post.Title = row.Title
+	post.Slug = row.Slug
 	post.Content = row.Content
 	post.Published = row.Published
-	post.CreatedAt = row.CreatedAt
-	post.UpdatedAt = row.UpdatedAt
+	post.PublishAt = utcTimePtr(row.PublishAt)
+	post.UnpublishAt = utcTimePtr(row.UnpublishAt)
+	post.CreatedAt = row.CreatedAt.UTC()
+	post.UpdatedAt = row.UpdatedAt.UTC()
 	post.Deleted = row.Deleted
-	post.DeletedAt = db.NullTimeToPtr(row.DeletedAt)
+	post.DeletedAt = utcTimePtr(row.DeletedAt)
+	post.Rank = db.NullToPtr(row.Rank)
+	post.Snippet = db.NullToPtr(row.Snippet)
+	return s.render(post)
+}
+
+// utcTimePtr returns the nullable time in UTC. Timestamps are normalized to UTC so that posts
+// read from the cache are identical to posts read from the database.
+func utcTimePtr(nt sql.NullTime) *time.Time {
+	if !nt.Valid {
+		return nil
+	}
+	return new(nt.Time.UTC())
+}
+
+// render renders the markdown content of the blog post to HTML, and builds the table of
+// contents.
+func (s *blogpostStore) render(post *Post) error {
+	doc, err := markdown.Render(post.Content)
+	if err != nil {
+		return err
+	}
+	post.ContentHTML = doc.HTML
+	post.TOC = doc.TOC
+	return nil
 }
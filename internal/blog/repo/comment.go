@@ -0,0 +1,294 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+var (
+	// ErrCommentsClosed is returned when commenting on a blog post that is not published.
+	ErrCommentsClosed = errors.New("blog post does not accept comments")
+	// ErrInvalidParentComment is returned when replying to a comment that is not an approved
+	// comment on the same blog post.
+	ErrInvalidParentComment = errors.New("parent comment invalid")
+)
+
+type Comment struct {
+	ID     uuid.UUID `json:"id"`
+	PostID uuid.UUID `json:"postId"`
+	// ParentID is the ID of the comment replied to. Nil for top-level comments. Clients build
+	// the thread from the parent IDs.
+	ParentID   *uuid.UUID `json:"parentId"`
+	AuthorName string     `json:"authorName"`
+	// AuthorEmail is only visible to moderators.
+	AuthorEmail *string            `json:"authorEmail,omitempty"`
+	Content     string             `json:"content"`
+	Status      data.CommentStatus `json:"status"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+	ModeratedAt *time.Time         `json:"moderatedAt"`
+}
+
+func newCommentFromRow(row *data.Comment) *Comment {
+	return &Comment{
+		ID:          row.ID,
+		PostID:      row.PostID,
+		ParentID:    db.NullToPtr(row.ParentID),
+		AuthorName:  row.AuthorName,
+		AuthorEmail: db.NullToPtr(row.AuthorEmail),
+		Content:     row.Content,
+		Status:      row.Status,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		ModeratedAt: db.NullToPtr(row.ModeratedAt),
+	}
+}
+
+// public returns the comment without the fields only visible to moderators.
+func (c *Comment) public() *Comment {
+	public := *c
+	public.AuthorEmail = nil
+	return &public
+}
+
+type CommentInput struct {
+	// ParentID is the ID of the approved comment replied to, if any.
+	ParentID   *uuid.UUID `json:"parentId"`
+	AuthorName string     `json:"authorName"`
+	// AuthorEmail is optional, and only visible to moderators.
+	AuthorEmail *string `json:"authorEmail"`
+	Content     string  `json:"content"`
+}
+
+type CommentPatch struct {
+	ID         uuid.UUID `json:"id"`
+	AuthorName *string   `json:"authorName"`
+	Content    *string   `json:"content"`
+}
+
+type CommentReader interface {
+	Read(ctx context.Context, ID uuid.UUID) (*Comment, error)
+	// List lists comments with any status, including the email of the author.
+	List(ctx context.Context, filter data.CommentFilter) ([]*Comment, *data.Metadata, error)
+	// ListApproved lists the approved comments on a blog post, without the fields only visible
+	// to moderators. The post ID and status of the filter are ignored.
+	ListApproved(
+		ctx context.Context,
+		postID uuid.UUID,
+		filter data.CommentFilter,
+	) ([]*Comment, *data.Metadata, error)
+}
+
+type CommentWriter interface {
+	// Submit adds a pending comment to a published blog post. The comment is not listed
+	// publicly until it has been approved.
+	Submit(ctx context.Context, postID uuid.UUID, input CommentInput) (*Comment, error)
+	// Moderate sets the status of a comment.
+	Moderate(ctx context.Context, ID uuid.UUID, status data.CommentStatus) (*Comment, error)
+	Update(ctx context.Context, patch CommentPatch) (*Comment, error)
+	// Delete removes the comment and all replies to it.
+	Delete(ctx context.Context, ID uuid.UUID) error
+}
+
+type CommentReaderWriter interface {
+	CommentReader
+	CommentWriter
+}
+
+type CommentService struct {
+	models data.Models
+}
+
+func newCommentRepository(models data.Models) CommentReaderWriter {
+	return &CommentService{models: models}
+}
+
+func (svc *CommentService) Read(ctx context.Context, ID uuid.UUID) (*Comment, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"comment",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading comment")
+	row, err := svc.models.Comments.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	ensure.Equal(row.ID, ID, "comment ID must match")
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment retrieved")
+
+	return newCommentFromRow(row), nil
+}
+
+func (svc *CommentService) List(
+	ctx context.Context,
+	filter data.CommentFilter,
+) ([]*Comment, *data.Metadata, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"comments",
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading comments")
+	rows, metadata, err := svc.models.Comments.SelectMany(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	ensure.NotNil(metadata, "comment metadata must not be nil")
+
+	comments := make([]*Comment, len(rows))
+	for i, row := range rows {
+		comments[i] = newCommentFromRow(row)
+	}
+	ensure.Equal(len(comments), metadata.ResponseLength, "comment count must match")
+	logger.LogAttrs(ctx, slog.LevelInfo, "comments retrieved")
+
+	return comments, metadata, nil
+}
+
+func (svc *CommentService) ListApproved(
+	ctx context.Context,
+	postID uuid.UUID,
+	filter data.CommentFilter,
+) ([]*Comment, *data.Metadata, error) {
+	filter.PostID.V, filter.PostID.Valid = postID, true
+	filter.Status.V, filter.Status.Valid = data.CommentApproved, true
+
+	comments, metadata, err := svc.List(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, comment := range comments {
+		comments[i] = comment.public()
+	}
+
+	return comments, metadata, nil
+}
+
+func (svc *CommentService) Submit(
+	ctx context.Context,
+	postID uuid.UUID,
+	input CommentInput,
+) (*Comment, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"comment",
+		slog.String("postId", postID.String()),
+		slog.Any("parentId", input.ParentID),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "submitting comment")
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	post, err := svc.models.Posts.SelectOneTx(ctx, tx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.Published || post.Deleted {
+		logger.LogAttrs(ctx, slog.LevelInfo, "blog post not published")
+		return nil, ErrCommentsClosed
+	}
+
+	if input.ParentID != nil {
+		parent, err := svc.models.Comments.SelectOneTx(ctx, tx, *input.ParentID)
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			return nil, ErrInvalidParentComment
+		case err != nil:
+			return nil, err
+		}
+		if parent.PostID != postID || parent.Status != data.CommentApproved {
+			logger.LogAttrs(ctx, slog.LevelInfo, "parent comment not approved on blog post")
+			return nil, ErrInvalidParentComment
+		}
+	}
+
+	row, err := svc.models.Comments.InsertTx(ctx, tx, data.CommentInput{
+		PostID:      postID,
+		ParentID:    db.PtrToNull(input.ParentID),
+		AuthorName:  input.AuthorName,
+		AuthorEmail: db.PtrToNull(input.AuthorEmail),
+		Content:     input.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment submitted", slog.String("id", row.ID.String()))
+
+	return newCommentFromRow(row), nil
+}
+
+func (svc *CommentService) Moderate(
+	ctx context.Context,
+	ID uuid.UUID,
+	status data.CommentStatus,
+) (*Comment, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"comment",
+		slog.String("id", ID.String()),
+		slog.String("status", string(status)),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "moderating comment")
+	row, err := svc.models.Comments.Update(ctx, data.CommentPatch{
+		ID:     ID,
+		Status: db.PtrToNull(&status),
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment moderated")
+
+	return newCommentFromRow(row), nil
+}
+
+func (svc *CommentService) Update(ctx context.Context, patch CommentPatch) (*Comment, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"comment",
+		slog.String("id", patch.ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "updating comment")
+	row, err := svc.models.Comments.Update(ctx, data.CommentPatch{
+		ID:         patch.ID,
+		AuthorName: db.PtrToNull(patch.AuthorName),
+		Content:    db.PtrToNull(patch.Content),
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment updated")
+
+	return newCommentFromRow(row), nil
+}
+
+func (svc *CommentService) Delete(ctx context.Context, ID uuid.UUID) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"comment",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "deleting comment")
+	if err := svc.models.Comments.Delete(ctx, ID); err != nil {
+		return err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "comment deleted")
+
+	return nil
+}
@@ -9,17 +9,22 @@ import (
 )
 
 type Repository struct {
-	db     *pgxpool.Pool
-	cache  cache.Cache
-	models data.Models
-	Posts  PostReaderWriter
+	db       *pgxpool.Pool
+	cache    cache.Cache
+	models   data.Models
+	Posts    PostReaderWriter
+	Tags     TagReader
+	Comments CommentReaderWriter
 }
 
 func NewRepository(db *pgxpool.Pool, c cache.Cache, timeout *time.Duration) Repository {
+	models := data.NewModels(db, timeout)
 	return Repository{
-		db:     db,
-		cache:  c,
-		models: data.NewModels(db, timeout),
-		Posts:  newPostRepository(db, c, timeout),
+		db:       db,
+		cache:    c,
+		models:   models,
+		Posts:    newPostRepository(db, c, timeout),
+		Tags:     newTagRepository(models),
+		Comments: newCommentRepository(models),
 	}
 }
@@ -0,0 +1,204 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/diff"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+type PostRevision struct {
+	ID        uuid.UUID `json:"id"`
+	PostID    uuid.UUID `json:"postId"`
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newPostRevisionFromRow(row *data.PostRevision) *PostRevision {
+	return &PostRevision{
+		ID:        row.ID,
+		PostID:    row.PostID,
+		Revision:  row.Revision,
+		Title:     row.Title,
+		Content:   row.Content,
+		CreatedAt: row.CreatedAt,
+	}
+}
+
+// RevisionDiff is a line-based comparison of the title and content of two versions of a blog
+// post.
+type RevisionDiff struct {
+	PostID uuid.UUID `json:"postId"`
+	// From is the ID of the revision compared from.
+	From uuid.UUID `json:"from"`
+	// To is the ID of the revision compared to. Nil when compared to the current post.
+	To      *uuid.UUID  `json:"to"`
+	Changed bool        `json:"changed"`
+	Title   []diff.Line `json:"title"`
+	Content []diff.Line `json:"content"`
+}
+
+type PostRevisionReader interface {
+	ListRevisions(
+		ctx context.Context,
+		filter data.PostRevisionFilter,
+	) ([]*PostRevision, *data.Metadata, error)
+	// DiffRevisions compares two revisions of a blog post. If to is nil, the revision is
+	// compared to the current version of the post. diff.ErrTooLarge is returned if the texts
+	// differ too much to be compared.
+	DiffRevisions(
+		ctx context.Context,
+		postID uuid.UUID,
+		from uuid.UUID,
+		to *uuid.UUID,
+	) (*RevisionDiff, error)
+}
+
+type PostRevisionWriter interface {
+	// RestoreRevision sets the title and content of a blog post to the ones stored in the
+	// given revision. The replaced title and content are recorded as a new revision.
+	RestoreRevision(ctx context.Context, postID uuid.UUID, revisionID uuid.UUID) (*Post, error)
+}
+
+func (svc *BlogpostService) ListRevisions(
+	ctx context.Context,
+	filter data.PostRevisionFilter,
+) ([]*PostRevision, *data.Metadata, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"revisions",
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading blog post revisions")
+	rows, metadata, err := svc.models.PostRevisions.SelectMany(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	ensure.NotNil(metadata, "revision metadata must not be nil")
+
+	revisions := make([]*PostRevision, len(rows))
+	for i, row := range rows {
+		revisions[i] = newPostRevisionFromRow(row)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "blog post revisions retrieved")
+
+	return revisions, metadata, nil
+}
+
+func (svc *BlogpostService) DiffRevisions(
+	ctx context.Context,
+	postID uuid.UUID,
+	from uuid.UUID,
+	to *uuid.UUID,
+) (*RevisionDiff, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"revisions",
+		slog.String("postId", postID.String()),
+		slog.String("from", from.String()),
+		slog.Any("to", to),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "comparing blog post revisions")
+	old, err := svc.readRevision(ctx, postID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var title, content string
+	switch to {
+	case nil:
+		post, err := svc.models.Posts.SelectOne(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+		title, content = post.Title, post.Content
+	default:
+		revision, err := svc.readRevision(ctx, postID, *to)
+		if err != nil {
+			return nil, err
+		}
+		title, content = revision.Title, revision.Content
+	}
+
+	revisionDiff := RevisionDiff{PostID: postID, From: from, To: to}
+	revisionDiff.Title, err = diff.Lines(old.Title, title)
+	if err != nil {
+		return nil, err
+	}
+	revisionDiff.Content, err = diff.Lines(old.Content, content)
+	if err != nil {
+		return nil, err
+	}
+	revisionDiff.Changed = diff.Changed(revisionDiff.Title) || diff.Changed(revisionDiff.Content)
+	logger.LogAttrs(
+		ctx, slog.LevelInfo, "blog post revisions compared", slog.Bool("changed", revisionDiff.Changed),
+	)
+
+	return &revisionDiff, nil
+}
+
+func (svc *BlogpostService) RestoreRevision(
+	ctx context.Context,
+	postID uuid.UUID,
+	revisionID uuid.UUID,
+) (*Post, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"revision",
+		slog.String("postId", postID.String()),
+		slog.String("id", revisionID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "restoring blog post revision")
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	row, err := svc.models.PostRevisions.SelectOneTx(ctx, tx, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if row.PostID != postID {
+		return nil, db.ErrRecordNotFound
+	}
+
+	post, err := svc.update(ctx, tx, PostPatch{ID: postID, Title: &row.Title, Content: &row.Content})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	svc.invalidate(ctx, postID)
+	logger.LogAttrs(ctx, slog.LevelInfo, "blog post revision restored")
+
+	return post, nil
+}
+
+// readRevision reads a revision and ensures it belongs to the given blog post.
+func (svc *BlogpostService) readRevision(
+	ctx context.Context,
+	postID uuid.UUID,
+	revisionID uuid.UUID,
+) (*data.PostRevision, error) {
+	row, err := svc.models.PostRevisions.SelectOne(ctx, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if row.PostID != postID {
+		return nil, db.ErrRecordNotFound
+	}
+
+	return row, nil
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// ScheduleResult contains the blog posts changed by a run of the publishing schedule.
+type ScheduleResult struct {
+	// Locked is false if another instance held the scheduler lock, and nothing was changed.
+	Locked      bool        `json:"locked"`
+	Published   []uuid.UUID `json:"published"`
+	Unpublished []uuid.UUID `json:"unpublished"`
+}
+
+type PostScheduler interface {
+	// PublishScheduled publishes and unpublishes all blog posts that are due according to
+	// their publish_at and unpublish_at timestamps. Only one instance of the application
+	// performs the changes at a time.
+	PublishScheduled(ctx context.Context) (*ScheduleResult, error)
+}
+
+func (svc *BlogpostService) PublishScheduled(ctx context.Context) (*ScheduleResult, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "publishing scheduled blog posts")
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	locked, err := db.TryAdvisoryXactLock(ctx, tx, db.BlogPublishSchedulerLock)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		logger.LogAttrs(ctx, slog.LevelInfo, "scheduler lock held by another instance")
+		return &ScheduleResult{Locked: false}, nil
+	}
+
+	published, err := svc.models.Posts.PublishDueTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	unpublished, err := svc.models.Posts.UnpublishDueTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, ID := range append(published, unpublished...) {
+		if err := svc.cache.Delete(ID); err != nil {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"unable to invalidate cache",
+				slog.String("id", ID.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"scheduled blog posts published",
+		slog.Int("published", len(published)),
+		slog.Int("unpublished", len(unpublished)),
+	)
+
+	return &ScheduleResult{Locked: true, Published: published, Unpublished: unpublished}, nil
+}
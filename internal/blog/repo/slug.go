@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/slug"
+)
+
+// uniqueSlug creates a slug from the title that is not used by any other blog post, adding a
+// numeric suffix on collisions. Use uuid.Nil as the post ID for new posts.
+func uniqueSlug(
+	ctx context.Context,
+	models *data.Models,
+	tx pgx.Tx,
+	postID uuid.UUID,
+	title string,
+) (string, error) {
+	base := slug.Make(title)
+	taken, err := models.PostSlugs.SelectTakenTx(ctx, tx, base, postID)
+	if err != nil {
+		return "", err
+	}
+
+	return slug.Unique(base, taken), nil
+}
+
+// retireSlug records the previous slug of a blog post in the slug history, so old links can be
+// redirected. If the post reclaims one of its own previous slugs, it is removed from the
+// history.
+func retireSlug(
+	ctx context.Context,
+	models *data.Models,
+	tx pgx.Tx,
+	postID uuid.UUID,
+	previous string,
+	current string,
+) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	if _, err := models.PostSlugs.InsertTx(ctx, tx, previous, postID); err != nil {
+		return err
+	}
+	if err := models.PostSlugs.DeleteTx(ctx, tx, current); err != nil {
+		return err
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"blog post slug changed",
+		slog.String("previous", previous),
+		slog.String("current", current),
+	)
+
+	return nil
+}
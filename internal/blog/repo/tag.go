@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/r3d5un/islandwind/internal/blog/data"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// PostCount is the number of published blog posts with the tag.
+	PostCount int64 `json:"postCount"`
+}
+
+func newTagFromRow(row *data.Tag) *Tag {
+	return &Tag{
+		ID:        row.ID,
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt,
+		PostCount: row.PostCount.V,
+	}
+}
+
+type TagReader interface {
+	List(ctx context.Context, filter data.TagFilter) ([]*Tag, *data.Metadata, error)
+}
+
+type TagService struct {
+	models data.Models
+}
+
+func newTagRepository(models data.Models) TagReader {
+	return &TagService{models: models}
+}
+
+func (svc *TagService) List(
+	ctx context.Context,
+	filter data.TagFilter,
+) ([]*Tag, *data.Metadata, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"tags",
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading tags")
+	rows, metadata, err := svc.models.Tags.SelectMany(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	ensure.NotNil(metadata, "tag metadata must not be nil")
+
+	tags := make([]*Tag, len(rows))
+	for i, row := range rows {
+		tags[i] = newTagFromRow(row)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "tags retrieved")
+
+	return tags, metadata, nil
+}
+
+// NormalizeTags lowercases and trims the given tag names, and removes empty and duplicate
+// names. The result is sorted.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// replaceTags sets the tags of a blog post to the given names, creating any tags that do not
+// exist yet. Returns the normalized tag names.
+func replaceTags(
+	ctx context.Context,
+	models *data.Models,
+	tx pgx.Tx,
+	postID uuid.UUID,
+	names []string,
+) ([]string, error) {
+	names = NormalizeTags(names)
+
+	tagIDs := make([]uuid.UUID, 0, len(names))
+	if len(names) > 0 {
+		tags, err := models.Tags.UpsertManyTx(ctx, tx, names)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			tagIDs = append(tagIDs, tag.ID)
+		}
+	}
+
+	if err := models.PostTags.ReplaceTx(ctx, tx, postID, tagIDs); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// tagsByPost groups the tag names of the given rows by blog post ID.
+func tagsByPost(rows []*data.PostTag) map[uuid.UUID][]string {
+	tags := make(map[uuid.UUID][]string)
+	for _, row := range rows {
+		tags[row.PostID] = append(tags[row.PostID], row.Name)
+	}
+	return tags
+}
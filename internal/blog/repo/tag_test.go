@@ -0,0 +1,17 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"go", "postgres"},
+		repo.NormalizeTags([]string{"Postgres", " go", "GO", "", "  "}),
+	)
+	assert.Empty(t, repo.NormalizeTags(nil))
+}
@@ -8,66 +8,263 @@ import (
 
 	"github.com/justinas/alice"
 	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/blog/data"
 	"github.com/r3d5un/islandwind/internal/blog/handlers"
+	"github.com/r3d5un/islandwind/internal/feed"
 	"github.com/rs/cors"
 )
 
 func (m *Module) addRoutes(ctx context.Context) {
 	routes := []struct {
-		Path         string `json:"path"`
-		handler      http.HandlerFunc
-		Method       string `json:"methods"`
-		AuthRequired bool   `json:"authRequired"`
+		Path    string `json:"path"`
+		handler http.HandlerFunc
+		Method  string `json:"methods"`
+		// Scopes are required of the access token authenticating the request. Routes
+		// without scopes are public.
+		Scopes []string `json:"scopes"`
 	}{
 		// healthcheck
 		{
 			"/api/v1/blog/healthcheck",
 			m.healthcheckHandler,
 			http.MethodGet,
-			false,
+			nil,
 		},
 		// blog posts
 		{
 			"/api/v1/blog/post",
 			handlers.PostBlogpostHandler(m.repo.Posts),
 			http.MethodPost,
-			true,
+			[]string{scope.BlogWrite},
 		},
 		{
 			"/api/v1/blog/post",
 			handlers.ListBlogpostHandler(m.repo.Posts),
 			http.MethodGet,
-			false,
+			nil,
 		},
 		{
 			"/api/v1/blog/post",
 			handlers.PatchBlogpostHandler(m.repo.Posts),
 			http.MethodPatch,
-			true,
+			[]string{scope.BlogWrite},
 		},
 		{
 			"/api/v1/blog/post",
 			handlers.DeleteBlogpostHandler(m.repo.Posts),
 			http.MethodDelete,
-			true,
+			[]string{scope.BlogDelete},
 		},
 		{
 			"/api/v1/blog/post",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			false,
+			nil,
 		},
 		{
 			"/api/v1/blog/post/{id}",
 			handlers.GetBlogpostHandler(m.repo.Posts),
 			http.MethodGet,
-			false,
+			nil,
 		},
 		{
 			"/api/v1/blog/post/{id}",
 			api.CorsPreflightHandler(),
 			http.MethodOptions,
-			false,
+			nil,
+		},
+		{
+			handlers.BlogpostBySlugPath + "{slug}",
+			handlers.GetBlogpostBySlugHandler(m.repo.Posts),
+			http.MethodGet,
+			nil,
+		},
+		{
+			handlers.BlogpostBySlugPath + "{slug}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		// comments
+		{
+			"/api/v1/blog/post/{id}/comment",
+			handlers.SubmitCommentHandler(
+				m.repo.Comments,
+				m.commentLimiter,
+				m.cfg.Server.ClientIPHeader,
+			),
+			http.MethodPost,
+			nil,
+		},
+		// A preflight pattern of /api/v1/blog/post/{id}/comment would conflict with the blog
+		// post by slug pattern, so preflight requests for all sub-resources of a blog post are
+		// handled by the same route.
+		{
+			"/api/v1/blog/post/{id}/{resource}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/comment",
+			handlers.ListApprovedCommentsHandler(m.repo.Comments),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/blog/comment",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/comment/moderation",
+			handlers.ListCommentsHandler(m.repo.Comments),
+			http.MethodGet,
+			[]string{scope.BlogModerate},
+		},
+		{
+			"/api/v1/blog/comment/moderation",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/comment/{id}",
+			handlers.GetCommentHandler(m.repo.Comments),
+			http.MethodGet,
+			[]string{scope.BlogModerate},
+		},
+		{
+			"/api/v1/blog/comment/{id}",
+			handlers.PatchCommentHandler(m.repo.Comments),
+			http.MethodPatch,
+			[]string{scope.BlogModerate},
+		},
+		{
+			"/api/v1/blog/comment/{id}",
+			handlers.DeleteCommentHandler(m.repo.Comments),
+			http.MethodDelete,
+			[]string{scope.BlogModerate},
+		},
+		{
+			"/api/v1/blog/comment/{id}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/comment/{id}/approve",
+			handlers.ModerateCommentHandler(m.repo.Comments, data.CommentApproved),
+			http.MethodPost,
+			[]string{scope.BlogModerate},
+		},
+		{
+			"/api/v1/blog/comment/{id}/approve",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/comment/{id}/reject",
+			handlers.ModerateCommentHandler(m.repo.Comments, data.CommentRejected),
+			http.MethodPost,
+			[]string{scope.BlogModerate},
+		},
+		{
+			"/api/v1/blog/comment/{id}/reject",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		// blog post revisions, by blog post ID. The revisions are kept out of the blog post
+		// paths, as /api/v1/blog/post/{id}/revision would conflict with the by slug pattern.
+		{
+			"/api/v1/blog/revision/{id}",
+			handlers.ListRevisionsHandler(m.repo.Posts),
+			http.MethodGet,
+			[]string{scope.BlogWrite},
+		},
+		{
+			"/api/v1/blog/revision/{id}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/revision/{id}/diff",
+			handlers.DiffRevisionsHandler(m.repo.Posts),
+			http.MethodGet,
+			[]string{scope.BlogWrite},
+		},
+		{
+			"/api/v1/blog/revision/{id}/diff",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/revision/{id}/{revisionId}/restore",
+			handlers.RestoreRevisionHandler(m.repo.Posts),
+			http.MethodPost,
+			[]string{scope.BlogWrite},
+		},
+		{
+			"/api/v1/blog/revision/{id}/{revisionId}/restore",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		// feeds
+		{
+			"/api/v1/blog/feed.rss",
+			handlers.FeedHandler(m.repo.Posts, feed.RSS, m.cfg.Blog),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/blog/feed.rss",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/feed.atom",
+			handlers.FeedHandler(m.repo.Posts, feed.Atom, m.cfg.Blog),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/blog/feed.atom",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/blog/feed.json",
+			handlers.FeedHandler(m.repo.Posts, feed.JSON, m.cfg.Blog),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/blog/feed.json",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		// tags
+		{
+			"/api/v1/blog/tag",
+			handlers.ListTagsHandler(m.repo.Tags),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/blog/tag",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
 		},
 	}
 
@@ -105,12 +302,12 @@ func (m *Module) addRoutes(ctx context.Context) {
 			func(next http.Handler) http.Handler {
 				return corsMiddleware.Handler(next)
 			},
-			// Require authentication for write requests
+			// Require an access token granting the scopes of the route
 			func(next http.Handler) http.Handler {
-				if !route.AuthRequired {
+				if len(route.Scopes) == 0 {
 					return next
 				}
-				return m.auth.AccessTokenMiddleware(next)
+				return m.auth.AccessTokenMiddleware(api.RequireScope(route.Scopes...)(next))
 			},
 		)
 
@@ -0,0 +1,55 @@
+package blog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/blog/repo"
+	"github.com/r3d5un/islandwind/internal/db"
+)
+
+// publishScheduler periodically publishes and unpublishes scheduled blog posts. Only the
+// instance holding the scheduler advisory lock changes any posts.
+type publishScheduler struct {
+	*db.PeriodicWorker
+	posts  repo.PostScheduler
+	logger *slog.Logger
+}
+
+func newPublishScheduler(
+	posts repo.PostScheduler,
+	interval time.Duration,
+	logger *slog.Logger,
+) *publishScheduler {
+	s := &publishScheduler{
+		posts:  posts,
+		logger: logger.With(slog.Group("scheduler", slog.Duration("interval", interval))),
+	}
+	s.PeriodicWorker = db.NewPeriodicWorker("publish scheduler", interval, s.run, s.logger)
+
+	return s
+}
+
+func (s *publishScheduler) run(ctx context.Context) {
+	result, err := s.posts.PublishScheduled(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.LogAttrs(
+			ctx, slog.LevelError, "unable to publish scheduled posts", slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	if len(result.Published) > 0 || len(result.Unpublished) > 0 {
+		s.logger.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"scheduled posts changed",
+			slog.Any("published", result.Published),
+			slog.Any("unpublished", result.Unpublished),
+		)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"strings"
 
 	"github.com/r3d5un/islandwind/internal/auth/config"
+	blogconfig "github.com/r3d5un/islandwind/internal/blog/config"
 	"github.com/r3d5un/islandwind/internal/db"
+	mediaconfig "github.com/r3d5un/islandwind/internal/media/config"
 	"github.com/spf13/viper"
 )
 
@@ -25,6 +27,8 @@ type Config struct {
 	DB        db.Config              `json:"db"`
 	Auth      config.Config          `json:"auth"`
 	BasicAuth config.BasicAuthConfig `json:"basicAuth"`
+	Blog      blogconfig.Config      `json:"blog"`
+	Media     mediaconfig.Config     `json:"media"`
 }
 
 // AppConfig contains the most top-level configuration for the application.
@@ -57,6 +61,12 @@ type ServerConfig struct {
 	//
 	// Set through the ISLANDWIND_SERVER_WRITETIMEOUT environment variable
 	WriteTimeout int `json:"writeTimeout"`
+	// ClientIPHeader is the request header containing the client IP address, e.g.
+	// X-Forwarded-For, when running behind a reverse proxy. Leave empty to use the address of
+	// the connection, as clients can set the header themselves.
+	//
+	// Set through the ISLANDWIND_SERVER_CLIENTIPHEADER environment variable
+	ClientIPHeader string `json:"clientIpHeader"`
 }
 
 func New() (*Config, error) {
@@ -69,12 +79,68 @@ func New() (*Config, error) {
 	viper.SetDefault("server.idleTimeout", 60)
 	viper.SetDefault("server.readTimeout", 5)
 	viper.SetDefault("server.writeTimeout", 10)
+	viper.SetDefault("server.clientIpHeader", "")
 	// Authentication
 	viper.SetDefault("basicAuth.username", "islandwind")
-	viper.SetDefault("basicAuth.password", "islandwind")
-	viper.SetDefault("auth.signingSecret", "accessTokenSecret")
-	viper.SetDefault("auth.refreshSigningSecret", "refreshTokenSecret")
+	viper.SetDefault("basicAuth.password", "")
+	viper.SetDefault("auth.signingKeyFile", "")
+	viper.SetDefault("auth.verificationKeyFiles", []string{})
+	viper.SetDefault("auth.refreshSigningSecret", "")
+	viper.SetDefault("auth.challengeSigningSecret", "")
 	viper.SetDefault("auth.tokenIssuer", "islandwind")
+	viper.SetDefault("auth.lockout.freeAttempts", 3)
+	viper.SetDefault("auth.lockout.baseDelaySeconds", 1)
+	viper.SetDefault("auth.lockout.maxDelaySeconds", 60)
+	viper.SetDefault("auth.lockout.threshold", 10)
+	viper.SetDefault("auth.lockout.ipThreshold", 100)
+	viper.SetDefault("auth.lockout.durationSeconds", 900)
+	viper.SetDefault("auth.lockout.resetSeconds", 3600)
+	viper.SetDefault("auth.clients", []string{})
+	viper.SetDefault("auth.audit.retentionDays", 90)
+	viper.SetDefault("auth.audit.pruneIntervalSeconds", 3600)
+	viper.SetDefault("auth.refreshTokenCleanup.intervalSeconds", 3600)
+	viper.SetDefault("auth.refreshTokenCleanup.batchSize", 1000)
+	viper.SetDefault("auth.allowedOrigins", []string{})
+	viper.SetDefault("auth.mail.transport", config.FileTransport)
+	viper.SetDefault("auth.mail.from", "islandwind <noreply@localhost>")
+	viper.SetDefault("auth.mail.baseUrl", "http://localhost:5173")
+	viper.SetDefault("auth.mail.passwordResetMinutes", 30)
+	viper.SetDefault("auth.mail.emailVerificationHours", 24)
+	viper.SetDefault("auth.mail.smtp.host", "localhost")
+	viper.SetDefault("auth.mail.smtp.port", 587)
+	viper.SetDefault("auth.mail.smtp.username", "")
+	viper.SetDefault("auth.mail.smtp.password", "")
+	viper.SetDefault("auth.mail.file.dir", "./data/mail")
+	viper.SetDefault("auth.oidc.issuer", "")
+	viper.SetDefault("auth.oidc.clientId", "")
+	viper.SetDefault("auth.oidc.clientSecret", "")
+	viper.SetDefault("auth.oidc.redirectUrl", "http://localhost:4000/api/v1/auth/oidc/callback")
+	viper.SetDefault("auth.oidc.postLoginUrl", "http://localhost:5173")
+	viper.SetDefault("auth.oidc.scopes", []string{"profile", "email"})
+	viper.SetDefault("auth.oidc.usernameClaim", "preferred_username")
+	viper.SetDefault("auth.oidc.roleClaim", "groups")
+	viper.SetDefault("auth.oidc.roleMappings", []string{})
+	viper.SetDefault("auth.oidc.defaultRole", "")
+	// Blog
+	viper.SetDefault("blog.publishIntervalSeconds", 30)
+	viper.SetDefault("blog.siteUrl", "http://localhost:5173")
+	viper.SetDefault("blog.feedTitle", "islandwind")
+	viper.SetDefault("blog.feedDescription", "")
+	viper.SetDefault("blog.feedSize", 20)
+	viper.SetDefault("blog.commentRateLimit", 5)
+	viper.SetDefault("blog.commentRateWindowSeconds", 600)
+	// Media
+	viper.SetDefault("media.storage", mediaconfig.LocalStorage)
+	viper.SetDefault("media.maxUploadBytes", 10<<20)
+	viper.SetDefault("media.variantWidths", []int{320, 640, 1280})
+	viper.SetDefault("media.variantIntervalSeconds", 10)
+	viper.SetDefault("media.local.root", "./data/media")
+	viper.SetDefault("media.s3.endpoint", "")
+	viper.SetDefault("media.s3.region", "us-east-1")
+	viper.SetDefault("media.s3.bucket", "")
+	viper.SetDefault("media.s3.accessKeyId", "")
+	viper.SetDefault("media.s3.secretAccessKey", "")
+	viper.SetDefault("media.s3.usePathStyle", true)
 	// Default Database Settings
 	viper.SetDefault(
 		"db.connstr",
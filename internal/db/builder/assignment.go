@@ -38,7 +38,7 @@ func NewNullableAssignment[T any](
 	if err != nil {
 		return assignment
 	}
-	assignment.Args = pgx.NamedArgs{column: v}
+	assignment.Args = pgx.NamedArgs{parameter: v}
 
 	return assignment
 }
@@ -18,7 +18,7 @@ func TestNullableAssignment(t *testing.T) {
 		predicate := builder.NewNullableAssignment(column, nullableValue)
 		assert.Equal(t, "column1 = @assignment_column1", predicate.Text)
 		assert.NotEmpty(t, predicate.Args)
-		assert.Equal(t, value, predicate.Args[column])
+		assert.Equal(t, value, predicate.Args["assignment_"+column])
 	})
 
 	t.Run("ExplicitNull", func(t *testing.T) {
@@ -27,7 +27,8 @@ func TestNullableAssignment(t *testing.T) {
 		predicate := builder.NewNullableAssignment(column, explicitNull)
 		assert.Equal(t, "column1 = @assignment_column1", predicate.Text)
 		assert.NotEmpty(t, predicate.Args)
-		assert.Nil(t, predicate.Args[column])
+		assert.Contains(t, predicate.Args, "assignment_"+column)
+		assert.Nil(t, predicate.Args["assignment_"+column])
 	})
 
 	t.Run("NotSpecified", func(t *testing.T) {
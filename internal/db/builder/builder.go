@@ -25,10 +25,18 @@ type OrderBy struct {
 	Order  Order
 }
 
+type JoinType string
+
+const (
+	InnerJoin JoinType = "JOIN"
+	LeftJoin  JoinType = "LEFT JOIN"
+)
+
 type QueryBuilder struct {
 	orderBy          []OrderBy
 	whereClauses     []string
 	joins            []string
+	groupBy          []string
 	namedArgs        pgx.NamedArgs
 	returningColumns []string
 	insertColumns    []string
@@ -43,10 +51,13 @@ func (qb QueryBuilder) clone() QueryBuilder {
 		orderBy:          slices.Clone(qb.orderBy),
 		whereClauses:     slices.Clone(qb.whereClauses),
 		joins:            slices.Clone(qb.joins),
+		groupBy:          slices.Clone(qb.groupBy),
 		namedArgs:        maps.Clone(qb.namedArgs),
 		returningColumns: slices.Clone(qb.returningColumns),
 		insertColumns:    slices.Clone(qb.insertColumns),
 		table:            qb.table,
+		limitSet:         qb.limitSet,
+		limit:            qb.limit,
 		tuples:           slices.Clone(qb.tuples),
 	}
 }
@@ -56,6 +67,7 @@ func newQueryBuilder() QueryBuilder {
 		orderBy:      make([]OrderBy, 0),
 		whereClauses: make([]string, 0),
 		joins:        make([]string, 0),
+		groupBy:      make([]string, 0),
 		namedArgs:    make(pgx.NamedArgs, 0),
 		table:        "",
 		limitSet:     false,
@@ -76,9 +88,30 @@ func (qb QueryBuilder) From(from string) QueryBuilder {
 	return clone
 }
 
+// Join adds an inner join to the query. The join is given as the table and the join
+// condition, e.g. "blog.tag t ON t.id = pt.tag_id".
 func (qb QueryBuilder) Join(join string) QueryBuilder {
+	return qb.JoinOf(InnerJoin, join)
+}
+
+// LeftJoin adds a left outer join to the query. The join is given as the table and the join
+// condition, e.g. "blog.post_tag pt ON pt.tag_id = t.id".
+func (qb QueryBuilder) LeftJoin(join string) QueryBuilder {
+	return qb.JoinOf(LeftJoin, join)
+}
+
+// JoinOf adds a join of the given type to the query.
+func (qb QueryBuilder) JoinOf(joinType JoinType, join string) QueryBuilder {
 	clone := qb.clone()
-	clone.joins = append(clone.joins, join)
+	clone.joins = append(clone.joins, string(joinType)+" "+join)
+	return clone
+}
+
+// GroupBy adds columns to the GROUP BY clause of the query. Useful in combination with joins
+// and aggregate select columns.
+func (qb QueryBuilder) GroupBy(cols ...string) QueryBuilder {
+	clone := qb.clone()
+	clone.groupBy = append(clone.groupBy, cols...)
 	return clone
 }
 
@@ -119,13 +152,18 @@ func (qb QueryBuilder) selectExp() (string, pgx.NamedArgs) {
 
 	if len(qb.joins) > 0 {
 		for _, join := range qb.joins {
-			builder.WriteString(" JOIN ")
+			builder.WriteString(" ")
 			builder.WriteString(join)
 		}
 	}
 
 	qb.addWhereExpression(&builder)
 
+	if len(qb.groupBy) > 0 {
+		builder.WriteString(" GROUP BY ")
+		builder.WriteString(strings.Join(qb.groupBy, ", "))
+	}
+
 	if len(qb.orderBy) > 0 {
 		builder.WriteString(" ORDER BY ")
 		for i, orderBy := range qb.orderBy {
@@ -139,7 +177,7 @@ func (qb QueryBuilder) selectExp() (string, pgx.NamedArgs) {
 	}
 
 	if qb.limitSet {
-		builder.WriteString("LIMIT ")
+		builder.WriteString(" LIMIT ")
 		builder.WriteString(strconv.Itoa(qb.limit))
 	}
 
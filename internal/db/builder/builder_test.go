@@ -73,6 +73,44 @@ func TestJoin(t *testing.T) {
 			stmt,
 		)
 	})
+
+	t.Run("LeftJoin", func(t *testing.T) {
+		stmt, _ := builder.From("table1 a").
+			LeftJoin("table2 b ON a.id = b.a_id").
+			Select("a.col1", "b.col2")
+
+		assert.Equal(
+			t, "SELECT a.col1, b.col2 FROM table1 a LEFT JOIN table2 b ON a.id = b.a_id;", stmt,
+		)
+	})
+
+	t.Run("QualifiedPredicate", func(t *testing.T) {
+		stmt, args := builder.From("table1 a").
+			Join("table2 b ON a.id = b.a_id").
+			Where(builder.NewGenericPredicate("b.col2", builder.Equal, "value")).
+			Select("a.col1")
+
+		assert.Equal(
+			t,
+			"SELECT a.col1 FROM table1 a JOIN table2 b ON a.id = b.a_id WHERE b.col2 = @predicate_b_col2;",
+			stmt,
+		)
+		assert.Equal(t, "value", args["predicate_b_col2"])
+	})
+
+	t.Run("GroupBy", func(t *testing.T) {
+		stmt, _ := builder.From("table1 a").
+			LeftJoin("table2 b ON a.id = b.a_id").
+			GroupBy("a.id", "a.col1").
+			OrderBy(builder.OrderBy{Column: "a.col1", Order: builder.Asc}).
+			Select("a.id", "a.col1", "COUNT(b.id) AS b_count")
+
+		assert.Equal(
+			t,
+			"SELECT a.id, a.col1, COUNT(b.id) AS b_count FROM table1 a LEFT JOIN table2 b ON a.id = b.a_id GROUP BY a.id, a.col1 ORDER BY a.col1 ASC;",
+			stmt,
+		)
+	})
 }
 
 func TestOrderBy(t *testing.T) {
@@ -92,3 +130,12 @@ func TestOrderBy(t *testing.T) {
 		assert.Equal(t, "SELECT col1, col2 FROM table ORDER BY col1 DESC;", stmt)
 	})
 }
+
+func TestLimit(t *testing.T) {
+	stmt, _ := builder.From("table").
+		OrderBy(builder.OrderBy{Column: "col1", Order: builder.Asc}).
+		Limit(10).
+		Select("col1")
+
+	assert.Equal(t, "SELECT col1 FROM table ORDER BY col1 ASC LIMIT 10;", stmt)
+}
@@ -2,6 +2,7 @@ package builder
 
 import (
 	"database/sql"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/oapi-codegen/nullable"
@@ -26,6 +27,21 @@ const (
 	//  using NewPredicate themselves as plain text.
 )
 
+// TextSearchConfig is the PostgreSQL text search configuration used to parse documents and
+// queries, e.g. 'english'.
+type TextSearchConfig string
+
+const (
+	English TextSearchConfig = "english"
+	Simple  TextSearchConfig = "simple"
+)
+
+// parameterName returns the named argument used for a column. Qualified column names, e.g.
+// "pt.post_id" in joined queries, are not valid argument names, so the dots are replaced.
+func parameterName(column string) string {
+	return predicatePrefix + strings.ReplaceAll(column, ".", "_")
+}
+
 type Predicate struct {
 	Text string        `json:"text"`
 	Arg  pgx.NamedArgs `json:"arg"`
@@ -47,7 +63,7 @@ func NewNullablePredicate[T any](
 		return predicate
 	}
 
-	parameter := predicatePrefix + column
+	parameter := parameterName(column)
 
 	predicate.Text = column + " " + string(cond) + " @" + parameter
 	if value.IsNull() {
@@ -73,7 +89,7 @@ func NewNullPredicate[T any](
 		return predicate
 	}
 
-	parameter := predicatePrefix + column
+	parameter := parameterName(column)
 	predicate.Text = column + " " + string(cond) + " @" + parameter
 	predicate.Arg = pgx.NamedArgs{parameter: value.V}
 
@@ -83,7 +99,7 @@ func NewNullPredicate[T any](
 func NewGenericPredicate[T any](
 	column string, cond PredicateCondition, value T,
 ) Predicate {
-	parameter := predicatePrefix + column
+	parameter := parameterName(column)
 	return Predicate{
 		Text: column + " " + string(cond) + " @" + parameter,
 		Arg:  pgx.NamedArgs{parameter: value},
@@ -93,3 +109,28 @@ func NewGenericPredicate[T any](
 func NewPredicate(text string, args pgx.NamedArgs) Predicate {
 	return Predicate{Text: text, Arg: args}
 }
+
+// TextSearchQuery returns the tsquery expression used by NewTextSearchPredicate for the given
+// column. The expression references the same named argument as the predicate, so it can be
+// reused in select columns, e.g. for ranking with ts_rank, as long as the predicate is part of
+// the same query.
+func TextSearchQuery(column string, config TextSearchConfig) string {
+	return "websearch_to_tsquery('" + string(config) + "', @" + parameterName(column) + ")"
+}
+
+// NewTextSearchPredicate creates a full-text search predicate matching a tsvector column against
+// a query written in web search syntax. An invalid value produces an empty predicate.
+func NewTextSearchPredicate(
+	column string, config TextSearchConfig, value sql.Null[string],
+) Predicate {
+	predicate := newPredicate()
+
+	if !value.Valid {
+		return predicate
+	}
+
+	predicate.Text = column + " @@ " + TextSearchQuery(column, config)
+	predicate.Arg = pgx.NamedArgs{parameterName(column): value.V}
+
+	return predicate
+}
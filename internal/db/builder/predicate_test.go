@@ -77,3 +77,27 @@ func TestNewPredicate(t *testing.T) {
 	assert.NotEmpty(t, predicate.Arg)
 	assert.Equal(t, "value", predicate.Arg["column1"])
 }
+
+func TestNewTextSearchPredicate(t *testing.T) {
+	column := "search_vector"
+	namedArgKey := "predicate_" + column
+
+	t.Run("Valid", func(t *testing.T) {
+		value := sql.Null[string]{V: "postgres search", Valid: true}
+		predicate := builder.NewTextSearchPredicate(column, builder.English, value)
+		assert.Equal(
+			t,
+			"search_vector @@ websearch_to_tsquery('english', @predicate_search_vector)",
+			predicate.Text,
+		)
+		assert.Equal(t, value.V, predicate.Arg[namedArgKey])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		predicate := builder.NewTextSearchPredicate(
+			column, builder.English, sql.Null[string]{Valid: false},
+		)
+		assert.Empty(t, predicate.Text)
+		assert.Empty(t, predicate.Arg)
+	})
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// AdvisoryLockKey identifies a Postgres advisory lock shared by all application instances.
+type AdvisoryLockKey int64
+
+const (
+	// BlogPublishSchedulerLock is held by the instance running the blog publishing scheduler.
+	BlogPublishSchedulerLock AdvisoryLockKey = 1_000_001
+	// MediaVariantLock is held by the instance generating media asset variants.
+	MediaVariantLock AdvisoryLockKey = 1_000_002
+	// AuthRefreshTokenCleanupLock is held by the instance deleting expired refresh tokens.
+	AuthRefreshTokenCleanupLock AdvisoryLockKey = 1_000_003
+)
+
+// TryAdvisoryXactLock attempts to take a transaction level advisory lock without waiting. The
+// lock is released when the transaction commits or rolls back. Returns false if the lock is
+// held by another transaction.
+func TryAdvisoryXactLock(ctx context.Context, tx pgx.Tx, key AdvisoryLockKey) (bool, error) {
+	const stmt string = `SELECT pg_try_advisory_xact_lock($1);`
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", stmt),
+		slog.Int64("key", int64(key)),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	var locked bool
+	if err := tx.QueryRow(ctx, stmt, int64(key)).Scan(&locked); err != nil {
+		return false, HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "advisory lock attempted", slog.Bool("locked", locked))
+
+	return locked, nil
+}
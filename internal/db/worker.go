@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PeriodicWorker runs an iteration in the background when started, and then once every
+// interval until stopped. Iterations changing rows shared by all application instances take an
+// advisory lock with TryAdvisoryXactLock in their transaction, so that running the worker on
+// several instances is safe.
+type PeriodicWorker struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context)
+	logger   *slog.Logger
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewPeriodicWorker returns a worker calling run every interval. The worker is disabled if the
+// interval is not positive.
+func NewPeriodicWorker(
+	name string,
+	interval time.Duration,
+	run func(ctx context.Context),
+	logger *slog.Logger,
+) *PeriodicWorker {
+	return &PeriodicWorker{
+		name:     name,
+		interval: interval,
+		run:      run,
+		logger:   logger.With(slog.String("worker", name)),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the worker in the background until Stop is called. The worker keeps running when
+// ctx is cancelled, as it is stopped by Stop on shutdown.
+func (w *PeriodicWorker) Start(ctx context.Context) {
+	if w.interval <= 0 {
+		w.logger.LogAttrs(ctx, slog.LevelWarn, "worker disabled")
+		return
+	}
+	ctx, w.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		w.logger.LogAttrs(ctx, slog.LevelInfo, "starting worker")
+		for {
+			w.run(ctx)
+			select {
+			case <-ctx.Done():
+				w.logger.LogAttrs(ctx, slog.LevelInfo, "worker stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels any running iteration and waits for the worker to return.
+func (w *PeriodicWorker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
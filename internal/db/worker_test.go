@@ -0,0 +1,63 @@
+package db_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriodicWorker(t *testing.T) {
+	logger := testsuite.NewTestLogger()
+
+	t.Run("RunsUntilStopped", func(t *testing.T) {
+		var runs atomic.Int32
+		worker := db.NewPeriodicWorker("test", 10*time.Millisecond, func(ctx context.Context) {
+			runs.Add(1)
+		}, &logger)
+
+		// The worker outlives the context it was started with.
+		ctx, cancel := context.WithCancel(context.Background())
+		worker.Start(ctx)
+		cancel()
+		assert.Eventually(t, func() bool {
+			return runs.Load() >= 3
+		}, time.Second, 5*time.Millisecond)
+
+		worker.Stop()
+		stopped := runs.Load()
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, stopped, runs.Load())
+	})
+
+	t.Run("StopCancelsIteration", func(t *testing.T) {
+		cancelled := make(chan struct{})
+		worker := db.NewPeriodicWorker("test", time.Hour, func(ctx context.Context) {
+			<-ctx.Done()
+			close(cancelled)
+		}, &logger)
+
+		worker.Start(context.Background())
+		worker.Stop()
+		select {
+		case <-cancelled:
+		default:
+			t.Fatal("iteration not cancelled before Stop returned")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var runs atomic.Int32
+		worker := db.NewPeriodicWorker("test", 0, func(ctx context.Context) {
+			runs.Add(1)
+		}, &logger)
+
+		worker.Start(context.Background())
+		worker.Stop()
+		assert.Zero(t, runs.Load())
+	})
+}
@@ -0,0 +1,150 @@
+// Package diff contains functionality for comparing text line by line.
+package diff
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxCells is the largest longest common subsequence table, counted as the number of changed
+// old lines times the number of changed new lines, that Lines is willing to allocate.
+const MaxCells = 1 << 22
+
+// ErrTooLarge is returned when the changed parts of the compared texts exceed MaxCells.
+var ErrTooLarge = errors.New("texts are too large to compare")
+
+// Operation describes how a line changed between two texts.
+type Operation string
+
+const (
+	Equal  Operation = "equal"
+	Insert Operation = "insert"
+	Delete Operation = "delete"
+)
+
+// Line is a single line in a diff.
+type Line struct {
+	// Op is the operation needed to turn the old text into the new text.
+	Op Operation `json:"op"`
+	// Text is the line content without the trailing newline.
+	Text string `json:"text"`
+	// OldNumber is the 1-based line number in the old text. Zero for inserted lines.
+	OldNumber int `json:"oldLine,omitzero"`
+	// NewNumber is the 1-based line number in the new text. Zero for deleted lines.
+	NewNumber int `json:"newLine,omitzero"`
+}
+
+// Lines compares two texts line by line and returns the lines of the longest common
+// subsequence marked as equal, and the remaining lines marked as deleted or inserted.
+// ErrTooLarge is returned if the lines that differ between the texts would need a table larger
+// than MaxCells.
+//
+// Example:
+//
+//	lines, err := diff.Lines("a\nb\nc", "a\nc\nd")
+//	// equal a, delete b, equal c, insert d
+func Lines(a, b string) ([]Line, error) {
+	oldLines := split(a)
+	newLines := split(b)
+
+	// Trim the common prefix and suffix to keep the LCS table small for typical edits.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix &&
+		suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	oldMiddle := oldLines[prefix : len(oldLines)-suffix]
+	newMiddle := newLines[prefix : len(newLines)-suffix]
+	if len(oldMiddle) > 0 && len(newMiddle) > MaxCells/len(oldMiddle) {
+		return nil, ErrTooLarge
+	}
+
+	lines := make([]Line, 0, len(oldLines)+len(newLines))
+	for i := range prefix {
+		lines = append(lines, Line{Op: Equal, Text: oldLines[i], OldNumber: i + 1, NewNumber: i + 1})
+	}
+
+	lines = append(lines, lcs(oldMiddle, newMiddle, prefix)...)
+
+	for i := range suffix {
+		oldIndex := len(oldLines) - suffix + i
+		newIndex := len(newLines) - suffix + i
+		lines = append(lines, Line{
+			Op:        Equal,
+			Text:      oldLines[oldIndex],
+			OldNumber: oldIndex + 1,
+			NewNumber: newIndex + 1,
+		})
+	}
+
+	return lines, nil
+}
+
+// Changed reports whether any of the given lines were inserted or deleted.
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// lcs diffs two slices of lines using a longest common subsequence table. The offset is added
+// to the reported line numbers. Callers must keep len(a)*len(b) within MaxCells.
+func lcs(a, b []string, offset int) []Line {
+	table := make([][]int32, len(a)+1)
+	for i := range table {
+		table[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{
+				Op: Equal, Text: a[i], OldNumber: offset + i + 1, NewNumber: offset + j + 1,
+			})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: a[i], OldNumber: offset + i + 1})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j], NewNumber: offset + j + 1})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Op: Delete, Text: a[i], OldNumber: offset + i + 1})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Op: Insert, Text: b[j], NewNumber: offset + j + 1})
+	}
+
+	return lines
+}
+
+func split(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,73 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLines(t *testing.T) {
+	t.Run("Identical", func(t *testing.T) {
+		lines, err := diff.Lines("a\nb", "a\nb")
+		require.NoError(t, err)
+		assert.Equal(t, []diff.Line{
+			{Op: diff.Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+			{Op: diff.Equal, Text: "b", OldNumber: 2, NewNumber: 2},
+		}, lines)
+		assert.False(t, diff.Changed(lines))
+	})
+
+	t.Run("InsertAndDelete", func(t *testing.T) {
+		lines, err := diff.Lines("a\nb\nc", "a\nc\nd")
+		require.NoError(t, err)
+		assert.Equal(t, []diff.Line{
+			{Op: diff.Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+			{Op: diff.Delete, Text: "b", OldNumber: 2},
+			{Op: diff.Equal, Text: "c", OldNumber: 3, NewNumber: 2},
+			{Op: diff.Insert, Text: "d", NewNumber: 3},
+		}, lines)
+		assert.True(t, diff.Changed(lines))
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		lines, err := diff.Lines("a\nb\nc", "a\nx\nc")
+		require.NoError(t, err)
+		assert.Equal(t, []diff.Line{
+			{Op: diff.Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+			{Op: diff.Delete, Text: "b", OldNumber: 2},
+			{Op: diff.Insert, Text: "x", NewNumber: 2},
+			{Op: diff.Equal, Text: "c", OldNumber: 3, NewNumber: 3},
+		}, lines)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		lines, err := diff.Lines("", "a")
+		require.NoError(t, err)
+		assert.Equal(t, []diff.Line{{Op: diff.Insert, Text: "a", NewNumber: 1}}, lines)
+
+		lines, err = diff.Lines("", "")
+		require.NoError(t, err)
+		assert.Empty(t, lines)
+	})
+
+	t.Run("CarriageReturns", func(t *testing.T) {
+		lines, err := diff.Lines("a\r\nb\r\n", "a\nb\n")
+		require.NoError(t, err)
+		assert.False(t, diff.Changed(lines))
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		oldText := strings.Repeat("a\n", 4096)
+		newText := strings.Repeat("b\n", 4096)
+
+		_, err := diff.Lines("start\n"+oldText+"end", "start\n"+newText+"end")
+		assert.ErrorIs(t, err, diff.ErrTooLarge)
+
+		lines, err := diff.Lines(oldText+"x", oldText+"y")
+		require.NoError(t, err)
+		assert.True(t, diff.Changed(lines))
+	})
+}
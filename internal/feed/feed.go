@@ -0,0 +1,267 @@
+// Package feed contains functionality for encoding syndication feeds in the RSS 2.0, Atom 1.0
+// and JSON Feed 1.1 formats.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"time"
+)
+
+// Format is a syndication feed format.
+type Format string
+
+const (
+	RSS  Format = "rss"
+	Atom Format = "atom"
+	JSON Format = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// ContentType returns the media type of the feed format.
+func (f Format) ContentType() string {
+	switch f {
+	case RSS:
+		return "application/rss+xml; charset=utf-8"
+	case Atom:
+		return "application/atom+xml; charset=utf-8"
+	case JSON:
+		return "application/feed+json; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Feed is a format independent syndication feed.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the URL of the website the feed belongs to.
+	Link string
+	// FeedURL is the URL the feed itself is served from.
+	FeedURL string
+	// Updated is the last time any of the items changed.
+	Updated time.Time
+	Items   []Item
+}
+
+// Item is a single entry in a feed.
+type Item struct {
+	// ID is a globally unique and permanent identifier for the item, e.g. a URN.
+	ID          string
+	Title       string
+	Link        string
+	ContentHTML string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Encode encodes the feed in the given format.
+func (f Feed) Encode(format Format) ([]byte, error) {
+	switch format {
+	case RSS:
+		return f.rss()
+	case Atom:
+		return f.atom()
+	case JSON:
+		return f.json()
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ETag returns a strong entity tag identifying the content of the feed in the given format.
+func (f Feed) ETag(format Format) string {
+	h := sha256.New()
+	h.Write([]byte(format))
+	h.Write([]byte(f.Title))
+	h.Write([]byte(f.Updated.UTC().Format(time.RFC3339Nano)))
+	for _, item := range f.Items {
+		h.Write([]byte(item.ID))
+		h.Write([]byte(item.Updated.UTC().Format(time.RFC3339Nano)))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+func (f Feed) rss() ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			AtomLink:      rssLink{Href: f.FeedURL, Rel: "self", Type: RSS.ContentType()},
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Items:         make([]rssItem, len(f.Items)),
+		},
+	}
+	for i, item := range f.Items {
+		doc.Channel.Items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.ID, IsPermaLink: false},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Categories:  item.Tags,
+			Description: item.ContentHTML,
+		}
+	}
+
+	return encodeXML(doc)
+}
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (f Feed) atom() ([]byte, error) {
+	doc := atomDocument{
+		ID:      f.FeedURL,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Link},
+			{Href: f.FeedURL, Rel: "self", Type: Atom.ContentType()},
+		},
+		Entries: make([]atomEntry, len(f.Items)),
+	}
+	for i, item := range f.Items {
+		categories := make([]atomCategory, len(item.Tags))
+		for j, tag := range item.Tags {
+			categories[j] = atomCategory{Term: tag}
+		}
+		doc.Entries[i] = atomEntry{
+			ID:         item.ID,
+			Title:      item.Title,
+			Link:       atomLink{Href: item.Link, Rel: "alternate"},
+			Published:  item.Published.UTC().Format(time.RFC3339),
+			Updated:    item.Updated.UTC().Format(time.RFC3339),
+			Categories: categories,
+			Content:    atomContent{Type: "html", Value: item.ContentHTML},
+		}
+	}
+
+	return encodeXML(doc)
+}
+
+type jsonDocument struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url"`
+	FeedURL     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	ContentHTML   string    `json:"content_html"`
+	DatePublished time.Time `json:"date_published"`
+	DateModified  time.Time `json:"date_modified"`
+	Tags          []string  `json:"tags,omitempty"`
+}
+
+func (f Feed) json() ([]byte, error) {
+	doc := jsonDocument{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Items:       make([]jsonItem, len(f.Items)),
+	}
+	for i, item := range f.Items {
+		doc.Items[i] = jsonItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			DatePublished: item.Published.UTC(),
+			DateModified:  item.Updated.UTC(),
+			Tags:          item.Tags,
+		}
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func encodeXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
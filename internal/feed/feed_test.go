@@ -0,0 +1,85 @@
+package feed_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/feed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFeed() feed.Feed {
+	updated := time.Date(2025, 3, 4, 12, 30, 0, 0, time.UTC)
+	return feed.Feed{
+		Title:       "islandwind",
+		Description: "A blog",
+		Link:        "https://example.com",
+		FeedURL:     "https://example.com/api/v1/blog/feed.rss",
+		Updated:     updated,
+		Items: []feed.Item{
+			{
+				ID:          "urn:uuid:8c4b7c49-2a45-4c4e-9d1f-1d6f0c0b6a11",
+				Title:       "Fish & Chips",
+				Link:        "https://example.com/blog/fish-and-chips",
+				ContentHTML: "<p>Some <em>content</em></p>",
+				Tags:        []string{"food"},
+				Published:   updated.Add(-time.Hour),
+				Updated:     updated,
+			},
+		},
+	}
+}
+
+func TestEncode(t *testing.T) {
+	f := newTestFeed()
+
+	t.Run("RSS", func(t *testing.T) {
+		body, err := f.Encode(feed.RSS)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `<rss version="2.0"`)
+		assert.Contains(t, string(body), "<title>Fish &amp; Chips</title>")
+		assert.Contains(t, string(body), "&lt;p&gt;Some &lt;em&gt;content&lt;/em&gt;&lt;/p&gt;")
+		assert.Contains(t, string(body), "<pubDate>Tue, 04 Mar 2025 11:30:00 +0000</pubDate>")
+		assert.NoError(t, xml.Unmarshal(body, new(any)))
+	})
+
+	t.Run("Atom", func(t *testing.T) {
+		body, err := f.Encode(feed.Atom)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+		assert.Contains(t, string(body), "<updated>2025-03-04T12:30:00Z</updated>")
+		assert.Contains(t, string(body), `<content type="html">`)
+		assert.Contains(t, string(body), `<category term="food"></category>`)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		body, err := f.Encode(feed.JSON)
+		require.NoError(t, err)
+
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(body, &doc))
+		assert.Equal(t, "https://jsonfeed.org/version/1.1", doc["version"])
+		items := doc["items"].([]any)
+		require.Len(t, items, 1)
+		assert.Equal(t, "2025-03-04T12:30:00Z", items[0].(map[string]any)["date_modified"])
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		_, err := f.Encode(feed.Format("yaml"))
+		assert.ErrorIs(t, err, feed.ErrUnknownFormat)
+	})
+}
+
+func TestETag(t *testing.T) {
+	f := newTestFeed()
+	etag := f.ETag(feed.RSS)
+
+	assert.Equal(t, etag, newTestFeed().ETag(feed.RSS))
+	assert.NotEqual(t, etag, f.ETag(feed.Atom))
+
+	f.Items[0].Updated = f.Items[0].Updated.Add(time.Second)
+	assert.NotEqual(t, etag, f.ETag(feed.RSS))
+}
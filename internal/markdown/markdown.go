@@ -0,0 +1,149 @@
+// Package markdown contains functionality for rendering markdown documents to sanitized HTML.
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/r3d5un/islandwind/internal/slug"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Heading is an entry in the table of contents of a document.
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// Document is a rendered markdown document.
+type Document struct {
+	HTML string
+	// TOC contains the headings of the document in order of appearance.
+	TOC []Heading
+}
+
+var tocKey = parser.NewContextKey()
+
+var renderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithASTTransformers(util.Prioritized(headingTransformer{}, 100)),
+	),
+)
+
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[a-z0-9-]+$`)).
+		OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^anchor$`)).OnElements("a")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render converts a markdown document to sanitized HTML. GitHub Flavored Markdown extensions,
+// such as tables and strikethrough, are enabled. Raw HTML in the source is omitted. Headings
+// are given unique IDs and a trailing anchor link, and fenced code blocks keep their language
+// as a "language-*" class.
+func Render(source string) (*Document, error) {
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf, parser.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	toc, _ := ctx.Get(tocKey).([]Heading)
+	if toc == nil {
+		toc = []Heading{}
+	}
+
+	return &Document{HTML: policy.Sanitize(buf.String()), TOC: toc}, nil
+}
+
+// headingTransformer collects the table of contents and appends an anchor link to each heading.
+type headingTransformer struct{}
+
+func (headingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var toc []Heading
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		value, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+		id := string(value.([]byte))
+
+		toc = append(toc, Heading{
+			Level: heading.Level,
+			ID:    id,
+			Text:  strings.TrimSpace(plainText(heading, source)),
+		})
+
+		anchor := ast.NewLink()
+		anchor.Destination = []byte("#" + id)
+		anchor.SetAttributeString("class", []byte("anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	pc.Set(tocKey, toc)
+}
+
+// plainText returns the text content of a node without any markup.
+func plainText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			sb.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(c.Value)
+		default:
+			sb.WriteString(plainText(c, source))
+		}
+	}
+	return sb.String()
+}
+
+// headingIDs generates heading IDs using the same rules as blog post slugs, suffixing
+// duplicates with a number.
+type headingIDs struct {
+	taken []string
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{}
+}
+
+func (ids *headingIDs) Generate(value []byte, _ ast.NodeKind) []byte {
+	id := slug.Unique(slug.Make(string(value)), ids.taken)
+	ids.taken = append(ids.taken, id)
+	return []byte(id)
+}
+
+func (ids *headingIDs) Put(value []byte) {
+	ids.taken = append(ids.taken, string(value))
+}
@@ -0,0 +1,52 @@
+package markdown_test
+
+import (
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/markdown"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	t.Run("Markdown", func(t *testing.T) {
+		doc, err := markdown.Render("Some *emphasised* text.")
+		require.NoError(t, err)
+		assert.Contains(t, doc.HTML, "<em>emphasised</em>")
+		assert.Empty(t, doc.TOC)
+		assert.NotNil(t, doc.TOC)
+	})
+
+	t.Run("HeadingAnchors", func(t *testing.T) {
+		doc, err := markdown.Render("# Hello *World*\n\n## Hello World\n\n### Ærlig `talt`")
+		require.NoError(t, err)
+		assert.Contains(
+			t, doc.HTML, `<h1 id="hello-world">Hello <em>World</em><a href="#hello-world" class="anchor"`,
+		)
+		assert.Contains(t, doc.HTML, `<h2 id="hello-world-2">`)
+		assert.Equal(t, []markdown.Heading{
+			{Level: 1, ID: "hello-world", Text: "Hello World"},
+			{Level: 2, ID: "hello-world-2", Text: "Hello World"},
+			{Level: 3, ID: "aerlig-talt", Text: "Ærlig talt"},
+		}, doc.TOC)
+	})
+
+	t.Run("CodeLanguage", func(t *testing.T) {
+		doc, err := markdown.Render("```go\nfmt.Println(\"hi\")\n```")
+		require.NoError(t, err)
+		assert.Contains(t, doc.HTML, `<code class="language-go">`)
+	})
+
+	t.Run("RawHTMLOmitted", func(t *testing.T) {
+		doc, err := markdown.Render("<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>")
+		require.NoError(t, err)
+		assert.NotContains(t, doc.HTML, "<script>")
+		assert.NotContains(t, doc.HTML, "onerror")
+	})
+
+	t.Run("DangerousLinks", func(t *testing.T) {
+		doc, err := markdown.Render("[click](javascript:alert(1))")
+		require.NoError(t, err)
+		assert.NotContains(t, doc.HTML, "javascript:")
+	})
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	LocalStorage string = "local"
+	S3Storage    string = "s3"
+)
+
+type Config struct {
+	// Storage selects the storage backend used for uploaded files, either "local" or "s3".
+	//
+	// Set through the ISLANDWIND_MEDIA_STORAGE environment variable.
+	Storage string `json:"storage"`
+	// MaxUploadBytes is the maximum size of an upload request.
+	//
+	// Set through the ISLANDWIND_MEDIA_MAXUPLOADBYTES environment variable.
+	MaxUploadBytes int64 `json:"maxUploadBytes"`
+	// VariantWidths are the widths of the resized variants generated for uploaded images.
+	// Variants are only generated for widths smaller than the image.
+	//
+	// Set through the ISLANDWIND_MEDIA_VARIANTWIDTHS environment variable as a comma separated
+	// list, e.g. "320,640,1280".
+	VariantWidths []int `json:"variantWidths"`
+	// VariantIntervalSeconds is how often the variant worker checks for uploaded images without
+	// generated variants. The worker is disabled if the interval is not positive.
+	//
+	// Set through the ISLANDWIND_MEDIA_VARIANTINTERVALSECONDS environment variable.
+	VariantIntervalSeconds int         `json:"variantIntervalSeconds"`
+	Local                  LocalConfig `json:"local"`
+	S3                     S3Config    `json:"s3"`
+}
+
+func (c Config) VariantInterval() time.Duration {
+	return time.Duration(c.VariantIntervalSeconds) * time.Second
+}
+
+// LocalConfig contains the configuration of the local filesystem storage backend.
+type LocalConfig struct {
+	// Root is the directory uploaded files are stored in. It is created if it does not exist.
+	//
+	// Set through the ISLANDWIND_MEDIA_LOCAL_ROOT environment variable.
+	Root string `json:"root"`
+}
+
+// S3Config contains the configuration of the S3-compatible storage backend.
+type S3Config struct {
+	// Endpoint is the base URL of the S3-compatible service, e.g. https://s3.eu-north-1.amazonaws.com.
+	//
+	// Set through the ISLANDWIND_MEDIA_S3_ENDPOINT environment variable.
+	Endpoint string `json:"endpoint"`
+	// Region is the region used when signing requests.
+	//
+	// Set through the ISLANDWIND_MEDIA_S3_REGION environment variable.
+	Region string `json:"region"`
+	// Bucket is the name of the bucket uploaded files are stored in.
+	//
+	// Set through the ISLANDWIND_MEDIA_S3_BUCKET environment variable.
+	Bucket string `json:"bucket"`
+	// AccessKeyID is the access key used when signing requests.
+	//
+	// Set through the ISLANDWIND_MEDIA_S3_ACCESSKEYID environment variable.
+	AccessKeyID string `json:"accessKeyId"`
+	// SecretAccessKey is the secret key used when signing requests.
+	//
+	// Field is safe for logging as the [S3Config] contains a custom [S3Config.LogValue] method.
+	//
+	// Set through the ISLANDWIND_MEDIA_S3_SECRETACCESSKEY environment variable.
+	SecretAccessKey string `json:"secretAccessKey"`
+	// UsePathStyle addresses the bucket as part of the path instead of the host name. Most
+	// self-hosted S3-compatible services require path-style addressing.
+	//
+	// Set through the ISLANDWIND_MEDIA_S3_USEPATHSTYLE environment variable.
+	UsePathStyle bool `json:"usePathStyle"`
+}
+
+func (c S3Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endpoint", c.Endpoint),
+		slog.String("region", c.Region),
+		slog.String("bucket", c.Bucket),
+		slog.String("accessKeyId", c.AccessKeyID),
+		slog.Bool("usePathStyle", c.UsePathStyle),
+	)
+}
@@ -0,0 +1,387 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// Asset is the database record for an uploaded media file.
+type Asset struct {
+	ID          uuid.UUID `json:"id"          db:"id"`
+	Filename    string    `json:"filename"    db:"filename"`
+	ContentType string    `json:"contentType" db:"content_type"`
+	SizeBytes   int64     `json:"sizeBytes"   db:"size_bytes"`
+	// SHA256 is the hex encoded SHA-256 hash of the file content, used to de-duplicate uploads.
+	SHA256 string `json:"sha256" db:"sha256"`
+	// StorageKey is the key of the file in the storage backend.
+	StorageKey string          `json:"storageKey" db:"storage_key"`
+	Width      sql.Null[int32] `json:"width"      db:"width"`
+	Height     sql.Null[int32] `json:"height"     db:"height"`
+	CreatedAt  time.Time       `json:"createdAt"  db:"created_at"`
+	// VariantsGeneratedAt is set once the variant worker has processed the asset, whether or
+	// not any variants were generated.
+	VariantsGeneratedAt sql.Null[time.Time] `json:"variantsGeneratedAt" db:"variants_generated_at"`
+}
+
+var assetColumns = builder.ColumnsFrom(Asset{})
+
+// AssetInput is the input type used by the AssetModel for creating new asset records.
+type AssetInput struct {
+	Filename    string          `json:"filename"`
+	ContentType string          `json:"contentType"`
+	SizeBytes   int64           `json:"sizeBytes"`
+	SHA256      string          `json:"sha256"`
+	StorageKey  string          `json:"storageKey"`
+	Width       sql.Null[int32] `json:"width"`
+	Height      sql.Null[int32] `json:"height"`
+}
+
+type AssetFilter struct {
+	ID          sql.Null[uuid.UUID] `json:"id"`
+	SHA256      sql.Null[string]    `json:"sha256"`
+	ContentType sql.Null[string]    `json:"contentType"`
+
+	LastSeen uuid.UUID `json:"lastSeen"`
+	PageSize int       `json:"pageSize"`
+}
+
+type AssetModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *AssetModel) insert(ctx context.Context, q db.Queryable, input AssetInput) (*Asset, error) {
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"filename":     {V: input.Filename, Valid: true},
+			"content_type": {V: input.ContentType, Valid: true},
+			"size_bytes":   {V: input.SizeBytes, Valid: true},
+			"sha256":       {V: input.SHA256, Valid: true},
+			"storage_key":  {V: input.StorageKey, Valid: true},
+			"width":        {V: input.Width.V, Valid: input.Width.Valid},
+			"height":       {V: input.Height.V, Valid: input.Height.Valid},
+		}).
+		Returning(assetColumns...).
+		Into("media.asset")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	a, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset inserted", slog.Any("asset", a))
+
+	return &a, nil
+}
+
+func (m *AssetModel) Insert(ctx context.Context, input AssetInput) (*Asset, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *AssetModel) InsertTx(ctx context.Context, tx pgx.Tx, input AssetInput) (*Asset, error) {
+	return m.insert(ctx, tx, input)
+}
+
+func (m *AssetModel) selectOne(ctx context.Context, q db.Queryable, id uuid.UUID) (*Asset, error) {
+	stmt, args := builder.From("media.asset").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Select(assetColumns...)
+
+	return m.selectOneWhere(ctx, q, stmt, args)
+}
+
+func (m *AssetModel) SelectOne(ctx context.Context, id uuid.UUID) (*Asset, error) {
+	return m.selectOne(ctx, m.DB, id)
+}
+
+func (m *AssetModel) SelectOneTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*Asset, error) {
+	return m.selectOne(ctx, tx, id)
+}
+
+func (m *AssetModel) selectOneBySHA256(
+	ctx context.Context,
+	q db.Queryable,
+	sha256 string,
+) (*Asset, error) {
+	stmt, args := builder.From("media.asset").
+		Where(builder.NewGenericPredicate("sha256", builder.Equal, sha256)).
+		Select(assetColumns...)
+
+	return m.selectOneWhere(ctx, q, stmt, args)
+}
+
+// SelectOneBySHA256 selects the asset with the given content hash.
+func (m *AssetModel) SelectOneBySHA256(ctx context.Context, sha256 string) (*Asset, error) {
+	return m.selectOneBySHA256(ctx, m.DB, sha256)
+}
+
+func (m *AssetModel) SelectOneBySHA256Tx(
+	ctx context.Context,
+	tx pgx.Tx,
+	sha256 string,
+) (*Asset, error) {
+	return m.selectOneBySHA256(ctx, tx, sha256)
+}
+
+func (m *AssetModel) selectOneWhere(
+	ctx context.Context,
+	q db.Queryable,
+	stmt string,
+	args pgx.NamedArgs,
+) (*Asset, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("args", args),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	a, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset selected", slog.Any("asset", a))
+
+	return &a, nil
+}
+
+func (m *AssetModel) selectMany(
+	ctx context.Context,
+	q db.Queryable,
+	filter AssetFilter,
+) ([]*Asset, *Metadata, error) {
+	stmt, args := builder.
+		From("media.asset").
+		Where(
+			builder.NewNullPredicate("id", builder.Equal, filter.ID),
+			builder.NewNullPredicate("sha256", builder.Equal, filter.SHA256),
+			builder.NewNullPredicate("content_type", builder.Equal, filter.ContentType),
+			builder.NewGenericPredicate("id", builder.Greater, filter.LastSeen),
+		).
+		OrderBy(builder.OrderBy{Column: "id", Order: builder.Asc}).
+		Limit(filter.PageSize).
+		Select(assetColumns...)
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("statement", logging.MinifySQL(stmt)),
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	assets := make([]*Asset, filter.PageSize)
+	i := 0
+	for rows.Next() {
+		a, err := m.scan(rows)
+		if err != nil {
+			return nil, nil, db.HandleError(ctx, err)
+		}
+		assets[i] = &a
+		i++
+	}
+	assets = assets[:i]
+	if err = rows.Err(); err != nil {
+		return nil, nil, db.HandleError(ctx, err)
+	}
+
+	metadata := Metadata{
+		Next:           false,
+		ResponseLength: len(assets),
+	}
+	if len(assets) > 0 {
+		metadata.LastSeen = assets[metadata.ResponseLength-1].ID
+		metadata.Next = true
+	}
+
+	return assets, &metadata, nil
+}
+
+func (m *AssetModel) SelectMany(ctx context.Context, filter AssetFilter) ([]*Asset, *Metadata, error) {
+	return m.selectMany(ctx, m.DB, filter)
+}
+
+func (m *AssetModel) SelectManyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	filter AssetFilter,
+) ([]*Asset, *Metadata, error) {
+	return m.selectMany(ctx, tx, filter)
+}
+
+func (m *AssetModel) delete(ctx context.Context, q db.Queryable, id uuid.UUID) error {
+	stmt, args := builder.From("media.asset").
+		Where(builder.NewGenericPredicate("id", builder.Equal, id)).
+		Delete()
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	if res.RowsAffected() == 0 {
+		return db.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *AssetModel) Delete(ctx context.Context, id uuid.UUID) error {
+	return m.delete(ctx, m.DB, id)
+}
+
+func (m *AssetModel) DeleteTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	return m.delete(ctx, tx, id)
+}
+
+// selectPendingVariants selects up to limit assets without generated variants, oldest first.
+func (m *AssetModel) selectPendingVariants(
+	ctx context.Context,
+	q db.Queryable,
+	limit int,
+) ([]*Asset, error) {
+	stmt, args := builder.
+		From("media.asset").
+		Where(builder.NewPredicate("variants_generated_at IS NULL", nil)).
+		OrderBy(builder.OrderBy{Column: "created_at", Order: builder.Asc}).
+		Limit(limit).
+		Select(assetColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Int("limit", limit),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	defer rows.Close()
+
+	var assets []*Asset
+	for rows.Next() {
+		a, err := m.scan(rows)
+		if err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		assets = append(assets, &a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "pending assets selected", slog.Int("count", len(assets)))
+
+	return assets, nil
+}
+
+func (m *AssetModel) SelectPendingVariantsTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	limit int,
+) ([]*Asset, error) {
+	return m.selectPendingVariants(ctx, tx, limit)
+}
+
+// markVariantsGenerated records that the variants of the asset have been generated.
+func (m *AssetModel) markVariantsGenerated(ctx context.Context, q db.Queryable, id uuid.UUID) error {
+	const stmt string = `
+UPDATE media.asset
+SET variants_generated_at = NOW()
+WHERE id = @id;
+`
+	args := pgx.NamedArgs{"id": id}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.String("id", id.String()),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	res, err := q.Exec(ctx, stmt, args)
+	if err != nil {
+		return db.HandleError(ctx, err)
+	}
+	if res.RowsAffected() == 0 {
+		return db.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *AssetModel) MarkVariantsGeneratedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	return m.markVariantsGenerated(ctx, tx, id)
+}
+
+func (m *AssetModel) scan(row pgx.Row) (Asset, error) {
+	var a Asset
+	err := row.Scan(
+		&a.ID,
+		&a.Filename,
+		&a.ContentType,
+		&a.SizeBytes,
+		&a.SHA256,
+		&a.StorageKey,
+		&a.Width,
+		&a.Height,
+		&a.CreatedAt,
+		&a.VariantsGeneratedAt,
+	)
+	if err != nil {
+		return a, err
+	}
+	return a, nil
+}
@@ -0,0 +1,77 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/media/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	input := data.AssetInput{
+		Filename:    "example.png",
+		ContentType: "image/png",
+		SizeBytes:   1024,
+		SHA256:      "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		StorageKey:  "9f/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08/original.png",
+		Width:       sql.Null[int32]{V: 64, Valid: true},
+		Height:      sql.Null[int32]{V: 32, Valid: true},
+	}
+
+	var inserted *data.Asset
+
+	t.Run("Insert", func(t *testing.T) {
+		var err error
+		inserted, err = models.Assets.Insert(ctx, input)
+		require.NoError(t, err)
+		assert.Equal(t, input.SHA256, inserted.SHA256)
+		assert.Equal(t, int32(64), inserted.Width.V)
+
+		_, err = models.Assets.Insert(ctx, input)
+		assert.ErrorIs(t, err, db.ErrUniqueConstraintViolation)
+	})
+
+	t.Run("SelectOne", func(t *testing.T) {
+		selected, err := models.Assets.SelectOne(ctx, inserted.ID)
+		require.NoError(t, err)
+		assert.Equal(t, *inserted, *selected)
+	})
+
+	t.Run("SelectOneBySHA256", func(t *testing.T) {
+		selected, err := models.Assets.SelectOneBySHA256(ctx, input.SHA256)
+		require.NoError(t, err)
+		assert.Equal(t, inserted.ID, selected.ID)
+	})
+
+	t.Run("SelectMany", func(t *testing.T) {
+		assets, metadata, err := models.Assets.SelectMany(ctx, data.AssetFilter{
+			ContentType: sql.Null[string]{V: "image/png", Valid: true},
+			PageSize:    10,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, assets)
+		assert.Equal(t, assets[len(assets)-1].ID, metadata.LastSeen)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, models.Assets.Delete(ctx, inserted.ID))
+
+		_, err := models.Assets.SelectOne(ctx, inserted.ID)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+
+		assert.ErrorIs(t, models.Assets.Delete(ctx, uuid.New()), db.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,13 @@
+package data
+
+import "github.com/google/uuid"
+
+// Metadata contains metadata about the results of a query.
+type Metadata struct {
+	// LastSeen is a [uuid.UUID] from the last item of the result the metadata object describes.
+	LastSeen uuid.UUID `json:"lastSeen,omitzero"`
+	// Next is true when there are more results in the dataset.
+	Next bool `json:"next"`
+	// ResponseLength is the number of results returned.
+	ResponseLength int `json:"responseLength"`
+}
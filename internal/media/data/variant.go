@@ -0,0 +1,221 @@
+package data
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/db/builder"
+	"github.com/r3d5un/islandwind/internal/logging"
+)
+
+// Variant is the database record for a resized copy of an image asset.
+type Variant struct {
+	ID          uuid.UUID `json:"id"          db:"id"`
+	AssetID     uuid.UUID `json:"assetId"     db:"asset_id"`
+	Width       int32     `json:"width"       db:"width"`
+	Height      int32     `json:"height"      db:"height"`
+	ContentType string    `json:"contentType" db:"content_type"`
+	SizeBytes   int64     `json:"sizeBytes"   db:"size_bytes"`
+	// StorageKey is the key of the file in the storage backend.
+	StorageKey string    `json:"storageKey" db:"storage_key"`
+	CreatedAt  time.Time `json:"createdAt"  db:"created_at"`
+}
+
+var variantColumns = builder.ColumnsFrom(Variant{})
+
+// VariantInput is the input type used by the VariantModel for creating new variant records.
+type VariantInput struct {
+	AssetID     uuid.UUID `json:"assetId"`
+	Width       int32     `json:"width"`
+	Height      int32     `json:"height"`
+	ContentType string    `json:"contentType"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	StorageKey  string    `json:"storageKey"`
+}
+
+type VariantModel struct {
+	DB      *pgxpool.Pool
+	Timeout *time.Duration
+}
+
+func (m *VariantModel) insert(
+	ctx context.Context,
+	q db.Queryable,
+	input VariantInput,
+) (*Variant, error) {
+	stmt, args, err := builder.
+		Insert(builder.Tuple{
+			"asset_id":     {V: input.AssetID, Valid: true},
+			"width":        {V: input.Width, Valid: true},
+			"height":       {V: input.Height, Valid: true},
+			"content_type": {V: input.ContentType, Valid: true},
+			"size_bytes":   {V: input.SizeBytes, Valid: true},
+			"storage_key":  {V: input.StorageKey, Valid: true},
+		}).
+		Returning(variantColumns...).
+		Into("media.variant")
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("input", input),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	v, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "variant inserted", slog.Any("variant", v))
+
+	return &v, nil
+}
+
+func (m *VariantModel) Insert(ctx context.Context, input VariantInput) (*Variant, error) {
+	return m.insert(ctx, m.DB, input)
+}
+
+func (m *VariantModel) InsertTx(ctx context.Context, tx pgx.Tx, input VariantInput) (*Variant, error) {
+	return m.insert(ctx, tx, input)
+}
+
+func (m *VariantModel) selectOne(
+	ctx context.Context,
+	q db.Queryable,
+	assetID uuid.UUID,
+	width int32,
+) (*Variant, error) {
+	stmt, args := builder.From("media.variant").
+		Where(
+			builder.NewGenericPredicate("asset_id", builder.Equal, assetID),
+			builder.NewGenericPredicate("width", builder.Equal, width),
+		).
+		Select(variantColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("args", args),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	v, err := m.scan(q.QueryRow(ctx, stmt, args))
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "variant selected", slog.Any("variant", v))
+
+	return &v, nil
+}
+
+// SelectOne selects the variant of the asset with the given width.
+func (m *VariantModel) SelectOne(ctx context.Context, assetID uuid.UUID, width int32) (*Variant, error) {
+	return m.selectOne(ctx, m.DB, assetID, width)
+}
+
+func (m *VariantModel) SelectOneTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	assetID uuid.UUID,
+	width int32,
+) (*Variant, error) {
+	return m.selectOne(ctx, tx, assetID, width)
+}
+
+func (m *VariantModel) selectByAssetIDs(
+	ctx context.Context,
+	q db.Queryable,
+	assetIDs []uuid.UUID,
+) ([]*Variant, error) {
+	stmt, args := builder.From("media.variant").
+		Where(builder.NewPredicate(
+			"asset_id = ANY(@asset_ids)", pgx.NamedArgs{"asset_ids": assetIDs},
+		)).
+		OrderBy(
+			builder.OrderBy{Column: "asset_id", Order: builder.Asc},
+			builder.OrderBy{Column: "width", Order: builder.Asc},
+		).
+		Select(variantColumns...)
+
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"query",
+		slog.String("query", logging.MinifySQL(stmt)),
+		slog.Any("assetIds", assetIDs),
+		slog.Duration("timeout", *m.Timeout),
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, *m.Timeout)
+	defer cancel()
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "performing query")
+	rows, err := q.Query(ctx, stmt, args)
+	if err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	defer rows.Close()
+
+	var variants []*Variant
+	for rows.Next() {
+		v, err := m.scan(rows)
+		if err != nil {
+			return nil, db.HandleError(ctx, err)
+		}
+		variants = append(variants, &v)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, db.HandleError(ctx, err)
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "variants selected", slog.Int("count", len(variants)))
+
+	return variants, nil
+}
+
+// SelectByAssetIDs selects the variants of all the given assets, ordered by asset and width.
+func (m *VariantModel) SelectByAssetIDs(
+	ctx context.Context,
+	assetIDs []uuid.UUID,
+) ([]*Variant, error) {
+	return m.selectByAssetIDs(ctx, m.DB, assetIDs)
+}
+
+func (m *VariantModel) SelectByAssetIDsTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	assetIDs []uuid.UUID,
+) ([]*Variant, error) {
+	return m.selectByAssetIDs(ctx, tx, assetIDs)
+}
+
+func (m *VariantModel) scan(row pgx.Row) (Variant, error) {
+	var v Variant
+	err := row.Scan(
+		&v.ID,
+		&v.AssetID,
+		&v.Width,
+		&v.Height,
+		&v.ContentType,
+		&v.SizeBytes,
+		&v.StorageKey,
+		&v.CreatedAt,
+	)
+	if err != nil {
+		return v, err
+	}
+	return v, nil
+}
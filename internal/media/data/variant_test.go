@@ -0,0 +1,110 @@
+package data_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/media/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantModel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	const hash string = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+	asset, err := models.Assets.Insert(ctx, data.AssetInput{
+		Filename:    "variants.jpg",
+		ContentType: "image/jpeg",
+		SizeBytes:   2048,
+		SHA256:      hash,
+		StorageKey:  "60/" + hash + "/original.jpg",
+	})
+	require.NoError(t, err)
+
+	t.Run("SelectPendingVariants", func(t *testing.T) {
+		tx, rollback, err := models.BeginTx(ctx)
+		require.NoError(t, err)
+		defer rollback()
+
+		pending, err := models.Assets.SelectPendingVariantsTx(ctx, tx, 100)
+		require.NoError(t, err)
+		assert.Contains(t, ids(pending), asset.ID)
+
+		require.NoError(t, models.Assets.MarkVariantsGeneratedTx(ctx, tx, asset.ID))
+		pending, err = models.Assets.SelectPendingVariantsTx(ctx, tx, 100)
+		require.NoError(t, err)
+		assert.NotContains(t, ids(pending), asset.ID)
+
+		err = models.Assets.MarkVariantsGeneratedTx(ctx, tx, uuid.New())
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	var inserted []*data.Variant
+
+	t.Run("Insert", func(t *testing.T) {
+		for _, width := range []int32{640, 320} {
+			v, err := models.Variants.Insert(ctx, data.VariantInput{
+				AssetID:     asset.ID,
+				Width:       width,
+				Height:      width / 2,
+				ContentType: "image/jpeg",
+				SizeBytes:   int64(width),
+				StorageKey:  fmt.Sprintf("60/%s/w%d.jpg", hash, width),
+			})
+			require.NoError(t, err)
+			inserted = append(inserted, v)
+		}
+
+		_, err := models.Variants.Insert(ctx, data.VariantInput{
+			AssetID:     asset.ID,
+			Width:       320,
+			Height:      160,
+			ContentType: "image/jpeg",
+			StorageKey:  "60/" + hash + "/duplicate.jpg",
+		})
+		assert.ErrorIs(t, err, db.ErrUniqueConstraintViolation)
+	})
+
+	t.Run("SelectOne", func(t *testing.T) {
+		selected, err := models.Variants.SelectOne(ctx, asset.ID, 320)
+		require.NoError(t, err)
+		assert.Equal(t, *inserted[1], *selected)
+
+		_, err = models.Variants.SelectOne(ctx, asset.ID, 1280)
+		assert.ErrorIs(t, err, db.ErrRecordNotFound)
+	})
+
+	t.Run("SelectByAssetIDs", func(t *testing.T) {
+		variants, err := models.Variants.SelectByAssetIDs(ctx, []uuid.UUID{asset.ID, uuid.New()})
+		require.NoError(t, err)
+		require.Len(t, variants, 2)
+		assert.Equal(t, int32(320), variants[0].Width)
+		assert.Equal(t, int32(640), variants[1].Width)
+	})
+
+	t.Run("DeleteCascades", func(t *testing.T) {
+		require.NoError(t, models.Assets.Delete(ctx, asset.ID))
+
+		variants, err := models.Variants.SelectByAssetIDs(ctx, []uuid.UUID{asset.ID})
+		require.NoError(t, err)
+		assert.Empty(t, variants)
+	})
+}
+
+func ids(assets []*data.Asset) []uuid.UUID {
+	IDs := make([]uuid.UUID, len(assets))
+	for i, a := range assets {
+		IDs[i] = a.ID
+	}
+	return IDs
+}
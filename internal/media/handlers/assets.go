@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/media/data"
+	"github.com/r3d5un/islandwind/internal/media/repo"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+	"github.com/r3d5un/islandwind/internal/validator"
+)
+
+// AssetPath is the path of the asset routes. The content of an asset is served from
+// AssetPath + "{id}/content", and the content of its variants from
+// AssetPath + "{id}/variant/{width}".
+const AssetPath string = "/api/v1/media/asset/"
+
+// UploadFormField is the name of the multipart form field containing the uploaded file.
+const UploadFormField string = "file"
+
+// AssetData is an asset with the URLs its content and variants are served from.
+type AssetData struct {
+	repo.Asset
+	URL      string        `json:"url"`
+	Variants []VariantData `json:"variants"`
+}
+
+// VariantData is a variant of an asset with the URL its content is served from.
+type VariantData struct {
+	repo.Variant
+	URL string `json:"url"`
+}
+
+func newAssetData(asset repo.Asset) AssetData {
+	variants := make([]VariantData, len(asset.Variants))
+	for i, v := range asset.Variants {
+		variants[i] = VariantData{
+			Variant: v,
+			URL:     fmt.Sprintf("%s%s/variant/%d", AssetPath, asset.ID, v.Width),
+		}
+	}
+	return AssetData{
+		Asset:    asset,
+		URL:      AssetPath + asset.ID.String() + "/content",
+		Variants: variants,
+	}
+}
+
+type AssetResponse struct {
+	Data AssetData `json:"data"`
+}
+
+type AssetListResponse struct {
+	Metadata data.Metadata `json:"metadata"`
+	Data     []AssetData   `json:"data"`
+}
+
+// UploadAssetHandler stores the file in the UploadFormField field of a multipart request. It
+// responds with 201 Created for new assets, and 200 OK if the file has already been uploaded.
+func UploadAssetHandler(
+	assets repo.AssetWriter,
+	maxUploadBytes int64,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			api.BadRequestResponse(w, r, err, "expected a multipart/form-data request body")
+			return
+		}
+
+		var part io.ReadCloser
+		var filename string
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					api.BadRequestResponse(
+						w, r, err, fmt.Sprintf("the %s field is required", UploadFormField),
+					)
+					return
+				}
+				uploadErrorResponse(ctx, w, r, err, maxUploadBytes)
+				return
+			}
+			if p.FormName() == UploadFormField {
+				part, filename = p, p.FileName()
+				break
+			}
+			_ = p.Close()
+		}
+		defer part.Close()
+
+		asset, created, err := assets.Upload(ctx, repo.UploadInput{Filename: filename, Body: part})
+		if err != nil {
+			uploadErrorResponse(ctx, w, r, err, maxUploadBytes)
+			return
+		}
+		ensure.NotNil(asset, "asset cannot be nil without errors")
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		api.RespondWithJSON(w, r, status, AssetResponse{Data: newAssetData(*asset)}, nil)
+	}
+}
+
+func uploadErrorResponse(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+	maxUploadBytes int64,
+) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		api.ErrorResponse(
+			w,
+			r,
+			http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("uploads must not exceed %d bytes", maxUploadBytes),
+		)
+	case errors.Is(err, repo.ErrUnsupportedMediaType):
+		api.ErrorResponse(
+			w,
+			r,
+			http.StatusUnsupportedMediaType,
+			fmt.Sprintf(
+				"unsupported media type, expected one of: %s",
+				strings.Join(repo.SupportedContentTypes(), ", "),
+			),
+		)
+	case errors.Is(err, context.DeadlineExceeded):
+		api.TimeoutResponse(ctx, w, r)
+	default:
+		api.ServerErrorResponse(w, r, err)
+	}
+}
+
+func GetAssetHandler(
+	assets repo.AssetReader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		asset, err := assets.Read(ctx, *id)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(asset, "asset cannot be nil without errors")
+
+		api.RespondWithJSON(w, r, http.StatusOK, AssetResponse{Data: newAssetData(*asset)}, nil)
+	}
+}
+
+// GetAssetContentHandler serves the content of an asset. As the content of an asset never
+// changes, responses may be cached indefinitely.
+func GetAssetContentHandler(
+	assets repo.AssetReader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		asset, content, err := assets.Open(ctx, *id)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound), errors.Is(err, storage.ErrObjectNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		defer content.Close()
+
+		serveContent(w, r, asset.SHA256, asset.ContentType, asset.Filename, asset.SizeBytes, content)
+	}
+}
+
+// GetAssetVariantContentHandler serves the content of the variant of an asset with the width in
+// the path. Like the content of the asset, responses may be cached indefinitely.
+func GetAssetVariantContentHandler(
+	assets repo.AssetReader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+		width, err := strconv.ParseInt(r.PathValue("width"), 10, 32)
+		if err != nil || width <= 0 {
+			api.InvalidParameterResponse(ctx, w, r, "width", errors.Join(errInvalidWidth, err))
+			return
+		}
+
+		asset, variant, content, err := assets.OpenVariant(ctx, *id, int32(width))
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound), errors.Is(err, storage.ErrObjectNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		defer content.Close()
+
+		serveContent(
+			w,
+			r,
+			fmt.Sprintf("%s-w%d", asset.SHA256, variant.Width),
+			variant.ContentType,
+			variantFilename(asset.Filename, variant),
+			variant.SizeBytes,
+			content,
+		)
+	}
+}
+
+var errInvalidWidth = errors.New("width must be a positive integer")
+
+// variantFilename returns the filename of the asset with the width of the variant added, and
+// the extension of the variant content type, e.g. "photo-w320.jpg".
+func variantFilename(filename string, variant *repo.Variant) string {
+	ext := filepath.Ext(filename)
+	name := strings.TrimSuffix(filename, ext)
+	if variantExt := repo.ContentTypeExtension(variant.ContentType); variantExt != "" {
+		ext = variantExt
+	}
+	return fmt.Sprintf("%s-w%d%s", name, variant.Width, ext)
+}
+
+// immutableCacheControl allows clients and proxies to cache content addressed responses for a
+// year without revalidating.
+const immutableCacheControl string = "public, max-age=31536000, immutable"
+
+func serveContent(
+	w http.ResponseWriter,
+	r *http.Request,
+	etag string,
+	contentType string,
+	filename string,
+	size int64,
+	content io.Reader,
+) {
+	ctx := r.Context()
+	etag = `"` + etag + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", immutableCacheControl)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprint(size))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set(
+		"Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}),
+	)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		logging.LoggerFromContext(ctx).LogAttrs(
+			ctx, slog.LevelError, "unable to write content", slog.String("error", err.Error()),
+		)
+	}
+}
+
+func ListAssetsHandler(
+	assets repo.AssetReader,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		v := validator.New()
+		qs := r.URL.Query()
+		filters := data.AssetFilter{}
+
+		filters.PageSize = api.ReadRequiredQueryInt(qs, "page_size", 25, v)
+		filters.ID = api.ReadQueryNull(api.ParseQueryUUID(qs, "id", v))
+		filters.SHA256 = api.ReadQueryNull(api.ParseQueryString(qs, "sha256", v))
+		filters.ContentType = api.ReadQueryNull(api.ParseQueryString(qs, "content_type", v))
+		filters.LastSeen = *api.ReadRequiredQueryUUID(qs, "last_seen", v, uuid.Nil)
+
+		if !v.Valid() {
+			api.ValidationFailedResponse(ctx, w, r, v.Errors)
+			return
+		}
+
+		list, metadata, err := assets.List(ctx, filters)
+		if err != nil {
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		ensure.NotNil(metadata, "metadata should not be nil without errors")
+
+		assetData := make([]AssetData, len(list))
+		for i, asset := range list {
+			assetData[i] = newAssetData(*asset)
+		}
+
+		api.RespondWithJSON(
+			w,
+			r,
+			http.StatusOK,
+			AssetListResponse{
+				Data:     assetData,
+				Metadata: *metadata,
+			},
+			nil,
+		)
+	}
+}
+
+func DeleteAssetHandler(
+	assets repo.AssetWriter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := api.ReadPathParamID(ctx, "id", r)
+		if err != nil {
+			api.InvalidParameterResponse(ctx, w, r, "id", err)
+			return
+		}
+
+		if err := assets.Delete(ctx, *id); err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				api.NotFoundResponse(ctx, w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				api.TimeoutResponse(ctx, w, r)
+			default:
+				api.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/media/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func newUploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(handlers.UploadFormField, filename)
+	require.NoError(t, err)
+	_, err = fw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, "", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestAssetHandlers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	t.Cleanup(func() {
+		t.Logf("cleaning up test: %s", t.Name())
+		defer cancel()
+	})
+
+	content := newPNG(t, 64, 32)
+	var asset handlers.AssetResponse
+
+	t.Run("UploadAssetHandler", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler := handlers.UploadAssetHandler(assetReaderWriter, 1<<20)
+		handler.ServeHTTP(rr, newUploadRequest(t, "../example.png", content))
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &asset))
+		assert.Equal(t, "example.png", asset.Data.Filename)
+		assert.Equal(t, "image/png", asset.Data.ContentType)
+		assert.Equal(t, int64(len(content)), asset.Data.SizeBytes)
+		require.NotNil(t, asset.Data.Width)
+		assert.Equal(t, int32(64), *asset.Data.Width)
+	})
+
+	t.Run("UploadDuplicate", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler := handlers.UploadAssetHandler(assetReaderWriter, 1<<20)
+		handler.ServeHTTP(rr, newUploadRequest(t, "copy.png", content))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var duplicate handlers.AssetResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &duplicate))
+		assert.Equal(t, asset.Data.ID, duplicate.Data.ID)
+	})
+
+	t.Run("UploadUnsupportedMediaType", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler := handlers.UploadAssetHandler(assetReaderWriter, 1<<20)
+		handler.ServeHTTP(rr, newUploadRequest(t, "notes.txt", []byte("plain text")))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("UploadTooLarge", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler := handlers.UploadAssetHandler(assetReaderWriter, 128)
+		handler.ServeHTTP(rr, newUploadRequest(t, "large.png", content))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("GetAssetContentHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", asset.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.GetAssetContentHandler(assetReaderWriter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+		assert.Equal(t, content, rr.Body.Bytes())
+
+		req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+		rr = httptest.NewRecorder()
+		handlers.GetAssetContentHandler(assetReaderWriter).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("GenerateVariants", func(t *testing.T) {
+		result, err := assetReaderWriter.GenerateVariants(ctx)
+		require.NoError(t, err)
+		assert.True(t, result.Locked)
+		assert.Contains(t, result.Processed, asset.Data.ID)
+
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", asset.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.GetAssetHandler(assetReaderWriter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &asset))
+		require.Len(t, asset.Data.Variants, 2)
+		assert.Equal(t, int32(16), asset.Data.Variants[0].Width)
+		assert.Equal(t, int32(8), asset.Data.Variants[0].Height)
+		assert.Equal(t, int32(32), asset.Data.Variants[1].Width)
+		assert.Equal(
+			t,
+			handlers.AssetPath+asset.Data.ID.String()+"/variant/16",
+			asset.Data.Variants[0].URL,
+		)
+	})
+
+	t.Run("GetAssetVariantContentHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", asset.Data.ID.String())
+		req.SetPathValue("width", "16")
+
+		rr := httptest.NewRecorder()
+		handlers.GetAssetVariantContentHandler(assetReaderWriter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "example-w16.png")
+
+		img, err := png.Decode(rr.Body)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 16, 8), img.Bounds())
+
+		req.SetPathValue("width", "64")
+		rr = httptest.NewRecorder()
+		handlers.GetAssetVariantContentHandler(assetReaderWriter).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("ListAssetsHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "?sha256="+asset.Data.SHA256, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handlers.ListAssetsHandler(assetReaderWriter).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var list handlers.AssetListResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+		require.Len(t, list.Data, 1)
+		assert.Equal(t, asset.Data.ID, list.Data[0].ID)
+	})
+
+	t.Run("DeleteAssetHandler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "", nil)
+		require.NoError(t, err)
+		req.SetPathValue("id", asset.Data.ID.String())
+
+		rr := httptest.NewRecorder()
+		handlers.DeleteAssetHandler(assetReaderWriter).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = httptest.NewRecorder()
+		handlers.GetAssetContentHandler(assetReaderWriter).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,60 @@
+package handlers_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	database "github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/media/repo"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+)
+
+var assetReaderWriter repo.AssetReaderWriter
+
+func TestMain(m *testing.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	logger := testsuite.NewTestLogger()
+
+	logger.Info("creating PostgreSQL container")
+	dbContainer, shutdown, err := database.NewPostgresTestcontainer(ctx)
+	if err != nil {
+		logger.Error("unable to start container", slog.String("error", err.Error()))
+		return
+	}
+	defer shutdown()
+
+	db, cfg, err := database.NewTestPool(ctx, dbContainer)
+	if err != nil {
+		logger.Error("unable to create database connection pool", slog.String("error", err.Error()))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "media")
+	if err != nil {
+		logger.Error("unable to create storage directory", slog.String("error", err.Error()))
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	localStorage, err := storage.NewLocalStorage(dir)
+	if err != nil {
+		logger.Error("unable to create local storage", slog.String("error", err.Error()))
+		return
+	}
+	defer localStorage.Close()
+
+	repository := repo.NewRepository(db, localStorage, []int{16, 32}, new(cfg.TimeoutDuration()))
+	assetReaderWriter = repository.Assets
+
+	exitCode := m.Run()
+
+	defer os.Exit(exitCode)
+}
@@ -0,0 +1,109 @@
+// Package imaging contains the image operations used to derive variants from uploaded images,
+// implemented with the standard library image packages.
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// ToRGBA returns the image as an *image.RGBA with its bounds starting at the origin.
+func ToRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	if rgba, ok := src.(*image.RGBA); ok && b.Min == (image.Point{}) {
+		return rgba
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// ScaledHeight returns the height of an image of the given size scaled to width, keeping the
+// aspect ratio.
+func ScaledHeight(srcWidth, srcHeight, width int) int {
+	return max(1, int(math.Round(float64(srcHeight)*float64(width)/float64(srcWidth))))
+}
+
+// Resize scales the image down to the given width, keeping the aspect ratio. Each destination
+// pixel is the area weighted average of the source pixels it covers. Images are never scaled
+// up, so a copy of the image is returned if it is not wider than width.
+func Resize(src *image.RGBA, width int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if width >= sw || width <= 0 {
+		return cloneRGBA(src)
+	}
+	height := ScaledHeight(sw, sh, width)
+
+	// The image is scaled horizontally into tmp, then vertically into dst. The intermediate
+	// result is kept as floats to avoid rounding twice.
+	tmp := make([]float64, width*sh*4)
+	xWeights := areaWeights(sw, width)
+	for y := range sh {
+		row := src.Pix[y*src.Stride:]
+		for x, ws := range xWeights {
+			var r, g, b, a float64
+			for _, w := range ws {
+				p := row[w.index*4:]
+				r += float64(p[0]) * w.weight
+				g += float64(p[1]) * w.weight
+				b += float64(p[2]) * w.weight
+				a += float64(p[3]) * w.weight
+			}
+			t := tmp[(y*width+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	yWeights := areaWeights(sh, height)
+	for y, ws := range yWeights {
+		for x := range width {
+			var r, g, b, a float64
+			for _, w := range ws {
+				t := tmp[(w.index*width+x)*4:]
+				r += t[0] * w.weight
+				g += t[1] * w.weight
+				b += t[2] * w.weight
+				a += t[3] * w.weight
+			}
+			p := dst.Pix[y*dst.Stride+x*4:]
+			p[0], p[1], p[2], p[3] = clamp(r), clamp(g), clamp(b), clamp(a)
+		}
+	}
+
+	return dst
+}
+
+type weight struct {
+	index  int
+	weight float64
+}
+
+// areaWeights returns, for each of the dstSize destination pixels, the source pixels it
+// covers when scaling down from srcSize, weighted by the covered fraction of each pixel.
+func areaWeights(srcSize, dstSize int) [][]weight {
+	scale := float64(srcSize) / float64(dstSize)
+	weights := make([][]weight, dstSize)
+	for i := range dstSize {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < srcSize && float64(j) < end; j++ {
+			overlap := min(end, float64(j+1)) - max(start, float64(j))
+			if overlap <= 0 {
+				continue
+			}
+			weights[i] = append(weights[i], weight{index: j, weight: overlap / scale})
+		}
+	}
+	return weights
+}
+
+func clamp(v float64) uint8 {
+	return uint8(min(255, max(0, math.Round(v))))
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,161 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/media/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResize(t *testing.T) {
+	t.Run("Uniform", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+		for i := range src.Pix {
+			src.Pix[i] = 200
+		}
+
+		dst := imaging.Resize(src, 30)
+		assert.Equal(t, image.Rect(0, 0, 30, 15), dst.Bounds())
+		for _, v := range dst.Pix {
+			assert.Equal(t, uint8(200), v)
+		}
+	})
+
+	t.Run("AveragesCoveredPixels", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+		for x := range 4 {
+			for y := range 2 {
+				if x%2 == 0 {
+					src.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				} else {
+					src.SetRGBA(x, y, color.RGBA{B: 255, A: 255})
+				}
+			}
+		}
+
+		dst := imaging.Resize(src, 2)
+		assert.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+		assert.Equal(t, color.RGBA{R: 128, B: 128, A: 255}, dst.RGBAAt(0, 0))
+	})
+
+	t.Run("NeverUpscales", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		dst := imaging.Resize(src, 20)
+		assert.Equal(t, src.Bounds(), dst.Bounds())
+	})
+}
+
+func TestScaledHeight(t *testing.T) {
+	assert.Equal(t, 240, imaging.ScaledHeight(640, 480, 320))
+	assert.Equal(t, 1, imaging.ScaledHeight(4000, 10, 100))
+}
+
+// newJPEGWithOrientation encodes a JPEG and inserts an EXIF segment with the orientation
+// after the start of image marker.
+func newJPEGWithOrientation(t *testing.T, order binary.ByteOrder, o imaging.Orientation) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 1)), nil))
+	encoded := buf.Bytes()
+
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(o))
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	content := append([]byte{}, encoded[:2]...)
+	content = append(content, app1...)
+	content = append(content, segment...)
+	return append(content, encoded[2:]...)
+}
+
+func TestReadOrientation(t *testing.T) {
+	t.Run("LittleEndian", func(t *testing.T) {
+		content := newJPEGWithOrientation(t, binary.LittleEndian, imaging.OrientationRotate90)
+		assert.Equal(t, imaging.OrientationRotate90, imaging.ReadOrientation(content))
+
+		_, err := jpeg.Decode(bytes.NewReader(content))
+		assert.NoError(t, err)
+	})
+
+	t.Run("BigEndian", func(t *testing.T) {
+		content := newJPEGWithOrientation(t, binary.BigEndian, imaging.OrientationRotate180)
+		assert.Equal(t, imaging.OrientationRotate180, imaging.ReadOrientation(content))
+	})
+
+	t.Run("WithoutEXIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)), nil))
+		assert.Equal(t, imaging.OrientationNormal, imaging.ReadOrientation(buf.Bytes()))
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		content := newJPEGWithOrientation(t, binary.LittleEndian, imaging.OrientationRotate90)
+		assert.Equal(t, imaging.OrientationNormal, imaging.ReadOrientation(content[:30]))
+		assert.Equal(t, imaging.OrientationNormal, imaging.ReadOrientation([]byte("not an image")))
+	})
+}
+
+func TestOrient(t *testing.T) {
+	// src is a 3x2 image where every pixel has a distinct red value:
+	//
+	//	0 1 2
+	//	3 4 5
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := range 6 {
+		src.SetRGBA(i%3, i/3, color.RGBA{R: uint8(i), A: 255})
+	}
+
+	rows := func(img *image.RGBA) [][]uint8 {
+		var rows [][]uint8
+		for y := range img.Bounds().Dy() {
+			var row []uint8
+			for x := range img.Bounds().Dx() {
+				row = append(row, img.RGBAAt(x, y).R)
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name        string
+		orientation imaging.Orientation
+		expected    [][]uint8
+	}{
+		{"Normal", imaging.OrientationNormal, [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{"FlipH", imaging.OrientationFlipH, [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{"Rotate180", imaging.OrientationRotate180, [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{"FlipV", imaging.OrientationFlipV, [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{"Transpose", imaging.OrientationTranspose, [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{"Rotate90", imaging.OrientationRotate90, [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{"Transverse", imaging.OrientationTransverse, [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{"Rotate270", imaging.OrientationRotate270, [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rows(imaging.Orient(src, tt.orientation)))
+		})
+	}
+}
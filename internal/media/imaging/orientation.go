@@ -0,0 +1,160 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Orientation is the value of the EXIF orientation tag, describing how the stored pixels must
+// be transformed to display the image upright.
+type Orientation int
+
+const (
+	OrientationNormal     Orientation = 1
+	OrientationFlipH      Orientation = 2
+	OrientationRotate180  Orientation = 3
+	OrientationFlipV      Orientation = 4
+	OrientationTranspose  Orientation = 5
+	OrientationRotate90   Orientation = 6
+	OrientationTransverse Orientation = 7
+	OrientationRotate270  Orientation = 8
+)
+
+const (
+	jpegMarkerPrefix byte = 0xff
+	jpegStartOfImage byte = 0xd8
+	jpegStartOfScan  byte = 0xda
+	jpegApp1         byte = 0xe1
+
+	exifHeader         string = "Exif\x00\x00"
+	exifOrientationTag uint16 = 0x0112
+	exifShortType      uint16 = 3
+
+	tiffHeaderBytes int = 8
+	ifdEntryBytes   int = 12
+)
+
+// ReadOrientation returns the EXIF orientation of a JPEG image. OrientationNormal is returned
+// if the image is not a JPEG, has no EXIF data, or the EXIF data cannot be parsed, as the
+// stored pixels are then displayed as is.
+func ReadOrientation(content []byte) Orientation {
+	if len(content) < 2 || content[0] != jpegMarkerPrefix || content[1] != jpegStartOfImage {
+		return OrientationNormal
+	}
+
+	for i := 2; i+4 <= len(content); {
+		if content[i] != jpegMarkerPrefix {
+			return OrientationNormal
+		}
+		marker := content[i+1]
+		if marker == jpegMarkerPrefix {
+			// Markers may be preceded by any number of fill bytes.
+			i++
+			continue
+		}
+		if marker == jpegStartOfScan {
+			return OrientationNormal
+		}
+
+		// The segment length includes the two length bytes, but not the marker.
+		length := int(binary.BigEndian.Uint16(content[i+2:]))
+		start, end := i+4, i+2+length
+		if length < 2 || end > len(content) {
+			return OrientationNormal
+		}
+		segment := content[start:end]
+		if marker == jpegApp1 && bytes.HasPrefix(segment, []byte(exifHeader)) {
+			return readTIFFOrientation(segment[len(exifHeader):])
+		}
+		i = end
+	}
+
+	return OrientationNormal
+}
+
+// readTIFFOrientation reads the orientation tag from the first IFD of the TIFF structure
+// embedded in the EXIF segment.
+func readTIFFOrientation(tiff []byte) Orientation {
+	if len(tiff) < tiffHeaderBytes {
+		return OrientationNormal
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < tiffHeaderBytes || offset+2 > len(tiff) {
+		return OrientationNormal
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for n := range entries {
+		entry := offset + 2 + n*ifdEntryBytes
+		if entry+ifdEntryBytes > len(tiff) {
+			return OrientationNormal
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		if order.Uint16(tiff[entry+2:]) != exifShortType {
+			return OrientationNormal
+		}
+		o := Orientation(order.Uint16(tiff[entry+8:]))
+		if o < OrientationNormal || o > OrientationRotate270 {
+			return OrientationNormal
+		}
+		return o
+	}
+
+	return OrientationNormal
+}
+
+// Orient transforms the image according to the orientation, so that it is displayed upright
+// without the EXIF orientation tag.
+func Orient(src *image.RGBA, o Orientation) *image.RGBA {
+	if o <= OrientationNormal || o > OrientationRotate270 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if o >= OrientationTranspose {
+		dw, dh = h, w
+	}
+
+	// source returns the source pixel displayed at x, y in the transformed image.
+	source := func(x, y int) (int, int) {
+		switch o {
+		case OrientationFlipH:
+			return w - 1 - x, y
+		case OrientationRotate180:
+			return w - 1 - x, h - 1 - y
+		case OrientationFlipV:
+			return x, h - 1 - y
+		case OrientationTranspose:
+			return y, x
+		case OrientationRotate90:
+			return y, h - 1 - x
+		case OrientationTransverse:
+			return w - 1 - y, h - 1 - x
+		default: // OrientationRotate270
+			return w - 1 - y, x
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			sx, sy := source(x, y)
+			copy(dst.Pix[y*dst.Stride+x*4:][:4], src.Pix[sy*src.Stride+sx*4:][:4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,102 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/config"
+	"github.com/r3d5un/islandwind/internal/logging"
+	mediaconfig "github.com/r3d5un/islandwind/internal/media/config"
+	"github.com/r3d5un/islandwind/internal/media/repo"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+)
+
+const moduleName string = "media"
+
+type Module struct {
+	name    string
+	logger  *slog.Logger
+	db      *pgxpool.Pool
+	cfg     *config.Config
+	storage storage.Storage
+	repo    repo.Repository
+	mux     *http.ServeMux
+	auth    AuthMiddlewareService
+
+	variants *variantWorker
+}
+
+type AuthMiddlewareService interface {
+	AccessTokenMiddleware(next http.Handler) http.Handler
+}
+
+func NewModule(
+	ctx context.Context,
+	cfg *config.Config,
+	db *pgxpool.Pool,
+	authModule AuthMiddlewareService,
+) (*Module, error) {
+	ctx, logger := logging.ContextLogger(ctx, slog.Group("module", slog.String("name", moduleName)))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "setting up module")
+	s, err := newStorage(cfg.Media)
+	if err != nil {
+		return nil, err
+	}
+
+	module := Module{
+		name:    moduleName,
+		logger:  logger,
+		db:      db,
+		cfg:     cfg,
+		storage: s,
+		repo: repo.NewRepository(
+			db,
+			s,
+			cfg.Media.VariantWidths,
+			new(time.Duration(cfg.DB.TimeoutSeconds)*time.Second),
+		),
+		auth: authModule,
+	}
+	module.variants = newVariantWorker(module.repo.Assets, cfg.Media.VariantInterval(), logger)
+	logger.LogAttrs(ctx, slog.LevelInfo, "module setup complete")
+
+	return &module, nil
+}
+
+// newStorage creates the storage backend selected by the configuration.
+func newStorage(cfg mediaconfig.Config) (storage.Storage, error) {
+	switch cfg.Storage {
+	case mediaconfig.LocalStorage:
+		return storage.NewLocalStorage(cfg.Local.Root)
+	case mediaconfig.S3Storage:
+		return storage.NewS3Storage(cfg.S3, &http.Client{Timeout: time.Minute})
+	default:
+		return nil, fmt.Errorf("unknown media storage backend: %q", cfg.Storage)
+	}
+}
+
+func (m *Module) Start(ctx context.Context, mux *http.ServeMux) {
+	m.mux = mux
+	m.addRoutes(ctx)
+	m.variants.Start(ctx)
+}
+
+func (m *Module) Shutdown() {
+	m.logger.LogAttrs(context.Background(), slog.LevelInfo, "shutting down module")
+	m.variants.Stop()
+	if s, ok := m.storage.(*storage.LocalStorage); ok {
+		if err := s.Close(); err != nil {
+			m.logger.LogAttrs(
+				context.Background(),
+				slog.LevelError,
+				"unable to close storage",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
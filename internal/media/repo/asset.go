@@ -0,0 +1,379 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/ensure"
+	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/media/data"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+)
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// contentTypeExtensions maps the accepted content types to the file extension used when
+// storing the file.
+var contentTypeExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// maxFilenameLength matches the length of the media.asset.filename column.
+const maxFilenameLength int = 255
+
+type Asset struct {
+	ID          uuid.UUID `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	SHA256      string    `json:"sha256"`
+	Width       *int32    `json:"width"`
+	Height      *int32    `json:"height"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Variants are the resized copies of the asset, ordered by width. Variants are generated in
+	// the background, and are empty until the asset has been processed.
+	Variants []Variant `json:"variants"`
+}
+
+func newAssetFromRow(row data.Asset, variants []*data.Variant) *Asset {
+	asset := &Asset{
+		ID:          row.ID,
+		Filename:    row.Filename,
+		ContentType: row.ContentType,
+		SizeBytes:   row.SizeBytes,
+		SHA256:      row.SHA256,
+		Width:       db.NullToPtr(row.Width),
+		Height:      db.NullToPtr(row.Height),
+		CreatedAt:   row.CreatedAt,
+		Variants:    make([]Variant, 0, len(variants)),
+	}
+	for _, v := range variants {
+		asset.Variants = append(asset.Variants, newVariantFromRow(*v))
+	}
+	return asset
+}
+
+// UploadInput is a file uploaded by a client.
+type UploadInput struct {
+	// Filename is the name of the file on the client. Any directories are removed.
+	Filename string
+	Body     io.Reader
+}
+
+type AssetReader interface {
+	Read(ctx context.Context, ID uuid.UUID) (*Asset, error)
+	// Open reads the asset and opens its content. The caller must close the returned reader.
+	Open(ctx context.Context, ID uuid.UUID) (*Asset, io.ReadCloser, error)
+	// OpenVariant reads the asset and opens the content of its variant with the given width.
+	// The caller must close the returned reader.
+	OpenVariant(ctx context.Context, ID uuid.UUID, width int32) (*Asset, *Variant, io.ReadCloser, error)
+	List(ctx context.Context, filter data.AssetFilter) ([]*Asset, *data.Metadata, error)
+}
+
+type AssetWriter interface {
+	// Upload stores the file and records its metadata. The content type is sniffed from the
+	// content, and only images are accepted. If an identical file has already been uploaded,
+	// the existing asset is returned and created is false.
+	Upload(ctx context.Context, input UploadInput) (asset *Asset, created bool, err error)
+	Delete(ctx context.Context, ID uuid.UUID) error
+}
+
+type AssetReaderWriter interface {
+	AssetReader
+	AssetWriter
+	VariantGenerator
+}
+
+type AssetService struct {
+	models  data.Models
+	storage storage.Storage
+	// variantWidths are the widths of the variants generated for each image, in ascending
+	// order.
+	variantWidths []int
+}
+
+func newAssetRepository(
+	models data.Models,
+	s storage.Storage,
+	variantWidths []int,
+) AssetReaderWriter {
+	widths := slices.DeleteFunc(slices.Clone(variantWidths), func(w int) bool { return w <= 0 })
+	slices.Sort(widths)
+	return &AssetService{
+		models:        models,
+		storage:       s,
+		variantWidths: slices.Compact(widths),
+	}
+}
+
+func (svc *AssetService) Read(ctx context.Context, ID uuid.UUID) (*Asset, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"asset",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading asset")
+	row, err := svc.models.Assets.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	ensure.Equal(row.ID, ID, "asset ID must match")
+	variants, err := svc.models.Variants.SelectByAssetIDs(ctx, []uuid.UUID{ID})
+	if err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset retrieved")
+
+	return newAssetFromRow(*row, variants), nil
+}
+
+func (svc *AssetService) Open(ctx context.Context, ID uuid.UUID) (*Asset, io.ReadCloser, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"asset",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "opening asset")
+	row, err := svc.models.Assets.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := svc.storage.Get(ctx, row.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset opened")
+
+	return newAssetFromRow(*row, nil), content, nil
+}
+
+func (svc *AssetService) List(
+	ctx context.Context,
+	filter data.AssetFilter,
+) ([]*Asset, *data.Metadata, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"assets",
+		slog.Any("filter", filter),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading assets")
+	rows, metadata, err := svc.models.Assets.SelectMany(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	IDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		IDs[i] = row.ID
+	}
+	variants, err := svc.models.Variants.SelectByAssetIDs(ctx, IDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	variantsByAsset := make(map[uuid.UUID][]*data.Variant, len(rows))
+	for _, v := range variants {
+		variantsByAsset[v.AssetID] = append(variantsByAsset[v.AssetID], v)
+	}
+
+	assets := make([]*Asset, len(rows))
+	for i, row := range rows {
+		assets[i] = newAssetFromRow(*row, variantsByAsset[row.ID])
+	}
+	ensure.Equal(len(assets), metadata.ResponseLength, "asset count must match")
+	logger.LogAttrs(ctx, slog.LevelInfo, "assets retrieved")
+
+	return assets, metadata, nil
+}
+
+func (svc *AssetService) Upload(ctx context.Context, input UploadInput) (*Asset, bool, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"upload",
+		slog.String("filename", input.Filename),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "reading upload")
+	content, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	contentType := http.DetectContentType(content)
+	ext, ok := contentTypeExtensions[contentType]
+	if !ok {
+		logger.LogAttrs(
+			ctx, slog.LevelInfo, "unsupported media type", slog.String("contentType", contentType),
+		)
+		return nil, false, ErrUnsupportedMediaType
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	logger = logger.With(slog.String("sha256", hash), slog.String("contentType", contentType))
+
+	existing, err := svc.models.Assets.SelectOneBySHA256(ctx, hash)
+	switch {
+	case err == nil:
+		logger.LogAttrs(ctx, slog.LevelInfo, "identical asset already uploaded")
+		variants, err := svc.models.Variants.SelectByAssetIDs(ctx, []uuid.UUID{existing.ID})
+		if err != nil {
+			return nil, false, err
+		}
+		return newAssetFromRow(*existing, variants), false, nil
+	case !errors.Is(err, db.ErrRecordNotFound):
+		return nil, false, err
+	}
+
+	assetInput := data.AssetInput{
+		Filename:    sanitizeFilename(input.Filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(content)),
+		SHA256:      hash,
+		StorageKey:  AssetKey(hash, "original"+ext),
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+		assetInput.Width.V, assetInput.Width.Valid = int32(cfg.Width), true
+		assetInput.Height.V, assetInput.Height.Valid = int32(cfg.Height), true
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "storing asset", slog.String("key", assetInput.StorageKey))
+	err = svc.storage.Put(
+		ctx, assetInput.StorageKey, bytes.NewReader(content), assetInput.SizeBytes, contentType,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	row, err := svc.models.Assets.Insert(ctx, assetInput)
+	switch {
+	case errors.Is(err, db.ErrUniqueConstraintViolation):
+		// The same file was uploaded concurrently. Both uploads wrote the same content to the
+		// same key, so the other asset can be returned as is.
+		logger.LogAttrs(ctx, slog.LevelInfo, "identical asset uploaded concurrently")
+		existing, err := svc.models.Assets.SelectOneBySHA256(ctx, hash)
+		if err != nil {
+			return nil, false, err
+		}
+		return newAssetFromRow(*existing, nil), false, nil
+	case err != nil:
+		return nil, false, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset uploaded", slog.String("id", row.ID.String()))
+
+	return newAssetFromRow(*row, nil), true, nil
+}
+
+// Delete removes the asset and variant records, followed by the stored files. Failing to
+// remove a file is logged, but does not fail the deletion, as an orphaned file is preferable
+// to a record pointing at a missing file.
+func (svc *AssetService) Delete(ctx context.Context, ID uuid.UUID) error {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"asset",
+		slog.String("id", ID.String()),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "deleting asset")
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	row, err := svc.models.Assets.SelectOneTx(ctx, tx, ID)
+	if err != nil {
+		return err
+	}
+	variants, err := svc.models.Variants.SelectByAssetIDsTx(ctx, tx, []uuid.UUID{ID})
+	if err != nil {
+		return err
+	}
+	// Variants are removed by the cascading foreign key.
+	if err := svc.models.Assets.DeleteTx(ctx, tx, ID); err != nil {
+		return err
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	keys := []string{row.StorageKey}
+	for _, v := range variants {
+		keys = append(keys, v.StorageKey)
+	}
+	for _, key := range keys {
+		if err := svc.storage.Delete(ctx, key); err != nil {
+			logger.LogAttrs(
+				ctx,
+				slog.LevelError,
+				"unable to delete stored file",
+				slog.String("key", key),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset deleted")
+
+	return nil
+}
+
+// AssetKey returns the storage key of a file belonging to the asset with the given content
+// hash. All files of an asset are stored in the same directory.
+func AssetKey(hash string, name string) string {
+	return hash[:2] + "/" + hash + "/" + name
+}
+
+// sanitizeFilename removes any directories and control characters from the client provided
+// filename, and truncates it to fit the database column.
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "upload"
+	}
+
+	runes := []rune(name)
+	if len(runes) > maxFilenameLength {
+		runes = runes[:maxFilenameLength]
+	}
+	return string(runes)
+}
+
+// ContentTypeExtension returns the file extension used when storing files of the content type,
+// or an empty string if the content type is not supported.
+func ContentTypeExtension(contentType string) string {
+	return contentTypeExtensions[contentType]
+}
+
+// SupportedContentTypes returns the content types accepted by AssetWriter.Upload.
+func SupportedContentTypes() []string {
+	types := make([]string, 0, len(contentTypeExtensions))
+	for contentType := range contentTypeExtensions {
+		types = append(types, contentType)
+	}
+	slices.Sort(types)
+	return types
+}
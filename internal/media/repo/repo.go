@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/r3d5un/islandwind/internal/media/data"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+)
+
+type Repository struct {
+	models data.Models
+	Assets AssetReaderWriter
+}
+
+// NewRepository creates the media repository. Variants with the given widths are generated for
+// uploaded images.
+func NewRepository(
+	db *pgxpool.Pool,
+	s storage.Storage,
+	variantWidths []int,
+	timeout *time.Duration,
+) Repository {
+	models := data.NewModels(db, timeout)
+	return Repository{
+		models: models,
+		Assets: newAssetRepository(models, s, variantWidths),
+	}
+}
@@ -0,0 +1,278 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/media/data"
+	"github.com/r3d5un/islandwind/internal/media/imaging"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+)
+
+const (
+	// variantBatchSize is the maximum number of assets processed by a single run of
+	// GenerateVariants.
+	variantBatchSize int = 10
+	// maxVariantPixels limits the size of the images variants are generated for, as the
+	// decoded image is held in memory.
+	maxVariantPixels   int = 50_000_000
+	variantJPEGQuality int = 85
+)
+
+// variantFormat is the encoding used for the variants of a content type.
+type variantFormat struct {
+	contentType string
+	ext         string
+	encode      func(w io.Writer, img image.Image) error
+}
+
+// variantFormats maps the content types variants are generated for to the encoding of the
+// variants. GIFs are encoded as PNG, so only the first frame of an animated GIF is kept.
+var variantFormats = map[string]variantFormat{
+	"image/jpeg": {
+		contentType: "image/jpeg",
+		ext:         ".jpg",
+		encode: func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: variantJPEGQuality})
+		},
+	},
+	"image/png": {contentType: "image/png", ext: ".png", encode: png.Encode},
+	"image/gif": {contentType: "image/png", ext: ".png", encode: png.Encode},
+}
+
+// Variant is a resized copy of an image asset.
+type Variant struct {
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+	ContentType string `json:"contentType"`
+	SizeBytes   int64  `json:"sizeBytes"`
+}
+
+func newVariantFromRow(row data.Variant) Variant {
+	return Variant{
+		Width:       row.Width,
+		Height:      row.Height,
+		ContentType: row.ContentType,
+		SizeBytes:   row.SizeBytes,
+	}
+}
+
+// VariantKey returns the storage key of the variant of an asset with the given width.
+func VariantKey(hash string, width int, ext string) string {
+	return AssetKey(hash, fmt.Sprintf("w%d%s", width, ext))
+}
+
+// VariantResult contains the assets processed by a run of GenerateVariants.
+type VariantResult struct {
+	// Locked is false if another instance held the variant lock, and nothing was processed.
+	Locked    bool        `json:"locked"`
+	Processed []uuid.UUID `json:"processed"`
+	Variants  int         `json:"variants"`
+}
+
+type VariantGenerator interface {
+	// GenerateVariants generates the configured variants for a batch of assets that have not
+	// been processed yet. Variants are only generated for JPEG, PNG and GIF images, and only
+	// for widths smaller than the image. The images are re-encoded, so no EXIF data is kept,
+	// and the EXIF orientation is applied to the pixels. Only one instance of the application
+	// generates variants at a time.
+	GenerateVariants(ctx context.Context) (*VariantResult, error)
+}
+
+func (svc *AssetService) OpenVariant(
+	ctx context.Context,
+	ID uuid.UUID,
+	width int32,
+) (*Asset, *Variant, io.ReadCloser, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"asset",
+		slog.String("id", ID.String()),
+		slog.Int("width", int(width)),
+	))
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "opening asset variant")
+	row, err := svc.models.Assets.SelectOne(ctx, ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	variantRow, err := svc.models.Variants.SelectOne(ctx, ID, width)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	content, err := svc.storage.Get(ctx, variantRow.StorageKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "asset variant opened")
+
+	variant := newVariantFromRow(*variantRow)
+	return newAssetFromRow(*row, nil), &variant, content, nil
+}
+
+func (svc *AssetService) GenerateVariants(ctx context.Context) (*VariantResult, error) {
+	logger := logging.LoggerFromContext(ctx)
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "generating asset variants")
+	tx, rollback, err := svc.models.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+
+	locked, err := db.TryAdvisoryXactLock(ctx, tx, db.MediaVariantLock)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		logger.LogAttrs(ctx, slog.LevelInfo, "variant lock held by another instance")
+		return &VariantResult{Locked: false}, nil
+	}
+
+	pending, err := svc.models.Assets.SelectPendingVariantsTx(ctx, tx, variantBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := VariantResult{Locked: true, Processed: make([]uuid.UUID, 0, len(pending))}
+	for _, asset := range pending {
+		variants, err := svc.generateAssetVariants(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		for _, input := range variants {
+			if _, err := svc.models.Variants.InsertTx(ctx, tx, input); err != nil {
+				return nil, err
+			}
+		}
+		if err := svc.models.Assets.MarkVariantsGeneratedTx(ctx, tx, asset.ID); err != nil {
+			return nil, err
+		}
+		result.Processed = append(result.Processed, asset.ID)
+		result.Variants += len(variants)
+	}
+
+	logger.LogAttrs(ctx, slog.LevelInfo, "committing changes")
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	logger.LogAttrs(
+		ctx,
+		slog.LevelInfo,
+		"asset variants generated",
+		slog.Int("processed", len(result.Processed)),
+		slog.Int("variants", result.Variants),
+	)
+
+	return &result, nil
+}
+
+// generateAssetVariants stores the variants of the asset, and returns the records to insert.
+// Assets that variants cannot be generated for return no variants, so that they are not
+// processed again. Any other error is returned, and the asset is retried on the next run.
+func (svc *AssetService) generateAssetVariants(
+	ctx context.Context,
+	asset *data.Asset,
+) ([]data.VariantInput, error) {
+	logger := logging.LoggerFromContext(ctx).With(slog.Group(
+		"asset",
+		slog.String("id", asset.ID.String()),
+		slog.String("contentType", asset.ContentType),
+	))
+
+	format, ok := variantFormats[asset.ContentType]
+	if !ok {
+		logger.LogAttrs(ctx, slog.LevelInfo, "variants not supported for content type")
+		return nil, nil
+	}
+
+	content, err := svc.readContent(ctx, asset.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			logger.LogAttrs(ctx, slog.LevelError, "asset content missing")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		logger.LogAttrs(
+			ctx, slog.LevelWarn, "unable to decode image", slog.String("error", err.Error()),
+		)
+		return nil, nil
+	}
+	if cfg.Width*cfg.Height > maxVariantPixels {
+		logger.LogAttrs(
+			ctx,
+			slog.LevelWarn,
+			"image too large for variants",
+			slog.Int("width", cfg.Width),
+			slog.Int("height", cfg.Height),
+		)
+		return nil, nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		logger.LogAttrs(
+			ctx, slog.LevelWarn, "unable to decode image", slog.String("error", err.Error()),
+		)
+		return nil, nil
+	}
+	img := imaging.Orient(imaging.ToRGBA(decoded), imaging.ReadOrientation(content))
+
+	var variants []data.VariantInput
+	for _, width := range svc.variantWidths {
+		if width >= img.Bounds().Dx() {
+			break
+		}
+
+		var buf bytes.Buffer
+		resized := imaging.Resize(img, width)
+		if err := format.encode(&buf, resized); err != nil {
+			return nil, err
+		}
+
+		input := data.VariantInput{
+			AssetID:     asset.ID,
+			Width:       int32(resized.Bounds().Dx()),
+			Height:      int32(resized.Bounds().Dy()),
+			ContentType: format.contentType,
+			SizeBytes:   int64(buf.Len()),
+			StorageKey:  VariantKey(asset.SHA256, width, format.ext),
+		}
+		logger.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"storing variant",
+			slog.Int("width", width),
+			slog.String("key", input.StorageKey),
+		)
+		err := svc.storage.Put(ctx, input.StorageKey, &buf, input.SizeBytes, input.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, input)
+	}
+
+	return variants, nil
+}
+
+func (svc *AssetService) readContent(ctx context.Context, key string) ([]byte, error) {
+	r, err := svc.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
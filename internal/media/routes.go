@@ -0,0 +1,150 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/justinas/alice"
+	"github.com/r3d5un/islandwind/internal/api"
+	"github.com/r3d5un/islandwind/internal/auth/scope"
+	"github.com/r3d5un/islandwind/internal/media/handlers"
+	"github.com/rs/cors"
+)
+
+func (m *Module) addRoutes(ctx context.Context) {
+	routes := []struct {
+		Path    string `json:"path"`
+		handler http.HandlerFunc
+		Method  string `json:"methods"`
+		// Scopes are required of the access token authenticating the request. Routes
+		// without scopes are public.
+		Scopes []string `json:"scopes"`
+	}{
+		// healthcheck
+		{
+			"/api/v1/media/healthcheck",
+			m.healthcheckHandler,
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/media/healthcheck",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		// assets
+		{
+			"/api/v1/media/asset",
+			handlers.UploadAssetHandler(m.repo.Assets, m.cfg.Media.MaxUploadBytes),
+			http.MethodPost,
+			[]string{scope.MediaWrite},
+		},
+		{
+			"/api/v1/media/asset",
+			handlers.ListAssetsHandler(m.repo.Assets),
+			http.MethodGet,
+			[]string{scope.MediaRead},
+		},
+		{
+			"/api/v1/media/asset",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/media/asset/{id}",
+			handlers.GetAssetHandler(m.repo.Assets),
+			http.MethodGet,
+			[]string{scope.MediaRead},
+		},
+		{
+			"/api/v1/media/asset/{id}",
+			handlers.DeleteAssetHandler(m.repo.Assets),
+			http.MethodDelete,
+			[]string{scope.MediaDelete},
+		},
+		{
+			"/api/v1/media/asset/{id}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		// The content is public, so that blog posts can reference uploaded images.
+		{
+			"/api/v1/media/asset/{id}/content",
+			handlers.GetAssetContentHandler(m.repo.Assets),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/media/asset/{id}/content",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+		{
+			"/api/v1/media/asset/{id}/variant/{width}",
+			handlers.GetAssetVariantContentHandler(m.repo.Assets),
+			http.MethodGet,
+			nil,
+		},
+		{
+			"/api/v1/media/asset/{id}/variant/{width}",
+			api.CorsPreflightHandler(),
+			http.MethodOptions,
+			nil,
+		},
+	}
+
+	corsMiddleware := cors.New(cors.Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{
+			http.MethodPost,
+			http.MethodPatch,
+			http.MethodDelete,
+			http.MethodGet,
+			http.MethodOptions,
+			http.MethodHead,
+		},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+	})
+
+	m.logger.LogAttrs(ctx, slog.LevelInfo, "adding routes")
+	for _, route := range routes {
+		m.logger.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"adding route",
+			slog.Group("route", slog.Any("route", route)),
+		)
+
+		chain := alice.New(
+			// Add logging middleware for all requests
+			func(next http.Handler) http.Handler {
+				handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r)
+				})
+				return api.LogRequestMiddleware(handlerFunc, *m.logger)
+			},
+			// Enable CORS for all requests
+			func(next http.Handler) http.Handler {
+				return corsMiddleware.Handler(next)
+			},
+			// Require an access token granting the scopes of the route
+			func(next http.Handler) http.Handler {
+				if len(route.Scopes) == 0 {
+					return next
+				}
+				return m.auth.AccessTokenMiddleware(api.RequireScope(route.Scopes...)(next))
+			},
+		)
+
+		m.mux.Handle(
+			fmt.Sprintf("%s %s", route.Method, route.Path),
+			chain.Then(route.handler),
+		)
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// LocalStorage stores objects as files below a root directory on the local filesystem.
+type LocalStorage struct {
+	root *os.Root
+}
+
+// NewLocalStorage opens the root directory, creating it if it does not exist.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Put writes the object to a temporary file which is renamed into place once complete, so
+// readers never observe partially written objects.
+func (s *LocalStorage) Put(
+	ctx context.Context,
+	key string,
+	body io.Reader,
+	_ int64,
+	_ string,
+) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if err := s.root.MkdirAll(path.Dir(key), 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := key + ".tmp-" + hex.EncodeToString(suffix)
+
+	f, err := s.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, contextReader{ctx: ctx, r: body}); err != nil {
+		_ = f.Close()
+		_ = s.root.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = s.root.Remove(tmp)
+		return err
+	}
+
+	return s.root.Rename(tmp, key)
+}
+
+func (s *LocalStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	f, err := s.root.Open(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := s.root.Remove(key)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Close releases the root directory.
+func (s *LocalStorage) Close() error {
+	return s.root.Close()
+}
+
+// contextReader stops reading once the context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
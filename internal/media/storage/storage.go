@@ -0,0 +1,41 @@
+// Package storage contains the backends used to store uploaded media files.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrInvalidKey     = errors.New("invalid object key")
+)
+
+// Storage is an interface for storing, reading and deleting objects by key. Keys are slash
+// separated relative paths, e.g. "ab/abcdef/original.png".
+type Storage interface {
+	// Put stores the object under the given key, overwriting any existing object.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the object stored under the given key. The caller must close the returned
+	// reader. Returns ErrObjectNotFound if the object does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under the given key. Deleting an object that does not
+	// exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// validateKey ensures the key is a clean relative path that cannot escape the storage root.
+func validateKey(key string) error {
+	if key == "" ||
+		strings.HasPrefix(key, "/") ||
+		strings.Contains(key, "\\") ||
+		path.Clean(key) != key ||
+		key == ".." ||
+		strings.HasPrefix(key, "../") {
+		return ErrInvalidKey
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/media/config"
+	"github.com/r3d5un/islandwind/internal/media/storage"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStorage(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	content := []byte("some image content")
+	key := "ab/abcdef/original.png"
+
+	t.Run("Put", func(t *testing.T) {
+		err := s.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/png")
+		require.NoError(t, err)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		r, err := s.Get(ctx, key)
+		require.NoError(t, err)
+		defer r.Close()
+
+		read, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, content, read)
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		for _, key := range []string{"", "/abs", "../escape", "a/../../b", "a//b"} {
+			_, err := s.Get(ctx, key)
+			assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, s.Delete(ctx, key))
+
+		_, err := s.Get(ctx, key)
+		assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+
+		assert.NoError(t, s.Delete(ctx, key), "deleting missing objects is not an error")
+	})
+}
+
+func TestLocalStorage(t *testing.T) {
+	s, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	testStorage(t, s)
+}
+
+func TestS3Storage(t *testing.T) {
+	server := testsuite.NewS3Server("islandwind")
+	t.Cleanup(server.Close)
+
+	s, err := storage.NewS3Storage(
+		config.S3Config{
+			Endpoint:        server.URL,
+			Region:          "us-east-1",
+			Bucket:          "media",
+			AccessKeyID:     "islandwind",
+			SecretAccessKey: "secret",
+			UsePathStyle:    true,
+		},
+		server.Client(),
+	)
+	require.NoError(t, err)
+
+	testStorage(t, s)
+
+	t.Run("ContentType", func(t *testing.T) {
+		ctx := context.Background()
+		require.NoError(t, s.Put(ctx, "a/b.gif", bytes.NewReader([]byte("GIF")), 3, "image/gif"))
+
+		obj, ok := server.Object("/media/a/b.gif")
+		require.True(t, ok)
+		assert.Equal(t, "image/gif", obj.ContentType)
+	})
+}
@@ -0,0 +1,61 @@
+package media
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/r3d5un/islandwind/internal/db"
+	"github.com/r3d5un/islandwind/internal/media/repo"
+)
+
+// variantWorker periodically generates the variants of uploaded images, keeping the image
+// processing out of the upload requests. Only the instance holding the variant advisory lock
+// generates variants.
+type variantWorker struct {
+	*db.PeriodicWorker
+	assets repo.VariantGenerator
+	logger *slog.Logger
+}
+
+func newVariantWorker(
+	assets repo.VariantGenerator,
+	interval time.Duration,
+	logger *slog.Logger,
+) *variantWorker {
+	w := &variantWorker{
+		assets: assets,
+		logger: logger.With(slog.Group("variantWorker", slog.Duration("interval", interval))),
+	}
+	w.PeriodicWorker = db.NewPeriodicWorker("variant worker", interval, w.run, w.logger)
+
+	return w
+}
+
+// run generates variants until no unprocessed assets remain, so that a large number of
+// uploads does not have to wait for several intervals.
+func (w *variantWorker) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		result, err := w.assets.GenerateVariants(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.logger.LogAttrs(
+				ctx, slog.LevelError, "unable to generate variants", slog.String("error", err.Error()),
+			)
+			return
+		}
+		if !result.Locked || len(result.Processed) == 0 {
+			return
+		}
+
+		w.logger.LogAttrs(
+			ctx,
+			slog.LevelInfo,
+			"asset variants generated",
+			slog.Any("processed", result.Processed),
+			slog.Int("variants", result.Variants),
+		)
+	}
+}
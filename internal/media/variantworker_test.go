@@ -0,0 +1,42 @@
+package media
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/r3d5un/islandwind/internal/media/repo"
+	"github.com/r3d5un/islandwind/internal/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVariantGenerator processes pending assets, one per call, before reporting that none
+// remain.
+type fakeVariantGenerator struct {
+	runs    atomic.Int32
+	pending atomic.Int32
+}
+
+func (f *fakeVariantGenerator) GenerateVariants(ctx context.Context) (*repo.VariantResult, error) {
+	f.runs.Add(1)
+	if f.pending.Add(-1) < 0 {
+		f.pending.Store(0)
+		return &repo.VariantResult{Locked: true}, nil
+	}
+	return &repo.VariantResult{Locked: true, Processed: []uuid.UUID{uuid.New()}, Variants: 3}, nil
+}
+
+func TestVariantWorker(t *testing.T) {
+	logger := testsuite.NewTestLogger()
+
+	t.Run("DrainsPendingAssets", func(t *testing.T) {
+		assets := &fakeVariantGenerator{}
+		assets.pending.Store(5)
+		worker := newVariantWorker(assets, time.Hour, &logger)
+
+		worker.run(context.Background())
+		assert.Equal(t, int32(6), assets.runs.Load())
+	})
+}
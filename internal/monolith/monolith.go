@@ -3,6 +3,7 @@ package monolith
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -22,6 +23,7 @@ import (
 	"github.com/r3d5un/islandwind/internal/config"
 	database "github.com/r3d5un/islandwind/internal/db"
 	"github.com/r3d5un/islandwind/internal/logging"
+	"github.com/r3d5un/islandwind/internal/media"
 	"github.com/spf13/viper"
 )
 
@@ -68,6 +70,12 @@ func NewMonolith(ctx context.Context) (context.Context, *Monolith, error) {
 	}
 	modules = append(modules, blogModule)
 
+	mediaModule, err := media.NewModule(ctx, cfg, db, authModule)
+	if err != nil {
+		return ctx, nil, err
+	}
+	modules = append(modules, mediaModule)
+
 	monolith = Monolith{
 		id:      instanceID,
 		cfg:     cfg,
@@ -177,6 +185,9 @@ func (m *Monolith) routes() http.Handler {
 	m.mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
 	m.mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 
+	// metrics
+	m.mux.Handle("/debug/vars", expvar.Handler())
+
 	handler := standard.Then(m.mux)
 	return handler
 }
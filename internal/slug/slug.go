@@ -0,0 +1,104 @@
+// Package slug contains functionality for creating human-readable URL path segments from text.
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxLength is the maximum length of a slug, excluding any collision suffix.
+	MaxLength int = 96
+	// Fallback is used when the text contains no characters that can be used in a slug.
+	Fallback string = "post"
+)
+
+// transliterations contains replacements for letters that do not decompose into an ASCII base
+// letter and a combining mark.
+var transliterations = map[rune]string{
+	'æ': "ae", 'ø': "o", 'å': "a", 'ß': "ss", 'œ': "oe", 'þ': "th", 'ð': "d", 'đ': "d",
+	'ł': "l", 'ı': "i", 'ħ': "h", 'ŋ': "ng", 'ſ': "s", '&': "and",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// Make creates a slug from the given text. Letters are lowercased and transliterated to ASCII,
+// and any other characters are collapsed into single hyphens.
+//
+// Example:
+//
+//	slug.Make("Blåbærsyltetøy & Crème Brûlée") // "blabaersyltetoy-and-creme-brulee"
+func Make(text string) string {
+	decomposed, _, err := transform.String(
+		transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC),
+		strings.ToLower(text),
+	)
+	if err != nil {
+		decomposed = strings.ToLower(text)
+	}
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range decomposed {
+		replacement, ok := transliterations[r]
+		switch {
+		case ok:
+			if replacement == "" {
+				continue
+			}
+			b.WriteString(replacement)
+			hyphen = false
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			hyphen = false
+		case !hyphen && b.Len() > 0:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > MaxLength {
+		slug = slug[:MaxLength]
+		if i := strings.LastIndexByte(slug, '-'); i > MaxLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimSuffix(slug, "-")
+	}
+	if slug == "" {
+		return Fallback
+	}
+
+	return slug
+}
+
+// WithSuffix returns the slug with a numeric collision suffix, e.g. "my-post-2".
+func WithSuffix(slug string, n int) string {
+	return slug + "-" + strconv.Itoa(n)
+}
+
+// Unique returns the first of slug, slug-2, slug-3, ... that is not taken.
+func Unique(slug string, taken []string) string {
+	set := make(map[string]struct{}, len(taken))
+	for _, s := range taken {
+		set[s] = struct{}{}
+	}
+
+	if _, exists := set[slug]; !exists {
+		return slug
+	}
+	for n := 2; ; n++ {
+		candidate := WithSuffix(slug, n)
+		if _, exists := set[candidate]; !exists {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package slug_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/r3d5un/islandwind/internal/slug"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"Simple", "Hello World", "hello-world"},
+		{"Punctuation", "  Go: Tips, Tricks & More!  ", "go-tips-tricks-and-more"},
+		{"Diacritics", "Crème Brûlée à la Carte", "creme-brulee-a-la-carte"},
+		{"Nordic", "Blåbærsyltetøy", "blabaersyltetoy"},
+		{"German", "Straße", "strasse"},
+		{"Cyrillic", "Привет мир", "privet-mir"},
+		{"Digits", "PostgreSQL 17 Release", "postgresql-17-release"},
+		{"Empty", "", slug.Fallback},
+		{"Symbols", "!!! ???", slug.Fallback},
+		{"Unsupported", "日本語", slug.Fallback},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, slug.Make(tc.text))
+		})
+	}
+
+	t.Run("Truncate", func(t *testing.T) {
+		s := slug.Make(strings.Repeat("lorem ipsum ", 20))
+		assert.LessOrEqual(t, len(s), slug.MaxLength)
+		assert.False(t, strings.HasSuffix(s, "-"))
+		assert.True(t, strings.HasPrefix(s, "lorem-ipsum-"))
+	})
+}
+
+func TestUnique(t *testing.T) {
+	assert.Equal(t, "post", slug.Unique("post", nil))
+	assert.Equal(t, "post-2", slug.Unique("post", []string{"post"}))
+	assert.Equal(t, "post-4", slug.Unique("post", []string{"post", "post-2", "post-3", "post-10"}))
+}
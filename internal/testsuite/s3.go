@@ -0,0 +1,81 @@
+package testsuite
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// S3Object is an object stored by the S3Server.
+type S3Object struct {
+	Body        []byte
+	ContentType string
+}
+
+// S3Server is an in-memory stand-in for an S3-compatible object storage service, supporting
+// path-style PutObject, GetObject and DeleteObject requests. Requests must carry a Signature
+// Version 4 Authorization header for the configured access key, but signatures are not
+// verified.
+type S3Server struct {
+	*httptest.Server
+	AccessKeyID string
+
+	mu      sync.Mutex
+	objects map[string]S3Object
+}
+
+// NewS3Server starts a new S3Server. The caller must call Close when done.
+func NewS3Server(accessKeyID string) *S3Server {
+	s := &S3Server{AccessKeyID: accessKeyID, objects: make(map[string]S3Object)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Object returns the object stored at the given path, e.g. "/bucket/key".
+func (s *S3Server) Object(path string) (S3Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[path]
+	return obj, ok
+}
+
+func (s *S3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "AWS4-HMAC-SHA256 Credential=" + s.AccessKeyID + "/"
+	if !strings.HasPrefix(r.Header.Get("Authorization"), prefix) ||
+		r.Header.Get("X-Amz-Date") == "" ||
+		r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = S3Object{Body: body, ContentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		obj, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.ContentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(obj.Body)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,143 @@
+package testsuite
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// SMTPMessage is a message received by the SMTPServer.
+type SMTPMessage struct {
+	From string
+	To   []string
+	Data []byte
+	// Username and Password are the PLAIN credentials the client authenticated with, if any.
+	Username string
+	Password string
+}
+
+// SMTPServer is an in-memory stand-in for an SMTP server, listening on localhost without TLS.
+// It advertises the PLAIN authentication mechanism and accepts any credentials.
+type SMTPServer struct {
+	Host string
+	Port int
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []SMTPMessage
+}
+
+// NewSMTPServer starts a new SMTPServer. The caller must call Close when done.
+func NewSMTPServer() *SMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("testsuite: failed to listen on a port: " + err.Error())
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &SMTPServer{Host: addr.IP.String(), Port: addr.Port, listener: listener}
+
+	s.wg.Go(s.serve)
+	return s
+}
+
+// Messages returns the messages received so far.
+func (s *SMTPServer) Messages() []SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMTPMessage(nil), s.messages...)
+}
+
+// Close stops accepting connections, and waits for open connections to close.
+func (s *SMTPServer) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *SMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Go(func() { s.handle(conn) })
+	}
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	c := textproto.NewConn(conn)
+	defer func() { _ = c.Close() }()
+
+	var msg SMTPMessage
+	reply := func(format string, args ...any) bool {
+		return c.PrintfLine(format, args...) == nil
+	}
+	if !reply("220 localhost ESMTP") {
+		return
+	}
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		var ok bool
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = reply("250-localhost") && reply("250 AUTH PLAIN")
+		case "HELO", "NOOP":
+			ok = reply("250 OK")
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(arg, " ")
+			credentials, err := base64.StdEncoding.DecodeString(response)
+			parts := bytes.Split(credentials, []byte{0})
+			if !strings.EqualFold(mechanism, "PLAIN") || err != nil || len(parts) != 3 {
+				ok = reply("504 unsupported authentication")
+				break
+			}
+			msg.Username, msg.Password = string(parts[1]), string(parts[2])
+			ok = reply("235 authenticated")
+		case "MAIL":
+			msg.From = smtpPath(arg)
+			ok = reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, smtpPath(arg))
+			ok = reply("250 OK")
+		case "DATA":
+			if !reply("354 end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			msg.Data, err = c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = SMTPMessage{Username: msg.Username, Password: msg.Password}
+			ok = reply("250 OK")
+		case "RSET":
+			msg = SMTPMessage{Username: msg.Username, Password: msg.Password}
+			ok = reply("250 OK")
+		case "QUIT":
+			_ = reply("221 bye")
+			return
+		default:
+			ok = reply("502 command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// smtpPath returns the address of a MAIL FROM:<address> or RCPT TO:<address> argument.
+func smtpPath(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
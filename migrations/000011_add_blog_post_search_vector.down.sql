@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS blog.idx_blog_post_search_vector;
+DROP TRIGGER IF EXISTS trigger_blog_post_search_vector ON blog.post;
+DROP FUNCTION IF EXISTS update_blog_post_search_vector();
+ALTER TABLE blog.post
+    DROP COLUMN IF EXISTS search_vector;
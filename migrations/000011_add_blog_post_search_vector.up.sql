@@ -0,0 +1,28 @@
+ALTER TABLE blog.post
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE OR REPLACE FUNCTION update_blog_post_search_vector()
+    RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.search_vector =
+            setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+            setweight(to_tsvector('english', COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_blog_post_search_vector ON blog.post;
+
+CREATE TRIGGER trigger_blog_post_search_vector
+    BEFORE INSERT OR UPDATE OF title, content ON blog.post
+    FOR EACH ROW
+EXECUTE PROCEDURE update_blog_post_search_vector();
+
+UPDATE blog.post
+SET search_vector =
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(content, '')), 'B');
+
+CREATE INDEX IF NOT EXISTS idx_blog_post_search_vector
+    ON blog.post USING GIN (search_vector);
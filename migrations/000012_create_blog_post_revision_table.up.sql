@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS blog.post_revision
+(
+    id         UUID        DEFAULT gen_random_uuid(),
+    post_id    UUID                      NOT NULL,
+    revision   INTEGER                   NOT NULL,
+    title      VARCHAR(1024)             NOT NULL,
+    content    TEXT                      NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_blog_post_revision_id PRIMARY KEY (id),
+    CONSTRAINT fk_blog_post_revision_post_id FOREIGN KEY (post_id)
+        REFERENCES blog.post (id) ON DELETE CASCADE,
+    CONSTRAINT uq_blog_post_revision_post_id_revision UNIQUE (post_id, revision)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS blog.post_tag;
+DROP TABLE IF EXISTS blog.tag;
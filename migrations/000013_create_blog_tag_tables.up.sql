@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS blog.tag
+(
+    id         UUID        DEFAULT gen_random_uuid(),
+    name       VARCHAR(128)              NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_blog_tag_id PRIMARY KEY (id),
+    CONSTRAINT uq_blog_tag_name UNIQUE (name),
+    CONSTRAINT ck_not_empty_tag_name CHECK ( name <> '' )
+);
+
+CREATE TABLE IF NOT EXISTS blog.post_tag
+(
+    post_id UUID NOT NULL,
+    tag_id  UUID NOT NULL,
+    CONSTRAINT pk_blog_post_tag PRIMARY KEY (post_id, tag_id),
+    CONSTRAINT fk_blog_post_tag_post_id FOREIGN KEY (post_id)
+        REFERENCES blog.post (id) ON DELETE CASCADE,
+    CONSTRAINT fk_blog_post_tag_tag_id FOREIGN KEY (tag_id)
+        REFERENCES blog.tag (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blog_post_tag_tag_id ON blog.post_tag (tag_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS blog.post_slug_history;
+
+ALTER TABLE blog.post
+    DROP CONSTRAINT IF EXISTS ck_not_empty_slug,
+    DROP CONSTRAINT IF EXISTS uq_blog_post_slug,
+    DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,53 @@
+ALTER TABLE blog.post
+    ADD COLUMN IF NOT EXISTS slug VARCHAR(128);
+
+-- Backfill existing posts. New slugs are generated by the application, which also handles
+-- transliteration of non-ASCII characters. Posts are numbered in the order they were created,
+-- and the suffix is increased until the slug is unused, as a suffixed slug may equal the base
+-- slug of another post.
+DO
+$$
+    DECLARE
+        p         RECORD;
+        base      VARCHAR(128);
+        candidate VARCHAR(128);
+        n         INTEGER;
+    BEGIN
+        FOR p IN SELECT id, title FROM blog.post WHERE slug IS NULL ORDER BY created_at, id
+            LOOP
+                base := COALESCE(
+                        NULLIF(
+                                TRIM(BOTH '-' FROM
+                                     LEFT(REGEXP_REPLACE(LOWER(p.title), '[^a-z0-9]+', '-', 'g'),
+                                          96)),
+                                ''),
+                        'post');
+                candidate := base;
+                n := 1;
+                WHILE EXISTS (SELECT 1 FROM blog.post WHERE slug = candidate)
+                    LOOP
+                        n := n + 1;
+                        candidate := base || '-' || n;
+                    END LOOP;
+                UPDATE blog.post SET slug = candidate WHERE id = p.id;
+            END LOOP;
+    END
+$$;
+
+ALTER TABLE blog.post
+    ALTER COLUMN slug SET NOT NULL,
+    ADD CONSTRAINT uq_blog_post_slug UNIQUE (slug),
+    ADD CONSTRAINT ck_not_empty_slug CHECK ( slug <> '' );
+
+CREATE TABLE IF NOT EXISTS blog.post_slug_history
+(
+    slug       VARCHAR(128)              NOT NULL,
+    post_id    UUID                      NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_blog_post_slug_history PRIMARY KEY (slug),
+    CONSTRAINT fk_blog_post_slug_history_post_id FOREIGN KEY (post_id)
+        REFERENCES blog.post (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blog_post_slug_history_post_id
+    ON blog.post_slug_history (post_id);
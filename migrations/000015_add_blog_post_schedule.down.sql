@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS blog.idx_blog_post_unpublish_at;
+DROP INDEX IF EXISTS blog.idx_blog_post_publish_at;
+
+ALTER TABLE blog.post
+    DROP CONSTRAINT IF EXISTS ck_blog_post_schedule,
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
@@ -0,0 +1,14 @@
+ALTER TABLE blog.post
+    ADD COLUMN IF NOT EXISTS publish_at   TIMESTAMPTZ DEFAULT NULL NULL,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ DEFAULT NULL NULL,
+    ADD CONSTRAINT ck_blog_post_schedule
+        CHECK ( publish_at IS NULL OR unpublish_at IS NULL OR publish_at < unpublish_at );
+
+-- Partial indexes used by the publishing scheduler to find due posts.
+CREATE INDEX IF NOT EXISTS idx_blog_post_publish_at
+    ON blog.post (publish_at)
+    WHERE publish_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_blog_post_unpublish_at
+    ON blog.post (unpublish_at)
+    WHERE unpublish_at IS NOT NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS media.asset
+(
+    id           UUID        DEFAULT gen_random_uuid(),
+    filename     VARCHAR(255)              NOT NULL,
+    content_type VARCHAR(128)              NOT NULL,
+    size_bytes   BIGINT                    NOT NULL,
+    sha256       CHAR(64)                  NOT NULL,
+    storage_key  VARCHAR(512)              NOT NULL,
+    width        INTEGER                   NULL,
+    height       INTEGER                   NULL,
+    created_at   TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_media_asset_id PRIMARY KEY (id),
+    CONSTRAINT uq_media_asset_sha256 UNIQUE (sha256),
+    CONSTRAINT uq_media_asset_storage_key UNIQUE (storage_key),
+    CONSTRAINT ck_media_asset_size_bytes CHECK ( size_bytes >= 0 )
+);
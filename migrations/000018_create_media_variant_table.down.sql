@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS media.variant;
+
+DROP INDEX IF EXISTS media.idx_media_asset_variants_pending;
+
+ALTER TABLE media.asset
+    DROP COLUMN IF EXISTS variants_generated_at;
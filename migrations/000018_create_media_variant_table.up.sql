@@ -0,0 +1,25 @@
+ALTER TABLE media.asset
+    ADD COLUMN IF NOT EXISTS variants_generated_at TIMESTAMPTZ DEFAULT NULL NULL;
+
+-- Partial index used by the variant worker to find assets without generated variants.
+CREATE INDEX IF NOT EXISTS idx_media_asset_variants_pending
+    ON media.asset (created_at)
+    WHERE variants_generated_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS media.variant
+(
+    id           UUID        DEFAULT gen_random_uuid(),
+    asset_id     UUID                      NOT NULL,
+    width        INTEGER                   NOT NULL,
+    height       INTEGER                   NOT NULL,
+    content_type VARCHAR(128)              NOT NULL,
+    size_bytes   BIGINT                    NOT NULL,
+    storage_key  VARCHAR(512)              NOT NULL,
+    created_at   TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_media_variant_id PRIMARY KEY (id),
+    CONSTRAINT fk_media_variant_asset_id FOREIGN KEY (asset_id)
+        REFERENCES media.asset (id) ON DELETE CASCADE,
+    CONSTRAINT uq_media_variant_asset_id_width UNIQUE (asset_id, width),
+    CONSTRAINT uq_media_variant_storage_key UNIQUE (storage_key),
+    CONSTRAINT ck_media_variant_size CHECK ( width > 0 AND height > 0 AND size_bytes >= 0 )
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trigger_blog_comment_timestamp_on_update ON blog.comment;
+
+DROP TABLE IF EXISTS blog.comment;
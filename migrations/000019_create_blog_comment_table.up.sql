@@ -0,0 +1,43 @@
+-- Comment IDs are time ordered, so that keyset pagination on the ID lists comments in the order
+-- they were written.
+CREATE TABLE IF NOT EXISTS blog.comment
+(
+    id           UUID         DEFAULT uuidv7(),
+    post_id      UUID                             NOT NULL,
+    parent_id    UUID         DEFAULT NULL        NULL,
+    author_name  VARCHAR(128)                     NOT NULL,
+    author_email VARCHAR(320) DEFAULT NULL        NULL,
+    content      TEXT                             NOT NULL,
+    status       VARCHAR(16)  DEFAULT 'pending'   NOT NULL,
+    created_at   TIMESTAMPTZ  DEFAULT NOW()       NOT NULL,
+    updated_at   TIMESTAMPTZ  DEFAULT NOW()       NOT NULL,
+    moderated_at TIMESTAMPTZ  DEFAULT NULL        NULL,
+    CONSTRAINT pk_blog_comment_id PRIMARY KEY (id),
+    CONSTRAINT fk_blog_comment_post_id FOREIGN KEY (post_id)
+        REFERENCES blog.post (id) ON DELETE CASCADE,
+    CONSTRAINT fk_blog_comment_parent_id FOREIGN KEY (parent_id)
+        REFERENCES blog.comment (id) ON DELETE CASCADE,
+    CONSTRAINT ck_blog_comment_status CHECK ( status IN ('pending', 'approved', 'rejected') ),
+    CONSTRAINT ck_blog_comment_not_empty_author_name CHECK ( author_name <> '' ),
+    CONSTRAINT ck_blog_comment_not_empty_content CHECK ( content <> '' ),
+    CONSTRAINT ck_blog_comment_parent_id CHECK ( parent_id IS NULL OR parent_id <> id )
+);
+
+CREATE INDEX IF NOT EXISTS idx_blog_comment_post_id_status
+    ON blog.comment (post_id, status, id);
+
+CREATE INDEX IF NOT EXISTS idx_blog_comment_parent_id
+    ON blog.comment (parent_id)
+    WHERE parent_id IS NOT NULL;
+
+-- Partial index used to list the moderation queue.
+CREATE INDEX IF NOT EXISTS idx_blog_comment_pending
+    ON blog.comment (id)
+    WHERE status = 'pending';
+
+DROP TRIGGER IF EXISTS trigger_blog_comment_timestamp_on_update ON blog.comment;
+
+CREATE TRIGGER trigger_blog_comment_timestamp_on_update
+    BEFORE UPDATE ON blog.comment
+    FOR EACH ROW
+EXECUTE PROCEDURE update_blog_post_updated_at_timestamp();
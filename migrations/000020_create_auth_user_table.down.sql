@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS auth.idx_refresh_token_user_id;
+ALTER TABLE auth.refresh_token
+    DROP CONSTRAINT IF EXISTS fk_refresh_token_user_id,
+    DROP COLUMN IF EXISTS user_id;
+
+DROP TRIGGER IF EXISTS trigger_auth_user_timestamp_on_update ON auth.user;
+DROP FUNCTION IF EXISTS update_auth_user_updated_at_timestamp();
+DROP TABLE IF EXISTS auth.user;
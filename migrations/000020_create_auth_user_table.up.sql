@@ -0,0 +1,45 @@
+-- Users authenticate with a username and password. Only the argon2id hash of the password is
+-- stored, encoded in the PHC string format.
+CREATE TABLE IF NOT EXISTS auth.user
+(
+    id            UUID         DEFAULT uuidv7(),
+    username      VARCHAR(128)                   NOT NULL,
+    password_hash VARCHAR(256)                   NOT NULL,
+    disabled      BOOLEAN      DEFAULT FALSE     NOT NULL,
+    created_at    TIMESTAMPTZ  DEFAULT NOW()     NOT NULL,
+    updated_at    TIMESTAMPTZ  DEFAULT NOW()     NOT NULL,
+    last_login_at TIMESTAMPTZ  DEFAULT NULL      NULL,
+    CONSTRAINT pk_auth_user_id PRIMARY KEY (id),
+    CONSTRAINT ck_auth_user_not_empty_username CHECK ( username <> '' )
+);
+
+-- Usernames are unique regardless of case.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_auth_user_username
+    ON auth.user (LOWER(username));
+
+CREATE OR REPLACE FUNCTION update_auth_user_updated_at_timestamp()
+    RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_auth_user_timestamp_on_update ON auth.user;
+
+CREATE TRIGGER trigger_auth_user_timestamp_on_update
+    BEFORE UPDATE ON auth.user
+    FOR EACH ROW
+EXECUTE PROCEDURE update_auth_user_updated_at_timestamp();
+
+-- Refresh tokens are issued to a user. Tokens issued before user accounts existed have no
+-- user, and can no longer be refreshed.
+ALTER TABLE auth.refresh_token
+    ADD COLUMN IF NOT EXISTS user_id UUID DEFAULT NULL NULL,
+    ADD CONSTRAINT fk_refresh_token_user_id FOREIGN KEY (user_id)
+        REFERENCES auth.user (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user_id
+    ON auth.refresh_token (user_id)
+    WHERE user_id IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE auth.user
+    DROP CONSTRAINT IF EXISTS ck_auth_user_role,
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,8 @@
+-- Existing users had full access before roles were introduced, so they are made admins. New
+-- users are authors unless another role is given.
+ALTER TABLE auth.user
+    ADD COLUMN IF NOT EXISTS role VARCHAR(32) DEFAULT 'admin' NOT NULL,
+    ADD CONSTRAINT ck_auth_user_role CHECK ( role IN ('admin', 'editor', 'author') );
+
+ALTER TABLE auth.user
+    ALTER COLUMN role SET DEFAULT 'author';
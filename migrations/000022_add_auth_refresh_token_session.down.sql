@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS auth.idx_refresh_token_session_id;
+
+ALTER TABLE auth.refresh_token
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS label,
+    DROP COLUMN IF EXISTS client_ip,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS session_id;
@@ -0,0 +1,17 @@
+-- A session is a refresh token family, i.e. the token issued at login and the tokens replacing
+-- it. Each existing token is its own session.
+ALTER TABLE auth.refresh_token
+    ADD COLUMN IF NOT EXISTS session_id   UUID         NULL,
+    ADD COLUMN IF NOT EXISTS user_agent   VARCHAR(512) NULL,
+    ADD COLUMN IF NOT EXISTS client_ip    VARCHAR(64)  NULL,
+    ADD COLUMN IF NOT EXISTS label        VARCHAR(128) NULL,
+    ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ  NULL;
+
+UPDATE auth.refresh_token
+SET session_id = id
+WHERE session_id IS NULL;
+
+ALTER TABLE auth.refresh_token
+    ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_session_id ON auth.refresh_token (session_id);
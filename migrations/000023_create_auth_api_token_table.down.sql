@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS auth.idx_auth_api_token_user_id;
+DROP TABLE IF EXISTS auth.api_token;
@@ -0,0 +1,22 @@
+-- Personal access tokens authenticate automation, e.g. CI publishing posts, without the login
+-- and refresh flow. Only the SHA-256 hash of the token is stored; the token itself is shown
+-- once when created.
+CREATE TABLE IF NOT EXISTS auth.api_token
+(
+    id           UUID         DEFAULT uuidv7(),
+    user_id      UUID                           NOT NULL,
+    name         VARCHAR(128)                   NOT NULL,
+    token_hash   VARCHAR(64)                    NOT NULL,
+    scopes       TEXT[]       DEFAULT '{}'      NOT NULL,
+    created_at   TIMESTAMPTZ  DEFAULT NOW()     NOT NULL,
+    expires_at   TIMESTAMPTZ  DEFAULT NULL      NULL,
+    last_used_at TIMESTAMPTZ  DEFAULT NULL      NULL,
+    revoked_at   TIMESTAMPTZ  DEFAULT NULL      NULL,
+    CONSTRAINT pk_auth_api_token_id PRIMARY KEY (id),
+    CONSTRAINT fk_auth_api_token_user_id FOREIGN KEY (user_id)
+        REFERENCES auth.user (id) ON DELETE CASCADE,
+    CONSTRAINT uq_auth_api_token_token_hash UNIQUE (token_hash),
+    CONSTRAINT ck_auth_api_token_not_empty_name CHECK ( name <> '' )
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_api_token_user_id ON auth.api_token (user_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS auth.recovery_code;
+
+ALTER TABLE auth.user
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_enabled,
+    DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1,23 @@
+-- Users may enrol an RFC 6238 TOTP authenticator. The secret is stored when enrolment starts,
+-- and totp_enabled is set once the user has confirmed a code. The secret must be readable to
+-- verify codes, so unlike passwords it is not hashed. totp_last_step is the time step of the
+-- last accepted code, so that a code cannot be used twice.
+ALTER TABLE auth.user
+    ADD COLUMN IF NOT EXISTS totp_secret    VARCHAR(128) DEFAULT NULL  NULL,
+    ADD COLUMN IF NOT EXISTS totp_enabled   BOOLEAN      DEFAULT FALSE NOT NULL,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT       DEFAULT NULL  NULL;
+
+-- Recovery codes replace a TOTP code when the authenticator is lost. Each code can be used
+-- once, and only the SHA-256 hash of the code is stored.
+CREATE TABLE IF NOT EXISTS auth.recovery_code
+(
+    id         UUID        DEFAULT uuidv7(),
+    user_id    UUID                      NOT NULL,
+    code_hash  VARCHAR(64)               NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    used_at    TIMESTAMPTZ DEFAULT NULL  NULL,
+    CONSTRAINT pk_auth_recovery_code_id PRIMARY KEY (id),
+    CONSTRAINT fk_auth_recovery_code_user_id FOREIGN KEY (user_id)
+        REFERENCES auth.user (id) ON DELETE CASCADE,
+    CONSTRAINT uq_auth_recovery_code_user_id_code_hash UNIQUE (user_id, code_hash)
+);
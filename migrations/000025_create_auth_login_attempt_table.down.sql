@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS auth.idx_auth_login_attempt_blocked_until;
+DROP TABLE IF EXISTS auth.login_attempt;
@@ -0,0 +1,18 @@
+-- Failed login attempts are counted per username, per client IP address and per user
+-- completing a TOTP challenge, so that guessing is throttled across all instances of the
+-- application. Attempts are delayed until blocked_until, and locked is set when the failures
+-- reach the lockout threshold. Rows are deleted on successful logins, or by administrators.
+CREATE TABLE IF NOT EXISTS auth.login_attempt
+(
+    kind            VARCHAR(16)                NOT NULL,
+    key             VARCHAR(256)               NOT NULL,
+    failures        INTEGER     DEFAULT 0      NOT NULL,
+    last_failure_at TIMESTAMPTZ DEFAULT NOW()  NOT NULL,
+    blocked_until   TIMESTAMPTZ DEFAULT NULL   NULL,
+    locked          BOOLEAN     DEFAULT FALSE  NOT NULL,
+    CONSTRAINT pk_auth_login_attempt_kind_key PRIMARY KEY (kind, key),
+    CONSTRAINT ck_auth_login_attempt_kind CHECK ( kind IN ('username', 'ip', 'totp') )
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_login_attempt_blocked_until
+    ON auth.login_attempt (blocked_until);
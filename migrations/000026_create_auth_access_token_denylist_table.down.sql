@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS auth.idx_auth_access_token_denylist_expires_at;
+DROP TABLE IF EXISTS auth.access_token_denylist;
@@ -0,0 +1,12 @@
+-- Access tokens are not stored when issued. Revoking an access token stores its jti until the
+-- token expires, after which the token is rejected regardless, and the row can be deleted.
+CREATE TABLE IF NOT EXISTS auth.access_token_denylist
+(
+    jti        UUID                      NOT NULL,
+    expires_at TIMESTAMPTZ               NOT NULL,
+    revoked_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_auth_access_token_denylist_jti PRIMARY KEY (jti)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_access_token_denylist_expires_at
+    ON auth.access_token_denylist (expires_at);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trigger_auth_access_token_denylist_notify_on_insert
+    ON auth.access_token_denylist;
+DROP FUNCTION IF EXISTS auth.notify_access_token_denylist();
@@ -0,0 +1,25 @@
+-- Every instance keeps the denylist in memory, and listens on the channel to learn of access
+-- tokens revoked by other instances.
+CREATE OR REPLACE FUNCTION auth.notify_access_token_denylist()
+    RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify(
+        'auth_access_token_denylist',
+        json_build_object(
+            'jti', NEW.jti,
+            'expiresAt', NEW.expires_at,
+            'revokedAt', NEW.revoked_at
+        )::TEXT
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_auth_access_token_denylist_notify_on_insert
+    ON auth.access_token_denylist;
+
+CREATE TRIGGER trigger_auth_access_token_denylist_notify_on_insert
+    AFTER INSERT ON auth.access_token_denylist
+    FOR EACH ROW
+EXECUTE PROCEDURE auth.notify_access_token_denylist();
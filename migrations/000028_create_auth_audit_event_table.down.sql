@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trigger_auth_audit_event_reject_update ON auth.audit_event;
+DROP FUNCTION IF EXISTS auth.reject_audit_event_update();
+DROP INDEX IF EXISTS auth.idx_auth_audit_event_token_ids;
+DROP INDEX IF EXISTS auth.idx_auth_audit_event_actor_id;
+DROP INDEX IF EXISTS auth.idx_auth_audit_event_occurred_at;
+DROP TABLE IF EXISTS auth.audit_event;
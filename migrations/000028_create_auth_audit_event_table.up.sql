@@ -0,0 +1,40 @@
+-- Security relevant events of the auth module, e.g. logins and logouts. Events are only ever
+-- inserted, and deleted by the retention job once they are older than the retention period.
+-- The IDs are UUIDv7, so that they are ordered by the time the events occurred.
+CREATE TABLE IF NOT EXISTS auth.audit_event
+(
+    id          UUID                              DEFAULT uuidv7(),
+    occurred_at TIMESTAMPTZ                       DEFAULT NOW() NOT NULL,
+    event_type  VARCHAR(64)                       NOT NULL,
+    outcome     VARCHAR(16)                       NOT NULL,
+    actor_id    UUID                              NULL,
+    client_ip   VARCHAR(64)                       NULL,
+    user_agent  VARCHAR(512)                      NULL,
+    session_id  UUID                              NULL,
+    token_ids   UUID[]      DEFAULT '{}'::UUID[]  NOT NULL,
+    details     JSONB       DEFAULT '{}'::JSONB   NOT NULL,
+    CONSTRAINT pk_auth_audit_event_id PRIMARY KEY (id),
+    CONSTRAINT ck_auth_audit_event_outcome CHECK ( outcome IN ('success', 'failure') )
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_event_occurred_at
+    ON auth.audit_event (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_event_actor_id
+    ON auth.audit_event (actor_id);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_event_token_ids
+    ON auth.audit_event USING GIN (token_ids);
+
+CREATE OR REPLACE FUNCTION auth.reject_audit_event_update()
+    RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'auth.audit_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_auth_audit_event_reject_update ON auth.audit_event;
+
+CREATE TRIGGER trigger_auth_audit_event_reject_update
+    BEFORE UPDATE ON auth.audit_event
+    FOR EACH ROW
+EXECUTE PROCEDURE auth.reject_audit_event_update();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS auth.uq_auth_user_lower_email;
+
+ALTER TABLE auth.user
+    DROP COLUMN IF EXISTS email_verified_at,
+    DROP COLUMN IF EXISTS email;
@@ -0,0 +1,8 @@
+-- Users may add an email address to reset their password by email. The address is unverified
+-- until the user follows the link sent to it, and changing the address clears the
+-- verification. Addresses are unique regardless of case.
+ALTER TABLE auth.user
+    ADD COLUMN IF NOT EXISTS email             VARCHAR(254) DEFAULT NULL NULL,
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ  DEFAULT NULL NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_auth_user_lower_email ON auth.user (LOWER(email));
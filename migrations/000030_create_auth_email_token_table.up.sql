@@ -0,0 +1,24 @@
+-- Email tokens are sent by email to reset the password of a user or to verify the email
+-- address of a user. Each token can be used once before it expires, and only the SHA-256 hash
+-- of the token is stored. Issuing a token deletes the earlier tokens of the user for the same
+-- purpose, so that only the latest email is valid.
+CREATE TABLE IF NOT EXISTS auth.email_token
+(
+    id         UUID        DEFAULT uuidv7(),
+    user_id    UUID                      NOT NULL,
+    purpose    VARCHAR(32)               NOT NULL,
+    token_hash VARCHAR(64)               NOT NULL,
+    -- email is the address the token was sent to. Verification tokens only verify the address
+    -- if the user has not changed it since.
+    email      VARCHAR(254)              NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    expires_at TIMESTAMPTZ               NOT NULL,
+    used_at    TIMESTAMPTZ DEFAULT NULL  NULL,
+    CONSTRAINT pk_auth_email_token_id PRIMARY KEY (id),
+    CONSTRAINT fk_auth_email_token_user_id FOREIGN KEY (user_id)
+        REFERENCES auth.user (id) ON DELETE CASCADE,
+    CONSTRAINT uq_auth_email_token_token_hash UNIQUE (token_hash),
+    CONSTRAINT ck_auth_email_token_purpose CHECK ( purpose IN ('password_reset', 'email_verification') )
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_email_token_user_id ON auth.email_token (user_id);
@@ -0,0 +1,18 @@
+-- User identities link users to the subjects of external OpenID Connect providers, so that
+-- users logging in with a provider are found by the issuer and subject of their ID token
+-- rather than by a claim they may be able to change, such as their username.
+CREATE TABLE IF NOT EXISTS auth.user_identity
+(
+    id            UUID        DEFAULT uuidv7(),
+    user_id       UUID                      NOT NULL,
+    issuer        VARCHAR(2048)             NOT NULL,
+    subject       VARCHAR(255)              NOT NULL,
+    created_at    TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    last_login_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_auth_user_identity_id PRIMARY KEY (id),
+    CONSTRAINT fk_auth_user_identity_user_id FOREIGN KEY (user_id)
+        REFERENCES auth.user (id) ON DELETE CASCADE,
+    CONSTRAINT uq_auth_user_identity_issuer_subject UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_user_identity_user_id ON auth.user_identity (user_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS auth.idx_refresh_token_expiration;
@@ -0,0 +1,2 @@
+-- Expired refresh tokens are deleted in batches, oldest first.
+CREATE INDEX IF NOT EXISTS idx_refresh_token_expiration ON auth.refresh_token (expiration);
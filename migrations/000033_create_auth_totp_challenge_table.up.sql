@@ -0,0 +1,16 @@
+-- TOTP challenges are issued to users with two-factor authentication who have given the
+-- correct password. The ID is the jti claim of the signed challenge token, and is marked as
+-- used when the TOTP or recovery code is accepted, so that each challenge logs in once.
+CREATE TABLE IF NOT EXISTS auth.totp_challenge
+(
+    id         UUID                      NOT NULL,
+    user_id    UUID                      NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    expires_at TIMESTAMPTZ               NOT NULL,
+    used_at    TIMESTAMPTZ DEFAULT NULL  NULL,
+    CONSTRAINT pk_auth_totp_challenge_id PRIMARY KEY (id),
+    CONSTRAINT fk_auth_totp_challenge_user_id FOREIGN KEY (user_id)
+        REFERENCES auth.user (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_totp_challenge_user_id ON auth.totp_challenge (user_id);
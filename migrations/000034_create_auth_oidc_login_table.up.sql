@@ -0,0 +1,19 @@
+-- OIDC logins hold the state, nonce and PKCE code verifier of a login with the OpenID Connect
+-- provider until the provider redirects back, so that the verifier never leaves the server.
+-- The browser keeps a random login token, and only the SHA-256 hash of the token is stored.
+-- Each login is deleted when it is completed, and expired logins are deleted as new logins
+-- begin.
+CREATE TABLE IF NOT EXISTS auth.oidc_login
+(
+    id         UUID        DEFAULT uuidv7(),
+    token_hash VARCHAR(64)               NOT NULL,
+    state      VARCHAR(64)               NOT NULL,
+    nonce      VARCHAR(64)               NOT NULL,
+    verifier   VARCHAR(128)              NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    expires_at TIMESTAMPTZ               NOT NULL,
+    CONSTRAINT pk_auth_oidc_login_id PRIMARY KEY (id),
+    CONSTRAINT uq_auth_oidc_login_token_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_oidc_login_expires_at ON auth.oidc_login (expires_at);
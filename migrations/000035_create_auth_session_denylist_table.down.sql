@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trigger_auth_session_denylist_notify_on_insert
+    ON auth.session_denylist;
+DROP FUNCTION IF EXISTS auth.notify_session_denylist();
+DROP INDEX IF EXISTS auth.idx_auth_session_denylist_expires_at;
+DROP TABLE IF EXISTS auth.session_denylist;
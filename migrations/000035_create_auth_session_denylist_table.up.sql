@@ -0,0 +1,38 @@
+-- Access tokens carry their session in the sid claim. Revoking a session stores its ID until
+-- the access tokens issued for the session have expired, denying them all without storing
+-- the tokens. Every instance keeps the session denylist in memory next to the access token
+-- denylist, and listens on the channel to learn of sessions revoked by other instances.
+CREATE TABLE IF NOT EXISTS auth.session_denylist
+(
+    session_id UUID                      NOT NULL,
+    expires_at TIMESTAMPTZ               NOT NULL,
+    revoked_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT pk_auth_session_denylist_session_id PRIMARY KEY (session_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_session_denylist_expires_at
+    ON auth.session_denylist (expires_at);
+
+CREATE OR REPLACE FUNCTION auth.notify_session_denylist()
+    RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify(
+        'auth_session_denylist',
+        json_build_object(
+            'sessionId', NEW.session_id,
+            'expiresAt', NEW.expires_at,
+            'revokedAt', NEW.revoked_at
+        )::TEXT
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_auth_session_denylist_notify_on_insert
+    ON auth.session_denylist;
+
+CREATE TRIGGER trigger_auth_session_denylist_notify_on_insert
+    AFTER INSERT ON auth.session_denylist
+    FOR EACH ROW
+EXECUTE PROCEDURE auth.notify_session_denylist();
@@ -5,7 +5,7 @@ import { logger } from '@/ui/logging.ts'
 
 export const useAuthStore = defineStore('tokens', {
   state: () => ({
-    tokens: new Tokens({ accessToken: '', refreshToken: '' }),
+    tokens: new Tokens({ accessToken: '' }),
     loggedIn: false,
     loading: false,
     logger: logger,
@@ -32,16 +32,22 @@ export const useAuthStore = defineStore('tokens', {
   },
 })
 
+// Browsers should leave cookie set, which keeps the refresh token in an HttpOnly cookie out of
+// reach of scripts. Other clients, such as tests running in Node, receive the refresh token in
+// the response body instead.
 export async function login(
   username: string,
   password: string,
+  cookie: boolean = true,
 ): Promise<Tokens | RequestFailureError> {
   try {
     const response: AxiosResponse<ITokens, number> = await axios({
       method: 'post',
       url: `${import.meta.env.VITE_API_URL}/api/v1/auth/login`,
+      params: cookie ? { cookie: true } : {},
       timeout: import.meta.env.VITE_API_TIMEOUT,
       auth: { username: username, password: password },
+      withCredentials: true,
     })
     return new Tokens(response.data)
   } catch (error) {
@@ -49,44 +55,67 @@ export async function login(
   }
 }
 
-export async function refresh(refreshToken: string): Promise<Tokens | RequestFailureError> {
+export async function refresh(tokens: Tokens): Promise<Tokens | RequestFailureError> {
   try {
-    const response: AxiosResponse<ITokens, number> = await axios.post(
-      `${import.meta.env.VITE_API_URL}/api/v1/auth/refresh`,
-      new RefreshRequestBody(refreshToken),
-      { timeout: import.meta.env.VITE_API_TIMEOUT },
-    )
+    const response: AxiosResponse<ITokens, number> = await axios({
+      method: 'post',
+      url: `${import.meta.env.VITE_API_URL}/api/v1/auth/refresh`,
+      timeout: import.meta.env.VITE_API_TIMEOUT,
+      withCredentials: true,
+      ...refreshTokenRequest(tokens),
+    })
     return new Tokens(response.data)
   } catch (error) {
     return handleRequestFailure(error)
   }
 }
 
-export async function invalidateRefreshToken(refreshToken: string): Promise<void> {
+// The access token is revoked along with the refresh token, if given.
+export async function invalidateRefreshToken(tokens: Tokens): Promise<void> {
   try {
+    const request = refreshTokenRequest(tokens)
+    if (tokens.accessToken) {
+      request.headers.Authorization = `Bearer ${tokens.accessToken}`
+    }
     await axios({
       method: 'post',
       url: `${import.meta.env.VITE_API_URL}/api/v1/auth/logout`,
-      data: new RefreshRequestBody(refreshToken),
       timeout: import.meta.env.VITE_API_TIMEOUT,
+      withCredentials: true,
+      ...request,
     })
   } catch (error) {
     throw handleRequestFailure(error)
   }
 }
 
+// The refresh token is sent in the body if the client holds it. Otherwise, the browser sends
+// the refresh token cookie, which requires the CSRF token issued along with the cookie.
+function refreshTokenRequest(tokens: Tokens): {
+  data?: RefreshRequestBody
+  headers: Record<string, string>
+} {
+  if (tokens.refreshToken) {
+    return { data: new RefreshRequestBody(tokens.refreshToken), headers: {} }
+  }
+  return { headers: tokens.csrfToken ? { 'X-CSRF-Token': tokens.csrfToken } : {} }
+}
+
 export interface ITokens {
   accessToken: string
-  refreshToken: string
+  refreshToken?: string
+  csrfToken?: string
 }
 
 export class Tokens {
   public accessToken: string
-  public refreshToken: string
+  public refreshToken?: string
+  public csrfToken?: string
 
   constructor(input: ITokens) {
     this.accessToken = input.accessToken
     this.refreshToken = input.refreshToken
+    this.csrfToken = input.csrfToken
   }
 }
 
@@ -30,6 +30,17 @@ export class BlogpostApiClient {
     }
   }
 
+  public async getBySlug(slug: string): Promise<Blogpost | RequestFailureError> {
+    try {
+      const response: AxiosResponse<IBlogpostResponse, number> = await this.client.get(
+        `/api/v1/blog/post/by-slug/${encodeURIComponent(slug)}`,
+      )
+      return new Blogpost(response.data.data)
+    } catch (error) {
+      return handleRequestFailure(error)
+    }
+  }
+
   public async list(): Promise<BlogpostListResponse | RequestFailureError> {
     try {
       const response: AxiosResponse<IBlogpostListResponse, number> =
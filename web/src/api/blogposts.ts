@@ -1,5 +1,3 @@
-import { marked } from 'marked'
-
 export interface IBlogpostResponse {
   data: IBlogpost
 }
@@ -42,11 +40,22 @@ export class BlogpostListResponse {
   }
 }
 
+export interface IHeading {
+  level: number
+  id: string
+  text: string
+}
+
 export interface IBlogpost {
   id: string
   title: string
+  slug: string
   content: string
+  contentHtml: string
+  toc: IHeading[]
   published: boolean
+  publishAt: Date | null
+  unpublishAt: Date | null
   createdAt: Date
   updatedAt: Date
   deleted: boolean
@@ -56,8 +65,13 @@ export interface IBlogpost {
 export class Blogpost {
   id: string
   title: string
+  slug: string
   content: string
+  contentHtml: string
+  toc: IHeading[]
   published: boolean
+  publishAt: Date | null
+  unpublishAt: Date | null
   createdAt: Date
   updatedAt: Date
   deleted: boolean
@@ -66,16 +80,22 @@ export class Blogpost {
   constructor(blogpost: IBlogpost) {
     this.id = blogpost.id
     this.title = blogpost.title
+    this.slug = blogpost.slug
     this.content = blogpost.content
+    this.contentHtml = blogpost.contentHtml
+    this.toc = blogpost.toc ?? []
     this.published = blogpost.published
+    this.publishAt = blogpost.publishAt ? new Date(blogpost.publishAt) : null
+    this.unpublishAt = blogpost.unpublishAt ? new Date(blogpost.unpublishAt) : null
     this.createdAt = new Date(blogpost.createdAt)
     this.updatedAt = new Date(blogpost.updatedAt)
     this.deleted = blogpost.deleted
     this.deletedAt = new Date(blogpost.deletedAt)
   }
 
+  // The content is rendered and sanitized by the API.
   public async markdownContent(): Promise<string> {
-    return marked(this.content)
+    return this.contentHtml
   }
 }
 
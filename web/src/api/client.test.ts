@@ -23,6 +23,9 @@ describe('BlogpostClient', () => {
   let databaseClient: Client
 
   const baseUrl: string = 'http://localhost:14000'
+  // The initial user seeded by the backend in deployments/docker-compose.testing.yaml.
+  const username: string = 'e2e-admin'
+  const password: string = 'e2e-admin-initial-password'
   let tokens: Tokens | RequestFailureError
   const apiClient: AxiosInstance = axios.create({
     baseURL: baseUrl,
@@ -69,14 +72,14 @@ describe('BlogpostClient', () => {
 
       logger.info('Inserting test data')
       await databaseClient.query(`
-          INSERT INTO blog.post (title, content, published)
-          VALUES ('Read Me', 'Read Me', false),
-                 ('Update Me', 'Update Me', false),
-                 ('Delete Me', 'Delete Me', false);
+          INSERT INTO blog.post (title, slug, content, published)
+          VALUES ('Read Me', 'read-me', 'Read Me', false),
+                 ('Update Me', 'update-me', 'Update Me', false),
+                 ('Delete Me', 'delete-me', 'Delete Me', false);
       `)
 
       logger.info('logging in')
-      tokens = await login('islandwind', 'islandwind')
+      tokens = await login(username, password, false)
       if (!(tokens instanceof Tokens)) {
         throw tokens
       }
@@ -130,6 +133,16 @@ describe('BlogpostClient', () => {
     }
   })
 
+  it('should read a blogpost by slug', async () => {
+    const result: Blogpost | RequestFailureError = await blogpostClient.getBySlug('read-me')
+
+    expect(result).toBeInstanceOf(Blogpost)
+    if (result instanceof Blogpost) {
+      expect(result.title).toBe('Read Me')
+      expect(result.slug).toBe('read-me')
+    }
+  })
+
   it('should list blogposts', async () => {
     const result: BlogpostListResponse | RequestFailureError = await blogpostClient.list()
     expect(result).toBeInstanceOf(BlogpostListResponse)
@@ -186,13 +199,13 @@ describe('BlogpostClient', () => {
   })
 
   it('should invalidate refresh token', async () => {
-    tokens = await login('islandwind', 'islandwind')
+    tokens = await login(username, password, false)
     if (!(tokens instanceof Tokens)) {
       throw tokens
     }
 
     try {
-      await invalidateRefreshToken(tokens.refreshToken)
+      await invalidateRefreshToken(tokens)
     } catch (error) {
       logger.error('unable to invalidate refresh token', { error: error })
       throw error
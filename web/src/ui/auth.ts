@@ -1,5 +1,5 @@
 import { handleRequestFailure, type RequestFailureError } from '@/api/errors.ts'
-import { useAuthStore, invalidateRefreshToken } from '@/api/auth.ts'
+import { useAuthStore, invalidateRefreshToken, Tokens } from '@/api/auth.ts'
 import { useLogger } from '@/ui/logging.ts'
 
 export async function logout(): Promise<void | RequestFailureError> {
@@ -8,11 +8,11 @@ export async function logout(): Promise<void | RequestFailureError> {
 
   try {
     logger.info('invalidating token')
-    await invalidateRefreshToken(authStore.tokens.refreshToken)
+    await invalidateRefreshToken(authStore.tokens)
 
     logger.info('purging local tokens')
     authStore.loggedIn = false
-    authStore.tokens = { accessToken: '', refreshToken: '' }
+    authStore.tokens = new Tokens({ accessToken: '' })
   } catch (error) {
     logger.error('unable to logout', { error: error })
     return handleRequestFailure(error)
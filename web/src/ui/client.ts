@@ -45,11 +45,10 @@ apiClient.interceptors.response.use(
 
       try {
         const authStore = useAuthStore()
-        const newTokens = await refresh(authStore.tokens.refreshToken)
+        const newTokens = await refresh(authStore.tokens)
 
         if (newTokens instanceof Tokens) {
-          authStore.tokens.accessToken = newTokens.accessToken
-          authStore.tokens.refreshToken = newTokens.refreshToken
+          authStore.tokens = newTokens
         }
 
         originalRequest.headers.Authorization = `Bearer ${authStore.tokens.accessToken}`
@@ -8,7 +8,7 @@ const routes = [
   { path: '/', name: 'Home', component: HomeView },
   { path: '/login', name: 'Login', component: LoginView },
   { path: '/blog', redirect: '/' },
-  { path: '/blog/:id', name: 'BlogpostView', component: BlogpostView },
+  { path: '/blog/:slug', name: 'BlogpostView', component: BlogpostView },
   { path: '/:pathMatch(.*)*', name: 'NotFound', component: NotFoundView },
 ]
 